@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+)
+
+// apiActor is the author of changes proposed by anonymous API runs
+const apiActor = "api"
+
+// proposeChange records a push as a change waiting for approval
+func (app *Application) proposeChange(source, config string, classifications map[string]qos.Classification, opts *ExecuteOptions) (changes.Change, error) {
+	author := opts.RequestedBy
+	if author == "" {
+		author = apiActor
+	}
+	change, created, err := app.changes.Propose(changes.Proposal{
+		Source:          source,
+		RunID:           opts.Tracker.ID(),
+		Config:          config,
+		Classifications: classifications,
+		SaveConfig:      opts.SaveConfig,
+		CatalystCenter:  opts.PushCatalyst,
+	}, author)
+	if err != nil {
+		return changes.Change{}, fmt.Errorf("failed to propose change: %w", err)
+	}
+	opts.Tracker.Change(change.ID)
+
+	fields := logger.Fields{
+		"change_id":     change.ID,
+		"author":        change.Author,
+		"state":         change.State,
+		"class_changes": len(change.ClassChanges),
+		"lines_added":   change.Diff.Added,
+		"lines_removed": change.Diff.Removed,
+		"expires_at":    change.ExpiresAt,
+	}
+	if change.CatalystCenter {
+		fields["catalyst_center"] = true
+	}
+	if created {
+		app.logger.Audit("change_propose", fields)
+		app.logger.WithFields(fields).Info("Configuration push proposed, waiting for approval")
+	} else {
+		app.logger.WithFields(fields).Info("Configuration push already proposed, waiting for approval")
+	}
+	return change, nil
+}
+
+// executeChange applies or rolls back a change for an API run
+func (app *Application) executeChange(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+	if app.changes == nil {
+		return fmt.Errorf("change approval is not enabled")
+	}
+	actor := req.RequestedBy
+	if actor == "" {
+		actor = apiActor
+	}
+
+	app.executeMu.Lock()
+	defer app.executeMu.Unlock()
+
+	tracker.Stage("push")
+	tracker.Change(req.ChangeID)
+	_, pushed, err := app.pushChange(ctx, req.ChangeID, actor, req.Comment, req.Rollback, req.Emergency)
+	if err == nil && !pushed {
+		tracker.Stage("queued")
+	}
+	return err
+}
+
+// pushChange applies or rolls back a change and audits the outcome. Outside
+// a maintenance window the push is refused, or queued and reported as not
+// pushed, unless it is an emergency.
+func (app *Application) pushChange(ctx context.Context, id, actor, comment string, rollback, emergency bool) (changes.Change, bool, error) {
+	action := "change_apply"
+	push := app.changes.Apply
+	required := changes.StateApproved
+	if rollback {
+		action = "change_rollback"
+		push = app.changes.Rollback
+		required = changes.StateApplied
+	}
+
+	// Only changes that could be pushed now are queued
+	change, err := app.changes.Get(id)
+	if err != nil {
+		return change, false, err
+	}
+	if change.State != required {
+		return change, false, fmt.Errorf("change %s is %s, expected %s: %w", id, change.State, required, changes.ErrState)
+	}
+	queued := maintenance.Push{
+		ChangeID:    id,
+		Rollback:    rollback,
+		Comment:     comment,
+		RequestedBy: actor,
+	}
+	proceed, err := app.checkWindow(queued, emergency)
+	// Applying a change with a Catalyst Center push needs its window too
+	if err == nil && proceed && change.CatalystCenter && !rollback {
+		queued.Target = app.catalystTarget()
+		proceed, err = app.checkWindow(queued, emergency)
+	}
+	if err != nil || !proceed {
+		return change, false, err
+	}
+
+	change, err = push(ctx, id, actor, comment)
+	fields := logger.Fields{
+		"change_id": id,
+		"actor":     actor,
+		"host":      app.config.SSH.Host,
+		"success":   err == nil,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	app.logger.Audit(action, fields)
+	if err != nil {
+		return change, false, fmt.Errorf("failed to push change %s: %w", id, err)
+	}
+	return change, true, nil
+}
+
+// applyChange pushes a change's configuration, or the configuration that
+// restores its base. The structured transports replace whole class-maps and
+// policy-maps, so the base configuration restores them as it is; the CLI
+// only adds, so stale statements are removed first. A change proposed with
+// a Catalyst Center push then pushes its Application Policy; a rollback
+// restores the switch only.
+func (app *Application) applyChange(ctx context.Context, change changes.Change, rollback bool) error {
+	config := change.Config
+	if rollback {
+		if app.transport != nil {
+			config = change.BaseConfig
+		} else {
+			var err error
+			config, err = changes.RollbackConfig(change)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := app.handleConfigPush(ctx, config, &ExecuteOptions{
+		PushConfig: true,
+		SaveConfig: change.SaveConfig,
+		ChangeID:   change.ID,
+		Rollback:   rollback,
+	}); err != nil {
+		return err
+	}
+	if !change.CatalystCenter || rollback {
+		return nil
+	}
+	result := &output.Result{
+		Classifications: change.Classifications,
+		Model:           qos.CurrentModel(),
+		DefaultClass:    app.classifier.GetDefaultClass(),
+		GeneratedAt:     time.Now(),
+	}
+	policy, err := app.catalystCenter.Policy(result)
+	if err == nil {
+		err = app.sendCatalystPolicy(ctx, policy)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to push Catalyst Center policy: %w", err)
+	}
+	return nil
+}
+
+// cliActor identifies the user running the command line
+func cliActor() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// runChanges performs "nbar-classifier changes <command> [id]"
+func runChanges(ctx context.Context, app *Application, args []string, comment string, emergency bool, stdout io.Writer) error {
+	if app.changes == nil {
+		return fmt.Errorf("change approval is not enabled, set approval.enabled in the configuration")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: nbar-classifier changes [flags] list|show|approve|reject|apply|rollback [id]")
+	}
+
+	command := args[0]
+	if command == "list" {
+		list, err := app.changes.List()
+		if err != nil {
+			return err
+		}
+		printChanges(stdout, list)
+		return nil
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: nbar-classifier changes [flags] %s <id>", command)
+	}
+	id := args[1]
+
+	actor := cliActor()
+	var change changes.Change
+	var err error
+	switch command {
+	case "show":
+		change, err = app.changes.Get(id)
+	case "approve":
+		change, err = app.changes.Approve(id, actor, comment)
+		if err == nil {
+			app.logger.Audit("change_approve", logger.Fields{"change_id": id, "author": change.Author, "actor": actor})
+		}
+	case "reject":
+		change, err = app.changes.Reject(id, actor, comment)
+		if err == nil {
+			app.logger.Audit("change_reject", logger.Fields{"change_id": id, "actor": actor})
+		}
+	case "apply", "rollback":
+		var pushed bool
+		app.executeMu.Lock()
+		change, pushed, err = app.pushChange(ctx, id, actor, comment, command == "rollback", emergency)
+		app.executeMu.Unlock()
+		if err == nil && !pushed {
+			fmt.Fprintf(stdout, "Outside the maintenance window, %s of change %s queued\n", command, id)
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown changes command %q, expected list, show, approve, reject, apply or rollback", command)
+	}
+	if err != nil {
+		return err
+	}
+
+	printChange(stdout, change)
+	return nil
+}
+
+// printChanges writes a table of changes
+func printChanges(w io.Writer, list []changes.Change) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tSTATE\tAUTHOR\tCREATED\tEXPIRES\tCLASS CHANGES\tDIFF")
+	for _, change := range list {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%d\t+%d -%d\n",
+			change.ID, change.State, change.Author,
+			change.CreatedAt.Format(time.RFC3339), change.ExpiresAt.Format(time.RFC3339),
+			len(change.ClassChanges), change.Diff.Added, change.Diff.Removed)
+	}
+	table.Flush()
+}
+
+// printChange writes a change with its class changes, diff and history
+func printChange(w io.Writer, change changes.Change) {
+	fmt.Fprintf(w, "Change %s (%s)\n", change.ID, change.State)
+	fmt.Fprintf(w, "Author:  %s\n", change.Author)
+	if change.ApprovedBy != "" {
+		fmt.Fprintf(w, "Approved by: %s\n", change.ApprovedBy)
+	}
+	if change.AppliedBy != "" {
+		fmt.Fprintf(w, "Applied by:  %s at %s\n", change.AppliedBy, change.AppliedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintf(w, "Created: %s\n", change.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Expires: %s\n", change.ExpiresAt.Format(time.RFC3339))
+	if change.BaseID != "" {
+		fmt.Fprintf(w, "Base:    %s\n", change.BaseID)
+	}
+	if change.CatalystCenter {
+		fmt.Fprintln(w, "Also pushes the Catalyst Center Application Policy when applied")
+	}
+
+	fmt.Fprintf(w, "\nClass changes (%d):\n", len(change.ClassChanges))
+	for _, classChange := range change.ClassChanges {
+		from := string(classChange.From)
+		if from == "" {
+			from = "new"
+		}
+		fmt.Fprintf(w, "  %s: %s -> %s\n", classChange.Protocol, from, classChange.To)
+	}
+
+	fmt.Fprintf(w, "\nDiff (+%d -%d):\n", change.Diff.Added, change.Diff.Removed)
+	fmt.Fprint(w, change.Diff.Unified)
+
+	fmt.Fprintln(w, "\nHistory:")
+	for _, event := range change.History {
+		line := fmt.Sprintf("  %s  %-12s %-10s %s", event.Time.Format(time.RFC3339), event.Action, event.To, event.Actor)
+		if event.Comment != "" {
+			line += "  " + event.Comment
+		}
+		if event.Error != "" {
+			line += "  error: " + event.Error
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
@@ -2,21 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ai"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/cache"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/catalystcenter"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/input"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/metrics"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/netflow"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/notify"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rules"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/transport"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
 )
 
 // Application represents the main application
@@ -25,9 +42,29 @@ type Application struct {
 	logger     *logger.Logger
 	metrics    *metrics.Metrics
 	cache      *cache.Cache
-	sshClient  *ssh.Client
+	device     ssh.Device
+	transport  transport.Transport
 	aiManager  *ai.Manager
 	classifier *qos.Classifier
+	rules      *rules.Manager
+
+	queuingPolicy  *qos.QueuingPolicy
+	outputs        *output.Registry
+	catalystCenter *output.CatalystCenterGenerator
+	web            *web.Server
+	auth           *auth.Authenticator
+	oidc           *auth.OIDCProvider
+	runs           *runs.Manager
+	changes        *changes.Manager
+	maintenance    *maintenance.Policy
+	pushQueue      *maintenance.Queue
+	notifier       *notify.Notifier
+
+	// executeMu serializes Execute between the command line and API runs
+	executeMu   sync.Mutex
+	stopCleanup func()
+	// notifyWG tracks notifications in flight
+	notifyWG sync.WaitGroup
 }
 
 // Version information (set by build)
@@ -38,20 +75,36 @@ var (
 )
 
 func main() {
+	// "nbar-classifier serve [flags]" runs the scheduled jobs until stopped,
+	// "nbar-classifier changes [flags] <command>" manages proposed changes,
+	// "nbar-classifier rollout [flags] <command>" rolls out to the inventory
+	serveMode := len(os.Args) > 1 && os.Args[1] == "serve"
+	changesMode := len(os.Args) > 1 && os.Args[1] == "changes"
+	rolloutMode := len(os.Args) > 1 && os.Args[1] == "rollout"
+	if serveMode || changesMode || rolloutMode {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	// Parse command line flags
 	var (
 		configPath      = flag.String("config", "", "Path to configuration file")
 		showVersion     = flag.Bool("version", false, "Show version information")
 		fetchFromSwitch = flag.Bool("fetch-from-switch", false, "Fetch protocol list from switch via SSH")
-		inputFile       = flag.String("input-file", "", "Input file containing NBAR protocol list")
-		outputType      = flag.String("output", "text", "Output format: 'text' or 'cisco'")
+		inputFile       = flag.String("input-file", "", "Input file: protocol list, protocol-discovery capture, running-config, show tech or collector CSV")
+		collectNetFlow  = flag.Bool("collect-netflow", false, "Collect protocols from NetFlow v9/IPFIX AVC exports")
+		inputFormat     = flag.String("input-format", "auto", "Input file format: auto, list, protocol-discovery, running-config, show-tech, csv")
+		outputType      = flag.String("output", "text", "Output formats, comma-separated: text, cisco, json, yaml, csv, ansible, ansible-vars, napalm, catalyst-center, juniper, arista, arista-avt, meraki")
+		outputFile      = flag.String("output-file", "", "Output files matching --output by position ('-' for stdout)")
 		pushConfig      = flag.Bool("push-config", false, "Push updated config to switch via SSH")
+		pushCatalyst    = flag.Bool("push-catalyst-center", false, "Push the Application Policy to Catalyst Center")
 		dryRun          = flag.Bool("dry-run", false, "Test implementation without pushing config")
 		saveConfig      = flag.Bool("save-config", false, "Save configuration to startup-config after pushing changes")
 		batchSize       = flag.Int("batch-size", 0, "Number of protocols to analyze in each AI batch (0 = use config)")
 		logLevel        = flag.String("log-level", "", "Log level (debug, info, warn, error)")
 		enableMetrics   = flag.Bool("enable-metrics", false, "Enable metrics server")
 		enableWeb       = flag.Bool("enable-web", false, "Enable web interface")
+		comment         = flag.String("comment", "", "Comment recorded with a change approval, rejection, application, rollout or rollback")
+		emergency       = flag.Bool("emergency", false, "Push outside the maintenance windows (audited)")
 	)
 	flag.Parse()
 
@@ -64,25 +117,42 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig(*configPath)
+	// Load configuration and override it with command line flags; serve
+	// mode loads it again on SIGHUP
+	loadConfig := func() (*config.Config, error) {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			return nil, err
+		}
+		if *batchSize > 0 {
+			cfg.App.BatchSize = *batchSize
+		}
+		if *logLevel != "" {
+			cfg.Logging.Level = *logLevel
+		}
+		if *enableMetrics {
+			cfg.Metrics.Enabled = true
+		}
+		if *enableWeb {
+			cfg.Web.Enabled = true
+		}
+		return cfg, nil
+	}
+
+	cfg, err := loadConfig()
 	if err != nil {
 		fmt.Printf("Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Override config with command line flags
-	if *batchSize > 0 {
-		cfg.App.BatchSize = *batchSize
-	}
-	if *logLevel != "" {
-		cfg.Logging.Level = *logLevel
-	}
-	if *enableMetrics {
-		cfg.Metrics.Enabled = true
+	// Keep stdout clean for generated output
+	for _, file := range output.SplitList(*outputFile) {
+		if file == output.Stdout && cfg.Logging.Output == "stdout" {
+			cfg.Logging.Output = "stderr"
+		}
 	}
-	if *enableWeb {
-		cfg.Web.Enabled = true
+	if (changesMode || rolloutMode) && cfg.Logging.Output == "stdout" {
+		cfg.Logging.Output = "stderr"
 	}
 
 	// Initialize logger
@@ -104,7 +174,6 @@ func main() {
 	if err != nil {
 		log.WithError(err).Fatal("Failed to create application")
 	}
-	defer app.Close()
 
 	// Set up signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -119,26 +188,71 @@ func main() {
 		cancel()
 	}()
 
-	// Start background services
-	if err := app.StartServices(ctx); err != nil {
-		log.WithError(err).Fatal("Failed to start services")
+	// Serve mode owns the application, which a reload replaces
+	if serveMode {
+		if err := serve(ctx, app, loadConfig); err != nil {
+			log.WithError(err).Fatal("Serve mode failed")
+		}
+		log.Info("Serve mode stopped")
+		return
+	}
+	defer app.Close()
+
+	if changesMode {
+		if err := runChanges(ctx, app, flag.Args(), *comment, *emergency, os.Stdout); err != nil {
+			log.WithError(err).Fatal("Change command failed")
+		}
+		return
+	}
+	if rolloutMode {
+		if err := runRollout(ctx, app, flag.Args(), *comment, *emergency, os.Stdout); err != nil {
+			log.WithError(err).Fatal("Rollout command failed")
+		}
+		return
 	}
 
 	// Execute main operation based on flags
-	if err := app.Execute(ctx, &ExecuteOptions{
+	if err := runOnce(ctx, app, &ExecuteOptions{
 		FetchFromSwitch: *fetchFromSwitch,
 		InputFile:       *inputFile,
+		InputFormat:     *inputFormat,
+		CollectNetFlow:  *collectNetFlow,
 		OutputType:      *outputType,
+		OutputFile:      *outputFile,
 		PushConfig:      *pushConfig,
+		PushCatalyst:    *pushCatalyst,
 		DryRun:          *dryRun,
 		SaveConfig:      *saveConfig,
-	}); err != nil {
+		Emergency:       *emergency,
+		RequestedBy:     cliActor(),
+	}, *enableWeb); err != nil {
 		log.WithError(err).Fatal("Execution failed")
 	}
 
 	log.Info("Application completed successfully")
 }
 
+// runOnce starts the background services, performs a one-shot execution
+// and stops the services. The web interface keeps serving until interrupted
+// only when keepServing is set (--enable-web); when the configuration alone
+// enables it, it serves for the duration of the run so cron and CI runs exit.
+func runOnce(ctx context.Context, app *Application, opts *ExecuteOptions, keepServing bool) error {
+	if err := app.StartServices(ctx); err != nil {
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+	defer app.StopServices()
+
+	if err := app.Execute(ctx, opts); err != nil {
+		return err
+	}
+
+	if keepServing && app.web != nil {
+		app.logger.WithField("address", app.web.GetAddress()).Info("Classification finished, web interface keeps running until interrupted")
+		<-ctx.Done()
+	}
+	return nil
+}
+
 // NewApplication creates a new application instance
 func NewApplication(cfg *config.Config, log *logger.Logger) (*Application, error) {
 	app := &Application{
@@ -162,7 +276,16 @@ func NewApplication(cfg *config.Config, log *logger.Logger) (*Application, error
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSH client: %w", err)
 	}
-	app.sshClient = sshClient
+	app.device = sshClient
+
+	// Initialize NETCONF/RESTCONF push transport
+	if cfg.Transport.Type != transport.TypeCLI {
+		deviceTransport, err := transport.New(cfg.TransportOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s transport: %w", cfg.Transport.Type, err)
+		}
+		app.transport = deviceTransport
+	}
 
 	// Initialize AI manager
 	aiManager, err := ai.NewManager(&cfg.AI, log)
@@ -171,6 +294,50 @@ func NewApplication(cfg *config.Config, log *logger.Logger) (*Application, error
 	}
 	app.aiManager = aiManager
 
+	// Initialize QoS class model
+	model, err := cfg.QoS.ClassModel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build QoS class model: %w", err)
+	}
+	qos.SetModel(model)
+	log.WithField("classes", model.Classes()).Info("Loaded QoS class model")
+
+	// Initialize egress queuing policy
+	if cfg.QoS.Queuing.Enabled {
+		app.queuingPolicy, err = cfg.QoS.QueuingPolicy(model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build queuing policy: %w", err)
+		}
+	}
+
+	// Initialize output generators
+	ciscoRenderer, err := render.NewCiscoRenderer(cfg.Output.Cisco.RendererOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cisco renderer: %w", err)
+	}
+	ciscoGenerator := output.NewCiscoGenerator(ciscoRenderer, app.queuingPolicy)
+	catalystMapping, err := cfg.Output.CatalystCenter.Mapping(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Catalyst Center mapping: %w", err)
+	}
+	app.catalystCenter = output.NewCatalystCenterGenerator(catalystMapping, cfg.Output.CatalystCenter.PolicyScope)
+	app.outputs = output.NewDefaultRegistry()
+	for _, generator := range []output.Generator{
+		ciscoGenerator,
+		output.NewAnsibleTasksGenerator(ciscoGenerator),
+		output.NewAnsibleVarsGenerator(ciscoGenerator),
+		output.NewNAPALMGenerator(ciscoGenerator),
+		app.catalystCenter,
+		output.NewJuniperGenerator(cfg.Output.Juniper.Options()),
+		output.NewAristaGenerator(cfg.Output.Arista.Options()),
+		output.NewAristaAVTGenerator(cfg.Output.AristaAVT.Options()),
+		output.NewMerakiGenerator(cfg.Output.Meraki.Options()),
+	} {
+		if err := app.outputs.Register(generator); err != nil {
+			return nil, fmt.Errorf("failed to register output generator: %w", err)
+		}
+	}
+
 	// Initialize QoS classifier
 	app.classifier = qos.NewClassifier(
 		qos.Class(cfg.QoS.DefaultClass),
@@ -187,19 +354,68 @@ func NewApplication(cfg *config.Config, log *logger.Logger) (*Application, error
 		return nil, fmt.Errorf("failed to load custom rules: %w", err)
 	}
 
+	// Apply overrides and rules changed through the web API
+	app.rules, err = rules.New(cfg.Rules.Options(), app.classifier, app.cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules store: %w", err)
+	}
+
+	// Hold pushes for approval
+	if cfg.Approval.Enabled {
+		app.changes, err = changes.New(cfg.Approval.Options(), app.applyChange)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load change store: %w", err)
+		}
+	}
+
+	// Limit pushes to maintenance windows
+	if cfg.Maintenance.Enabled {
+		app.maintenance, err = cfg.Maintenance.Policy()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build maintenance windows: %w", err)
+		}
+		if cfg.Maintenance.OutsideWindow == config.OutsideWindowQueue {
+			app.pushQueue, err = maintenance.NewQueue(cfg.Maintenance.QueueFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load push queue: %w", err)
+			}
+		}
+	}
+
+	// Initialize notifications
+	if cfg.Notifications.Enabled {
+		app.notifier, err = notify.New(cfg.Notifications.Options())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notifier: %w", err)
+		}
+	}
+
+	// Initialize web API authentication
+	if cfg.Web.Auth.Enabled {
+		app.auth, err = auth.New(cfg.Web.Auth.Options(cfg.Security))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create authenticator: %w", err)
+		}
+		if cfg.Web.Auth.OIDC.Enabled {
+			app.oidc, err = auth.NewOIDCProvider(cfg.Web.Auth.OIDC.Options(cfg.Security))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
+			}
+		}
+	}
+
 	return app, nil
 }
 
 // loadPredefinedClassifications loads predefined protocol classifications
 func (app *Application) loadPredefinedClassifications() error {
-	for className, classConfig := range app.config.QoS.Classes {
-		class := qos.Class(className)
+	for class, protocols := range app.config.QoS.ClassProtocols() {
 		if !class.IsValid() {
-			app.logger.WithField("class", className).Warn("Invalid QoS class in configuration")
+			app.logger.WithField("class", class).Warn("Invalid QoS class in configuration")
 			continue
 		}
 
-		for _, protocol := range classConfig.Protocols {
+		for _, protocol := range protocols {
 			app.classifier.AddPredefinedClassification(protocol, class)
 		}
 	}
@@ -247,26 +463,188 @@ func (app *Application) StartServices(ctx context.Context) error {
 		}()
 	}
 
-	// Start cache cleanup routine
-	if app.cache != nil {
-		app.cache.StartCleanupRoutine(time.Hour)
+	app.startCacheCleanup()
+
+	// Start web server and the runner of API runs
+	if app.config.Web.Enabled && app.web == nil {
+		if app.runs == nil {
+			var err error
+			app.runs, err = runs.New(app.config.Runs.Options(), app.executeRun)
+			if err != nil {
+				return fmt.Errorf("failed to create run manager: %w", err)
+			}
+			// Serve mode owns the runner and dispatches queued pushes itself
+			app.startQueueDispatcher(ctx)
+		}
+		if app.auth == nil {
+			app.logger.Security("web_auth_disabled", logger.Fields{
+				"address": fmt.Sprintf("%s:%d", app.config.Web.Host, app.config.Web.Port),
+			})
+		}
+		app.web = web.New(&app.config.Web, app.logger, app.webBackend())
+		go func() {
+			if err := app.web.Start(); err != nil {
+				app.logger.WithError(err).Error("Web server failed")
+			}
+		}()
 	}
 
 	return nil
 }
 
+// StopServices shuts the metrics and web servers down
+func (app *Application) StopServices() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if app.runs != nil {
+		app.runs.Close()
+	}
+	if app.metrics != nil {
+		if err := app.metrics.StopServer(ctx); err != nil {
+			app.logger.WithError(err).Warn("Failed to stop metrics server")
+		}
+	}
+	if app.web != nil {
+		if err := app.web.Stop(); err != nil {
+			app.logger.WithError(err).Warn("Failed to stop web server")
+		}
+	}
+}
+
+// webBackend returns the state served by the web API
+func (app *Application) webBackend() web.Backend {
+	return web.Backend{
+		Version:    Version,
+		Cache:      app.cache,
+		Classifier: app.classifier,
+		Rules:      app.rules,
+		Auth:       app.auth,
+		OIDC:       app.oidc,
+		AI:         app.aiManager,
+		Runs:       app.runs,
+		Outputs:    app.outputs,
+		Changes:    app.changes,
+
+		Maintenance:       app.maintenance,
+		MaintenanceTarget: app.maintenanceTarget(),
+		PushQueue:         app.pushQueue,
+	}
+}
+
+// executeRun performs a run requested through the web API. Outputs are
+// recorded in the run instead of being written.
+func (app *Application) executeRun(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+	if req.Source == runs.SourceChange {
+		app.logger.Audit("run_start", logger.Fields{
+			"source":       req.Source,
+			"change_id":    req.ChangeID,
+			"rollback":     req.Rollback,
+			"requested_by": req.RequestedBy,
+		})
+		return app.executeChange(ctx, req, tracker)
+	}
+
+	formats := req.Output
+	if formats == "" {
+		formats = "cisco"
+	}
+	opts := &ExecuteOptions{
+		InputFormat:  req.InputFormat,
+		OutputType:   formats,
+		PushConfig:   req.Push,
+		PushCatalyst: req.PushCatalyst,
+		DryRun:       req.DryRun,
+		SaveConfig:   req.SaveConfig,
+		Emergency:    req.Emergency,
+		Stdout:       io.Discard,
+		Tracker:      tracker,
+		RequestedBy:  req.RequestedBy,
+	}
+	if opts.InputFormat == "" {
+		opts.InputFormat = string(input.FormatAuto)
+	}
+	switch req.Source {
+	case runs.SourceSwitch:
+		opts.FetchFromSwitch = true
+	case runs.SourceFile:
+		opts.InputFile = req.InputFile
+	case runs.SourceNetFlow:
+		opts.CollectNetFlow = true
+	case runs.SourceInline:
+		opts.Protocols = req.Protocols
+	}
+
+	app.logger.Audit("run_start", logger.Fields{
+		"source":       req.Source,
+		"push":         req.Push,
+		"dry_run":      req.DryRun,
+		"requested_by": req.RequestedBy,
+	})
+	return app.Execute(ctx, opts)
+}
+
+// recordRun publishes a finished classification run to the web API; traffic
+// holds the byte counts of the protocols when the source reports them
+func (app *Application) recordRun(source string, start time.Time, protocols []string, traffic map[string]uint64, result *output.Result, targets *outputTargets) {
+	if app.web == nil {
+		return
+	}
+	run := &web.RunResult{
+		Source:          source,
+		Protocols:       protocols,
+		Classifications: result.Classifications,
+		Traffic:         traffic,
+		Attachments:     result.Attachments,
+		StartedAt:       start,
+		FinishedAt:      time.Now(),
+	}
+	for _, target := range targets.Targets {
+		run.Outputs = append(run.Outputs, target.Format)
+	}
+	app.web.SetLastRun(run)
+}
+
+// startCacheCleanup starts the cache cleanup routine, which Close stops
+func (app *Application) startCacheCleanup() {
+	if app.cache != nil && app.stopCleanup == nil {
+		app.stopCleanup = app.cache.StartCleanupRoutine(time.Hour)
+	}
+}
+
 // ExecuteOptions contains options for the main execution
 type ExecuteOptions struct {
 	FetchFromSwitch bool
 	InputFile       string
+	InputFormat     string
+	CollectNetFlow  bool
 	OutputType      string
+	OutputFile      string
 	PushConfig      bool
+	PushCatalyst    bool
 	DryRun          bool
 	SaveConfig      bool
+
+	// Protocols are classified instead of fetching or loading protocols
+	Protocols []string
+	// Stdout receives outputs written to "-", os.Stdout if nil
+	Stdout io.Writer
+	// Tracker receives the progress of API runs
+	Tracker *runs.Tracker
+	// Emergency pushes outside the maintenance windows
+	Emergency bool
+	// RequestedBy is who asked for the run; they author proposed changes
+	RequestedBy string
+	// ChangeID and Rollback identify the change a push applies or rolls back
+	ChangeID string
+	Rollback bool
 }
 
 // Execute runs the main application logic
 func (app *Application) Execute(ctx context.Context, opts *ExecuteOptions) error {
+	app.executeMu.Lock()
+	defer app.executeMu.Unlock()
+
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -278,77 +656,275 @@ func (app *Application) Execute(ctx context.Context, opts *ExecuteOptions) error
 		})
 	}()
 
+	// Resolve output targets before doing any work
+	targets, err := app.resolveTargets(opts.OutputType, opts.OutputFile)
+	if err != nil {
+		return err
+	}
+	wantsCisco := targets.Has("cisco")
+	if (opts.PushConfig || (opts.DryRun && !opts.PushCatalyst)) && !wantsCisco {
+		return fmt.Errorf("--push-config and --dry-run require the cisco output")
+	}
+	// With approval enabled the Catalyst Center policy is pushed when the
+	// proposed change is applied, never by the run itself
+	if opts.PushCatalyst && !opts.DryRun && app.changes != nil && !opts.PushConfig {
+		return fmt.Errorf("change approval is enabled: --push-catalyst-center needs --push-config, and the Catalyst Center policy is pushed when the approved change is applied")
+	}
+	inputFormat, err := input.ParseFormat(opts.InputFormat)
+	if err != nil {
+		return err
+	}
+
 	// Fetch protocols
 	var protocols []string
-	var err error
+	var traffic map[string]uint64
+	var source string
+	opts.Tracker.Stage("discovery")
 
 	if opts.FetchFromSwitch {
+		source = config.ServeSourceSwitch
 		app.logger.Info("Fetching protocols from switch")
-		protocols, err = app.sshClient.FetchProtocols()
+		discovery, err := app.device.FetchProtocolDiscovery()
 		if err != nil {
 			return fmt.Errorf("failed to fetch protocols from switch: %w", err)
 		}
+		protocols, traffic = discovery.Protocols(), discovery.Traffic()
 		app.logger.WithField("count", len(protocols)).Info("Fetched protocols from switch")
 	} else if opts.InputFile != "" {
+		source = config.ServeSourceFile
 		app.logger.WithField("file", opts.InputFile).Info("Loading protocols from file")
-		protocols, err = app.loadProtocolsFromFile(opts.InputFile)
+		protocols, err = app.loadProtocolsFromFile(opts.InputFile, inputFormat)
 		if err != nil {
 			return fmt.Errorf("failed to load protocols from file: %w", err)
 		}
 		app.logger.WithField("count", len(protocols)).Info("Loaded protocols from file")
+	} else if opts.CollectNetFlow {
+		source = config.ServeSourceNetFlow
+		protocols, err = app.collectProtocols(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to collect protocols from NetFlow: %w", err)
+		}
+		app.logger.WithField("count", len(protocols)).Info("Collected protocols from NetFlow")
+	} else if len(opts.Protocols) > 0 {
+		source = runs.SourceInline
+		parsed, err := input.Parse(strings.Join(opts.Protocols, "\n"), input.FormatList)
+		if err != nil {
+			return err
+		}
+		if len(parsed.Invalid) > 0 {
+			return fmt.Errorf("invalid protocol names: %s", strings.Join(parsed.Invalid, ", "))
+		}
+		protocols = parsed.Protocols
 	} else {
-		return fmt.Errorf("either --fetch-from-switch or --input-file must be specified")
+		return fmt.Errorf("one of --fetch-from-switch, --input-file or --collect-netflow must be specified")
 	}
+	opts.Tracker.Protocols(len(protocols))
 
-	// Classify protocols
-	app.logger.Info("Starting protocol classification")
-	classifications, err := app.classifyProtocols(ctx, protocols)
+	// Classify protocols and compute service-policy attachments
+	opts.Tracker.Stage("classification")
+	result, err := app.buildResult(ctx, protocols, targets.WantsDeviceConfig, opts.FetchFromSwitch, opts.Tracker)
 	if err != nil {
-		return fmt.Errorf("failed to classify protocols: %w", err)
+		return err
 	}
 
-	// Generate output
-	output, err := app.generateOutput(classifications, opts.OutputType)
+	// Generate and write outputs
+	opts.Tracker.Stage("output")
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	outputs, err := app.writeOutputs(targets, result, stdout)
 	if err != nil {
-		return fmt.Errorf("failed to generate output: %w", err)
+		return err
 	}
-
-	// Write output to file
-	outputFile := "nbar-protocols-qos.txt"
-	if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	ciscoConfig := outputs["cisco"]
+	for _, target := range targets.Targets {
+		opts.Tracker.Output(target.Format, outputs[target.Format])
 	}
 
 	// Log statistics
-	app.logStatistics(classifications)
-
-	// Handle config push/dry run
-	if opts.PushConfig || opts.DryRun {
-		if err := app.handleConfigPush(ctx, output, opts); err != nil {
+	app.logStatistics(result.Classifications)
+	app.recordRun(source, start, protocols, traffic, result, targets)
+
+	// Handle config push/dry run; with approval enabled a push only
+	// proposes a change
+	if wantsCisco && opts.PushConfig && !opts.DryRun && app.changes != nil {
+		opts.Tracker.Stage("approval")
+		if _, err := app.proposeChange(source, ciscoConfig, result.Classifications, opts); err != nil {
+			return err
+		}
+	} else if wantsCisco && (opts.PushConfig || opts.DryRun) {
+		opts.Tracker.Stage("push")
+		proceed := true
+		if opts.PushConfig && !opts.DryRun {
+			proceed, err = app.checkWindow(maintenance.Push{
+				Config:      ciscoConfig,
+				SaveConfig:  opts.SaveConfig,
+				RequestedBy: opts.RequestedBy,
+			}, opts.Emergency)
+			if err != nil {
+				return err
+			}
+		}
+		if !proceed {
+			opts.Tracker.Stage("queued")
+		} else if err := app.handleConfigPush(ctx, ciscoConfig, opts); err != nil {
 			return fmt.Errorf("failed to handle config push: %w", err)
 		}
 	}
 
-	app.logger.WithField("output_file", outputFile).Info("Classification completed successfully")
+	// Handle Catalyst Center push; with approval enabled it was proposed
+	// with the change
+	if opts.PushCatalyst && (opts.DryRun || app.changes == nil) {
+		opts.Tracker.Stage("catalyst_center")
+		pushed, err := app.pushCatalystCenter(ctx, result, opts)
+		if err != nil {
+			return fmt.Errorf("failed to push Catalyst Center policy: %w", err)
+		}
+		if !pushed && !opts.DryRun {
+			opts.Tracker.Stage("queued")
+		}
+	}
+
+	app.logger.Info("Classification completed successfully")
 	return nil
 }
 
+// outputTargets are the resolved --output targets
+type outputTargets struct {
+	Targets []output.Target
+	// WantsDeviceConfig is set when a target renders switch configuration
+	// that includes service-policy attachments
+	WantsDeviceConfig bool
+}
+
+// Has reports whether a format is among the targets
+func (t *outputTargets) Has(format string) bool {
+	for _, target := range t.Targets {
+		if target.Format == format {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTargets resolves comma-separated output formats and files
+func (app *Application) resolveTargets(formats, files string) (*outputTargets, error) {
+	targets, err := app.outputs.Targets(output.SplitList(formats), output.SplitList(files))
+	if err != nil {
+		return nil, err
+	}
+	resolved := &outputTargets{Targets: targets}
+	for _, target := range targets {
+		generator, err := app.outputs.Get(target.Format)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := generator.(output.DeviceConfigGenerator); ok {
+			resolved.WantsDeviceConfig = true
+		}
+	}
+	return resolved, nil
+}
+
+// buildResult classifies the protocols and, when device configuration is
+// generated from switch data, computes the service-policy attachments
+func (app *Application) buildResult(ctx context.Context, protocols []string, wantsDeviceConfig, fromSwitch bool, tracker *runs.Tracker) (*output.Result, error) {
+	app.logger.Info("Starting protocol classification")
+	classifications, err := app.classifyProtocols(ctx, protocols, tracker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify protocols: %w", err)
+	}
+
+	summary := make(map[string]int)
+	for class, count := range qos.GetClassStatistics(classifications) {
+		summary[class.String()] = count
+	}
+	tracker.Classified(summary)
+
+	result := &output.Result{
+		Classifications: classifications,
+		Model:           qos.CurrentModel(),
+		DefaultClass:    app.classifier.GetDefaultClass(),
+		GeneratedAt:     time.Now(),
+	}
+
+	if wantsDeviceConfig && app.config.QoS.Attachment.Enabled {
+		if fromSwitch {
+			result.Attachments, err = app.computeAttachments()
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute service-policy attachments: %w", err)
+			}
+		} else {
+			app.logger.Warn("Service-policy attachment requires protocols from the switch, skipping")
+		}
+	}
+
+	return result, nil
+}
+
+// writeOutputs generates and writes every target and returns the generated
+// data by format
+func (app *Application) writeOutputs(targets *outputTargets, result *output.Result, stdout io.Writer) (map[string]string, error) {
+	generated := make(map[string]string)
+	for _, target := range targets.Targets {
+		generator, err := app.outputs.Get(target.Format)
+		if err != nil {
+			return nil, err
+		}
+		data, err := generator.Generate(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s output: %w", target.Format, err)
+		}
+		if err := output.Write(target.Path, data, stdout); err != nil {
+			return nil, fmt.Errorf("failed to write %s output: %w", target.Format, err)
+		}
+		generated[target.Format] = string(data)
+		if reporter, ok := generator.(output.UnmappedReporter); ok {
+			if unmapped := reporter.Unmapped(result); len(unmapped) > 0 {
+				app.logger.WithFields(logger.Fields{
+					"format":    target.Format,
+					"count":     len(unmapped),
+					"protocols": strings.Join(unmapped, ", "),
+				}).Warn("Protocols without a usable vendor application mapping were skipped")
+			}
+		}
+		app.logger.WithFields(logger.Fields{
+			"format": target.Format,
+			"file":   target.Path,
+		}).Info("Output written")
+	}
+	return generated, nil
+}
+
 // Close closes the application and cleans up resources
 func (app *Application) Close() error {
 	var errors []error
 
+	if app.stopCleanup != nil {
+		app.stopCleanup()
+	}
+
+	app.notifyWG.Wait()
+
 	if app.cache != nil {
 		if err := app.cache.Save(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to save cache: %w", err))
 		}
 	}
 
-	if app.sshClient != nil {
-		if err := app.sshClient.Close(); err != nil {
+	if app.device != nil {
+		if err := app.device.Close(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to close SSH client: %w", err))
 		}
 	}
 
+	if app.transport != nil {
+		if err := app.transport.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("failed to close %s transport: %w", app.transport.Name(), err))
+		}
+	}
+
 	if app.aiManager != nil {
 		if err := app.aiManager.Close(); err != nil {
 			errors = append(errors, fmt.Errorf("failed to close AI manager: %w", err))
@@ -362,52 +938,111 @@ func (app *Application) Close() error {
 	return nil
 }
 
-// loadProtocolsFromFile loads protocols from an input file
-func (app *Application) loadProtocolsFromFile(filename string) ([]string, error) {
-	app.logger.WithField("file", filename).Debug("Loading protocols from file")
-
-	// Read the file
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+// collectProtocols runs the NetFlow collector for the configured duration.
+// Newly observed protocols are classified at every flush interval so the
+// cache is warm by the time the collection ends.
+func (app *Application) collectProtocols(ctx context.Context) ([]string, error) {
+	cfg := app.config.NetFlow
+	collector := netflow.New(cfg.Options())
+	if err := collector.Listen(); err != nil {
+		return nil, err
 	}
+	defer collector.Close()
 
-	// Parse protocols (one per line, ignore empty lines and comments)
-	var protocols []string
-	lines := strings.Split(string(data), "\n")
+	app.logger.WithFields(logger.Fields{
+		"listen":   collector.Addr().String(),
+		"duration": cfg.Duration,
+	}).Info("Collecting protocols from NetFlow exports")
 
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
+	collectCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
-			continue
-		}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- collector.Serve(collectCtx)
+	}()
 
-		// Validate protocol name
-		if err := qos.ValidateProtocolName(line); err != nil {
-			app.logger.WithFields(logger.Fields{
-				"line":     i + 1,
-				"protocol": line,
-				"error":    err.Error(),
-			}).Warn("Invalid protocol name, skipping")
-			continue
+	ticker := time.NewTicker(cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if newProtocols := collector.TakeNew(); len(newProtocols) > 0 {
+				app.logger.WithField("protocols", newProtocols).Info("Observed new protocols")
+				if _, err := app.classifyProtocols(collectCtx, newProtocols, nil); err != nil {
+					app.logger.WithError(err).Warn("Failed to classify new protocols")
+				}
+			}
+		case <-collectCtx.Done():
+			if err := <-serveErr; err != nil {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			stats := collector.Stats()
+			fields := logger.Fields{
+				"packets":           stats.Packets,
+				"records":           stats.Records,
+				"exporters":         stats.Exporters,
+				"applications":      stats.Applications,
+				"unresolved":        stats.Unresolved,
+				"missing_templates": stats.MissingTemplates,
+				"errors":            stats.Errors,
+			}
+			if err := collector.LastError(); err != nil {
+				fields["last_error"] = err.Error()
+			}
+			app.logger.WithFields(fields).Info("NetFlow collection finished")
+
+			protocols := collector.Protocols()
+			if len(protocols) == 0 {
+				return nil, fmt.Errorf("no applications observed in %s on %s", cfg.Duration, cfg.Listen)
+			}
+			return protocols, nil
 		}
+	}
+}
+
+// loadProtocolsFromFile loads protocols from an input file, detecting its
+// format unless one is given
+func (app *Application) loadProtocolsFromFile(filename string, format input.Format) ([]string, error) {
+	app.logger.WithFields(logger.Fields{
+		"file":   filename,
+		"format": format,
+	}).Debug("Loading protocols from file")
+
+	result, err := input.Load(filename, format)
+	if err != nil {
+		return nil, err
+	}
 
-		protocols = append(protocols, strings.ToLower(line))
+	for _, entry := range result.Invalid {
+		app.logger.WithFields(logger.Fields{
+			"file":     filename,
+			"protocol": entry,
+		}).Warn("Invalid protocol name, skipping")
 	}
 
 	app.logger.WithFields(logger.Fields{
-		"file":           filename,
-		"total_lines":    len(lines),
-		"valid_protocols": len(protocols),
+		"file":              filename,
+		"format":            result.Format,
+		"valid_protocols":   len(result.Protocols),
+		"invalid_protocols": len(result.Invalid),
 	}).Info("Loaded protocols from file")
 
-	return protocols, nil
+	if len(result.Protocols) == 0 {
+		return nil, fmt.Errorf("no protocols found in %s (detected format %s)", filename, result.Format)
+	}
+
+	return result.Protocols, nil
 }
 
-// classifyProtocols classifies a list of protocols
-func (app *Application) classifyProtocols(ctx context.Context, protocols []string) (map[string]qos.Classification, error) {
+// classifyProtocols classifies a list of protocols, reporting AI batches to
+// the tracker
+func (app *Application) classifyProtocols(ctx context.Context, protocols []string, tracker *runs.Tracker) (map[string]qos.Classification, error) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -420,6 +1055,8 @@ func (app *Application) classifyProtocols(ctx context.Context, protocols []strin
 
 	// First, try to classify using predefined rules and cache
 	needAIClassification := make([]string, 0)
+	// Protocols missing from the cache are new
+	var newProtocols []string
 
 	for _, protocol := range protocols {
 		// Check cache first
@@ -434,6 +1071,7 @@ func (app *Application) classifyProtocols(ctx context.Context, protocols []strin
 		if app.metrics != nil {
 			app.metrics.RecordCacheMiss("protocol_classification")
 		}
+		newProtocols = append(newProtocols, protocol)
 
 		// Try predefined classification
 		classification := app.classifier.ClassifyProtocol(protocol)
@@ -456,7 +1094,9 @@ func (app *Application) classifyProtocols(ctx context.Context, protocols []strin
 	if len(needAIClassification) > 0 {
 		app.logger.WithField("count", len(needAIClassification)).Info("Starting AI classification")
 
-		aiResults, err := app.aiManager.ClassifyProtocols(ctx, needAIClassification, app.config.App.BatchSize)
+		aiResults, err := app.aiManager.ClassifyProtocolsWithProgress(ctx, needAIClassification, app.config.App.BatchSize, func(batch ai.BatchResult) {
+			tracker.Batch(runs.Batch(batch))
+		})
 		if err != nil {
 			app.logger.WithError(err).Warn("AI classification failed, using default classifications")
 			// Continue with default classifications instead of failing
@@ -483,6 +1123,7 @@ func (app *Application) classifyProtocols(ctx context.Context, protocols []strin
 			}
 		}
 	}
+	app.notifyNewProtocols(newProtocols, results)
 
 	// Record metrics
 	if app.metrics != nil {
@@ -498,124 +1139,66 @@ func (app *Application) classifyProtocols(ctx context.Context, protocols []strin
 	return results, nil
 }
 
-// generateOutput generates the output in the specified format
-func (app *Application) generateOutput(classifications map[string]qos.Classification, outputType string) (string, error) {
-	switch outputType {
-	case "cisco":
-		return app.generateCiscoConfig(classifications)
-	case "text":
-		return app.generateTextOutput(classifications)
-	default:
-		return "", fmt.Errorf("unsupported output type: %s", outputType)
-	}
-}
-
-// generateCiscoConfig generates Cisco configuration output
-func (app *Application) generateCiscoConfig(classifications map[string]qos.Classification) (string, error) {
-	// Group protocols by QoS class
-	grouped := qos.GroupProtocolsByClass(classifications)
-
-	var output strings.Builder
-
-	// Helper function to write class-maps with max 16 protocols each
-	writeClassMaps := func(className, description string, protocols []string) []string {
-		if len(protocols) == 0 {
-			return []string{}
-		}
-
-		numClassMaps := (len(protocols) + 15) / 16
-		classMapNames := make([]string, 0, numClassMaps)
-
-		for i := 0; i < numClassMaps; i++ {
-			classMapName := className
-			if numClassMaps > 1 {
-				classMapName = fmt.Sprintf("%s_%d", className, i+1)
-			}
-			classMapNames = append(classMapNames, classMapName)
-
-			output.WriteString(fmt.Sprintf("class-map match-any %s\n", classMapName))
-			output.WriteString(fmt.Sprintf(" description %s\n", description))
-
-			start := i * 16
-			end := start + 16
-			if end > len(protocols) {
-				end = len(protocols)
-			}
-
-			for j := start; j < end; j++ {
-				output.WriteString(fmt.Sprintf(" match protocol %s\n", protocols[j]))
-			}
-			output.WriteString("!\n")
-		}
+// computeAttachments discovers the switch interfaces and computes the
+// service-policy changes needed to match the configured attachment targets
+func (app *Application) computeAttachments() ([]interfaces.Change, error) {
+	attachment := app.config.QoS.Attachment
 
-		return classMapNames
+	selector, err := attachment.Selector()
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate class-maps for each QoS class
-	efClassMaps := writeClassMaps("QOS_EF", "Expedited Forwarding - Real-time traffic", grouped[qos.EF])
-	af41ClassMaps := writeClassMaps("QOS_AF41", "Assured Forwarding 41 - Business-critical applications", grouped[qos.AF41])
-	af21ClassMaps := writeClassMaps("QOS_AF21", "Assured Forwarding 21 - Important data applications", grouped[qos.AF21])
-	_ = writeClassMaps("QOS_CS1", "Class Selector 1 - Background traffic", grouped[qos.CS1])
-
-	// Generate policy-map
-	output.WriteString("! Ingress marking policy-map\n")
-	output.WriteString("policy-map PM_MARK_AVC_WIRED_INGRESS\n")
-	output.WriteString(" description Marks incoming traffic based on App (AVC) or VLAN fallback. CS1 traffic handled by class-default.\n")
-
-	for _, cm := range efClassMaps {
-		output.WriteString(fmt.Sprintf(" class %s\n  set dscp ef\n", cm))
-	}
-	for _, cm := range af41ClassMaps {
-		output.WriteString(fmt.Sprintf(" class %s\n  set dscp af41\n", cm))
-	}
-	for _, cm := range af21ClassMaps {
-		output.WriteString(fmt.Sprintf(" class %s\n  set dscp af21\n", cm))
+	status, err := app.device.FetchInterfaceStatus()
+	if err != nil {
+		return nil, err
 	}
-	output.WriteString(" class class-default\n  set dscp cs1\n!\n")
-
-	return output.String(), nil
-}
-
-// generateTextOutput generates human-readable text output
-func (app *Application) generateTextOutput(classifications map[string]qos.Classification) (string, error) {
-	grouped := qos.GroupProtocolsByClass(classifications)
 
-	var output strings.Builder
+	runningConfig, err := app.device.FetchRunningConfig()
+	if err != nil {
+		return nil, err
+	}
 
-	currentDate := time.Now().Format("2006-01-02")
-	output.WriteString("# NBAR Protocols Classified by QoS\n")
-	output.WriteString(fmt.Sprintf("# Generated on %s using AI\n", currentDate))
-	output.WriteString("# For use with Cisco 9300 Switch\n\n")
+	ifaces := interfaces.Merge(status, interfaces.ParseRunningConfig(runningConfig))
+	changes, conflicts := interfaces.Diff(ifaces, selector, attachment.InputPolicy, attachment.OutputPolicy)
 
-	// Write each QoS class section
-	classes := []qos.Class{qos.EF, qos.AF41, qos.AF21, qos.CS1}
-	for _, class := range classes {
-		protocols := grouped[class]
-		if len(protocols) == 0 {
-			continue
-		}
+	for _, conflict := range conflicts {
+		app.logger.WithFields(logger.Fields{
+			"interface":       conflict.Interface,
+			"direction":       conflict.Direction,
+			"attached_policy": conflict.AttachedPolicy,
+		}).Warn("Interface already has a different service-policy attached, skipping")
+	}
 
-		output.WriteString(fmt.Sprintf("## %s - %s\n", class, class.Description()))
-		for i, protocol := range protocols {
-			output.WriteString(fmt.Sprintf("%d. %s\n", i+1, protocol))
+	added, removed := 0, 0
+	for _, change := range changes {
+		if change.Remove {
+			removed++
+		} else {
+			added++
 		}
-		output.WriteString("\n")
 	}
+	app.logger.WithFields(logger.Fields{
+		"interfaces": len(ifaces),
+		"added":      added,
+		"removed":    removed,
+		"conflicts":  len(conflicts),
+	}).Info("Computed service-policy attachments")
 
-	return output.String(), nil
+	return changes, nil
 }
 
 // logStatistics logs classification statistics
 func (app *Application) logStatistics(classifications map[string]qos.Classification) {
 	stats := qos.GetClassStatistics(classifications)
 
-	app.logger.WithFields(logger.Fields{
+	fields := logger.Fields{
 		"total_protocols": len(classifications),
-		"ef_count":        stats[qos.EF],
-		"af41_count":      stats[qos.AF41],
-		"af21_count":      stats[qos.AF21],
-		"cs1_count":       stats[qos.CS1],
-	}).Info("Classification statistics")
+	}
+	for _, class := range qos.CurrentModel().MarkingClasses() {
+		fields[strings.ToLower(class.String())+"_count"] = stats[class]
+	}
+	app.logger.WithFields(fields).Info("Classification statistics")
 
 	// Update metrics
 	if app.metrics != nil {
@@ -625,6 +1208,71 @@ func (app *Application) logStatistics(classifications map[string]qos.Classificat
 	}
 }
 
+// pushCatalystCenter pushes the Application Policy to Catalyst Center.
+// Outside a maintenance window of the Catalyst Center target the push is
+// refused or queued like a switch push; it reports whether it was made.
+func (app *Application) pushCatalystCenter(ctx context.Context, result *output.Result, opts *ExecuteOptions) (bool, error) {
+	policy, err := app.catalystCenter.Policy(result)
+	if err != nil {
+		return false, err
+	}
+
+	if opts.DryRun {
+		app.logger.WithFields(logger.Fields{
+			"policy_scope":     policy.PolicyScope,
+			"application_sets": len(policy.ApplicationSets),
+		}).Info("Dry-run: Catalyst Center policy ready for deployment")
+		return false, nil
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode Catalyst Center policy: %w", err)
+	}
+	proceed, err := app.checkWindow(maintenance.Push{
+		Target:         app.catalystTarget(),
+		Config:         string(data),
+		CatalystCenter: true,
+		RequestedBy:    opts.RequestedBy,
+	}, opts.Emergency)
+	if err != nil || !proceed {
+		return false, err
+	}
+	return true, app.sendCatalystPolicy(ctx, policy)
+}
+
+// sendCatalystPolicy sends an Application Policy to Catalyst Center
+func (app *Application) sendCatalystPolicy(ctx context.Context, policy *catalystcenter.Policy) error {
+	client, err := catalystcenter.New(app.config.Output.CatalystCenter.ClientOptions())
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	pushResult, err := client.Push(ctx, policy)
+	if err != nil {
+		return err
+	}
+
+	if len(pushResult.Unknown) > 0 {
+		app.logger.WithField("applications", pushResult.Unknown).Warn("Applications not found in Catalyst Center, skipped")
+	}
+	app.logger.Performance("catalyst_center_push", time.Since(start), logger.Fields{
+		"policy_scope": policy.PolicyScope,
+		"created_sets": len(pushResult.CreatedSets),
+		"moved":        pushResult.Moved,
+		"unchanged":    pushResult.Unchanged,
+		"unknown":      len(pushResult.Unknown),
+		"task_ids":     pushResult.TaskIDs,
+	})
+	app.logger.Audit("catalyst_center_push", logger.Fields{
+		"policy_scope": policy.PolicyScope,
+		"base_url":     app.config.Output.CatalystCenter.BaseURL,
+	})
+
+	return nil
+}
+
 // handleConfigPush handles configuration push or dry run
 func (app *Application) handleConfigPush(ctx context.Context, config string, opts *ExecuteOptions) error {
 	if opts.DryRun {
@@ -645,27 +1293,107 @@ func (app *Application) handleConfigPush(ctx context.Context, config string, opt
 			app.logger.WithField("file", dryRunFile).Info("Dry-run configuration written to file")
 		}
 
+		if app.transport != nil {
+			app.previewStructuredConfig(ctx, config)
+		}
+
 		return nil
 	}
 
-	if opts.PushConfig {
-		app.logger.Info("Pushing configuration to switch")
+	if !opts.PushConfig {
+		return nil
+	}
+	err := app.pushConfig(ctx, config, opts.SaveConfig)
+	app.notifyPush(opts, err)
+	return err
+}
+
+// pushConfig applies the configuration through the configured transport or
+// the switch CLI
+func (app *Application) pushConfig(ctx context.Context, config string, save bool) error {
+	if app.transport != nil {
+		return app.pushStructuredConfig(ctx, config, save)
+	}
+
+	app.logger.Info("Pushing configuration to switch")
 
-		// Push configuration to switch
-		if err := app.sshClient.PushConfig(config); err != nil {
-			return fmt.Errorf("failed to push configuration: %w", err)
+	// Push configuration to switch
+	if err := app.device.PushConfig(config); err != nil {
+		return fmt.Errorf("failed to push configuration: %w", err)
+	}
+
+	app.logger.Info("Configuration successfully pushed to switch")
+
+	// Save configuration if requested
+	if save {
+		app.logger.Info("Saving configuration to startup-config")
+		if err := app.device.SaveConfig(); err != nil {
+			return fmt.Errorf("failed to save configuration: %w", err)
 		}
+		app.logger.Info("Configuration successfully saved to startup-config")
+	}
 
-		app.logger.Info("Configuration successfully pushed to switch")
+	return nil
+}
 
-		// Save configuration if requested
-		if opts.SaveConfig {
-			app.logger.Info("Saving configuration to startup-config")
-			if err := app.sshClient.SaveConfig(); err != nil {
-				return fmt.Errorf("failed to save configuration: %w", err)
-			}
-			app.logger.Info("Configuration successfully saved to startup-config")
+// previewStructuredConfig checks that the configuration converts to the YANG
+// models and logs the class-maps a push would change
+func (app *Application) previewStructuredConfig(ctx context.Context, config string) {
+	qosConfig, err := transport.ParseCLI(config)
+	if err != nil {
+		app.logger.WithError(err).Warn("Dry-run: configuration cannot be pushed over " + app.transport.Name())
+		return
+	}
+
+	current, err := app.transport.ClassMaps(ctx)
+	if err != nil {
+		app.logger.WithError(err).Warn("Dry-run: failed to read class-maps from switch")
+		return
+	}
+
+	app.logger.WithFields(logger.Fields{
+		"transport":   app.transport.Name(),
+		"class_maps":  len(qosConfig.ClassMaps),
+		"policy_maps": len(qosConfig.PolicyMaps),
+		"attachments": len(qosConfig.Attachments),
+		"changed":     transport.ChangedClassMaps(current, qosConfig.ClassMaps),
+	}).Info("Dry-run: structured configuration ready for deployment")
+}
+
+// pushStructuredConfig applies the configuration through NETCONF or RESTCONF
+func (app *Application) pushStructuredConfig(ctx context.Context, config string, save bool) error {
+	qosConfig, err := transport.ParseCLI(config)
+	if err != nil {
+		return fmt.Errorf("failed to convert configuration for %s: %w", app.transport.Name(), err)
+	}
+
+	app.logger.WithField("transport", app.transport.Name()).Info("Pushing configuration to switch")
+
+	start := time.Now()
+	result, err := app.transport.Apply(ctx, qosConfig)
+	if err != nil {
+		return fmt.Errorf("failed to push configuration: %w", err)
+	}
+	app.logger.Performance("push_config", time.Since(start), logger.Fields{
+		"transport":        result.Transport,
+		"datastore":        result.Datastore,
+		"confirmed_commit": result.ConfirmedCommit,
+		"class_maps":       result.ClassMaps,
+		"policy_maps":      result.PolicyMaps,
+		"attachments":      result.Attachments,
+	})
+	app.logger.Audit("push_config", logger.Fields{
+		"host":      app.config.SSH.Host,
+		"transport": result.Transport,
+		"datastore": result.Datastore,
+	})
+
+	if save {
+		app.logger.Info("Saving configuration to startup-config")
+		if err := app.transport.Save(ctx); err != nil {
+			return err
 		}
+		app.logger.Info("Configuration successfully saved to startup-config")
 	}
 
 	return nil
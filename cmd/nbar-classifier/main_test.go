@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/daemon"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh/sshtest"
+)
+
+// newWebApplication creates an application whose configuration enables the
+// web interface on a free local port
+func newWebApplication(t *testing.T) *Application {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	app, _ := newTestApplication(t, &sshtest.Transcript{}, fmt.Sprintf(`
+web:
+  enabled: true
+  host: "127.0.0.1"
+  port: %d
+`, port))
+	require.True(t, app.config.Web.Enabled)
+	return app
+}
+
+// newTestApplication creates an application connected to a fake switch
+// replaying transcript; extra is appended to the configuration
+func newTestApplication(t *testing.T, transcript *sshtest.Transcript, extra string) (*Application, *sshtest.Server) {
+	t.Helper()
+	server, err := sshtest.NewServer(transcript)
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	dir := t.TempDir()
+	key := strings.ReplaceAll(strings.TrimSpace(server.ClientKey), "\n", "\n    ")
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`
+ssh:
+  host: %q
+  port: %q
+  user: %q
+  key_file: |
+    %s
+ai:
+  api_key: "test"
+cache:
+  file_path: %q
+  backup_path: %q
+logging:
+  level: "error"
+  output: "stderr"
+metrics:
+  enabled: false
+runs:
+  history_file: %q
+`, server.Host, server.Port, sshtest.User, key,
+		filepath.Join(dir, "cache.json"), filepath.Join(dir, "cache.backup.json"),
+		filepath.Join(dir, "runs.json"))+extra), 0o600))
+
+	cfg, err := config.LoadConfig(path)
+	require.NoError(t, err)
+	log, err := logger.New(&cfg.Logging)
+	require.NoError(t, err)
+	app, err := NewApplication(cfg, log)
+	require.NoError(t, err)
+	t.Cleanup(func() { app.Close() })
+	return app, server
+}
+
+func TestRunOnceWithWebEnabled(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "protocols.txt")
+	require.NoError(t, os.WriteFile(input, []byte("rtp-audio\nhttps\n"), 0o600))
+	opts := func() *ExecuteOptions {
+		return &ExecuteOptions{InputFile: input, OutputType: "text", OutputFile: "-", Stdout: io.Discard}
+	}
+
+	t.Run("One-shot run exits", func(t *testing.T) {
+		app := newWebApplication(t)
+		done := make(chan error, 1)
+		go func() { done <- runOnce(context.Background(), app, opts(), false) }()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(30 * time.Second):
+			t.Fatal("one-shot run with web.enabled did not return")
+		}
+	})
+
+	t.Run("--enable-web keeps serving until interrupted", func(t *testing.T) {
+		app := newWebApplication(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- runOnce(ctx, app, opts(), true) }()
+		select {
+		case <-done:
+			t.Fatal("run returned before it was interrupted")
+		case <-time.After(500 * time.Millisecond):
+		}
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(30 * time.Second):
+			t.Fatal("run did not return after it was interrupted")
+		}
+	})
+}
+
+func TestDriftCheckComparesPolicyMaps(t *testing.T) {
+	desired := `class-map match-any QOS_EF
+ description Voice
+ match protocol rtp
+!
+policy-map PM_MARK
+ class QOS_EF
+  set dscp ef
+ class class-default
+  set dscp default
+!
+policy-map PM_QUEUE
+ class QOS_Q_EF
+  priority level 1
+  police rate percent 10
+ class class-default
+  bandwidth remaining percent 100
+!
+`
+	// Only the queuing policy-map differs; the running configuration also
+	// carries statements the templates never generate
+	transcript, err := sshtest.ParseTranscript(`sw1#show running-config
+hostname sw1
+!
+class-map match-any QOS_EF
+ description Voice
+ match protocol rtp
+!
+policy-map PM_MARK
+ class QOS_EF
+  set dscp ef
+ class class-default
+  set dscp default
+!
+policy-map PM_QUEUE
+ class QOS_Q_EF
+  priority level 1
+  police rate percent 20
+ class class-default
+  bandwidth remaining percent 100
+!
+policy-map PM_UNMANAGED
+ class class-default
+  shape average 1000000
+!
+end
+sw1#`)
+	require.NoError(t, err)
+	app, device := newTestApplication(t, transcript, "")
+
+	store, err := daemon.OpenStore("")
+	require.NoError(t, err)
+	require.NoError(t, store.Update(func(state *daemon.State) {
+		state.DesiredConfig = desired
+		state.ClassifiedAt = time.Now()
+	}))
+	s := &server{logger: app.logger, store: store, app: app}
+
+	require.NoError(t, s.checkDrift(context.Background()))
+	assert.Equal(t, []string{"PM_QUEUE"}, store.Snapshot().Drift)
+
+	require.NoError(t, s.push(context.Background()))
+	require.Len(t, device.Pushed(), 1)
+	assert.Contains(t, device.Pushed()[0], "police rate percent 10")
+	assert.Empty(t, store.Snapshot().Drift)
+}
+
+func TestCatalystCenterPushNeedsApproval(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	catalyst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer catalyst.Close()
+	requested := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(requests)
+	}
+
+	dir := t.TempDir()
+	app, device := newTestApplication(t, &sshtest.Transcript{}, fmt.Sprintf(`
+approval:
+  enabled: true
+  store_file: %q
+  audit_file: %q
+output:
+  catalyst_center:
+    base_url: %q
+    username: "admin"
+    password: "secret"
+`, filepath.Join(dir, "changes.json"), filepath.Join(dir, "audit.jsonl"), catalyst.URL))
+	input := filepath.Join(dir, "protocols.txt")
+	require.NoError(t, os.WriteFile(input, []byte("rtp-audio\nhttps\n"), 0o600))
+	opts := func() *ExecuteOptions {
+		return &ExecuteOptions{InputFile: input, OutputType: "cisco", OutputFile: "-", Stdout: io.Discard, PushCatalyst: true, RequestedBy: "alice"}
+	}
+	ctx := context.Background()
+
+	// Without a switch push there is no change to hold the policy
+	err := app.Execute(ctx, opts())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--push-catalyst-center needs --push-config")
+	assert.Zero(t, requested())
+
+	run := opts()
+	run.PushConfig = true
+	require.NoError(t, app.Execute(ctx, run))
+	assert.Zero(t, requested())
+	assert.Empty(t, device.Pushed())
+	list, err := app.changes.List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	change := list[0]
+	assert.Equal(t, changes.StateDraft, change.State)
+	assert.True(t, change.CatalystCenter)
+
+	// The policy is pushed when the approved change is applied
+	_, err = app.changes.Approve(change.ID, "bob", "")
+	require.NoError(t, err)
+	_, _, err = app.pushChange(ctx, change.ID, "bob", "", false, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Catalyst Center")
+	assert.Len(t, device.Pushed(), 1)
+	assert.NotZero(t, requested())
+}
+
+func TestCatalystCenterPushChecksMaintenanceWindow(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	catalyst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer catalyst.Close()
+	requested := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	now := time.Now()
+	dir := t.TempDir()
+	newApp := func(outsideWindow string) *Application {
+		app, _ := newTestApplication(t, &sshtest.Transcript{}, fmt.Sprintf(`
+maintenance:
+  enabled: true
+  outside_window: %q
+  queue_file: %q
+  blackouts:
+    - name: "freeze"
+      devices: ["catalyst-center"]
+      dates: ["%s..%s"]
+output:
+  catalyst_center:
+    base_url: %q
+    username: "admin"
+    password: "secret"
+    maintenance_target: "catalyst-center"
+`, outsideWindow, filepath.Join(dir, "queue.json"), now.AddDate(0, 0, -1).Format("2006-01-02"), now.AddDate(0, 0, 1).Format("2006-01-02"), catalyst.URL))
+		return app
+	}
+	input := filepath.Join(dir, "protocols.txt")
+	require.NoError(t, os.WriteFile(input, []byte("rtp-audio\nhttps\n"), 0o600))
+	opts := func() *ExecuteOptions {
+		return &ExecuteOptions{InputFile: input, OutputType: "cisco", OutputFile: "-", Stdout: io.Discard, PushCatalyst: true, RequestedBy: "alice"}
+	}
+	ctx := context.Background()
+
+	app := newApp("refuse")
+	err := app.Execute(ctx, opts())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "push refused")
+	assert.Zero(t, requested())
+
+	// An emergency push goes ahead
+	run := opts()
+	run.Emergency = true
+	require.Error(t, app.Execute(ctx, run))
+	assert.NotZero(t, requested())
+
+	mu.Lock()
+	requests = 0
+	mu.Unlock()
+	app = newApp("queue")
+	require.NoError(t, app.Execute(ctx, opts()))
+	assert.Zero(t, requested())
+	queued, err := app.pushQueue.List()
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	assert.True(t, queued[0].CatalystCenter)
+	assert.Equal(t, "catalyst-center", queued[0].Target.Host)
+	assert.Contains(t, queued[0].Config, "policyScope")
+}
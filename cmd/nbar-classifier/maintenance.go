@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/catalystcenter"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+)
+
+// queueDispatchInterval is how often queued pushes are checked outside
+// serve mode
+const queueDispatchInterval = time.Minute
+
+// maintenanceTarget returns the device pushes go to
+func (app *Application) maintenanceTarget() maintenance.Target {
+	return app.config.Maintenance.Target(app.config.SSH.Host)
+}
+
+// catalystTarget returns the target Catalyst Center pushes are checked
+// against
+func (app *Application) catalystTarget() maintenance.Target {
+	return app.config.Maintenance.Target(app.config.Output.CatalystCenter.MaintenanceHost())
+}
+
+// checkWindow decides whether a push may be made now. Outside a maintenance
+// window the push is refused, or queued and reported as not to be made now.
+// Emergency pushes are made anyway and audited. The push goes to its target,
+// or to the switch when it has none.
+func (app *Application) checkWindow(push maintenance.Push, emergency bool) (bool, error) {
+	if app.maintenance == nil {
+		return true, nil
+	}
+	target := push.Target
+	if target.Host == "" {
+		target = app.maintenanceTarget()
+	}
+	decision := app.maintenance.Check(target, time.Now())
+	if decision.Allowed {
+		return true, nil
+	}
+
+	fields := logger.Fields{
+		"host":         target.Host,
+		"reason":       decision.Reason(),
+		"requested_by": push.RequestedBy,
+	}
+	if push.ChangeID != "" {
+		fields["change_id"] = push.ChangeID
+	}
+	if push.CatalystCenter {
+		fields["catalyst_center"] = true
+	}
+	if emergency {
+		app.logger.Security("maintenance_override", fields)
+		app.logger.Audit("maintenance_override", fields)
+		return true, nil
+	}
+	if app.pushQueue == nil {
+		app.logger.Audit("push_refused", fields)
+		return false, fmt.Errorf("push refused: %s", decision.Reason())
+	}
+
+	push.Target = target
+	push.NotBefore = decision.Next
+	queued, err := app.pushQueue.Add(push)
+	if err != nil {
+		return false, fmt.Errorf("failed to queue push: %w", err)
+	}
+	fields["push_id"] = queued.ID
+	app.logger.Audit("push_queued", fields)
+	app.logger.WithFields(fields).Info("Push queued until the maintenance window opens")
+	return false, nil
+}
+
+// dispatchQueuedPushes makes the queued pushes to this device and its
+// Catalyst Center whose window is open. A push is taken off the queue before it is made, so a failed push
+// is not retried.
+func (app *Application) dispatchQueuedPushes(ctx context.Context) error {
+	if app.pushQueue == nil {
+		return nil
+	}
+	pushes, err := app.pushQueue.List()
+	if err != nil {
+		return err
+	}
+
+	catalyst := app.catalystTarget().Host
+	var errs []error
+	now := time.Now()
+	for _, push := range pushes {
+		ours := push.Target.Host == app.config.SSH.Host || catalyst != "" && push.Target.Host == catalyst
+		if !ours || !app.maintenance.Check(push.Target, now).Allowed {
+			continue
+		}
+		if _, err := app.pushQueue.Remove(push.ID); err != nil {
+			// Another process took it
+			if !errors.Is(err, maintenance.ErrNotFound) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := app.makeQueuedPush(ctx, push); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// makeQueuedPush makes a push taken off the queue
+func (app *Application) makeQueuedPush(ctx context.Context, push maintenance.Push) error {
+	app.executeMu.Lock()
+	defer app.executeMu.Unlock()
+
+	var err error
+	if push.CatalystCenter {
+		var policy catalystcenter.Policy
+		if err = json.Unmarshal([]byte(push.Config), &policy); err == nil {
+			err = app.sendCatalystPolicy(ctx, &policy)
+		}
+	} else if push.ChangeID != "" {
+		if app.changes == nil {
+			err = fmt.Errorf("change approval is not enabled")
+		} else {
+			_, _, err = app.pushChange(ctx, push.ChangeID, push.RequestedBy, push.Comment, push.Rollback, false)
+		}
+	} else {
+		err = app.handleConfigPush(ctx, push.Config, &ExecuteOptions{
+			PushConfig:  true,
+			SaveConfig:  push.SaveConfig,
+			RequestedBy: push.RequestedBy,
+		})
+	}
+
+	fields := logger.Fields{
+		"push_id":      push.ID,
+		"host":         push.Target.Host,
+		"requested_by": push.RequestedBy,
+		"queued_at":    push.QueuedAt,
+		"success":      err == nil,
+	}
+	if push.ChangeID != "" {
+		fields["change_id"] = push.ChangeID
+	}
+	if push.CatalystCenter {
+		fields["catalyst_center"] = true
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	app.logger.Audit("queued_push", fields)
+	if err != nil {
+		return fmt.Errorf("queued push %s failed: %w", push.ID, err)
+	}
+	return nil
+}
+
+// startQueueDispatcher makes queued pushes when their window opens until the
+// context is cancelled. Serve mode dispatches them as a job instead.
+func (app *Application) startQueueDispatcher(ctx context.Context) {
+	if app.pushQueue == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(queueDispatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := app.dispatchQueuedPushes(ctx); err != nil {
+					app.logger.WithError(err).Error("Failed to make queued pushes")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/notify"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// notifyTimeout bounds the delivery of a notification, retries included
+const notifyTimeout = time.Minute
+
+// notify sends an event in the background so a slow channel does not hold
+// up a push; Close waits for notifications in flight
+func (app *Application) notify(event notify.Event) {
+	if app.notifier == nil {
+		return
+	}
+	if event.Host == "" {
+		event.Host = app.config.SSH.Host
+	}
+	event.Time = time.Now()
+
+	app.notifyWG.Add(1)
+	go func() {
+		defer app.notifyWG.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := app.notifier.Notify(ctx, event); err != nil {
+			app.logger.WithError(err).WithField("event", event.Kind).Warn("Failed to send notification")
+		}
+	}()
+}
+
+// notifyPush reports the outcome of a push
+func (app *Application) notifyPush(opts *ExecuteOptions, err error) {
+	event := notify.Event{
+		Kind:        notify.KindPushSucceeded,
+		ChangeID:    opts.ChangeID,
+		Rollback:    opts.Rollback,
+		RequestedBy: opts.RequestedBy,
+	}
+	if err != nil {
+		event.Kind = notify.KindPushFailed
+		event.Error = err.Error()
+	}
+	app.notify(event)
+}
+
+// notifyNewProtocols reports protocols classified for the first time
+func (app *Application) notifyNewProtocols(protocols []string, results map[string]qos.Classification) {
+	if len(protocols) == 0 {
+		return
+	}
+	event := notify.Event{Kind: notify.KindNewProtocols}
+	for _, protocol := range protocols {
+		event.Protocols = append(event.Protocols, notify.ProtocolClass{
+			Protocol: protocol,
+			Class:    results[protocol].Class.String(),
+		})
+	}
+	sort.Slice(event.Protocols, func(i, j int) bool {
+		return event.Protocols[i].Protocol < event.Protocols[j].Protocol
+	})
+	app.notify(event)
+}
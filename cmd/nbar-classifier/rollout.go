@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/notify"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rollout"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh"
+)
+
+// runRollout performs "nbar-classifier rollout plan|start <config>". With
+// approval enabled, start takes the ID of an approved change instead of a
+// configuration file, and the change records the rollout.
+func runRollout(ctx context.Context, app *Application, args []string, comment string, emergency bool, stdout io.Writer) error {
+	waves, err := app.config.Rollout.Plan(app.config.Maintenance)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: nbar-classifier rollout [flags] plan|start <config file or change id>")
+	}
+
+	switch args[0] {
+	case "plan":
+		printRolloutPlan(stdout, waves)
+		return nil
+	case "start":
+	default:
+		return fmt.Errorf("unknown rollout command %q, expected plan or start", args[0])
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: nbar-classifier rollout [flags] start <config file or change id>")
+	}
+
+	config, changeID, err := app.rolloutConfig(args[1])
+	if err != nil {
+		return err
+	}
+
+	actor := cliActor()
+	rolloutConfig := app.config.Rollout
+	fields := logger.Fields{
+		"actor":     actor,
+		"waves":     len(waves),
+		"emergency": emergency,
+	}
+	if changeID != "" {
+		fields["change_id"] = changeID
+	}
+	app.logger.Audit("rollout_start", fields)
+
+	var report rollout.Report
+	run := func(ctx context.Context, config string) ([]string, error) {
+		var err error
+		report, err = rollout.Run(ctx, rollout.Options{
+			Waves:             waves,
+			Parallel:          rolloutConfig.Parallel,
+			MaxDrops:          rolloutConfig.MaxDrops,
+			MaxInterfaceDrops: rolloutConfig.MaxInterfaceDrops,
+			SaveConfig:        rolloutConfig.SaveConfig,
+			Gate: func(target maintenance.Target) error {
+				return app.checkRolloutWindow(target, actor, emergency)
+			},
+			Observe: func(event rollout.Event) {
+				app.logRolloutEvent(event, changeID)
+			},
+		}, config, app.dialRolloutDevice)
+		return pushedHosts(report), err
+	}
+
+	if changeID != "" {
+		_, err = app.changes.RollOut(ctx, changeID, actor, comment, func(ctx context.Context, change changes.Change) ([]string, error) {
+			return run(ctx, change.Config)
+		})
+	} else {
+		_, err = run(ctx, config)
+	}
+
+	fields["status"] = report.Status
+	fields["pushed"] = pushedHosts(report)
+	if report.Reason != "" {
+		fields["reason"] = report.Reason
+	}
+	app.logger.Audit("rollout_finish", fields)
+
+	if len(report.Waves) > 0 {
+		printRolloutReport(stdout, report)
+	}
+	return err
+}
+
+// rolloutConfig returns the configuration to roll out: the configuration of
+// an approved change when approval is enabled, otherwise the file
+func (app *Application) rolloutConfig(arg string) (string, string, error) {
+	if app.changes == nil {
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read rollout configuration: %w", err)
+		}
+		return string(data), "", nil
+	}
+
+	change, err := app.changes.Get(arg)
+	if err != nil {
+		return "", "", err
+	}
+	if change.State != changes.StateApproved {
+		return "", "", fmt.Errorf("change %s is %s, expected %s: %w", change.ID, change.State, changes.StateApproved, changes.ErrState)
+	}
+	// A rollout reaches switches only and would mark the change applied
+	// without its Catalyst Center policy
+	if change.CatalystCenter {
+		return "", "", fmt.Errorf("change %s also pushes a Catalyst Center policy, apply it with \"changes apply\" instead", change.ID)
+	}
+	return change.Config, change.ID, nil
+}
+
+// checkRolloutWindow refuses to start a wave outside a device's maintenance
+// window. Emergency rollouts go ahead and are audited.
+func (app *Application) checkRolloutWindow(target maintenance.Target, actor string, emergency bool) error {
+	if app.maintenance == nil {
+		return nil
+	}
+	decision := app.maintenance.Check(target, time.Now())
+	if decision.Allowed {
+		return nil
+	}
+
+	fields := logger.Fields{
+		"host":         target.Host,
+		"reason":       decision.Reason(),
+		"requested_by": actor,
+	}
+	if emergency {
+		app.logger.Security("maintenance_override", fields)
+		app.logger.Audit("maintenance_override", fields)
+		return nil
+	}
+	app.logger.Audit("push_refused", fields)
+	return fmt.Errorf("push refused: %s", decision.Reason())
+}
+
+// dialRolloutDevice connects to an inventory device over SSH
+func (app *Application) dialRolloutDevice(target maintenance.Target) (rollout.Device, error) {
+	cfg := app.config.Rollout.SSH(app.config.SSH, target.Host)
+	return ssh.New(&cfg, app.logger)
+}
+
+// logRolloutEvent logs the progress of a rollout and notifies the outcome
+// of each device once its wave has ended
+func (app *Application) logRolloutEvent(event rollout.Event, changeID string) {
+	if event.Stage == "done" {
+		kind := notify.KindPushSucceeded
+		if event.Status == rollout.StatusFailed {
+			kind = notify.KindPushFailed
+		}
+		app.notify(notify.Event{Kind: kind, Host: event.Host, ChangeID: changeID, Error: event.Error})
+	}
+
+	fields := logger.Fields{
+		"component": "rollout",
+		"wave":      event.Wave,
+		"host":      event.Host,
+		"stage":     event.Stage,
+	}
+	if event.Status != "" {
+		fields["status"] = event.Status
+	}
+	entry := app.logger.WithFields(fields)
+	if event.Error != "" {
+		entry.WithField("error", event.Error).Warn("Rollout step failed")
+		return
+	}
+	entry.Info("Rollout progress")
+}
+
+// pushedHosts returns the devices that received the configuration
+func pushedHosts(report rollout.Report) []string {
+	var hosts []string
+	for _, wave := range report.Waves {
+		for _, device := range wave.Devices {
+			if !device.PushedAt.IsZero() {
+				hosts = append(hosts, device.Host)
+			}
+		}
+	}
+	return hosts
+}
+
+// printRolloutPlan writes the waves and their devices
+func printRolloutPlan(w io.Writer, waves []rollout.Wave) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "WAVE\tSOAK\tHOST\tGROUPS")
+	for _, wave := range waves {
+		for _, target := range wave.Targets {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", wave.Name, wave.Soak, target.Host, strings.Join(target.Groups, ","))
+		}
+	}
+	table.Flush()
+}
+
+// printRolloutReport writes the outcome of each device
+func printRolloutReport(w io.Writer, report rollout.Report) {
+	fmt.Fprintf(w, "Rollout %s\n", report.Status)
+	if report.Reason != "" {
+		fmt.Fprintf(w, "Reason: %s\n", report.Reason)
+	}
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "WAVE\tHOST\tSTATUS\tDROPS\tERROR")
+	for _, wave := range report.Waves {
+		for _, device := range wave.Devices {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", wave.Name, device.Host, device.Status, formatDrops(device.Drops), device.Error)
+		}
+	}
+	table.Flush()
+}
+
+// formatDrops lists the counters that grew
+func formatDrops(drops map[string]uint64) string {
+	parts := make([]string, 0, len(drops))
+	for key, value := range drops {
+		parts = append(parts, fmt.Sprintf("%s +%d", key, value))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
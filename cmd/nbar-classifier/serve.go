@@ -0,0 +1,590 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/daemon"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/input"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/metrics"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/netflow"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/notify"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/schedule"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/transport"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// Serve mode job names
+const (
+	jobDiscovery      = "discovery"
+	jobClassification = "classification"
+	jobDriftCheck     = "drift_check"
+	jobPush           = "push"
+	jobQueuedPushes   = "queued_pushes"
+)
+
+// queuedPushSchedule is how often serve mode checks for queued pushes whose
+// window opened
+const queuedPushSchedule = "* * * * *"
+
+// serveActor is the author of changes proposed by the push job
+const serveActor = "serve"
+
+// server runs the serve mode: scheduled discovery, classification, drift
+// check and push, with state kept between runs
+type server struct {
+	logger     *logger.Logger
+	loadConfig func() (*config.Config, error)
+	store      *daemon.Store
+	scheduler  *daemon.Scheduler
+	metrics    *metrics.Metrics
+	web        *web.Server
+	runs       *runs.Manager
+
+	// busy keeps jobs, API runs and reloads from overlapping
+	busy sync.Mutex
+
+	// ctx lives as long as the server and bounds the NetFlow collector
+	ctx context.Context
+
+	// app and the collector are replaced by a reload, which runs between
+	// jobs
+	mu               sync.Mutex
+	app              *Application
+	collector        *netflow.Collector
+	collectorOptions netflow.Options
+	stopCollector    context.CancelFunc
+	// traffic holds the byte counts of the last switch discovery
+	traffic map[string]uint64
+}
+
+// serve runs the scheduled jobs until the context is cancelled. SIGHUP
+// reloads the configuration with loadConfig. The application is closed on
+// return.
+func serve(ctx context.Context, app *Application, loadConfig func() (*config.Config, error)) error {
+	store, err := daemon.OpenStore(app.config.Serve.StateFile)
+	if err != nil {
+		app.Close()
+		return err
+	}
+
+	s := &server{
+		logger:     app.logger,
+		loadConfig: loadConfig,
+		store:      store,
+		scheduler:  daemon.NewScheduler(app.logger, store),
+		metrics:    app.metrics,
+		ctx:        ctx,
+		app:        app,
+	}
+	defer func() {
+		s.stopNetFlow()
+		if err := s.current().Close(); err != nil {
+			s.logger.WithError(err).Warn("Failed to close application")
+		}
+	}()
+
+	if err := s.configure(app.config); err != nil {
+		return err
+	}
+
+	if app.config.Web.Enabled {
+		s.runs, err = runs.New(app.config.Runs.Options(), s.executeRun)
+		if err != nil {
+			return fmt.Errorf("failed to create run manager: %w", err)
+		}
+		app.runs = s.runs
+	}
+	if err := app.StartServices(ctx); err != nil {
+		return err
+	}
+	s.web = app.web
+	defer app.StopServices()
+	s.publishState(app)
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	go func() {
+		for {
+			select {
+			case <-hangup:
+				s.logger.Info("Received reload signal")
+				s.scheduler.Do(s.reload)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	s.logger.WithFields(logger.Fields{
+		"state_file": app.config.Serve.StateFile,
+		"source":     app.config.Serve.Source,
+		"push":       app.config.Serve.Push.Enabled,
+	}).Info("Serve mode started")
+
+	return s.scheduler.Run(ctx)
+}
+
+// current returns the application the jobs use
+func (s *server) current() *Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.app
+}
+
+// configure builds the jobs of cfg and starts or restarts the NetFlow
+// collector when discovery uses it
+func (s *server) configure(cfg *config.Config) error {
+	jobs, err := s.jobs(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Serve.Source == config.ServeSourceNetFlow {
+		if err := s.startNetFlow(cfg.NetFlow.Options()); err != nil {
+			return err
+		}
+	} else {
+		s.stopNetFlow()
+	}
+
+	return s.scheduler.SetJobs(jobs)
+}
+
+// jobs builds the scheduled jobs; push is only scheduled when enabled
+func (s *server) jobs(cfg *config.Config) ([]daemon.Job, error) {
+	type jobSpec struct {
+		name string
+		job  config.JobConfig
+		run  func(context.Context) error
+	}
+	serveConfig := cfg.Serve
+	specs := []jobSpec{
+		{jobDiscovery, serveConfig.Discovery, s.discover},
+		{jobClassification, serveConfig.Classification, s.classify},
+		{jobDriftCheck, serveConfig.DriftCheck, s.checkDrift},
+	}
+	if serveConfig.Push.Enabled {
+		specs = append(specs, jobSpec{jobPush, serveConfig.Push.JobConfig, s.push})
+	}
+	if cfg.Maintenance.Enabled && cfg.Maintenance.OutsideWindow == config.OutsideWindowQueue {
+		specs = append(specs, jobSpec{jobQueuedPushes, config.JobConfig{Schedule: queuedPushSchedule}, s.dispatchQueuedPushes})
+	}
+
+	jobs := make([]daemon.Job, 0, len(specs))
+	for _, spec := range specs {
+		sched, err := schedule.Parse(spec.job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s schedule: %w", spec.name, err)
+		}
+		jobs = append(jobs, daemon.Job{
+			Name:       spec.name,
+			Schedule:   sched,
+			RunOnStart: spec.job.RunOnStart,
+			Run:        s.exclusive(spec.run),
+		})
+	}
+	return jobs, nil
+}
+
+// exclusive wraps a job so it does not overlap API runs
+func (s *server) exclusive(run func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		s.busy.Lock()
+		defer s.busy.Unlock()
+		return run(ctx)
+	}
+}
+
+// executeRun performs an API run with the current application
+func (s *server) executeRun(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+	s.busy.Lock()
+	defer s.busy.Unlock()
+	return s.current().executeRun(ctx, req, tracker)
+}
+
+// startNetFlow starts the long-lived collector discovery reads from, unless
+// it already runs with the same options
+func (s *server) startNetFlow(opts netflow.Options) error {
+	s.mu.Lock()
+	running := s.collector != nil && s.collectorOptions == opts
+	s.mu.Unlock()
+	if running {
+		return nil
+	}
+	s.stopNetFlow()
+
+	collector := netflow.New(opts)
+	if err := collector.Listen(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	go func() {
+		if err := collector.Serve(ctx); err != nil {
+			s.logger.WithError(err).Error("NetFlow collector failed")
+		}
+	}()
+
+	s.mu.Lock()
+	s.collector = collector
+	s.collectorOptions = opts
+	s.stopCollector = cancel
+	s.mu.Unlock()
+
+	s.logger.WithField("listen", collector.Addr().String()).Info("Collecting protocols from NetFlow exports")
+	return nil
+}
+
+// stopNetFlow stops the collector if it runs
+func (s *server) stopNetFlow() {
+	s.mu.Lock()
+	collector, cancel := s.collector, s.stopCollector
+	s.collector, s.stopCollector = nil, nil
+	s.mu.Unlock()
+
+	if collector != nil {
+		cancel()
+		collector.Close()
+	}
+}
+
+// publishState shows the stored classifications in the web API until the
+// first classification job finishes
+func (s *server) publishState(app *Application) {
+	state := s.store.Snapshot()
+	if s.web == nil || state.ClassifiedAt.IsZero() {
+		return
+	}
+	s.web.SetLastRun(&web.RunResult{
+		Source:          app.config.Serve.Source,
+		Protocols:       state.Protocols,
+		Classifications: state.Classifications,
+		StartedAt:       state.Job(jobClassification).LastStart,
+		FinishedAt:      state.ClassifiedAt,
+	})
+}
+
+// reload loads the configuration again and replaces the application. The
+// metrics and web servers keep running with their original settings.
+func (s *server) reload() {
+	s.busy.Lock()
+	defer s.busy.Unlock()
+
+	cfg, err := s.loadConfig()
+	if err != nil {
+		s.logger.WithError(err).Error("Configuration reload failed, keeping the current configuration")
+		return
+	}
+
+	old := s.current()
+	if err := old.cache.Save(); err != nil {
+		s.logger.WithError(err).Warn("Failed to save cache before reload")
+	}
+
+	app, err := NewApplication(cfg, s.logger)
+	if err != nil {
+		s.logger.WithError(err).Error("Configuration reload failed, keeping the current configuration")
+		return
+	}
+	app.metrics = s.metrics
+	app.web = s.web
+	app.runs = s.runs
+	app.startCacheCleanup()
+	if s.web != nil {
+		s.web.SetBackend(app.webBackend())
+	}
+
+	s.mu.Lock()
+	s.app = app
+	s.mu.Unlock()
+
+	if err := s.configure(cfg); err != nil {
+		s.logger.WithError(err).Error("Failed to apply reloaded serve configuration")
+	}
+	if err := old.Close(); err != nil {
+		s.logger.WithError(err).Warn("Failed to close previous application")
+	}
+	if err := s.logger.SetLevel(cfg.Logging.Level); err != nil {
+		s.logger.WithError(err).Warn("Failed to apply reloaded log level")
+	}
+	if cfg.Metrics.Enabled != old.config.Metrics.Enabled || cfg.Web.Enabled != old.config.Web.Enabled {
+		s.logger.Warn("Enabling or disabling the metrics and web servers requires a restart")
+	}
+
+	s.logger.ConfigChange("reload", logger.Fields{
+		"source": cfg.Serve.Source,
+		"push":   cfg.Serve.Push.Enabled,
+	})
+}
+
+// discover fetches the protocols from the configured source and merges them
+// into the state
+func (s *server) discover(ctx context.Context) error {
+	app := s.current()
+	serveConfig := app.config.Serve
+
+	var protocols []string
+	switch serveConfig.Source {
+	case config.ServeSourceSwitch:
+		discovery, err := app.device.FetchProtocolDiscovery()
+		if err != nil {
+			return fmt.Errorf("failed to fetch protocols from switch: %w", err)
+		}
+		protocols = discovery.Protocols()
+		s.mu.Lock()
+		s.traffic = discovery.Traffic()
+		s.mu.Unlock()
+	case config.ServeSourceFile:
+		format, err := input.ParseFormat(serveConfig.InputFormat)
+		if err != nil {
+			return err
+		}
+		protocols, err = app.loadProtocolsFromFile(serveConfig.InputFile, format)
+		if err != nil {
+			return fmt.Errorf("failed to load protocols from file: %w", err)
+		}
+	case config.ServeSourceNetFlow:
+		s.mu.Lock()
+		collector := s.collector
+		s.mu.Unlock()
+		if collector == nil {
+			return fmt.Errorf("NetFlow collector is not running")
+		}
+		protocols = collector.Protocols()
+		if err := collector.LastError(); err != nil {
+			app.logger.WithError(err).Debug("Last NetFlow decode error")
+		}
+	}
+
+	var added []string
+	if err := s.store.Update(func(state *daemon.State) {
+		added = state.AddProtocols(protocols)
+		state.NewProtocols = append(state.NewProtocols, added...)
+		state.DiscoveredAt = time.Now()
+	}); err != nil {
+		return err
+	}
+
+	fields := logger.Fields{
+		"source":    serveConfig.Source,
+		"protocols": len(protocols),
+		"new":       len(added),
+	}
+	if len(added) > 0 {
+		fields["new_protocols"] = added
+	}
+	app.logger.WithFields(fields).Info("Discovered protocols")
+	return nil
+}
+
+// classify classifies all known protocols, writes the serve outputs and
+// records the desired Cisco configuration
+func (s *server) classify(ctx context.Context) error {
+	app := s.current()
+	serveConfig := app.config.Serve
+
+	start := time.Now()
+	state := s.store.Snapshot()
+	if len(state.Protocols) == 0 {
+		app.logger.Info("No protocols discovered yet, skipping classification")
+		return nil
+	}
+
+	targets, err := app.resolveTargets(serveConfig.Output, serveConfig.OutputFile)
+	if err != nil {
+		return err
+	}
+	result, err := app.buildResult(ctx, state.Protocols, true, serveConfig.Source == config.ServeSourceSwitch, nil)
+	if err != nil {
+		return err
+	}
+	outputs, err := app.writeOutputs(targets, result, os.Stdout)
+	if err != nil {
+		return err
+	}
+	app.logStatistics(result.Classifications)
+	s.mu.Lock()
+	traffic := s.traffic
+	s.mu.Unlock()
+	app.recordRun(serveConfig.Source, start, state.Protocols, traffic, result, targets)
+
+	// The desired configuration is needed for drift checks even when the
+	// cisco output is not written
+	desired, written := outputs["cisco"]
+	if !written {
+		generator, err := app.outputs.Get("cisco")
+		if err != nil {
+			return err
+		}
+		data, err := generator.Generate(result)
+		if err != nil {
+			return fmt.Errorf("failed to generate cisco output: %w", err)
+		}
+		desired = string(data)
+	}
+
+	if err := app.cache.Save(); err != nil {
+		app.logger.WithError(err).Warn("Failed to save cache")
+	}
+
+	return s.store.Update(func(state *daemon.State) {
+		state.Classifications = result.Classifications
+		state.DesiredConfig = desired
+		state.NewProtocols = nil
+		state.ClassifiedAt = time.Now()
+	})
+}
+
+// dispatchQueuedPushes makes the queued pushes whose window opened
+func (s *server) dispatchQueuedPushes(ctx context.Context) error {
+	return s.current().dispatchQueuedPushes(ctx)
+}
+
+// checkDrift compares the class-maps and policy-maps on the switch with the
+// desired configuration, and the interface service-policy attachments with
+// the configured attachment targets
+func (s *server) checkDrift(ctx context.Context) error {
+	app := s.current()
+
+	state := s.store.Snapshot()
+	if state.DesiredConfig == "" {
+		app.logger.Info("No desired configuration yet, skipping drift check")
+		return nil
+	}
+
+	desired, err := transport.ParseCLI(state.DesiredConfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse desired configuration: %w", err)
+	}
+
+	var classMaps []transport.ClassMap
+	var policyMaps []transport.PolicyMap
+	if app.transport != nil {
+		classMaps, err = app.transport.ClassMaps(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read class-maps from switch: %w", err)
+		}
+		policyMaps, err = app.transport.PolicyMaps(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read policy-maps from switch: %w", err)
+		}
+	} else {
+		runningConfig, err := app.device.FetchRunningConfig()
+		if err != nil {
+			return fmt.Errorf("failed to fetch running configuration: %w", err)
+		}
+		classMaps = transport.ParseRunningClassMaps(runningConfig)
+		policyMaps = transport.ParseRunningPolicyMaps(runningConfig)
+	}
+
+	drift := transport.ChangedClassMaps(classMaps, desired.ClassMaps)
+	drift = append(drift, transport.ChangedPolicyMaps(policyMaps, desired.PolicyMaps)...)
+
+	// The attachments rendered into the desired configuration were needed
+	// when it was classified; the interfaces are compared as they are now
+	var attachments []interfaces.Change
+	if app.config.QoS.Attachment.Enabled && app.config.Serve.Source == config.ServeSourceSwitch {
+		attachments, err = app.computeAttachments()
+		if err != nil {
+			return fmt.Errorf("failed to compute service-policy attachments: %w", err)
+		}
+		for _, change := range attachments {
+			drift = append(drift, fmt.Sprintf("%s %s", change.Interface, change.Direction))
+		}
+	}
+
+	if err := s.store.Update(func(state *daemon.State) {
+		state.Drift = drift
+		state.AttachmentDrift = attachments
+		state.DriftCheckedAt = time.Now()
+	}); err != nil {
+		return err
+	}
+
+	if len(drift) > 0 {
+		app.logger.WithFields(logger.Fields{
+			"count": len(drift),
+			"drift": drift,
+		}).Warn("Switch configuration drifted from the desired configuration")
+		// Drift is reported once until it changes
+		if strings.Join(drift, "\n") != strings.Join(state.Drift, "\n") {
+			app.notify(notify.Event{Kind: notify.KindDrift, ClassMaps: drift})
+		}
+	} else {
+		app.logger.Info("Switch configuration matches the desired configuration")
+	}
+	return nil
+}
+
+// push applies the desired configuration, or proposes it as a change when
+// approval is enabled, when the last drift check, made after the last
+// classification, found drift
+func (s *server) push(ctx context.Context) error {
+	app := s.current()
+	pushConfig := app.config.Serve.Push
+
+	state := s.store.Snapshot()
+	if len(state.Drift) == 0 {
+		app.logger.Info("No drift detected, nothing to push")
+		return nil
+	}
+	if state.DriftCheckedAt.Before(state.ClassifiedAt) {
+		app.logger.Info("Desired configuration changed since the last drift check, skipping push")
+		return nil
+	}
+	desired := state.DesiredConfig
+	if app.config.QoS.Attachment.Enabled && app.config.Serve.Source == config.ServeSourceSwitch {
+		desired = output.WithAttachments(desired, state.AttachmentDrift)
+	}
+
+	// With approval enabled the push waits as a change; proposing the same
+	// configuration again returns the pending change
+	if app.changes != nil && !pushConfig.DryRun {
+		_, err := app.proposeChange(app.config.Serve.Source, desired, state.Classifications, &ExecuteOptions{
+			SaveConfig:  pushConfig.SaveConfig,
+			RequestedBy: serveActor,
+		})
+		return err
+	}
+
+	// The job runs again, so it waits for a window instead of queuing
+	if app.maintenance != nil && !pushConfig.DryRun {
+		if decision := app.maintenance.Check(app.maintenanceTarget(), time.Now()); !decision.Allowed {
+			app.logger.WithField("reason", decision.Reason()).Info("Skipping push outside the maintenance window")
+			return nil
+		}
+	}
+
+	if err := app.handleConfigPush(ctx, desired, &ExecuteOptions{
+		PushConfig: true,
+		DryRun:     pushConfig.DryRun,
+		SaveConfig: pushConfig.SaveConfig,
+	}); err != nil {
+		return fmt.Errorf("failed to push configuration: %w", err)
+	}
+	if pushConfig.DryRun {
+		return nil
+	}
+
+	app.logger.Audit("serve_push", logger.Fields{
+		"host":  app.config.SSH.Host,
+		"drift": state.Drift,
+	})
+	return s.store.Update(func(state *daemon.State) {
+		state.Drift = nil
+		state.AttachmentDrift = nil
+		state.PushedAt = time.Now()
+	})
+}
@@ -340,15 +340,9 @@ func (p *DeepSeekProvider) parseManually(content string, protocols []string) map
 	for _, line := range lines {
 		for _, protocol := range protocols {
 			if strings.Contains(strings.ToLower(line), strings.ToLower(protocol)) {
-				class := qos.CS1 // Default
-				upperLine := strings.ToUpper(line)
-
-				if strings.Contains(upperLine, "EF") {
-					class = qos.EF
-				} else if strings.Contains(upperLine, "AF41") {
-					class = qos.AF41
-				} else if strings.Contains(upperLine, "AF21") {
-					class = qos.AF21
+				class := matchClassName(line)
+				if class == "" {
+					class = qos.CurrentModel().DefaultClass()
 				}
 
 				results[protocol] = qos.Classification{
@@ -376,7 +370,7 @@ func (p *DeepSeekProvider) mapToClassifications(classifications []qos.Classifica
 				"protocol": classification.Protocol,
 				"class":    classification.Class,
 			}).Warn("Invalid QoS class from AI, using default")
-			classification.Class = qos.CS1
+			classification.Class = qos.CurrentModel().DefaultClass()
 		}
 
 		// Set confidence and source
@@ -390,3 +384,39 @@ func (p *DeepSeekProvider) mapToClassifications(classifications []qos.Classifica
 
 	return results
 }
+
+// matchClassName returns the highest priority class whose name appears as a
+// word in the given line, or an empty class if none does
+func matchClassName(line string) qos.Class {
+	upperLine := strings.ToUpper(line)
+	for _, class := range qos.CurrentModel().MarkingClasses() {
+		if containsWord(upperLine, strings.ToUpper(class.String())) {
+			return class
+		}
+	}
+	return ""
+}
+
+// containsWord reports whether word appears in s between non-word characters
+func containsWord(s, word string) bool {
+	if word == "" {
+		return false
+	}
+	for offset := 0; offset < len(s); {
+		i := strings.Index(s[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		if (start == 0 || !isWordChar(s[start-1])) && (end == len(s) || !isWordChar(s[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+	return false
+}
+
+// isWordChar reports whether c is a letter, digit or underscore
+func isWordChar(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
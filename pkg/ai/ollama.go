@@ -77,7 +77,7 @@ func (p *OllamaProvider) ClassifyProtocols(ctx context.Context, protocols []stri
 	for _, protocol := range protocols {
 		results[protocol] = qos.Classification{
 			Protocol:   protocol,
-			Class:      qos.CS1,
+			Class:      qos.CurrentModel().DefaultClass(),
 			Confidence: 0.5,
 			Source:     "ai",
 		}
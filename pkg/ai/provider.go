@@ -124,8 +124,25 @@ func (m *Manager) createProvider(providerName string, cfg *config.AIConfig) (Pro
 	}
 }
 
+// BatchResult reports the outcome of one AI classification batch
+type BatchResult struct {
+	Index      int           `json:"index"`
+	Total      int           `json:"total"`
+	Protocols  []string      `json:"protocols"`
+	Provider   string        `json:"provider,omitempty"`
+	Classified int           `json:"classified"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+}
+
 // ClassifyProtocols classifies protocols using the available providers
 func (m *Manager) ClassifyProtocols(ctx context.Context, protocols []string, batchSize int) (map[string]qos.Classification, error) {
+	return m.ClassifyProtocolsWithProgress(ctx, protocols, batchSize, nil)
+}
+
+// ClassifyProtocolsWithProgress classifies protocols like ClassifyProtocols
+// and calls report, if not nil, after every batch
+func (m *Manager) ClassifyProtocolsWithProgress(ctx context.Context, protocols []string, batchSize int, report func(BatchResult)) (map[string]qos.Classification, error) {
 	if len(protocols) == 0 {
 		return make(map[string]qos.Classification), nil
 	}
@@ -137,6 +154,7 @@ func (m *Manager) ClassifyProtocols(ctx context.Context, protocols []string, bat
 
 	// Process protocols in batches
 	results := make(map[string]qos.Classification)
+	total := (len(protocols) + batchSize - 1) / batchSize
 
 	for i := 0; i < len(protocols); i += batchSize {
 		end := i + batchSize
@@ -151,7 +169,22 @@ func (m *Manager) ClassifyProtocols(ctx context.Context, protocols []string, bat
 		}
 
 		// Try each provider until one succeeds
-		batchResults, err := m.classifyBatch(ctx, batch)
+		start := time.Now()
+		batchResults, provider, err := m.classifyBatch(ctx, batch)
+		if report != nil {
+			result := BatchResult{
+				Index:      i / batchSize,
+				Total:      total,
+				Protocols:  batch,
+				Provider:   provider,
+				Classified: len(batchResults),
+				Duration:   time.Since(start),
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			report(result)
+		}
 		if err != nil {
 			return nil, fmt.Errorf("failed to classify batch: %w", err)
 		}
@@ -166,8 +199,9 @@ func (m *Manager) ClassifyProtocols(ctx context.Context, protocols []string, bat
 	return results, nil
 }
 
-// classifyBatch classifies a batch of protocols using available providers
-func (m *Manager) classifyBatch(ctx context.Context, protocols []string) (map[string]qos.Classification, error) {
+// classifyBatch classifies a batch of protocols using available providers and
+// returns the name of the provider that succeeded
+func (m *Manager) classifyBatch(ctx context.Context, protocols []string) (map[string]qos.Classification, string, error) {
 	var lastErr error
 
 	for i, provider := range m.providers {
@@ -198,14 +232,14 @@ func (m *Manager) classifyBatch(ctx context.Context, protocols []string) (map[st
 				classification.Timestamp = time.Now().Unix()
 				results[protocol] = classification
 			}
-			return results, nil
+			return results, provider.Name(), nil
 		}
 
 		lastErr = err
 		m.logger.WithError(err).WithField("provider", provider.Name()).Warn("Provider failed, trying next")
 	}
 
-	return nil, fmt.Errorf("all AI providers failed, last error: %w", lastErr)
+	return nil, "", fmt.Errorf("all AI providers failed, last error: %w", lastErr)
 }
 
 // GetPrimaryProvider returns the primary AI provider
@@ -293,18 +327,21 @@ func BuildClassificationPrompt(protocols []string) string {
 		protocolsText += fmt.Sprintf("%d. %s\n", i+1, protocol)
 	}
 
+	classesText := ""
+	for _, def := range qos.CurrentModel().Definitions() {
+		if def.Name == qos.Other {
+			continue
+		}
+		classesText += fmt.Sprintf("- %s: %s\n", def.Name, def.Description)
+	}
+
 	return fmt.Sprintf(`Classify these network protocols into QoS classes for a Cisco 9300 switch:
 
 %s
-
 QoS Classes:
-- EF: Real-time (voice/video calls)
-- AF41: Business-critical (interactive apps)
-- AF21: Important (email, transfers)
-- CS1: Background (updates, browsing)
-
+%s
 Respond ONLY with JSON array:
-[{"protocol":"name","class":"CLASS"}]`, protocolsText)
+[{"protocol":"name","class":"CLASS"}]`, protocolsText, classesText)
 }
 
 // ParseClassificationResponse parses the AI response into classifications
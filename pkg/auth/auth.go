@@ -0,0 +1,326 @@
+// Package auth authenticates web API requests with API tokens and HS256 JWTs
+// and authorizes them by role.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Role grants access to a set of endpoints; each role includes the ones
+// before it
+type Role string
+
+// Roles from least to most privileged
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "classifier-operator"
+	RoleDeployer Role = "deployer"
+	RoleAdmin    Role = "admin"
+)
+
+// roleRank orders the roles
+var roleRank = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleDeployer: 3,
+	RoleAdmin:    4,
+}
+
+// IsValid checks if the role is known
+func (r Role) IsValid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows reports whether the role includes the required role
+func (r Role) Allows(required Role) bool {
+	return r.IsValid() && roleRank[r] >= roleRank[required]
+}
+
+// Authentication methods
+const (
+	MethodToken = "token"
+	MethodJWT   = "jwt"
+)
+
+// Errors returned by Authenticate
+var (
+	ErrNoCredentials      = errors.New("authentication required")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// minSecretLength is the minimum JWT secret length in bytes
+const minSecretLength = 32
+
+// Principal is an authenticated caller
+type Principal struct {
+	Subject   string    `json:"subject"`
+	Role      Role      `json:"role"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Token is a static API token for automation
+type Token struct {
+	Name  string
+	Token string
+	Role  Role
+}
+
+// Options configures the authenticator
+type Options struct {
+	// JWTSecret verifies and signs HS256 tokens; empty disables JWTs
+	JWTSecret string
+	// Issuer and Audience are checked when set
+	Issuer   string
+	Audience string
+	// TokenTTL is the lifetime of issued JWTs
+	TokenTTL time.Duration
+	Tokens   []Token
+}
+
+// Authenticator verifies request credentials
+type Authenticator struct {
+	options Options
+	tokens  map[[sha256.Size]byte]Token
+}
+
+// New validates the options and creates an authenticator
+func New(opts Options) (*Authenticator, error) {
+	if opts.JWTSecret != "" && len(opts.JWTSecret) < minSecretLength {
+		return nil, fmt.Errorf("JWT secret must be at least %d bytes", minSecretLength)
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = time.Hour
+	}
+
+	a := &Authenticator{
+		options: opts,
+		tokens:  make(map[[sha256.Size]byte]Token),
+	}
+	names := make(map[string]bool)
+	for _, token := range opts.Tokens {
+		if token.Name == "" || token.Token == "" {
+			return nil, fmt.Errorf("API tokens need a name and a token")
+		}
+		if names[token.Name] {
+			return nil, fmt.Errorf("duplicate API token name %q", token.Name)
+		}
+		if !token.Role.IsValid() {
+			return nil, fmt.Errorf("API token %s has unknown role %q", token.Name, token.Role)
+		}
+		names[token.Name] = true
+		a.tokens[sha256.Sum256([]byte(token.Token))] = token
+	}
+	return a, nil
+}
+
+// Authenticate verifies the bearer credentials of a request
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	scheme, credentials, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || credentials == "" {
+		return Principal{}, fmt.Errorf("%w: expected a bearer token", ErrInvalidCredentials)
+	}
+
+	if strings.Count(credentials, ".") == 2 {
+		return a.verifyJWT(credentials, time.Now())
+	}
+	return a.verifyToken(credentials)
+}
+
+// CanIssue reports whether the authenticator can sign JWTs
+func (a *Authenticator) CanIssue() bool {
+	return a.options.JWTSecret != ""
+}
+
+// Issue signs a JWT for a subject and role that expires after the
+// configured TTL
+func (a *Authenticator) Issue(subject string, role Role) (string, time.Time, error) {
+	if !a.CanIssue() {
+		return "", time.Time{}, fmt.Errorf("no JWT secret configured")
+	}
+	if !role.IsValid() {
+		return "", time.Time{}, fmt.Errorf("unknown role %q", role)
+	}
+
+	now := time.Now()
+	expires := now.Add(a.options.TokenTTL)
+	claims := claims{
+		Subject:   subject,
+		Role:      role,
+		Issuer:    a.options.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	}
+	if a.options.Audience != "" {
+		claims.Audience = audience{a.options.Audience}
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + a.sign(signingInput), expires, nil
+}
+
+// verifyToken looks up a static API token
+func (a *Authenticator) verifyToken(credentials string) (Principal, error) {
+	sum := sha256.Sum256([]byte(credentials))
+	for hash, token := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			return Principal{Subject: token.Name, Role: token.Role, Method: MethodToken}, nil
+		}
+	}
+	return Principal{}, fmt.Errorf("%w: unknown API token", ErrInvalidCredentials)
+}
+
+// claims are the JWT claims the authenticator reads and writes
+type claims struct {
+	Subject   string   `json:"sub"`
+	Role      Role     `json:"role,omitempty"`
+	Roles     []Role   `json:"roles,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// audience is a JWT audience, either a string or a list of strings
+type audience []string
+
+// UnmarshalJSON accepts both audience forms
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid audience: %w", err)
+	}
+	*a = list
+	return nil
+}
+
+// verifyJWT checks the signature and claims of an HS256 JWT
+func (a *Authenticator) verifyJWT(token string, now time.Time) (Principal, error) {
+	if !a.CanIssue() {
+		return Principal{}, fmt.Errorf("%w: JWTs are not accepted", ErrInvalidCredentials)
+	}
+
+	parts := strings.Split(token, ".")
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, err
+	}
+	if header.Algorithm != "HS256" {
+		return Principal{}, fmt.Errorf("%w: unsupported JWT algorithm %q", ErrInvalidCredentials, header.Algorithm)
+	}
+	expected := a.sign(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return Principal{}, fmt.Errorf("%w: bad JWT signature", ErrInvalidCredentials)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return Principal{}, err
+	}
+	switch {
+	case c.Subject == "":
+		return Principal{}, fmt.Errorf("%w: JWT has no subject", ErrInvalidCredentials)
+	case c.ExpiresAt == 0:
+		return Principal{}, fmt.Errorf("%w: JWT has no expiry", ErrInvalidCredentials)
+	case now.Unix() >= c.ExpiresAt:
+		return Principal{}, fmt.Errorf("%w: JWT expired", ErrInvalidCredentials)
+	case c.NotBefore != 0 && now.Unix() < c.NotBefore:
+		return Principal{}, fmt.Errorf("%w: JWT not valid yet", ErrInvalidCredentials)
+	case a.options.Issuer != "" && c.Issuer != a.options.Issuer:
+		return Principal{}, fmt.Errorf("%w: unexpected JWT issuer %q", ErrInvalidCredentials, c.Issuer)
+	case a.options.Audience != "" && !c.Audience.contains(a.options.Audience):
+		return Principal{}, fmt.Errorf("%w: JWT audience does not match", ErrInvalidCredentials)
+	}
+
+	role := highestRole(append([]Role{c.Role}, c.Roles...))
+	if role == "" {
+		return Principal{}, fmt.Errorf("%w: JWT has no known role", ErrInvalidCredentials)
+	}
+	return Principal{
+		Subject:   c.Subject,
+		Role:      role,
+		Method:    MethodJWT,
+		ExpiresAt: time.Unix(c.ExpiresAt, 0),
+	}, nil
+}
+
+// contains reports whether the audience includes a value
+func (a audience) contains(value string) bool {
+	for _, entry := range a {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// sign returns the encoded HS256 signature of the signing input
+func (a *Authenticator) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, []byte(a.options.JWTSecret))
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed JWT", ErrInvalidCredentials)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed JWT", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// highestRole returns the most privileged known role, or an empty role
+func highestRole(roles []Role) Role {
+	var best Role
+	for _, role := range roles {
+		if role.IsValid() && roleRank[role] > roleRank[best] {
+			best = role
+		}
+	}
+	return best
+}
+
+// principalKey is the context key of the authenticated principal
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal of an authenticated request
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
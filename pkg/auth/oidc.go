@@ -0,0 +1,404 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MethodOIDC marks principals logged in through OpenID Connect
+const MethodOIDC = "oidc"
+
+// ErrNoRole is returned when none of a user's groups maps to a role
+var ErrNoRole = errors.New("no role is mapped to the user's groups")
+
+// OIDCOptions configures OpenID Connect login
+type OIDCOptions struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested in addition to openid
+	Scopes []string
+	// GroupsClaim names the ID token claim holding the user's groups
+	GroupsClaim string
+	// GroupRoles maps groups to roles; users get the highest mapped role
+	GroupRoles map[string]Role
+	// SessionTimeout is the lifetime of a login session
+	SessionTimeout time.Duration
+	HTTPClient     *http.Client
+}
+
+// Identity is a user verified by the identity provider
+type Identity struct {
+	Subject string   `json:"subject"`
+	Email   string   `json:"email,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Groups  []string `json:"groups,omitempty"`
+	Role    Role     `json:"role"`
+}
+
+// Username returns the most readable identifier of the user
+func (i Identity) Username() string {
+	if i.Email != "" {
+		return i.Email
+	}
+	return i.Subject
+}
+
+// AuthRequest is a pending authorization code request
+type AuthRequest struct {
+	State    string
+	Nonce    string
+	Verifier string
+	URL      string
+}
+
+// providerMetadata is the part of the discovery document the client uses
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against an
+// OpenID Connect issuer. Discovery and keys are fetched on first use.
+type OIDCProvider struct {
+	options OIDCOptions
+	client  *http.Client
+
+	mu       sync.Mutex
+	metadata *providerMetadata
+	keys     map[string]*rsa.PublicKey
+}
+
+// NewOIDCProvider validates the options and creates a provider
+func NewOIDCProvider(opts OIDCOptions) (*OIDCProvider, error) {
+	if opts.IssuerURL == "" || opts.ClientID == "" || opts.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC needs an issuer URL, a client ID and a redirect URL")
+	}
+	if len(opts.GroupRoles) == 0 {
+		return nil, fmt.Errorf("OIDC needs at least one group role mapping")
+	}
+	for group, role := range opts.GroupRoles {
+		if !role.IsValid() {
+			return nil, fmt.Errorf("OIDC group %s has unknown role %q", group, role)
+		}
+	}
+	if opts.GroupsClaim == "" {
+		opts.GroupsClaim = "groups"
+	}
+	if opts.SessionTimeout <= 0 {
+		opts.SessionTimeout = 24 * time.Hour
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &OIDCProvider{options: opts, client: client}, nil
+}
+
+// SessionTimeout returns the lifetime of login sessions
+func (p *OIDCProvider) SessionTimeout() time.Duration {
+	return p.options.SessionTimeout
+}
+
+// SecureCookies reports whether the redirect URL is served over HTTPS
+func (p *OIDCProvider) SecureCookies() bool {
+	return strings.HasPrefix(p.options.RedirectURL, "https://")
+}
+
+// AuthCodeURL starts a login, returning the identity provider URL and the
+// secrets needed to finish it
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context) (AuthRequest, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return AuthRequest{}, err
+	}
+
+	req := AuthRequest{}
+	for _, value := range []*string{&req.State, &req.Nonce, &req.Verifier} {
+		if *value, err = randomString(32); err != nil {
+			return AuthRequest{}, err
+		}
+	}
+	challenge := sha256.Sum256([]byte(req.Verifier))
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.options.ClientID},
+		"redirect_uri":          {p.options.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, p.options.Scopes...), " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	req.URL = metadata.AuthorizationEndpoint + separator + query.Encode()
+	return req, nil
+}
+
+// Exchange redeems an authorization code and verifies the ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.options.RedirectURL},
+		"client_id":     {p.options.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.options.ClientSecret != "" {
+		form.Set("client_secret", p.options.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.doJSON(req, &token); err != nil {
+		return Identity{}, fmt.Errorf("token request failed: %w", err)
+	}
+	if token.IDToken == "" {
+		return Identity{}, fmt.Errorf("token response has no ID token")
+	}
+	return p.verifyIDToken(ctx, metadata, token.IDToken, nonce, time.Now())
+}
+
+// EndSessionURL returns the identity provider logout URL, if it has one
+func (p *OIDCProvider) EndSessionURL(ctx context.Context) string {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return ""
+	}
+	return metadata.EndSessionEndpoint
+}
+
+// verifyIDToken checks the signature and claims of an RS256 ID token and
+// maps the user's groups to a role
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, metadata *providerMetadata, token, nonce string, now time.Time) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, fmt.Errorf("malformed ID token")
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Identity{}, err
+	}
+	if header.Algorithm != "RS256" {
+		return Identity{}, fmt.Errorf("unsupported ID token algorithm %q", header.Algorithm)
+	}
+	key, err := p.key(ctx, metadata, header.KeyID)
+	if err != nil {
+		return Identity{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, fmt.Errorf("malformed ID token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return Identity{}, fmt.Errorf("bad ID token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Identity{}, fmt.Errorf("malformed ID token payload")
+	}
+	var claims struct {
+		Issuer    string   `json:"iss"`
+		Subject   string   `json:"sub"`
+		Audience  audience `json:"aud"`
+		ExpiresAt int64    `json:"exp"`
+		Nonce     string   `json:"nonce"`
+		Email     string   `json:"email"`
+		Name      string   `json:"name"`
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return Identity{}, fmt.Errorf("malformed ID token claims")
+	}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return Identity{}, fmt.Errorf("malformed ID token claims")
+	}
+
+	switch {
+	case claims.Issuer != metadata.Issuer:
+		return Identity{}, fmt.Errorf("unexpected ID token issuer %q", claims.Issuer)
+	case !claims.Audience.contains(p.options.ClientID):
+		return Identity{}, fmt.Errorf("ID token audience does not match the client ID")
+	case claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt:
+		return Identity{}, fmt.Errorf("ID token expired")
+	case claims.Nonce != nonce:
+		return Identity{}, fmt.Errorf("ID token nonce does not match")
+	case claims.Subject == "":
+		return Identity{}, fmt.Errorf("ID token has no subject")
+	}
+
+	identity := Identity{Subject: claims.Subject, Email: claims.Email, Name: claims.Name}
+	if value, exists := raw[p.options.GroupsClaim]; exists {
+		var groups audience
+		if err := json.Unmarshal(value, &groups); err != nil {
+			return Identity{}, fmt.Errorf("invalid %s claim: %w", p.options.GroupsClaim, err)
+		}
+		identity.Groups = groups
+	}
+
+	var roles []Role
+	for _, group := range identity.Groups {
+		roles = append(roles, p.options.GroupRoles[group])
+	}
+	if identity.Role = highestRole(roles); identity.Role == "" {
+		return identity, ErrNoRole
+	}
+	return identity, nil
+}
+
+// discover fetches and caches the discovery document
+func (p *OIDCProvider) discover(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	issuer := strings.TrimSuffix(p.options.IssuerURL, "/")
+	req, err := http.NewRequestWithContext(ctx, "GET", issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+	var metadata providerMetadata
+	if err := p.doJSON(req, &metadata); err != nil {
+		return nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery returned issuer %q, expected %q", metadata.Issuer, p.options.IssuerURL)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery document is missing endpoints")
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// key returns a signing key, refetching the key set for unknown key IDs
+// so that key rotation is picked up
+func (p *OIDCProvider) key(ctx context.Context, metadata *providerMetadata, keyID string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.findKey(keyID); key != nil {
+		return key, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		p.keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if key := p.findKey(keyID); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no signing key %q", keyID)
+}
+
+// findKey looks a key up; without a key ID the only key is used. The lock
+// must be held.
+func (p *OIDCProvider) findKey(keyID string) *rsa.PublicKey {
+	if keyID == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[keyID]
+}
+
+// doJSON performs a request and decodes a JSON response
+func (p *OIDCProvider) doJSON(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// randomString returns a URL-safe random string of n bytes of entropy
+func randomString(n int) (string, error) {
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Session is a server-side login session
+type Session struct {
+	ID        string
+	Principal Principal
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SessionStore keeps login sessions in memory; session IDs are the only
+// thing handed to the browser
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewSessionStore creates an empty session store
+func NewSessionStore() *SessionStore {
+	return &SessionStore{sessions: make(map[string]Session)}
+}
+
+// Create starts a session for a principal that expires after ttl
+func (s *SessionStore) Create(principal Principal, ttl time.Duration) (Session, error) {
+	id, err := randomString(32)
+	if err != nil {
+		return Session{}, err
+	}
+	now := time.Now()
+	session := Session{
+		ID:        id,
+		Principal: principal,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	session.Principal.Method = MethodOIDC
+	session.Principal.ExpiresAt = session.ExpiresAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeExpired(now)
+	s.sessions[id] = session
+	return session, nil
+}
+
+// Get returns a session that has not expired
+func (s *SessionStore) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[id]
+	if !exists {
+		return Session{}, false
+	}
+	if !time.Now().Before(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	return session, true
+}
+
+// Delete ends a session
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// removeExpired drops expired sessions; the lock must be held
+func (s *SessionStore) removeExpired(now time.Time) {
+	for id, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
@@ -243,14 +243,27 @@ func (c *Cache) Cleanup() {
 	c.stats.LastCleanup = now
 }
 
-// StartCleanupRoutine starts a background cleanup routine
-func (c *Cache) StartCleanupRoutine(interval time.Duration) {
+// StartCleanupRoutine starts a background cleanup routine; the returned
+// function stops it
+func (c *Cache) StartCleanupRoutine(interval time.Duration) func() {
 	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
 	go func() {
-		for range ticker.C {
-			c.Cleanup()
+		for {
+			select {
+			case <-ticker.C:
+				c.Cleanup()
+			case <-done:
+				ticker.Stop()
+				return
+			}
 		}
 	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
 }
 
 // Load loads cache from file
@@ -0,0 +1,287 @@
+package catalystcenter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// API paths
+const (
+	authPath            = "/dna/system/api/v1/auth/token"
+	applicationSetPath  = "/dna/intent/api/v1/application-policy-application-set"
+	applicationsPath    = "/dna/intent/api/v1/applications"
+	applicationPolicies = "/dna/intent/api/v1/app-policy-intent"
+)
+
+// Options contains the Catalyst Center connection settings
+type Options struct {
+	BaseURL            string
+	Username           string
+	Password           string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+}
+
+// Client pushes application policies to Catalyst Center
+type Client struct {
+	options    Options
+	httpClient *http.Client
+	token      string
+}
+
+// PushResult summarizes a policy push
+type PushResult struct {
+	CreatedSets  []string
+	Moved        int
+	Unchanged    int
+	Unknown      []string
+	TaskIDs      []string
+	PolicyIntent *PolicyIntent
+}
+
+// New creates a Catalyst Center client
+func New(opts Options) (*Client, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("Catalyst Center base URL is required")
+	}
+	if _, err := url.Parse(opts.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid Catalyst Center base URL: %w", err)
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec G402 -- lab controllers with self-signed certificates
+	}
+
+	return &Client{
+		options:    opts,
+		httpClient: &http.Client{Timeout: opts.Timeout, Transport: transport},
+	}, nil
+}
+
+// Push moves the classified applications into their application sets and
+// creates the business relevance policy for the policy scope
+func (c *Client) Push(ctx context.Context, policy *Policy) (*PushResult, error) {
+	result := &PushResult{}
+
+	if err := c.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	// Resolve application sets, creating missing ones
+	ids, err := c.applicationSetIDs(ctx, policy.ApplicationSets)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, set := range policy.ApplicationSets {
+		if _, exists := ids[set.Name]; !exists {
+			missing = append(missing, set.Name)
+		}
+	}
+	if len(missing) > 0 {
+		if err := c.createApplicationSets(ctx, missing); err != nil {
+			return nil, err
+		}
+		result.CreatedSets = missing
+		if ids, err = c.applicationSetIDs(ctx, policy.ApplicationSets); err != nil {
+			return nil, err
+		}
+		for _, name := range missing {
+			if _, exists := ids[name]; !exists {
+				return nil, fmt.Errorf("application set %s was not created", name)
+			}
+		}
+	}
+
+	// Move applications whose application set differs
+	var updates []application
+	for _, set := range policy.ApplicationSets {
+		for _, name := range set.Applications {
+			app, found, err := c.application(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				result.Unknown = append(result.Unknown, name)
+				continue
+			}
+			if app.ApplicationSet.IDRef == ids[set.Name] {
+				result.Unchanged++
+				continue
+			}
+			updates = append(updates, application{ID: app.ID, Name: app.Name, ApplicationSet: IDRef{IDRef: ids[set.Name]}})
+		}
+	}
+	if len(updates) > 0 {
+		taskID, err := c.call(ctx, http.MethodPut, applicationsPath, updates)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update applications: %w", err)
+		}
+		result.Moved = len(updates)
+		result.TaskIDs = append(result.TaskIDs, taskID)
+	}
+
+	// Apply business relevance for the policy scope
+	result.PolicyIntent = policy.Intent(ids)
+	taskID, err := c.call(ctx, http.MethodPost, applicationPolicies, result.PolicyIntent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create application policy: %w", err)
+	}
+	result.TaskIDs = append(result.TaskIDs, taskID)
+
+	return result, nil
+}
+
+// authenticate obtains an API token
+func (c *Client) authenticate(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.options.BaseURL+authPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create auth request: %w", err)
+	}
+	req.SetBasicAuth(c.options.Username, c.options.Password)
+	req.Header.Set("Content-Type", "application/json")
+
+	var response struct {
+		Token string `json:"Token"`
+	}
+	if err := c.send(req, &response); err != nil {
+		return fmt.Errorf("Catalyst Center authentication failed: %w", err)
+	}
+	if response.Token == "" {
+		return fmt.Errorf("Catalyst Center authentication returned no token")
+	}
+	c.token = response.Token
+	return nil
+}
+
+// application is an application as returned and accepted by the API
+type application struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	ApplicationSet IDRef  `json:"applicationSet"`
+}
+
+// applicationSetIDs looks up the IDs of the given application sets by name
+func (c *Client) applicationSetIDs(ctx context.Context, sets []ApplicationSet) (map[string]string, error) {
+	ids := make(map[string]string)
+	for _, set := range sets {
+		var response struct {
+			Response []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"response"`
+		}
+		if err := c.get(ctx, applicationSetPath+"?name="+url.QueryEscape(set.Name), &response); err != nil {
+			return nil, fmt.Errorf("failed to look up application set %s: %w", set.Name, err)
+		}
+		for _, found := range response.Response {
+			if found.Name == set.Name {
+				ids[set.Name] = found.ID
+			}
+		}
+	}
+	return ids, nil
+}
+
+// createApplicationSets creates application sets with the given names
+func (c *Client) createApplicationSets(ctx context.Context, names []string) error {
+	body := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		body = append(body, map[string]string{"name": name})
+	}
+	if _, err := c.call(ctx, http.MethodPost, applicationSetPath, body); err != nil {
+		return fmt.Errorf("failed to create application sets: %w", err)
+	}
+	return nil
+}
+
+// application looks up an application by name
+func (c *Client) application(ctx context.Context, name string) (application, bool, error) {
+	var response struct {
+		Response []application `json:"response"`
+	}
+	if err := c.get(ctx, applicationsPath+"?name="+url.QueryEscape(name), &response); err != nil {
+		return application{}, false, fmt.Errorf("failed to look up application %s: %w", name, err)
+	}
+	for _, app := range response.Response {
+		if app.Name == name {
+			return app, true, nil
+		}
+	}
+	return application{}, false, nil
+}
+
+// get performs an authenticated GET request
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.options.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Auth-Token", c.token)
+	return c.send(req, out)
+}
+
+// call performs an authenticated request with a JSON body and returns the
+// task ID of the asynchronous operation
+func (c *Client) call(ctx context.Context, method, path string, body interface{}) (string, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.options.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Auth-Token", c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	var response struct {
+		Response struct {
+			TaskID string `json:"taskId"`
+		} `json:"response"`
+	}
+	if err := c.send(req, &response); err != nil {
+		return "", err
+	}
+	return response.Response.TaskID, nil
+}
+
+// send executes a request and decodes the JSON response
+func (c *Client) send(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if out != nil && len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
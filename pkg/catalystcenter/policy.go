@@ -0,0 +1,193 @@
+package catalystcenter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// Business relevance levels of the Application Policy
+const (
+	BusinessRelevant   = "BUSINESS_RELEVANT"
+	Default            = "DEFAULT"
+	BusinessIrrelevant = "BUSINESS_IRRELEVANT"
+)
+
+// DefaultApplicationSetPrefix prefixes the application set created per class
+const DefaultApplicationSetPrefix = "nbar-"
+
+// ClassMapping maps a QoS class to a Catalyst Center application set and
+// business relevance level
+type ClassMapping struct {
+	Relevance      string `json:"relevance" yaml:"relevance"`
+	ApplicationSet string `json:"application_set" yaml:"application_set"`
+}
+
+// DefaultRelevance derives the business relevance from a class DSCP value.
+// Best effort is DEFAULT, scavenger is BUSINESS_IRRELEVANT and everything
+// else is BUSINESS_RELEVANT.
+func DefaultRelevance(dscp string) string {
+	value, err := qos.ParseDSCP(dscp)
+	switch {
+	case err != nil || value == 0:
+		return Default
+	case value == 8: // cs1
+		return BusinessIrrelevant
+	default:
+		return BusinessRelevant
+	}
+}
+
+// DefaultMapping maps every marking class of the model to its own application
+// set with the relevance derived from its DSCP
+func DefaultMapping(model *qos.Model) map[qos.Class]ClassMapping {
+	mapping := make(map[qos.Class]ClassMapping)
+	for _, def := range model.Definitions() {
+		if def.Name == qos.Other {
+			continue
+		}
+		mapping[def.Name] = ClassMapping{
+			Relevance:      DefaultRelevance(def.DSCP),
+			ApplicationSet: DefaultApplicationSetPrefix + strings.ToLower(def.Name.String()),
+		}
+	}
+	return mapping
+}
+
+// ValidateMapping checks relevance levels and application set names
+func ValidateMapping(mapping map[qos.Class]ClassMapping) error {
+	relevance := make(map[string]string)
+	for class, m := range mapping {
+		switch m.Relevance {
+		case BusinessRelevant, Default, BusinessIrrelevant:
+		default:
+			return fmt.Errorf("class %s: invalid relevance %q", class, m.Relevance)
+		}
+		if m.ApplicationSet == "" {
+			return fmt.Errorf("class %s: application set cannot be empty", class)
+		}
+		if other, exists := relevance[m.ApplicationSet]; exists && other != m.Relevance {
+			return fmt.Errorf("application set %s is mapped with relevance %s and %s", m.ApplicationSet, other, m.Relevance)
+		}
+		relevance[m.ApplicationSet] = m.Relevance
+	}
+	return nil
+}
+
+// ApplicationSet is an application set with its relevance and applications
+type ApplicationSet struct {
+	Name         string      `json:"name"`
+	Relevance    string      `json:"relevance"`
+	Classes      []qos.Class `json:"classes"`
+	Applications []string    `json:"applications"`
+}
+
+// Policy is the Application Policy derived from the classifications
+type Policy struct {
+	PolicyScope     string           `json:"policyScope"`
+	ApplicationSets []ApplicationSet `json:"applicationSets"`
+}
+
+// BuildPolicy groups the classified applications into application sets using
+// the class mapping. Classes without a mapping are skipped.
+func BuildPolicy(classifications map[string]qos.Classification, model *qos.Model, mapping map[qos.Class]ClassMapping, scope string) (*Policy, error) {
+	if scope == "" {
+		return nil, fmt.Errorf("policy scope cannot be empty")
+	}
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+
+	sets := make(map[string]*ApplicationSet)
+	var order []string
+	for _, class := range model.Classes() {
+		m, exists := mapping[class]
+		if !exists {
+			continue
+		}
+		set, exists := sets[m.ApplicationSet]
+		if !exists {
+			set = &ApplicationSet{Name: m.ApplicationSet, Relevance: m.Relevance, Applications: make([]string, 0)}
+			sets[m.ApplicationSet] = set
+			order = append(order, m.ApplicationSet)
+		}
+		set.Classes = append(set.Classes, class)
+	}
+
+	for protocol, classification := range classifications {
+		m, exists := mapping[classification.Class]
+		if !exists {
+			continue
+		}
+		set := sets[m.ApplicationSet]
+		set.Applications = append(set.Applications, protocol)
+	}
+
+	policy := &Policy{PolicyScope: scope}
+	for _, name := range order {
+		sort.Strings(sets[name].Applications)
+		policy.ApplicationSets = append(policy.ApplicationSets, *sets[name])
+	}
+
+	return policy, nil
+}
+
+// PolicyIntent is the request body of the app-policy-intent API
+type PolicyIntent struct {
+	CreateList []PolicyEntry `json:"createList"`
+}
+
+// PolicyEntry is a single application policy rule
+type PolicyEntry struct {
+	Name               string   `json:"name"`
+	PolicyScope        string   `json:"policyScope"`
+	DeletePolicyStatus string   `json:"deletePolicyStatus"`
+	ExclusiveContract  Contract `json:"exclusiveContract"`
+	Producer           Producer `json:"producer"`
+}
+
+// Contract is the set of clauses applied by a policy rule
+type Contract struct {
+	Clause []Clause `json:"clause"`
+}
+
+// Clause is a single policy clause
+type Clause struct {
+	Type           string `json:"type"`
+	RelevanceLevel string `json:"relevanceLevel"`
+}
+
+// Producer references the application sets a rule applies to
+type Producer struct {
+	ScalableGroup []IDRef `json:"scalableGroup"`
+}
+
+// IDRef references a Catalyst Center object by ID
+type IDRef struct {
+	IDRef string `json:"idRef"`
+}
+
+// Intent builds the app-policy-intent request body. ids maps application set
+// names to their Catalyst Center IDs; sets without an ID reference their name.
+func (p *Policy) Intent(ids map[string]string) *PolicyIntent {
+	intent := &PolicyIntent{CreateList: make([]PolicyEntry, 0, len(p.ApplicationSets))}
+	for _, set := range p.ApplicationSets {
+		ref := set.Name
+		if id, exists := ids[set.Name]; exists {
+			ref = id
+		}
+		intent.CreateList = append(intent.CreateList, PolicyEntry{
+			Name:               p.PolicyScope + "_" + set.Name,
+			PolicyScope:        p.PolicyScope,
+			DeletePolicyStatus: "NONE",
+			ExclusiveContract: Contract{Clause: []Clause{{
+				Type:           "BUSINESS_RELEVANCE",
+				RelevanceLevel: set.Relevance,
+			}}},
+			Producer: Producer{ScalableGroup: []IDRef{{IDRef: ref}}},
+		})
+	}
+	return intent
+}
@@ -0,0 +1,676 @@
+// Package changes holds proposed switch configuration changes until someone
+// other than their author approves them, and records everything that happens
+// to them. The store file is read before every operation, so approvals made
+// from the command line are seen by a running server.
+package changes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// State is the state of a change
+type State string
+
+// Change states
+const (
+	StateDraft    State = "draft"
+	StateApproved State = "approved"
+	StateApplied  State = "applied"
+	// StatePartiallyApplied is a change a rollout pushed to some devices
+	// before it halted
+	StatePartiallyApplied State = "partially-applied"
+	StateRolledBack       State = "rolled-back"
+	StateRejected         State = "rejected"
+	StateExpired          State = "expired"
+)
+
+// Pending reports whether the change may still be applied
+func (s State) Pending() bool {
+	return s == StateDraft || s == StateApproved
+}
+
+// Actions recorded in the history of a change
+const (
+	ActionPropose     = "propose"
+	ActionApprove     = "approve"
+	ActionReject      = "reject"
+	ActionApply       = "apply"
+	ActionApplyFailed = "apply_failed"
+	ActionRollback    = "rollback"
+	ActionRollout     = "rollout"
+	ActionRolloutFail = "rollout_failed"
+	ActionExpire      = "expire"
+)
+
+// SystemActor is the actor of events nobody triggered, e.g. expiry
+const SystemActor = "system"
+
+// Errors returned by the Manager
+var (
+	ErrNotFound     = errors.New("change not found")
+	ErrState        = errors.New("not allowed in the change's state")
+	ErrSelfApproval = errors.New("changes must be approved by someone other than their author")
+	ErrNoActor      = errors.New("the actor is unknown")
+	ErrNoRollback   = errors.New("no earlier applied configuration to roll back to")
+)
+
+// Event is an entry of a change's history
+type Event struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	From    State     `json:"from,omitempty"`
+	To      State     `json:"to"`
+	Comment string    `json:"comment,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	// ChangeID identifies the change in the audit file
+	ChangeID string `json:"change_id,omitempty"`
+}
+
+// ClassChange is a protocol whose class differs from the applied
+// configuration
+type ClassChange struct {
+	Protocol string    `json:"protocol"`
+	From     qos.Class `json:"from,omitempty"`
+	To       qos.Class `json:"to"`
+}
+
+// Proposal is the outcome of a run that would push configuration
+type Proposal struct {
+	// Source is where the run's protocols came from
+	Source string
+	// RunID is the API run that proposed the change, if any
+	RunID           string
+	Config          string
+	Classifications map[string]qos.Classification
+	SaveConfig      bool
+	// CatalystCenter also pushes the Application Policy built from the
+	// classifications to Catalyst Center
+	CatalystCenter bool
+	Comment        string
+}
+
+// Change is a proposed configuration change
+type Change struct {
+	ID        string    `json:"id"`
+	State     State     `json:"state"`
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Config is the Cisco configuration the change pushes
+	Config          string                        `json:"config"`
+	Classifications map[string]qos.Classification `json:"classifications"`
+	SaveConfig      bool                          `json:"save_config,omitempty"`
+	// CatalystCenter is set when applying the change also pushes the
+	// Application Policy built from its classifications to Catalyst Center
+	CatalystCenter bool `json:"catalyst_center,omitempty"`
+	// BaseID is the applied change this one was compared with, and
+	// BaseConfig its configuration, which a rollback restores
+	BaseID       string        `json:"base_id,omitempty"`
+	BaseConfig   string        `json:"base_config,omitempty"`
+	Diff         output.Diff   `json:"diff"`
+	ClassChanges []ClassChange `json:"class_changes"`
+
+	ApprovedBy string    `json:"approved_by,omitempty"`
+	AppliedBy  string    `json:"applied_by,omitempty"`
+	AppliedAt  time.Time `json:"applied_at,omitempty"`
+	// Hosts are the devices a rollout pushed the change to
+	Hosts   []string `json:"hosts,omitempty"`
+	History []Event  `json:"history"`
+}
+
+// Applier pushes a change, or with rollback restores its base configuration
+type Applier func(ctx context.Context, change Change, rollback bool) error
+
+// FleetPusher pushes a change to several devices and returns the devices
+// that received it, also when it fails part way
+type FleetPusher func(ctx context.Context, change Change) ([]string, error)
+
+// Options configures a Manager
+type Options struct {
+	// StoreFile persists the changes; empty keeps them in memory only
+	StoreFile string
+	// AuditFile receives one JSON line per event; empty keeps the history
+	// in the changes only
+	AuditFile string
+	// TTL is how long a change may wait for approval and application
+	TTL time.Duration
+	// MaxHistory is the number of finished changes kept
+	MaxHistory int
+}
+
+// Manager keeps the changes and moves them through their states
+type Manager struct {
+	options Options
+	apply   Applier
+
+	// applyMu serializes pushes, which run without holding mu
+	applyMu sync.Mutex
+	mu      sync.Mutex
+	changes []*Change
+}
+
+// New creates a manager, checking that the store file can be read
+func New(opts Options, apply Applier) (*Manager, error) {
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.MaxHistory <= 0 {
+		opts.MaxHistory = 200
+	}
+	m := &Manager{options: opts, apply: apply}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Propose records a draft change. A pending change with the same
+// configuration and Catalyst Center push is returned instead of creating another one, which is
+// reported by created.
+func (m *Manager) Propose(proposal Proposal, author string) (change Change, created bool, err error) {
+	if author == "" {
+		return Change{}, false, ErrNoActor
+	}
+	id, err := newID()
+	if err != nil {
+		return Change{}, false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return Change{}, false, err
+	}
+
+	for _, existing := range m.changes {
+		if existing.State.Pending() && existing.Config == proposal.Config && existing.CatalystCenter == proposal.CatalystCenter {
+			return copyChange(existing), false, nil
+		}
+	}
+
+	now := time.Now()
+	c := &Change{
+		ID:              id,
+		State:           StateDraft,
+		Author:          author,
+		Comment:         proposal.Comment,
+		Source:          proposal.Source,
+		RunID:           proposal.RunID,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(m.options.TTL),
+		Config:          proposal.Config,
+		Classifications: proposal.Classifications,
+		SaveConfig:      proposal.SaveConfig,
+		CatalystCenter:  proposal.CatalystCenter,
+		ClassChanges:    []ClassChange{},
+	}
+
+	var baseClassifications map[string]qos.Classification
+	baseName := "applied"
+	if base := m.current(); base != nil {
+		c.BaseID = base.ID
+		c.BaseConfig = base.Config
+		baseClassifications = base.Classifications
+		baseName = "change-" + base.ID
+	}
+	c.Diff = output.Compare(baseName, "change-"+id, c.BaseConfig, c.Config)
+	for protocol, classification := range c.Classifications {
+		if previous, exists := baseClassifications[protocol]; !exists || previous.Class != classification.Class {
+			c.ClassChanges = append(c.ClassChanges, ClassChange{Protocol: protocol, From: previous.Class, To: classification.Class})
+		}
+	}
+	sort.Slice(c.ClassChanges, func(i, j int) bool {
+		return c.ClassChanges[i].Protocol < c.ClassChanges[j].Protocol
+	})
+
+	if err := m.record(c, author, ActionPropose, StateDraft, proposal.Comment, ""); err != nil {
+		return Change{}, false, err
+	}
+	m.changes = append(m.changes, c)
+	m.trim()
+	if err := m.save(); err != nil {
+		return Change{}, false, err
+	}
+	return copyChange(c), true, nil
+}
+
+// List returns the changes, newest first
+func (m *Manager) List() ([]Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return nil, err
+	}
+
+	changes := make([]Change, 0, len(m.changes))
+	for i := len(m.changes) - 1; i >= 0; i-- {
+		changes = append(changes, copyChange(m.changes[i]))
+	}
+	return changes, nil
+}
+
+// Get returns a change
+func (m *Manager) Get(id string) (Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return Change{}, err
+	}
+	c, err := m.find(id)
+	if err != nil {
+		return Change{}, err
+	}
+	return copyChange(c), nil
+}
+
+// Approve approves a draft change; the approver must not be its author
+func (m *Manager) Approve(id, actor, comment string) (Change, error) {
+	return m.transition(id, actor, comment, ActionApprove, StateApproved, func(c *Change) error {
+		if c.State != StateDraft {
+			return fmt.Errorf("cannot approve a change that is %s: %w", c.State, ErrState)
+		}
+		if strings.EqualFold(c.Author, actor) {
+			return ErrSelfApproval
+		}
+		c.ApprovedBy = actor
+		return nil
+	})
+}
+
+// Reject rejects a pending change
+func (m *Manager) Reject(id, actor, comment string) (Change, error) {
+	return m.transition(id, actor, comment, ActionReject, StateRejected, func(c *Change) error {
+		if !c.State.Pending() {
+			return fmt.Errorf("cannot reject a change that is %s: %w", c.State, ErrState)
+		}
+		return nil
+	})
+}
+
+// Apply pushes an approved change. A failed push leaves the change approved
+// and is recorded in its history.
+func (m *Manager) Apply(ctx context.Context, id, actor, comment string) (Change, error) {
+	return m.push(ctx, id, actor, comment, false)
+}
+
+// Rollback restores the configuration an applied change replaced. Only the
+// most recently applied change can be rolled back.
+func (m *Manager) Rollback(ctx context.Context, id, actor, comment string) (Change, error) {
+	return m.push(ctx, id, actor, comment, true)
+}
+
+// RollOut pushes an approved change to a fleet with push instead of the
+// Applier. The change is applied when the push succeeds and partially
+// applied when it fails after some devices received it; neither can be
+// applied again. A push that reached no device leaves the change approved.
+// Every outcome is recorded in the change's history.
+func (m *Manager) RollOut(ctx context.Context, id, actor, comment string, push FleetPusher) (Change, error) {
+	if actor == "" {
+		return Change{}, ErrNoActor
+	}
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	snapshot, err := m.snapshot(id, false)
+	if err != nil {
+		return Change{}, err
+	}
+	hosts, pushErr := push(ctx, snapshot)
+
+	action, to, errText := ActionRollout, StateApplied, ""
+	if pushErr != nil {
+		action, to, errText = ActionRolloutFail, StatePartiallyApplied, pushErr.Error()
+		if len(hosts) == 0 {
+			to = ""
+		}
+	}
+	c, err := m.finish(id, actor, comment, action, to, errText, func(c *Change) {
+		if len(hosts) > 0 {
+			c.AppliedBy = actor
+			c.AppliedAt = time.Now()
+			c.Hosts = append([]string(nil), hosts...)
+		}
+	})
+	if err != nil {
+		return Change{}, err
+	}
+	return c, pushErr
+}
+
+// push applies a change or rolls it back
+func (m *Manager) push(ctx context.Context, id, actor, comment string, rollback bool) (Change, error) {
+	if actor == "" {
+		return Change{}, ErrNoActor
+	}
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+
+	snapshot, err := m.snapshot(id, rollback)
+	if err != nil {
+		return Change{}, err
+	}
+	pushErr := m.apply(ctx, snapshot, rollback)
+
+	if pushErr != nil {
+		// The configuration on the switch is unknown, the state stays
+		c, err := m.finish(id, actor, comment, ActionApplyFailed, "", pushErr.Error(), nil)
+		if err != nil {
+			return Change{}, err
+		}
+		return c, pushErr
+	}
+	if rollback {
+		return m.finish(id, actor, comment, ActionRollback, StateRolledBack, "", nil)
+	}
+	return m.finish(id, actor, comment, ActionApply, StateApplied, "", func(c *Change) {
+		c.AppliedBy = actor
+		c.AppliedAt = time.Now()
+	})
+}
+
+// snapshot returns a copy of a change after checking that it can be applied
+// or rolled back; applyMu must be held
+func (m *Manager) snapshot(id string, rollback bool) (Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return Change{}, err
+	}
+	c, err := m.find(id)
+	if err != nil {
+		return Change{}, err
+	}
+	if err := checkPush(c, m.current(), rollback); err != nil {
+		return Change{}, err
+	}
+	return copyChange(c), nil
+}
+
+// finish records the outcome of a push, updating the change first; an
+// empty state keeps the change's state
+func (m *Manager) finish(id, actor, comment, action string, to State, errText string, update func(*Change)) (Change, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return Change{}, err
+	}
+	c, err := m.find(id)
+	if err != nil {
+		return Change{}, err
+	}
+
+	if to == "" {
+		to = c.State
+	}
+	if update != nil {
+		update(c)
+	}
+	if err := m.record(c, actor, action, to, comment, errText); err != nil {
+		return Change{}, err
+	}
+	if err := m.save(); err != nil {
+		return Change{}, err
+	}
+	return copyChange(c), nil
+}
+
+// checkPush checks that a change can be applied or rolled back
+func checkPush(c, current *Change, rollback bool) error {
+	if rollback {
+		if c.State != StateApplied {
+			return fmt.Errorf("cannot roll back a change that is %s: %w", c.State, ErrState)
+		}
+		if current == nil || current.ID != c.ID {
+			return fmt.Errorf("only the most recently applied change can be rolled back: %w", ErrState)
+		}
+		if c.BaseConfig == "" {
+			return ErrNoRollback
+		}
+		return nil
+	}
+	if c.State != StateApproved {
+		return fmt.Errorf("cannot apply a change that is %s: %w", c.State, ErrState)
+	}
+	return nil
+}
+
+// transition moves a change to another state after check accepts it
+func (m *Manager) transition(id, actor, comment, action string, to State, check func(*Change) error) (Change, error) {
+	if actor == "" {
+		return Change{}, ErrNoActor
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.refresh(); err != nil {
+		return Change{}, err
+	}
+	c, err := m.find(id)
+	if err != nil {
+		return Change{}, err
+	}
+
+	before := copyChange(c)
+	if err := check(c); err != nil {
+		*c = before
+		return Change{}, err
+	}
+	if err := m.record(c, actor, action, to, comment, ""); err != nil {
+		*c = before
+		return Change{}, err
+	}
+	if err := m.save(); err != nil {
+		return Change{}, err
+	}
+	return copyChange(c), nil
+}
+
+// record appends an event to a change's history and the audit file and
+// moves the change to the event's state; the lock must be held. An event
+// that cannot be audited does not happen.
+func (m *Manager) record(c *Change, actor, action string, to State, comment, errText string) error {
+	event := Event{
+		Time:    time.Now(),
+		Actor:   actor,
+		Action:  action,
+		To:      to,
+		Comment: comment,
+		Error:   errText,
+	}
+	if action != ActionPropose {
+		event.From = c.State
+	}
+
+	audited := event
+	audited.ChangeID = c.ID
+	if err := m.appendAudit(audited); err != nil {
+		return err
+	}
+	c.State = to
+	c.History = append(c.History, event)
+	return nil
+}
+
+// current returns the most recently applied change; the lock must be held
+func (m *Manager) current() *Change {
+	var current *Change
+	for _, c := range m.changes {
+		if c.State == StateApplied && (current == nil || c.AppliedAt.After(current.AppliedAt)) {
+			current = c
+		}
+	}
+	return current
+}
+
+// find returns a change by ID; the lock must be held
+func (m *Manager) find(id string) (*Change, error) {
+	for _, c := range m.changes {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// refresh reloads the store and expires pending changes past their expiry;
+// the lock must be held
+func (m *Manager) refresh() error {
+	if err := m.load(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expired := false
+	for _, c := range m.changes {
+		if c.State.Pending() && now.After(c.ExpiresAt) {
+			if err := m.record(c, SystemActor, ActionExpire, StateExpired, "", ""); err != nil {
+				return err
+			}
+			expired = true
+		}
+	}
+	if expired {
+		return m.save()
+	}
+	return nil
+}
+
+// trim drops the oldest finished changes beyond MaxHistory, keeping the
+// applied one; the lock must be held
+func (m *Manager) trim() {
+	finished := 0
+	for _, c := range m.changes {
+		if !c.State.Pending() {
+			finished++
+		}
+	}
+	current := m.current()
+	kept := m.changes[:0]
+	for _, c := range m.changes {
+		if finished > m.options.MaxHistory && !c.State.Pending() && c != current {
+			finished--
+			continue
+		}
+		kept = append(kept, c)
+	}
+	m.changes = kept
+}
+
+// appendAudit appends an event to the audit file
+func (m *Manager) appendAudit(event Event) error {
+	if m.options.AuditFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal change audit entry: %w", err)
+	}
+	if err := ensureDir(m.options.AuditFile); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(m.options.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open change audit file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write change audit file: %w", err)
+	}
+	return nil
+}
+
+// load reads the store file; the lock must be held
+func (m *Manager) load() error {
+	if m.options.StoreFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.options.StoreFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read change store: %w", err)
+	}
+	var changes []*Change
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return fmt.Errorf("failed to parse change store %s: %w", m.options.StoreFile, err)
+	}
+	m.changes = changes
+	return nil
+}
+
+// save writes the store file atomically; the lock must be held
+func (m *Manager) save() error {
+	if m.options.StoreFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m.changes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal change store: %w", err)
+	}
+	if err := ensureDir(m.options.StoreFile); err != nil {
+		return err
+	}
+	tmp := m.options.StoreFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write change store: %w", err)
+	}
+	if err := os.Rename(tmp, m.options.StoreFile); err != nil {
+		return fmt.Errorf("failed to replace change store: %w", err)
+	}
+	return nil
+}
+
+// ensureDir creates the parent directory of a file
+func ensureDir(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// copyChange returns a copy that does not share slices or maps with the
+// change
+func copyChange(c *Change) Change {
+	cp := *c
+	cp.ClassChanges = append([]ClassChange(nil), c.ClassChanges...)
+	cp.Hosts = append([]string(nil), c.Hosts...)
+	cp.History = append([]Event(nil), c.History...)
+	if c.Classifications != nil {
+		cp.Classifications = make(map[string]qos.Classification, len(c.Classifications))
+		for protocol, classification := range c.Classifications {
+			cp.Classifications[protocol] = classification
+		}
+	}
+	return cp
+}
+
+// newID returns a random change ID
+func newID() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate change ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,91 @@
+package changes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/transport"
+)
+
+// RollbackConfig returns the CLI configuration that turns a switch running a
+// change's configuration back into its base configuration. Pushing CLI
+// configuration only adds to what is there, so protocols, policy classes and
+// class-maps the base does not have are removed before the base is
+// re-applied.
+func RollbackConfig(change Change) (string, error) {
+	if change.BaseConfig == "" {
+		return "", ErrNoRollback
+	}
+	applied, err := transport.ParseCLI(change.Config)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse change configuration: %w", err)
+	}
+	base, err := transport.ParseCLI(change.BaseConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse base configuration: %w", err)
+	}
+
+	baseClassMaps := make(map[string]transport.ClassMap, len(base.ClassMaps))
+	for _, classMap := range base.ClassMaps {
+		baseClassMaps[classMap.Name] = classMap
+	}
+	basePolicyMaps := make(map[string]map[string]bool, len(base.PolicyMaps))
+	for _, policyMap := range base.PolicyMaps {
+		classes := make(map[string]bool, len(policyMap.Classes))
+		for _, class := range policyMap.Classes {
+			classes[class.Name] = true
+		}
+		basePolicyMaps[policyMap.Name] = classes
+	}
+
+	var b strings.Builder
+	for _, policyMap := range applied.PolicyMaps {
+		classes, exists := basePolicyMaps[policyMap.Name]
+		if !exists {
+			continue
+		}
+		var removed []string
+		for _, class := range policyMap.Classes {
+			if !classes[class.Name] {
+				removed = append(removed, class.Name)
+			}
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "policy-map %s\n", policyMap.Name)
+		for _, class := range removed {
+			fmt.Fprintf(&b, " no class %s\n", class)
+		}
+		b.WriteString("!\n")
+	}
+
+	for _, classMap := range applied.ClassMaps {
+		baseClassMap, exists := baseClassMaps[classMap.Name]
+		if !exists {
+			fmt.Fprintf(&b, "no class-map %s %s\n!\n", classMap.Prematch, classMap.Name)
+			continue
+		}
+		kept := make(map[string]bool, len(baseClassMap.Protocols))
+		for _, protocol := range baseClassMap.Protocols {
+			kept[protocol] = true
+		}
+		var removed []string
+		for _, protocol := range classMap.Protocols {
+			if !kept[protocol] {
+				removed = append(removed, protocol)
+			}
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "class-map %s %s\n", classMap.Prematch, classMap.Name)
+		for _, protocol := range removed {
+			fmt.Fprintf(&b, " no match protocol %s\n", protocol)
+		}
+		b.WriteString("!\n")
+	}
+
+	b.WriteString(change.BaseConfig)
+	return b.String(), nil
+}
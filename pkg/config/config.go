@@ -2,12 +2,29 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/catalystcenter"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/netflow"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/notify"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rollout"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rules"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/schedule"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/transport"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,6 +36,12 @@ type Config struct {
 	// SSH connection settings
 	SSH SSHConfig `yaml:"ssh"`
 
+	// Configuration push transport settings
+	Transport TransportConfig `yaml:"transport"`
+
+	// NetFlow/IPFIX collector settings
+	NetFlow NetFlowConfig `yaml:"netflow"`
+
 	// AI provider settings
 	AI AIConfig `yaml:"ai"`
 
@@ -39,6 +62,30 @@ type Config struct {
 
 	// Security settings
 	Security SecurityConfig `yaml:"security"`
+
+	// Output generation settings
+	Output OutputConfig `yaml:"output"`
+
+	// Serve mode settings
+	Serve ServeConfig `yaml:"serve"`
+
+	// Runs requested through the web API
+	Runs RunsConfig `yaml:"runs"`
+
+	// Overrides and rules changed through the web API
+	Rules RulesConfig `yaml:"rules"`
+
+	// Approval of configuration pushes
+	Approval ApprovalConfig `yaml:"approval"`
+
+	// Maintenance windows configuration pushes are limited to
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+
+	// Staged rollouts to a fleet of switches
+	Rollout RolloutConfig `yaml:"rollout"`
+
+	// Notifications of new protocols, pushes and drift
+	Notifications NotificationsConfig `yaml:"notifications"`
 }
 
 // AppConfig contains general application settings
@@ -63,6 +110,477 @@ type SSHConfig struct {
 	KeepAlive          time.Duration `yaml:"keep_alive"`
 }
 
+// TransportConfig selects how configuration is pushed to the switch. The
+// host, user and key come from the SSH settings.
+type TransportConfig struct {
+	Type               string        `yaml:"type"`
+	Port               string        `yaml:"port"`
+	Password           string        `yaml:"password"`
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify"`
+	Timeout            time.Duration `yaml:"timeout"`
+	ConfirmedCommit    bool          `yaml:"confirmed_commit"`
+	ConfirmTimeout     time.Duration `yaml:"confirm_timeout"`
+}
+
+// TransportOptions converts the transport and SSH settings to NETCONF or
+// RESTCONF transport options
+func (c *Config) TransportOptions() transport.Options {
+	opts := transport.Options{
+		Type:               c.Transport.Type,
+		Host:               c.SSH.Host,
+		Port:               c.Transport.Port,
+		Username:           c.SSH.User,
+		Password:           c.Transport.Password,
+		InsecureSkipVerify: c.Transport.InsecureSkipVerify,
+		Timeout:            c.Transport.Timeout,
+		ConfirmedCommit:    c.Transport.ConfirmedCommit,
+		ConfirmTimeout:     c.Transport.ConfirmTimeout,
+	}
+	// NETCONF runs over SSH and can reuse the CLI key
+	if c.Transport.Type == transport.TypeNETCONF {
+		opts.KeyFile = c.SSH.KeyFile
+	}
+	return opts
+}
+
+// NetFlowConfig configures the NetFlow v9/IPFIX collector used as a protocol
+// source instead of polling protocol discovery
+type NetFlowConfig struct {
+	Listen          string        `yaml:"listen"`
+	Duration        time.Duration `yaml:"duration"`
+	FlushInterval   time.Duration `yaml:"flush_interval"`
+	TemplateTimeout time.Duration `yaml:"template_timeout"`
+}
+
+// Options converts the settings to collector options
+func (n NetFlowConfig) Options() netflow.Options {
+	return netflow.Options{
+		Listen:          n.Listen,
+		TemplateTimeout: n.TemplateTimeout,
+	}
+}
+
+// ServeConfig configures the long-running serve mode. Jobs due at the same
+// time run in the order discovery, classification, drift check, push.
+type ServeConfig struct {
+	StateFile string `yaml:"state_file"`
+	// Source is where discovery gets protocols: switch, netflow or file
+	Source      string `yaml:"source"`
+	InputFile   string `yaml:"input_file"`
+	InputFormat string `yaml:"input_format"`
+	// Output and OutputFile select the outputs written after classification,
+	// as with --output and --output-file
+	Output     string `yaml:"output"`
+	OutputFile string `yaml:"output_file"`
+
+	Discovery      JobConfig     `yaml:"discovery"`
+	Classification JobConfig     `yaml:"classification"`
+	DriftCheck     JobConfig     `yaml:"drift_check"`
+	Push           PushJobConfig `yaml:"push"`
+}
+
+// JobConfig schedules a serve mode job
+type JobConfig struct {
+	// Schedule is a cron expression, a descriptor such as @hourly or
+	// "@every 15m"
+	Schedule   string `yaml:"schedule"`
+	RunOnStart bool   `yaml:"run_on_start"`
+}
+
+// PushJobConfig schedules pushing the desired configuration when drift is
+// detected. Pushing is disabled unless enabled.
+type PushJobConfig struct {
+	JobConfig  `yaml:",inline"`
+	Enabled    bool `yaml:"enabled"`
+	SaveConfig bool `yaml:"save_config"`
+	DryRun     bool `yaml:"dry_run"`
+}
+
+// RunsConfig configures runs started through the web API
+type RunsConfig struct {
+	HistoryFile string        `yaml:"history_file"`
+	MaxHistory  int           `yaml:"max_history"`
+	MaxQueued   int           `yaml:"max_queued"`
+	Timeout     time.Duration `yaml:"timeout"`
+	// InputDir is the only directory runs may read input files from
+	InputDir string `yaml:"input_dir"`
+}
+
+// Options converts the settings to run manager options
+func (r RunsConfig) Options() runs.Options {
+	return runs.Options{
+		HistoryFile: r.HistoryFile,
+		MaxHistory:  r.MaxHistory,
+		MaxQueued:   r.MaxQueued,
+		Timeout:     r.Timeout,
+		InputDir:    r.InputDir,
+	}
+}
+
+// RulesConfig configures overrides and custom rules changed through the web
+// API
+type RulesConfig struct {
+	StoreFile string `yaml:"store_file"`
+	AuditFile string `yaml:"audit_file"`
+}
+
+// Options converts the settings to rules manager options
+func (r RulesConfig) Options() rules.Options {
+	return rules.Options{
+		StoreFile: r.StoreFile,
+		AuditFile: r.AuditFile,
+	}
+}
+
+// ApprovalConfig configures the approval of configuration pushes. When
+// enabled, pushes become proposed changes that someone other than their
+// author must approve before they are applied.
+type ApprovalConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	StoreFile  string        `yaml:"store_file"`
+	AuditFile  string        `yaml:"audit_file"`
+	Expiry     time.Duration `yaml:"expiry"`
+	MaxHistory int           `yaml:"max_history"`
+}
+
+// Options converts the settings to change manager options
+func (a ApprovalConfig) Options() changes.Options {
+	return changes.Options{
+		StoreFile:  a.StoreFile,
+		AuditFile:  a.AuditFile,
+		TTL:        a.Expiry,
+		MaxHistory: a.MaxHistory,
+	}
+}
+
+// What happens to pushes requested outside a maintenance window
+const (
+	OutsideWindowRefuse = "refuse"
+	OutsideWindowQueue  = "queue"
+)
+
+// MaintenanceConfig limits configuration pushes to maintenance windows.
+// Pushes outside a window are refused, or queued until the window opens.
+type MaintenanceConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	OutsideWindow string `yaml:"outside_window"`
+	QueueFile     string `yaml:"queue_file"`
+	// Timezone is used by windows and blackouts without their own
+	Timezone string `yaml:"timezone"`
+	// Groups lists the devices of each group by host name
+	Groups    map[string][]string         `yaml:"groups"`
+	Windows   []MaintenanceWindowConfig   `yaml:"windows"`
+	Blackouts []MaintenanceBlackoutConfig `yaml:"blackouts"`
+}
+
+// MaintenanceWindowConfig is a recurring window opening on a cron schedule
+// for a duration, or a calendar window from start to end
+type MaintenanceWindowConfig struct {
+	Name     string        `yaml:"name"`
+	Devices  []string      `yaml:"devices"`
+	Groups   []string      `yaml:"groups"`
+	Timezone string        `yaml:"timezone"`
+	Schedule string        `yaml:"schedule"`
+	Duration time.Duration `yaml:"duration"`
+	// Start and End are "2006-01-02 15:04" or RFC 3339 times
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// MaintenanceBlackoutConfig blocks pushes on whole days. Dates are
+// "2006-01-02" or inclusive ranges "2006-01-02..2006-01-05".
+type MaintenanceBlackoutConfig struct {
+	Name     string   `yaml:"name"`
+	Devices  []string `yaml:"devices"`
+	Groups   []string `yaml:"groups"`
+	Timezone string   `yaml:"timezone"`
+	Dates    []string `yaml:"dates"`
+}
+
+// Target returns the device host with the groups it belongs to
+func (m MaintenanceConfig) Target(host string) maintenance.Target {
+	target := maintenance.Target{Host: host}
+	for group, members := range m.Groups {
+		for _, member := range members {
+			if strings.EqualFold(member, host) {
+				target.Groups = append(target.Groups, group)
+				break
+			}
+		}
+	}
+	sort.Strings(target.Groups)
+	return target
+}
+
+// Policy converts the windows and blackouts to a maintenance policy
+func (m MaintenanceConfig) Policy() (*maintenance.Policy, error) {
+	var windows []maintenance.Window
+	for i, w := range m.Windows {
+		name := w.Name
+		if name == "" {
+			name = fmt.Sprintf("window %d", i+1)
+		}
+		location, err := loadLocation(w.Timezone, m.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %q: %w", name, err)
+		}
+		window := maintenance.Window{
+			Name:     name,
+			Scope:    maintenance.Scope{Devices: w.Devices, Groups: w.Groups},
+			Duration: w.Duration,
+		}
+		switch {
+		case w.Schedule != "" && (w.Start != "" || w.End != ""):
+			return nil, fmt.Errorf("maintenance window %q has both a schedule and start/end", name)
+		case w.Schedule != "":
+			spec := strings.TrimSpace(w.Schedule)
+			if strings.HasPrefix(spec, "@every") {
+				return nil, fmt.Errorf("maintenance window %q: @every schedules are not supported", name)
+			}
+			if !strings.HasPrefix(spec, "TZ=") && !strings.HasPrefix(spec, "CRON_TZ=") {
+				spec = "TZ=" + location.String() + " " + spec
+			}
+			window.Schedule, err = schedule.Parse(spec)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance window %q: %w", name, err)
+			}
+		default:
+			if window.Start, err = parseWindowTime(w.Start, location); err != nil {
+				return nil, fmt.Errorf("maintenance window %q start: %w", name, err)
+			}
+			if window.End, err = parseWindowTime(w.End, location); err != nil {
+				return nil, fmt.Errorf("maintenance window %q end: %w", name, err)
+			}
+		}
+		windows = append(windows, window)
+	}
+
+	var blackouts []maintenance.Blackout
+	for i, b := range m.Blackouts {
+		name := b.Name
+		if name == "" {
+			name = fmt.Sprintf("blackout %d", i+1)
+		}
+		location, err := loadLocation(b.Timezone, m.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance blackout %q: %w", name, err)
+		}
+		if len(b.Dates) == 0 {
+			return nil, fmt.Errorf("maintenance blackout %q has no dates", name)
+		}
+		for _, dates := range b.Dates {
+			first, last, _ := strings.Cut(dates, "..")
+			if last == "" {
+				last = first
+			}
+			start, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(first), location)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance blackout %q: invalid date %q", name, dates)
+			}
+			end, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(last), location)
+			if err != nil {
+				return nil, fmt.Errorf("maintenance blackout %q: invalid date %q", name, dates)
+			}
+			blackouts = append(blackouts, maintenance.Blackout{
+				Name:  name,
+				Scope: maintenance.Scope{Devices: b.Devices, Groups: b.Groups},
+				Start: start,
+				End:   end.AddDate(0, 0, 1),
+			})
+		}
+	}
+
+	return maintenance.NewPolicy(windows, blackouts)
+}
+
+// RolloutConfig configures staged rollouts to the switches of the
+// inventory. Each device goes in the first wave that selects it and devices
+// no wave selects go in a last wave; the first wave is the canary.
+type RolloutConfig struct {
+	Inventory []InventoryDeviceConfig `yaml:"inventory"`
+	Waves     []RolloutWaveConfig     `yaml:"waves"`
+	// Soak is how long each wave runs before its drop counters are checked
+	Soak     time.Duration `yaml:"soak"`
+	Parallel int           `yaml:"parallel"`
+	MaxDrops uint64        `yaml:"max_drops"`
+	// MaxInterfaceDrops is checked against the output drops of the
+	// interfaces that carry the pushed policy-maps
+	MaxInterfaceDrops uint64 `yaml:"max_interface_drops"`
+	SaveConfig        bool   `yaml:"save_config"`
+}
+
+// InventoryDeviceConfig is a switch of the inventory. Port and user default
+// to the SSH settings.
+type InventoryDeviceConfig struct {
+	Host   string   `yaml:"host"`
+	Port   string   `yaml:"port"`
+	User   string   `yaml:"user"`
+	Groups []string `yaml:"groups"`
+}
+
+// RolloutWaveConfig selects the devices of a wave by host name, which may
+// contain shell wildcards, or group
+type RolloutWaveConfig struct {
+	Name    string        `yaml:"name"`
+	Devices []string      `yaml:"devices"`
+	Groups  []string      `yaml:"groups"`
+	Soak    time.Duration `yaml:"soak"`
+}
+
+// Plan assigns the inventory to waves. Devices are in their inventory
+// groups and in the maintenance groups that list them.
+func (r RolloutConfig) Plan(m MaintenanceConfig) ([]rollout.Wave, error) {
+	if len(r.Inventory) == 0 {
+		return nil, fmt.Errorf("rollout inventory is empty")
+	}
+
+	waves := make([]rollout.Wave, len(r.Waves), len(r.Waves)+1)
+	for i, w := range r.Waves {
+		waves[i] = rollout.Wave{Name: w.Name, Soak: w.Soak}
+		if waves[i].Name == "" {
+			waves[i].Name = fmt.Sprintf("wave %d", i+1)
+		}
+		if waves[i].Soak == 0 {
+			waves[i].Soak = r.Soak
+		}
+	}
+	remaining := rollout.Wave{Name: "remaining", Soak: r.Soak}
+
+	for _, device := range r.Inventory {
+		target := m.Target(device.Host)
+		target.Groups = append(target.Groups, device.Groups...)
+		sort.Strings(target.Groups)
+
+		assigned := false
+		for i, w := range r.Waves {
+			if (maintenance.Scope{Devices: w.Devices, Groups: w.Groups}).Matches(target) {
+				waves[i].Targets = append(waves[i].Targets, target)
+				assigned = true
+				break
+			}
+		}
+		if !assigned {
+			remaining.Targets = append(remaining.Targets, target)
+		}
+	}
+
+	for _, wave := range waves {
+		if len(wave.Targets) == 0 {
+			return nil, fmt.Errorf("rollout wave %s selects no devices", wave.Name)
+		}
+	}
+	if len(remaining.Targets) > 0 {
+		waves = append(waves, remaining)
+	}
+	return waves, nil
+}
+
+// SSH returns the SSH settings of an inventory device
+func (r RolloutConfig) SSH(base SSHConfig, host string) SSHConfig {
+	base.Host = host
+	for _, device := range r.Inventory {
+		if strings.EqualFold(device.Host, host) {
+			if device.Port != "" {
+				base.Port = device.Port
+			}
+			if device.User != "" {
+				base.User = device.User
+			}
+			break
+		}
+	}
+	return base
+}
+
+// NotificationsConfig configures the notifications sent to chat and webhook
+// channels. Templates override the built-in message of an event kind.
+type NotificationsConfig struct {
+	Enabled   bool                        `yaml:"enabled"`
+	Attempts  int                         `yaml:"attempts"`
+	Backoff   time.Duration               `yaml:"backoff"`
+	Timeout   time.Duration               `yaml:"timeout"`
+	Templates map[string]string           `yaml:"templates"`
+	Channels  []NotificationChannelConfig `yaml:"channels"`
+}
+
+// NotificationChannelConfig is a Slack, Teams or generic webhook channel.
+// Events lists the event kinds routed to it, all of them when empty.
+type NotificationChannelConfig struct {
+	Name      string            `yaml:"name"`
+	Type      string            `yaml:"type"`
+	URL       string            `yaml:"url"`
+	Secret    string            `yaml:"secret"`
+	Events    []string          `yaml:"events"`
+	Templates map[string]string `yaml:"templates"`
+}
+
+// Options converts the settings to notifier options
+func (n NotificationsConfig) Options() notify.Options {
+	opts := notify.Options{
+		Templates: kindTemplates(n.Templates),
+		Attempts:  n.Attempts,
+		Backoff:   n.Backoff,
+		Timeout:   n.Timeout,
+	}
+	for _, c := range n.Channels {
+		channel := notify.Channel{
+			Name:      c.Name,
+			Type:      c.Type,
+			URL:       c.URL,
+			Secret:    c.Secret,
+			Templates: kindTemplates(c.Templates),
+		}
+		for _, event := range c.Events {
+			channel.Events = append(channel.Events, notify.Kind(event))
+		}
+		opts.Channels = append(opts.Channels, channel)
+	}
+	return opts
+}
+
+// kindTemplates keys templates by event kind
+func kindTemplates(templates map[string]string) map[notify.Kind]string {
+	result := make(map[notify.Kind]string, len(templates))
+	for kind, text := range templates {
+		result[notify.Kind(kind)] = text
+	}
+	return result
+}
+
+// loadLocation loads the first time zone set, or local time
+func loadLocation(names ...string) (*time.Location, error) {
+	for _, name := range names {
+		if name != "" {
+			location, err := time.LoadLocation(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+			}
+			return location, nil
+		}
+	}
+	return time.Local, nil
+}
+
+// parseWindowTime parses a calendar window time in the window's time zone
+// unless it has its own offset
+func parseWindowTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected \"2006-01-02 15:04\" or RFC 3339", value)
+	}
+	return t, nil
+}
+
+// Serve mode protocol sources
+const (
+	ServeSourceSwitch  = "switch"
+	ServeSourceNetFlow = "netflow"
+	ServeSourceFile    = "file"
+)
+
 // AIConfig contains AI provider settings
 type AIConfig struct {
 	Provider    string                    `yaml:"provider"`
@@ -101,12 +619,30 @@ type ProviderConfig struct {
 
 // QoSConfig contains QoS classification settings
 type QoSConfig struct {
+	Preset              string                    `yaml:"preset"`
 	Classes             map[string]QoSClassConfig `yaml:"classes"`
 	DefaultClass        string                    `yaml:"default_class"`
 	CustomRules         []CustomRuleConfig        `yaml:"custom_rules"`
 	ProtocolFamilies    map[string][]string       `yaml:"protocol_families"`
 	LearningEnabled     bool                      `yaml:"learning_enabled"`
 	ConfidenceThreshold float64                   `yaml:"confidence_threshold"`
+	Queuing             QueuingConfig             `yaml:"queuing"`
+	Attachment          AttachmentConfig          `yaml:"attachment"`
+}
+
+// QueuingConfig contains egress queuing policy settings
+type QueuingConfig struct {
+	Enabled    bool                        `yaml:"enabled"`
+	PolicyName string                      `yaml:"policy_name"`
+	Classes    map[string]QueueClassConfig `yaml:"classes"`
+}
+
+// QueueClassConfig contains egress queuing settings for a specific QoS class
+type QueueClassConfig struct {
+	PriorityLevel             int `yaml:"priority_level"`
+	PolicePercent             int `yaml:"police_percent"`
+	BandwidthRemainingPercent int `yaml:"bandwidth_remaining_percent"`
+	QueueBuffersRatio         int `yaml:"queue_buffers_ratio"`
 }
 
 // QoSClassConfig contains settings for a specific QoS class
@@ -118,6 +654,148 @@ type QoSClassConfig struct {
 	Protocols   []string `yaml:"protocols"`
 }
 
+// presetName returns the configured preset, falling back to the default
+// preset when neither a preset nor any classes are configured
+func (q *QoSConfig) presetName() string {
+	if q.Preset == "" && len(q.Classes) == 0 {
+		return qos.DefaultPreset
+	}
+	return q.Preset
+}
+
+// ClassDefinitions converts the configured preset and classes into QoS class
+// definitions. Classes configured alongside a preset override the preset's
+// class of the same name or extend the preset with new classes.
+func (q *QoSConfig) ClassDefinitions() ([]qos.ClassDefinition, error) {
+	var definitions []qos.ClassDefinition
+	index := make(map[qos.Class]int)
+
+	if name := q.presetName(); name != "" {
+		preset, exists := qos.LookupPreset(name)
+		if !exists {
+			return nil, fmt.Errorf("unknown QoS preset %q (available: %s)", name, strings.Join(qos.PresetNames(), ", "))
+		}
+		for i, def := range preset.Classes {
+			index[def.Name] = i
+		}
+		definitions = preset.Classes
+	}
+
+	for className, classConfig := range q.Classes {
+		def := qos.ClassDefinition{
+			Name:        qos.Class(className),
+			DisplayName: classConfig.Name,
+			Description: classConfig.Description,
+			DSCP:        classConfig.DSCP,
+			Priority:    classConfig.Priority,
+		}
+
+		i, exists := index[def.Name]
+		if !exists {
+			definitions = append(definitions, def)
+			continue
+		}
+
+		// Override only the fields set in configuration
+		if def.DisplayName != "" {
+			definitions[i].DisplayName = def.DisplayName
+		}
+		if def.Description != "" {
+			definitions[i].Description = def.Description
+		}
+		if def.DSCP != "" {
+			definitions[i].DSCP = def.DSCP
+		}
+		if def.Priority != 0 {
+			definitions[i].Priority = def.Priority
+		}
+	}
+
+	return definitions, nil
+}
+
+// ClassModel builds and validates the QoS class model from configuration
+func (q *QoSConfig) ClassModel() (*qos.Model, error) {
+	definitions, err := q.ClassDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	return qos.NewModel(definitions, qos.Class(q.DefaultClass))
+}
+
+// ClassProtocols returns the predefined protocols for each class, combining
+// the preset seed lists with the protocols configured for each class
+func (q *QoSConfig) ClassProtocols() map[qos.Class][]string {
+	result := make(map[qos.Class][]string)
+	configured := make(map[string]bool)
+
+	for className, classConfig := range q.Classes {
+		class := qos.Class(className)
+		for _, protocol := range classConfig.Protocols {
+			configured[strings.ToLower(protocol)] = true
+		}
+		result[class] = append(result[class], classConfig.Protocols...)
+	}
+
+	// Seed protocols from the preset unless configuration assigns them elsewhere
+	if preset, exists := qos.LookupPreset(q.presetName()); exists {
+		for class, protocols := range preset.Protocols {
+			for _, protocol := range protocols {
+				if !configured[protocol] {
+					result[class] = append(result[class], protocol)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// AttachmentConfig contains interface service-policy attachment settings
+type AttachmentConfig struct {
+	Enabled      bool                     `yaml:"enabled"`
+	InputPolicy  string                   `yaml:"input_policy"`
+	OutputPolicy string                   `yaml:"output_policy"`
+	Targets      []AttachmentTargetConfig `yaml:"targets"`
+}
+
+// AttachmentTargetConfig selects interfaces to attach service policies to
+type AttachmentTargetConfig struct {
+	Interfaces  []string `yaml:"interfaces"`
+	Description string   `yaml:"description"`
+	AccessVLANs []int    `yaml:"access_vlans"`
+	Direction   string   `yaml:"direction"`
+}
+
+// Selector builds and validates the interface selector for policy attachment
+func (a *AttachmentConfig) Selector() (*interfaces.Selector, error) {
+	targets := make([]interfaces.Target, 0, len(a.Targets))
+	for _, target := range a.Targets {
+		targets = append(targets, interfaces.Target{
+			Interfaces:         target.Interfaces,
+			DescriptionPattern: target.Description,
+			AccessVLANs:        target.AccessVLANs,
+			Direction:          interfaces.Direction(target.Direction),
+		})
+	}
+	return interfaces.NewSelector(targets)
+}
+
+// QueuingPolicy builds and validates the egress queuing policy for the class model
+func (q *QoSConfig) QueuingPolicy(model *qos.Model) (*qos.QueuingPolicy, error) {
+	queues := make([]qos.QueueDefinition, 0, len(q.Queuing.Classes))
+	for className, queueConfig := range q.Queuing.Classes {
+		queues = append(queues, qos.QueueDefinition{
+			Class:                     qos.Class(className),
+			PriorityLevel:             queueConfig.PriorityLevel,
+			PolicePercent:             queueConfig.PolicePercent,
+			BandwidthRemainingPercent: queueConfig.BandwidthRemainingPercent,
+			QueueBuffersRatio:         queueConfig.QueueBuffersRatio,
+		})
+	}
+	return qos.NewQueuingPolicy(q.Queuing.PolicyName, queues, model)
+}
+
 // CustomRuleConfig contains custom classification rules
 type CustomRuleConfig struct {
 	Name     string `yaml:"name"`
@@ -168,6 +846,79 @@ type WebConfig struct {
 	KeyFile     string `yaml:"key_file"`
 	StaticDir   string `yaml:"static_dir"`
 	TemplateDir string `yaml:"template_dir"`
+	// CORSOrigins lists the browser origins allowed to call the API; "*"
+	// allows any origin
+	CORSOrigins []string      `yaml:"cors_origins"`
+	Auth        WebAuthConfig `yaml:"auth"`
+}
+
+// WebAuthConfig configures authentication of the web API. JWTs are signed
+// with security.jwt_secret.
+type WebAuthConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Issuer and Audience are required in JWTs when set
+	Issuer   string           `yaml:"issuer"`
+	Audience string           `yaml:"audience"`
+	Tokens   []APITokenConfig `yaml:"tokens"`
+	OIDC     OIDCConfig       `yaml:"oidc"`
+}
+
+// OIDCConfig configures OpenID Connect single sign-on for the web UI
+type OIDCConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	IssuerURL    string   `yaml:"issuer_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+	GroupsClaim  string   `yaml:"groups_claim"`
+	// GroupRoles maps identity provider groups to roles
+	GroupRoles map[string]string `yaml:"group_roles"`
+}
+
+// Options converts the settings to OIDC provider options; sessions last for
+// the session timeout
+func (o OIDCConfig) Options(security SecurityConfig) auth.OIDCOptions {
+	opts := auth.OIDCOptions{
+		IssuerURL:      o.IssuerURL,
+		ClientID:       o.ClientID,
+		ClientSecret:   o.ClientSecret,
+		RedirectURL:    o.RedirectURL,
+		Scopes:         o.Scopes,
+		GroupsClaim:    o.GroupsClaim,
+		GroupRoles:     make(map[string]auth.Role),
+		SessionTimeout: security.SessionTimeout,
+	}
+	for group, role := range o.GroupRoles {
+		opts.GroupRoles[group] = auth.Role(role)
+	}
+	return opts
+}
+
+// APITokenConfig is a static API token for automation
+type APITokenConfig struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+// Options converts the settings to authenticator options; issued JWTs last
+// for the session timeout
+func (w WebAuthConfig) Options(security SecurityConfig) auth.Options {
+	opts := auth.Options{
+		JWTSecret: security.JWTSecret,
+		Issuer:    w.Issuer,
+		Audience:  w.Audience,
+		TokenTTL:  security.SessionTimeout,
+	}
+	for _, token := range w.Tokens {
+		opts.Tokens = append(opts.Tokens, auth.Token{
+			Name:  token.Name,
+			Token: token.Token,
+			Role:  auth.Role(token.Role),
+		})
+	}
+	return opts
 }
 
 // SecurityConfig contains security settings
@@ -180,6 +931,124 @@ type SecurityConfig struct {
 	SessionTimeout     time.Duration `yaml:"session_timeout"`
 }
 
+// OutputConfig contains output generation settings
+type OutputConfig struct {
+	Cisco          CiscoOutputConfig    `yaml:"cisco"`
+	CatalystCenter CatalystCenterConfig `yaml:"catalyst_center"`
+	Juniper        VendorOutputConfig   `yaml:"juniper"`
+	Arista         VendorOutputConfig   `yaml:"arista"`
+	AristaAVT      VendorOutputConfig   `yaml:"arista_avt"`
+	Meraki         VendorOutputConfig   `yaml:"meraki"`
+}
+
+// VendorOutputConfig contains the policy name and NBAR to vendor application
+// mapping of a multi-vendor generator
+type VendorOutputConfig struct {
+	PolicyName   string            `yaml:"policy_name"`
+	Applications map[string]string `yaml:"applications"`
+	Strict       bool              `yaml:"strict"`
+}
+
+// Options converts the settings to vendor generator options
+func (c *VendorOutputConfig) Options() output.VendorOptions {
+	return output.VendorOptions{
+		PolicyName:   c.PolicyName,
+		Applications: c.Applications,
+		Strict:       c.Strict,
+	}
+}
+
+// CiscoOutputConfig contains Cisco configuration template and naming settings
+type CiscoOutputConfig struct {
+	Template                string `yaml:"template"`
+	MarkingPolicyName       string `yaml:"marking_policy_name"`
+	ClassMapName            string `yaml:"class_map_name"`
+	QueueClassMapName       string `yaml:"queue_class_map_name"`
+	MaxProtocolsPerClassMap int    `yaml:"max_protocols_per_class_map"`
+	DefaultClassHandling    string `yaml:"default_class_handling"`
+	ClassDefaultDSCP        string `yaml:"class_default_dscp"`
+}
+
+// RendererOptions converts the settings to Cisco renderer options
+func (c *CiscoOutputConfig) RendererOptions() render.CiscoOptions {
+	return render.CiscoOptions{
+		Template:                c.Template,
+		ClassMapName:            c.ClassMapName,
+		QueueClassMapName:       c.QueueClassMapName,
+		MarkingPolicyName:       c.MarkingPolicyName,
+		MaxProtocolsPerClassMap: c.MaxProtocolsPerClassMap,
+		DefaultClassHandling:    c.DefaultClassHandling,
+		ClassDefaultDSCP:        c.ClassDefaultDSCP,
+	}
+}
+
+// CatalystCenterConfig contains Catalyst Center Application Policy settings
+type CatalystCenterConfig struct {
+	BaseURL            string                         `yaml:"base_url"`
+	Username           string                         `yaml:"username"`
+	Password           string                         `yaml:"password"`
+	InsecureSkipVerify bool                           `yaml:"insecure_skip_verify"`
+	Timeout            time.Duration                  `yaml:"timeout"`
+	PolicyScope        string                         `yaml:"policy_scope"`
+	Classes            map[string]CatalystClassConfig `yaml:"classes"`
+	// MaintenanceTarget is the device name maintenance windows and blackouts
+	// match for Catalyst Center pushes; defaults to the base URL host
+	MaintenanceTarget string `yaml:"maintenance_target"`
+}
+
+// CatalystClassConfig maps a QoS class to an application set and relevance
+type CatalystClassConfig struct {
+	Relevance      string `yaml:"relevance"`
+	ApplicationSet string `yaml:"application_set"`
+}
+
+// MaintenanceHost returns the device name Catalyst Center pushes are
+// checked against in the maintenance policy
+func (c *CatalystCenterConfig) MaintenanceHost() string {
+	if c.MaintenanceTarget != "" {
+		return c.MaintenanceTarget
+	}
+	if u, err := url.Parse(c.BaseURL); err == nil {
+		return u.Hostname()
+	}
+	return ""
+}
+
+// ClientOptions converts the settings to Catalyst Center client options
+func (c *CatalystCenterConfig) ClientOptions() catalystcenter.Options {
+	return catalystcenter.Options{
+		BaseURL:            c.BaseURL,
+		Username:           c.Username,
+		Password:           c.Password,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		Timeout:            c.Timeout,
+	}
+}
+
+// Mapping returns the class to application set mapping for the model.
+// Configured classes override the default mapping field by field.
+func (c *CatalystCenterConfig) Mapping(model *qos.Model) (map[qos.Class]catalystcenter.ClassMapping, error) {
+	mapping := catalystcenter.DefaultMapping(model)
+	for className, classConfig := range c.Classes {
+		class := qos.Class(className)
+		if !model.Contains(class) {
+			return nil, fmt.Errorf("Catalyst Center mapping references unknown QoS class %s", className)
+		}
+		m := mapping[class]
+		if classConfig.Relevance != "" {
+			m.Relevance = strings.ToUpper(classConfig.Relevance)
+		}
+		if classConfig.ApplicationSet != "" {
+			m.ApplicationSet = classConfig.ApplicationSet
+		}
+		mapping[class] = m
+	}
+	if err := catalystcenter.ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
 // LoadConfig loads configuration from file
 func LoadConfig(configPath string) (*Config, error) {
 	// Set default config path if not provided
@@ -275,6 +1144,117 @@ func applyDefaults(config *Config) {
 		config.SSH.KeepAlive = 30 * time.Second
 	}
 
+	// Transport defaults
+	if config.Transport.Type == "" {
+		config.Transport.Type = transport.TypeCLI
+	}
+	if config.Transport.Timeout == 0 {
+		config.Transport.Timeout = 30 * time.Second
+	}
+	if config.Transport.ConfirmTimeout == 0 {
+		config.Transport.ConfirmTimeout = transport.DefaultConfirmTimeout
+	}
+
+	// NetFlow collector defaults
+	if config.NetFlow.Listen == "" {
+		config.NetFlow.Listen = netflow.DefaultListen
+	}
+	if config.NetFlow.Duration == 0 {
+		config.NetFlow.Duration = 5 * time.Minute
+	}
+	if config.NetFlow.FlushInterval == 0 {
+		config.NetFlow.FlushInterval = 30 * time.Second
+	}
+	if config.NetFlow.TemplateTimeout == 0 {
+		config.NetFlow.TemplateTimeout = 30 * time.Minute
+	}
+
+	// Serve mode defaults
+	if config.Serve.StateFile == "" {
+		config.Serve.StateFile = "nbar-serve-state.json"
+	}
+	if config.Serve.Source == "" {
+		config.Serve.Source = ServeSourceSwitch
+	}
+	if config.Serve.Output == "" {
+		config.Serve.Output = "cisco"
+	}
+	if config.Serve.Discovery.Schedule == "" {
+		config.Serve.Discovery.Schedule = "0 * * * *"
+	}
+	if config.Serve.Classification.Schedule == "" {
+		config.Serve.Classification.Schedule = "5 * * * *"
+	}
+	if config.Serve.DriftCheck.Schedule == "" {
+		config.Serve.DriftCheck.Schedule = "*/30 * * * *"
+	}
+	if config.Serve.Push.Schedule == "" {
+		config.Serve.Push.Schedule = "45 * * * *"
+	}
+
+	// Run defaults
+	if config.Runs.HistoryFile == "" {
+		config.Runs.HistoryFile = "nbar-runs.json"
+	}
+	if config.Runs.MaxHistory == 0 {
+		config.Runs.MaxHistory = 100
+	}
+	if config.Runs.MaxQueued == 0 {
+		config.Runs.MaxQueued = 10
+	}
+	if config.Runs.Timeout == 0 {
+		config.Runs.Timeout = 30 * time.Minute
+	}
+
+	// Rules defaults
+	if config.Rules.StoreFile == "" {
+		config.Rules.StoreFile = "nbar-rules.json"
+	}
+	if config.Rules.AuditFile == "" {
+		config.Rules.AuditFile = "nbar-rules-audit.jsonl"
+	}
+
+	// Approval defaults
+	if config.Approval.StoreFile == "" {
+		config.Approval.StoreFile = "nbar-changes.json"
+	}
+	if config.Approval.AuditFile == "" {
+		config.Approval.AuditFile = "nbar-changes-audit.jsonl"
+	}
+	if config.Approval.Expiry == 0 {
+		config.Approval.Expiry = 24 * time.Hour
+	}
+	if config.Approval.MaxHistory == 0 {
+		config.Approval.MaxHistory = 200
+	}
+
+	// Maintenance defaults
+	if config.Maintenance.OutsideWindow == "" {
+		config.Maintenance.OutsideWindow = OutsideWindowRefuse
+	}
+	if config.Maintenance.QueueFile == "" {
+		config.Maintenance.QueueFile = "nbar-push-queue.json"
+	}
+
+	// Rollout defaults
+	if config.Rollout.Soak == 0 {
+		config.Rollout.Soak = 10 * time.Minute
+	}
+	if config.Rollout.Parallel == 0 {
+		config.Rollout.Parallel = 1
+	}
+
+	// Notification defaults
+	if config.Notifications.Attempts == 0 {
+		config.Notifications.Attempts = 3
+	}
+	if config.Notifications.Backoff == 0 {
+		config.Notifications.Backoff = 2 * time.Second
+	}
+	if config.Notifications.Timeout == 0 {
+		config.Notifications.Timeout = 10 * time.Second
+	}
+
 	// AI defaults
 	if config.AI.Provider == "" {
 		config.AI.Provider = "deepseek"
@@ -306,13 +1286,56 @@ func applyDefaults(config *Config) {
 		config.AI.RateLimit.MaxBackoff = 60 * time.Second
 	}
 
+	// Cisco output defaults
+	if config.Output.Cisco.Template == "" {
+		config.Output.Cisco.Template = "wired"
+	}
+	if config.Output.Cisco.MarkingPolicyName == "" {
+		config.Output.Cisco.MarkingPolicyName = "PM_MARK_AVC_WIRED_INGRESS"
+		if config.Output.Cisco.Template == "wireless" {
+			config.Output.Cisco.MarkingPolicyName = "PM_MARK_AVC_WIRELESS_INGRESS"
+		}
+	}
+	if config.Output.Cisco.ClassMapName == "" {
+		config.Output.Cisco.ClassMapName = render.DefaultClassMapName
+	}
+	if config.Output.Cisco.QueueClassMapName == "" {
+		config.Output.Cisco.QueueClassMapName = render.DefaultQueueClassMapName
+	}
+	if config.Output.Cisco.MaxProtocolsPerClassMap == 0 {
+		config.Output.Cisco.MaxProtocolsPerClassMap = render.DefaultMaxProtocols
+	}
+	if config.Output.Cisco.DefaultClassHandling == "" {
+		config.Output.Cisco.DefaultClassHandling = render.DefaultClassImplicit
+	}
+
+	// Catalyst Center defaults
+	if config.Output.CatalystCenter.PolicyScope == "" {
+		config.Output.CatalystCenter.PolicyScope = "nbar-qos"
+	}
+	if config.Output.CatalystCenter.Timeout == 0 {
+		config.Output.CatalystCenter.Timeout = 30 * time.Second
+	}
+
 	// QoS defaults
 	if config.QoS.DefaultClass == "" {
-		config.QoS.DefaultClass = "CS1"
+		config.QoS.DefaultClass = string(qos.CS1)
+		if preset, exists := qos.LookupPreset(config.QoS.presetName()); exists {
+			config.QoS.DefaultClass = string(preset.DefaultClass)
+		}
 	}
 	if config.QoS.ConfidenceThreshold == 0 {
 		config.QoS.ConfidenceThreshold = 0.8
 	}
+	if config.QoS.Queuing.PolicyName == "" {
+		config.QoS.Queuing.PolicyName = "PM_QUEUE_WIRED_EGRESS"
+	}
+	if config.QoS.Attachment.InputPolicy == "" {
+		config.QoS.Attachment.InputPolicy = config.Output.Cisco.MarkingPolicyName
+	}
+	if config.QoS.Attachment.OutputPolicy == "" && config.QoS.Queuing.Enabled {
+		config.QoS.Attachment.OutputPolicy = config.QoS.Queuing.PolicyName
+	}
 
 	// Cache defaults
 	if config.Cache.TTL == 0 {
@@ -359,6 +1382,12 @@ func applyDefaults(config *Config) {
 	if config.Security.SessionTimeout == 0 {
 		config.Security.SessionTimeout = 24 * time.Hour
 	}
+	if config.Web.Auth.OIDC.Scopes == nil {
+		config.Web.Auth.OIDC.Scopes = []string{"profile", "email"}
+	}
+	if config.Web.Auth.OIDC.GroupsClaim == "" {
+		config.Web.Auth.OIDC.GroupsClaim = "groups"
+	}
 }
 
 // validateConfig validates the configuration
@@ -382,6 +1411,136 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("AI temperature must be between 0 and 2")
 	}
 
+	// Validate push transport
+	if config.Transport.Type != transport.TypeCLI {
+		if _, err := transport.New(config.TransportOptions()); err != nil {
+			return fmt.Errorf("invalid transport settings: %w", err)
+		}
+	}
+
+	// Validate NetFlow collector
+	if config.NetFlow.Duration < 0 || config.NetFlow.FlushInterval < 0 {
+		return fmt.Errorf("NetFlow duration and flush interval must not be negative")
+	}
+
+	// Validate web API authentication
+	if config.Web.Auth.Enabled {
+		if config.Security.JWTSecret == "" && len(config.Web.Auth.Tokens) == 0 && !config.Web.Auth.OIDC.Enabled {
+			return fmt.Errorf("web authentication needs security.jwt_secret, API tokens or OIDC")
+		}
+		if _, err := auth.New(config.Web.Auth.Options(config.Security)); err != nil {
+			return fmt.Errorf("invalid web authentication: %w", err)
+		}
+		if config.Web.Auth.OIDC.Enabled {
+			if _, err := auth.NewOIDCProvider(config.Web.Auth.OIDC.Options(config.Security)); err != nil {
+				return fmt.Errorf("invalid OIDC configuration: %w", err)
+			}
+		}
+	}
+
+	// Validate runs
+	if config.Runs.MaxHistory < 0 || config.Runs.MaxQueued < 0 || config.Runs.Timeout < 0 {
+		return fmt.Errorf("runs max_history, max_queued and timeout must not be negative")
+	}
+
+	// Validate approval
+	if config.Approval.Expiry < 0 || config.Approval.MaxHistory < 0 {
+		return fmt.Errorf("approval expiry and max_history must not be negative")
+	}
+
+	// Validate maintenance windows
+	if config.Maintenance.Enabled {
+		switch config.Maintenance.OutsideWindow {
+		case OutsideWindowRefuse, OutsideWindowQueue:
+		default:
+			return fmt.Errorf("unknown maintenance outside_window %q, expected refuse or queue", config.Maintenance.OutsideWindow)
+		}
+		if _, err := config.Maintenance.Policy(); err != nil {
+			return fmt.Errorf("invalid maintenance configuration: %w", err)
+		}
+	}
+
+	// Validate rollouts
+	if config.Rollout.Soak < 0 || config.Rollout.Parallel < 0 {
+		return fmt.Errorf("rollout soak and parallel must not be negative")
+	}
+	if len(config.Rollout.Inventory) > 0 {
+		hosts := make(map[string]bool)
+		for _, device := range config.Rollout.Inventory {
+			host := strings.ToLower(device.Host)
+			if host == "" {
+				return fmt.Errorf("rollout inventory device requires a host")
+			}
+			if hosts[host] {
+				return fmt.Errorf("rollout inventory lists %s twice", device.Host)
+			}
+			hosts[host] = true
+		}
+		if _, err := config.Rollout.Plan(config.Maintenance); err != nil {
+			return fmt.Errorf("invalid rollout configuration: %w", err)
+		}
+	}
+
+	// Validate notifications
+	if config.Notifications.Enabled {
+		if config.Notifications.Attempts < 0 || config.Notifications.Backoff < 0 || config.Notifications.Timeout < 0 {
+			return fmt.Errorf("notifications attempts, backoff and timeout must not be negative")
+		}
+		if _, err := notify.New(config.Notifications.Options()); err != nil {
+			return fmt.Errorf("invalid notifications configuration: %w", err)
+		}
+	}
+
+	// Validate serve mode
+	switch config.Serve.Source {
+	case ServeSourceSwitch, ServeSourceNetFlow:
+	case ServeSourceFile:
+		if config.Serve.InputFile == "" {
+			return fmt.Errorf("serve source file requires serve.input_file")
+		}
+	default:
+		return fmt.Errorf("unknown serve source %q", config.Serve.Source)
+	}
+	for name, job := range map[string]JobConfig{
+		"discovery":      config.Serve.Discovery,
+		"classification": config.Serve.Classification,
+		"drift_check":    config.Serve.DriftCheck,
+		"push":           config.Serve.Push.JobConfig,
+	} {
+		if _, err := schedule.Parse(job.Schedule); err != nil {
+			return fmt.Errorf("invalid serve.%s schedule: %w", name, err)
+		}
+	}
+
+	// Validate QoS class model
+	model, err := config.QoS.ClassModel()
+	if err != nil {
+		return fmt.Errorf("invalid QoS class model: %w", err)
+	}
+	for _, rule := range config.QoS.CustomRules {
+		if !model.Contains(qos.Class(rule.Class)) {
+			return fmt.Errorf("custom rule %s references unknown QoS class %s", rule.Name, rule.Class)
+		}
+	}
+	if config.QoS.Queuing.Enabled {
+		if _, err := config.QoS.QueuingPolicy(model); err != nil {
+			return fmt.Errorf("invalid queuing policy: %w", err)
+		}
+	}
+	if config.QoS.Attachment.Enabled {
+		if _, err := config.QoS.Attachment.Selector(); err != nil {
+			return fmt.Errorf("invalid attachment targets: %w", err)
+		}
+	}
+
+	// Validate Cisco output templates and naming
+	if _, err := render.NewCiscoRenderer(config.Output.Cisco.RendererOptions()); err != nil {
+		return fmt.Errorf("invalid Cisco output settings: %w", err)
+	}
+	if _, err := config.Output.CatalystCenter.Mapping(model); err != nil {
+		return fmt.Errorf("invalid Catalyst Center mapping: %w", err)
+	}
+
 	return nil
 }
 
@@ -419,6 +1578,67 @@ func resolve1PasswordReferences(config *Config) error {
 		config.AI.APIKey = resolved
 	}
 
+	// Resolve NETCONF/RESTCONF password
+	if strings.HasPrefix(config.Transport.Password, "op://") {
+		resolved, err := resolve1PasswordReference(config.Transport.Password)
+		if err != nil {
+			return fmt.Errorf("failed to resolve transport password: %w", err)
+		}
+		config.Transport.Password = resolved
+	}
+
+	// Resolve Catalyst Center password
+	if strings.HasPrefix(config.Output.CatalystCenter.Password, "op://") {
+		resolved, err := resolve1PasswordReference(config.Output.CatalystCenter.Password)
+		if err != nil {
+			return fmt.Errorf("failed to resolve Catalyst Center password: %w", err)
+		}
+		config.Output.CatalystCenter.Password = resolved
+	}
+
+	// Resolve JWT secret and API tokens
+	if strings.HasPrefix(config.Security.JWTSecret, "op://") {
+		resolved, err := resolve1PasswordReference(config.Security.JWTSecret)
+		if err != nil {
+			return fmt.Errorf("failed to resolve JWT secret: %w", err)
+		}
+		config.Security.JWTSecret = resolved
+	}
+	if strings.HasPrefix(config.Web.Auth.OIDC.ClientSecret, "op://") {
+		resolved, err := resolve1PasswordReference(config.Web.Auth.OIDC.ClientSecret)
+		if err != nil {
+			return fmt.Errorf("failed to resolve OIDC client secret: %w", err)
+		}
+		config.Web.Auth.OIDC.ClientSecret = resolved
+	}
+	for i, token := range config.Web.Auth.Tokens {
+		if strings.HasPrefix(token.Token, "op://") {
+			resolved, err := resolve1PasswordReference(token.Token)
+			if err != nil {
+				return fmt.Errorf("failed to resolve API token %s: %w", token.Name, err)
+			}
+			config.Web.Auth.Tokens[i].Token = resolved
+		}
+	}
+
+	// Resolve notification channel URLs and signing secrets
+	for i, channel := range config.Notifications.Channels {
+		if strings.HasPrefix(channel.URL, "op://") {
+			resolved, err := resolve1PasswordReference(channel.URL)
+			if err != nil {
+				return fmt.Errorf("failed to resolve notification channel %s URL: %w", channel.Name, err)
+			}
+			config.Notifications.Channels[i].URL = resolved
+		}
+		if strings.HasPrefix(channel.Secret, "op://") {
+			resolved, err := resolve1PasswordReference(channel.Secret)
+			if err != nil {
+				return fmt.Errorf("failed to resolve notification channel %s secret: %w", channel.Name, err)
+			}
+			config.Notifications.Channels[i].Secret = resolved
+		}
+	}
+
 	// Resolve provider-specific API keys
 	for providerName, providerConfig := range config.AI.Providers {
 		if strings.HasPrefix(providerConfig.APIKey, "op://") {
@@ -447,4 +1667,4 @@ func resolve1PasswordReference(reference string) (string, error) {
 	}
 
 	return strings.TrimSpace(string(output)), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,257 @@
+// Package daemon runs the scheduled jobs of the serve mode and keeps their
+// state between runs.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/schedule"
+)
+
+// Job is a scheduled unit of work
+type Job struct {
+	Name     string
+	Schedule schedule.Schedule
+	// RunOnStart runs the job as soon as the scheduler starts
+	RunOnStart bool
+	Run        func(ctx context.Context) error
+}
+
+// Scheduler runs jobs one at a time. Jobs due at the same time run in the
+// order they were given, so a pipeline such as discovery, classification and
+// drift check can share one schedule. Activations missed while another job
+// runs are coalesced into a single run.
+type Scheduler struct {
+	logger *logger.Logger
+	store  *Store
+
+	mu       sync.Mutex
+	jobs     []Job
+	changed  bool
+	started  bool
+	triggers []string
+	tasks    []func()
+	wake     chan struct{}
+}
+
+// NewScheduler creates a scheduler that records job runs in store
+func NewScheduler(log *logger.Logger, store *Store) *Scheduler {
+	return &Scheduler{
+		logger: log,
+		store:  store,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// SetJobs replaces the jobs; it may be called while the scheduler runs, e.g.
+// after a configuration reload
+func (s *Scheduler) SetJobs(jobs []Job) error {
+	names := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if job.Name == "" || job.Schedule == nil || job.Run == nil {
+			return fmt.Errorf("job %q needs a name, schedule and run function", job.Name)
+		}
+		if names[job.Name] {
+			return fmt.Errorf("duplicate job %q", job.Name)
+		}
+		names[job.Name] = true
+	}
+
+	s.mu.Lock()
+	s.jobs = append([]Job(nil), jobs...)
+	s.changed = true
+	s.mu.Unlock()
+	s.notify()
+	return nil
+}
+
+// Trigger queues an immediate run of a job
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	found := false
+	for _, job := range s.jobs {
+		if job.Name == name {
+			found = true
+			break
+		}
+	}
+	if found {
+		s.triggers = append(s.triggers, name)
+	}
+	s.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("unknown job %q", name)
+	}
+	s.notify()
+	return nil
+}
+
+// Do queues fn to run between jobs, so it never overlaps a job
+func (s *Scheduler) Do(fn func()) {
+	s.mu.Lock()
+	s.tasks = append(s.tasks, fn)
+	s.mu.Unlock()
+	s.notify()
+}
+
+// notify wakes the run loop
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs jobs until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return fmt.Errorf("scheduler is already running")
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	next := make(map[string]time.Time)
+	first := true
+
+	for {
+		s.mu.Lock()
+		jobs := s.jobs
+		changed := s.changed
+		s.changed = false
+		tasks := s.tasks
+		s.tasks = nil
+		triggers := s.triggers
+		s.triggers = nil
+		s.mu.Unlock()
+
+		if first || changed {
+			now := time.Now()
+			next = make(map[string]time.Time, len(jobs))
+			for _, job := range jobs {
+				if first && job.RunOnStart {
+					next[job.Name] = now
+				} else {
+					next[job.Name] = job.Schedule.Next(now)
+				}
+				s.recordNext(job.Name, next[job.Name])
+			}
+			first = false
+		}
+
+		for _, task := range tasks {
+			task()
+		}
+		for _, name := range triggers {
+			for _, job := range jobs {
+				if job.Name == name {
+					s.runJob(ctx, job)
+					next[job.Name] = job.Schedule.Next(time.Now())
+					s.recordNext(job.Name, next[job.Name])
+				}
+			}
+		}
+
+		now := time.Now()
+		ran := false
+		for _, job := range jobs {
+			if at := next[job.Name]; !at.IsZero() && !at.After(now) {
+				s.runJob(ctx, job)
+				next[job.Name] = job.Schedule.Next(time.Now())
+				s.recordNext(job.Name, next[job.Name])
+				ran = true
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+		if ran {
+			continue
+		}
+
+		var earliest time.Time
+		for _, at := range next {
+			if !at.IsZero() && (earliest.IsZero() || at.Before(earliest)) {
+				earliest = at
+			}
+		}
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if !earliest.IsZero() {
+			timer = time.NewTimer(time.Until(earliest))
+			wait = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-s.wake:
+		case <-wait:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// runJob runs a job, recovering from panics, and records the outcome
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	start := time.Now()
+	s.update(job.Name, func(state *JobState) {
+		state.LastStart = start
+	})
+	s.logger.WithComponent("scheduler").WithField("job", job.Name).Info("Starting scheduled job")
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		return job.Run(ctx)
+	}()
+
+	duration := time.Since(start)
+	s.update(job.Name, func(state *JobState) {
+		state.LastEnd = time.Now()
+		state.Duration = duration
+		state.Runs++
+		if err != nil {
+			state.Failures++
+			state.LastError = err.Error()
+		} else {
+			state.LastSuccess = state.LastEnd
+			state.LastError = ""
+		}
+	})
+
+	fields := logger.Fields{"job": job.Name, "success": err == nil}
+	s.logger.Performance("scheduled_job", duration, fields)
+	if err != nil {
+		s.logger.WithComponent("scheduler").WithError(err).WithField("job", job.Name).Error("Scheduled job failed")
+	}
+}
+
+// recordNext stores the next activation of a job
+func (s *Scheduler) recordNext(name string, at time.Time) {
+	s.update(name, func(state *JobState) {
+		state.NextRun = at
+	})
+}
+
+// update changes the stored state of a job
+func (s *Scheduler) update(name string, fn func(*JobState)) {
+	if err := s.store.Update(func(state *State) {
+		fn(state.Job(name))
+	}); err != nil {
+		s.logger.WithError(err).Warn("Failed to save scheduler state")
+	}
+}
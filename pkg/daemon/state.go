@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// JobState records the runs of a scheduled job
+type JobState struct {
+	LastStart   time.Time     `json:"last_start,omitempty"`
+	LastEnd     time.Time     `json:"last_end,omitempty"`
+	LastSuccess time.Time     `json:"last_success,omitempty"`
+	LastError   string        `json:"last_error,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	NextRun     time.Time     `json:"next_run,omitempty"`
+	Runs        int           `json:"runs"`
+	Failures    int           `json:"failures"`
+}
+
+// State is kept between runs of the serve mode
+type State struct {
+	Jobs map[string]*JobState `json:"jobs"`
+
+	// Protocols observed by discovery, sorted
+	Protocols    []string  `json:"protocols"`
+	NewProtocols []string  `json:"new_protocols,omitempty"`
+	DiscoveredAt time.Time `json:"discovered_at,omitempty"`
+
+	// Classifications and the Cisco configuration generated from them
+	Classifications map[string]qos.Classification `json:"classifications,omitempty"`
+	DesiredConfig   string                        `json:"desired_config,omitempty"`
+	ClassifiedAt    time.Time                     `json:"classified_at,omitempty"`
+
+	// Drift lists the class-maps, policy-maps and interface service-policy
+	// attachments that differ between the switch and the desired
+	// configuration at the last drift check
+	Drift []string `json:"drift,omitempty"`
+	// AttachmentDrift are the service-policy changes the interfaces needed
+	// at the last drift check
+	AttachmentDrift []interfaces.Change `json:"attachment_drift,omitempty"`
+	DriftCheckedAt  time.Time           `json:"drift_checked_at,omitempty"`
+
+	PushedAt  time.Time `json:"pushed_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AddProtocols merges newly observed protocols and returns the ones that were
+// not known before
+func (s *State) AddProtocols(protocols []string) []string {
+	known := make(map[string]bool, len(s.Protocols))
+	for _, protocol := range s.Protocols {
+		known[protocol] = true
+	}
+	var added []string
+	for _, protocol := range protocols {
+		if !known[protocol] {
+			known[protocol] = true
+			added = append(added, protocol)
+			s.Protocols = append(s.Protocols, protocol)
+		}
+	}
+	sort.Strings(s.Protocols)
+	return added
+}
+
+// Job returns the state of a job, creating it if needed
+func (s *State) Job(name string) *JobState {
+	if s.Jobs == nil {
+		s.Jobs = make(map[string]*JobState)
+	}
+	job, exists := s.Jobs[name]
+	if !exists {
+		job = &JobState{}
+		s.Jobs[name] = job
+	}
+	return job
+}
+
+// Store keeps the state in memory and persists it to a JSON file
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	state State
+}
+
+// OpenStore loads the state file if it exists; an empty path keeps the state
+// in memory only
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Snapshot returns a copy of the state
+func (s *Store) Snapshot() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Round-trip through JSON for a deep copy
+	data, _ := json.Marshal(s.state)
+	var state State
+	_ = json.Unmarshal(data, &state)
+	return state
+}
+
+// Update applies fn to the state and saves it
+func (s *Store) Update(fn func(*State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&s.state)
+	s.state.UpdatedAt = time.Now()
+	return s.save()
+}
+
+// save writes the state file atomically
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package input
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// applicationColumns are the normalized header names collectors use for the
+// NBAR application, in order of preference. A generic "protocol" column is
+// only used when no application column exists since NetFlow exports often
+// use it for the IP protocol.
+var applicationColumns = []string{
+	"nbarapplication", "nbarapplicationname", "applicationname", "application",
+	"appname", "app", "nbarprotocol", "protocolname", "protocol",
+}
+
+// applicationPrefixes are prefixes collectors put in front of NBAR names
+var applicationPrefixes = []string{"layer7 ", "layer 7 ", "nbar ", "nbar:", "cisco "}
+
+// parseCSV reads the application column of a collector export. The delimiter
+// is detected from the header line.
+func parseCSV(data string) ([]string, error) {
+	lines := strings.Split(data, "\n")
+	for len(lines) > 0 && (strings.TrimSpace(lines[0]) == "" || strings.HasPrefix(lines[0], "#")) {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+
+	delimiter, found := detectDelimiter(lines[0])
+	if !found {
+		return nil, fmt.Errorf("header row has no delimiter")
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header row: %w", err)
+	}
+	column := applicationColumn(header)
+	if column < 0 {
+		return nil, fmt.Errorf("no application column in header %q", strings.Join(header, string(delimiter)))
+	}
+
+	var entries []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", err)
+		}
+		if column >= len(record) {
+			continue
+		}
+		if value := normalizeApplication(record[column]); value != "" {
+			entries = append(entries, value)
+		}
+	}
+	return entries, nil
+}
+
+// detectDelimiter returns the most frequent of comma, semicolon and tab
+func detectDelimiter(header string) (rune, bool) {
+	best, count := ',', 0
+	for _, delimiter := range []rune{',', ';', '\t'} {
+		if n := strings.Count(header, string(delimiter)); n > count {
+			best, count = delimiter, n
+		}
+	}
+	return best, count > 0
+}
+
+// applicationColumn returns the index of the preferred application column
+func applicationColumn(header []string) int {
+	index := make(map[string]int)
+	for i, name := range header {
+		normalized := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(name))
+		if _, exists := index[normalized]; !exists {
+			index[normalized] = i
+		}
+	}
+	for _, name := range applicationColumns {
+		if i, exists := index[name]; exists {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeApplication converts a collector application value such as
+// "layer7 ms-teams" or "MS-Teams" to an NBAR protocol name
+func normalizeApplication(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, prefix := range applicationPrefixes {
+		value = strings.TrimPrefix(value, prefix)
+	}
+	return strings.Join(strings.Fields(value), "-")
+}
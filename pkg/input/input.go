@@ -0,0 +1,202 @@
+// Package input reads protocol lists from offline captures: plain lists,
+// saved "show ip nbar protocol-discovery" output, running-config and
+// "show tech-support" files, and CSV exports from NetFlow/AVC collectors.
+package input
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh"
+)
+
+// Format identifies the kind of input file
+type Format string
+
+// Supported input formats
+const (
+	FormatAuto              Format = "auto"
+	FormatList              Format = "list"
+	FormatProtocolDiscovery Format = "protocol-discovery"
+	FormatRunningConfig     Format = "running-config"
+	FormatShowTech          Format = "show-tech"
+	FormatCSV               Format = "csv"
+)
+
+// Formats lists the formats accepted by ParseFormat
+var Formats = []Format{FormatAuto, FormatList, FormatProtocolDiscovery, FormatRunningConfig, FormatShowTech, FormatCSV}
+
+// ParseFormat validates a format name; empty selects auto-detection
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return FormatAuto, nil
+	}
+	for _, format := range Formats {
+		if Format(strings.ToLower(name)) == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown input format %q", name)
+}
+
+// Result is the outcome of parsing an input file
+type Result struct {
+	Format    Format
+	Protocols []string
+	// Invalid lists the entries that are not valid protocol names
+	Invalid []string
+}
+
+var (
+	// showTechSection matches the section banners of "show tech-support"
+	showTechSection = regexp.MustCompile(`^-{5,}\s*(show .+?)\s*-{5,}$`)
+	// discoveryHeader matches the table header of protocol discovery output
+	discoveryHeader = regexp.MustCompile(`^\s*Protocol\s+.*(Packet Count|Byte Count|Bit Rate)`)
+	// configLine matches statements that only appear in configuration files
+	configLine = regexp.MustCompile(`^(Building configuration|Current configuration|version \d|hostname \S|class-map |policy-map |interface \S|!$)`)
+	// matchProtocol matches "match protocol <name>" in class-maps
+	matchProtocol = regexp.MustCompile(`^\s*match\s+(?:not\s+)?protocol\s+(\S+)`)
+)
+
+// Load reads and parses an input file
+func Load(path string, format Format) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	result, err := Parse(string(data), format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return result, nil
+}
+
+// Parse extracts the protocols of an input in the given format, detecting the
+// format when it is FormatAuto
+func Parse(data string, format Format) (*Result, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	if format == FormatAuto || format == "" {
+		format = Detect(data)
+	}
+
+	var entries []string
+	var err error
+	switch format {
+	case FormatList:
+		entries = parseList(data)
+	case FormatProtocolDiscovery:
+		entries, err = ssh.ParseProtocolDiscovery(data)
+	case FormatRunningConfig:
+		entries = parseRunningConfig(data)
+	case FormatShowTech:
+		entries, err = parseShowTech(data)
+	case FormatCSV:
+		entries, err = parseCSV(data)
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s input: %w", format, err)
+	}
+
+	result := &Result{Format: format}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		protocol := strings.ToLower(entry)
+		if !qos.IsValidProtocolName(protocol) {
+			result.Invalid = append(result.Invalid, entry)
+			continue
+		}
+		if !seen[protocol] {
+			seen[protocol] = true
+			result.Protocols = append(result.Protocols, protocol)
+		}
+	}
+	return result, nil
+}
+
+// Detect guesses the format of an input
+func Detect(data string) Format {
+	lines := strings.Split(data, "\n")
+
+	for _, line := range lines {
+		if showTechSection.MatchString(strings.TrimSpace(line)) {
+			return FormatShowTech
+		}
+	}
+	for _, line := range lines {
+		if discoveryHeader.MatchString(line) {
+			return FormatProtocolDiscovery
+		}
+	}
+	for _, line := range lines {
+		if configLine.MatchString(strings.TrimRight(line, " ")) {
+			return FormatRunningConfig
+		}
+	}
+	if _, err := parseCSV(data); err == nil {
+		return FormatCSV
+	}
+	return FormatList
+}
+
+// parseList reads one protocol per line, ignoring blank lines and comments
+func parseList(data string) []string {
+	var entries []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries
+}
+
+// parseRunningConfig collects the protocols matched by class-maps
+func parseRunningConfig(data string) []string {
+	var entries []string
+	for _, line := range strings.Split(data, "\n") {
+		if m := matchProtocol.FindStringSubmatch(line); m != nil && m[1] != "attribute" {
+			entries = append(entries, m[1])
+		}
+	}
+	return entries
+}
+
+// parseShowTech splits "show tech-support" into its sections and parses the
+// protocol discovery and configuration sections
+func parseShowTech(data string) ([]string, error) {
+	sections := make(map[string][]string)
+	var order []string
+	current := ""
+	for _, line := range strings.Split(data, "\n") {
+		if m := showTechSection.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			current = strings.Join(strings.Fields(m[1]), " ")
+			if _, exists := sections[current]; !exists {
+				order = append(order, current)
+			}
+			continue
+		}
+		sections[current] = append(sections[current], line)
+	}
+
+	var entries []string
+	for _, name := range order {
+		body := strings.Join(sections[name], "\n")
+		switch {
+		case strings.HasPrefix(name, "show ip nbar protocol-discovery"):
+			protocols, err := ssh.ParseProtocolDiscovery(body)
+			if err != nil {
+				return nil, fmt.Errorf("section %q: %w", name, err)
+			}
+			entries = append(entries, protocols...)
+		case name == "show running-config" || name == "show startup-config":
+			entries = append(entries, parseRunningConfig(body)...)
+		}
+	}
+	return entries, nil
+}
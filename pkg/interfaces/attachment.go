@@ -0,0 +1,202 @@
+package interfaces
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Direction is the direction a service policy is attached in
+type Direction string
+
+// Service policy directions
+const (
+	Input  Direction = "input"
+	Output Direction = "output"
+	Both   Direction = "both"
+)
+
+// Target selects interfaces by name range, description and access VLAN.
+// All criteria set on a target must match; an interface is selected if any
+// target matches it.
+type Target struct {
+	Interfaces         []string  `yaml:"interfaces" json:"interfaces,omitempty"`
+	DescriptionPattern string    `yaml:"description" json:"description,omitempty"`
+	AccessVLANs        []int     `yaml:"access_vlans" json:"access_vlans,omitempty"`
+	Direction          Direction `yaml:"direction" json:"direction,omitempty"`
+}
+
+// compiledTarget is a validated target ready for matching
+type compiledTarget struct {
+	names     map[string]bool
+	regex     *regexp.Regexp
+	vlans     map[int]bool
+	direction Direction
+}
+
+// Selector matches interfaces against a set of targets
+type Selector struct {
+	targets []compiledTarget
+}
+
+// NewSelector validates and compiles the given targets
+func NewSelector(targets []Target) (*Selector, error) {
+	s := &Selector{}
+
+	for i, target := range targets {
+		ct := compiledTarget{direction: target.Direction}
+		if ct.direction == "" {
+			ct.direction = Both
+		}
+		if ct.direction != Input && ct.direction != Output && ct.direction != Both {
+			return nil, fmt.Errorf("target %d: invalid direction %q", i+1, target.Direction)
+		}
+
+		if len(target.Interfaces) == 0 && target.DescriptionPattern == "" && len(target.AccessVLANs) == 0 {
+			return nil, fmt.Errorf("target %d: at least one of interfaces, description or access_vlans is required", i+1)
+		}
+
+		if len(target.Interfaces) > 0 {
+			ct.names = make(map[string]bool)
+			for _, spec := range target.Interfaces {
+				names, err := ExpandRange(spec)
+				if err != nil {
+					return nil, fmt.Errorf("target %d: %w", i+1, err)
+				}
+				for _, name := range names {
+					ct.names[name] = true
+				}
+			}
+		}
+
+		if target.DescriptionPattern != "" {
+			regex, err := regexp.Compile(target.DescriptionPattern)
+			if err != nil {
+				return nil, fmt.Errorf("target %d: invalid description pattern: %w", i+1, err)
+			}
+			ct.regex = regex
+		}
+
+		if len(target.AccessVLANs) > 0 {
+			ct.vlans = make(map[int]bool)
+			for _, vlan := range target.AccessVLANs {
+				if vlan < 1 || vlan > 4094 {
+					return nil, fmt.Errorf("target %d: invalid VLAN %d", i+1, vlan)
+				}
+				ct.vlans[vlan] = true
+			}
+		}
+
+		s.targets = append(s.targets, ct)
+	}
+
+	return s, nil
+}
+
+// Match reports whether the interface is selected for input and output policies
+func (s *Selector) Match(iface Interface) (input, output bool) {
+	for _, target := range s.targets {
+		if !target.matches(iface) {
+			continue
+		}
+		if target.direction == Input || target.direction == Both {
+			input = true
+		}
+		if target.direction == Output || target.direction == Both {
+			output = true
+		}
+	}
+	return input, output
+}
+
+// matches checks all criteria of the target against the interface
+func (t compiledTarget) matches(iface Interface) bool {
+	if t.names != nil && !t.names[iface.Name] {
+		return false
+	}
+	if t.regex != nil && !t.regex.MatchString(iface.Description) {
+		return false
+	}
+	if t.vlans != nil {
+		vlan, isAccess := iface.AccessVLAN()
+		if !isAccess || !t.vlans[vlan] {
+			return false
+		}
+	}
+	return true
+}
+
+// Change is a service-policy statement to add to or remove from an interface
+type Change struct {
+	Interface string    `json:"interface"`
+	Direction Direction `json:"direction"`
+	Policy    string    `json:"policy"`
+	Remove    bool      `json:"remove,omitempty"`
+}
+
+// Conflict describes a selected interface that already has a different policy
+// attached; such interfaces are left unchanged
+type Conflict struct {
+	Interface      string    `json:"interface"`
+	Direction      Direction `json:"direction"`
+	AttachedPolicy string    `json:"attached_policy"`
+}
+
+// Diff computes the service-policy changes needed so that exactly the selected
+// interfaces carry the given input and output policies. An empty policy name
+// disables that direction.
+func Diff(ifaces []Interface, selector *Selector, inputPolicy, outputPolicy string) ([]Change, []Conflict) {
+	var changes []Change
+	var conflicts []Conflict
+
+	check := func(iface Interface, direction Direction, want bool, policy, attached string) {
+		if policy == "" {
+			return
+		}
+		switch {
+		case want && attached == policy:
+			// Already attached
+		case want && attached != "":
+			conflicts = append(conflicts, Conflict{Interface: iface.Name, Direction: direction, AttachedPolicy: attached})
+		case want:
+			changes = append(changes, Change{Interface: iface.Name, Direction: direction, Policy: policy})
+		case attached == policy:
+			changes = append(changes, Change{Interface: iface.Name, Direction: direction, Policy: policy, Remove: true})
+		}
+	}
+
+	for _, iface := range ifaces {
+		input, output := selector.Match(iface)
+		check(iface, Input, input, inputPolicy, iface.InputPolicy)
+		check(iface, Output, output, outputPolicy, iface.OutputPolicy)
+	}
+
+	return changes, conflicts
+}
+
+// RenderChanges renders the changes as IOS-XE interface configuration
+func RenderChanges(changes []Change) string {
+	var output strings.Builder
+
+	current := ""
+	for _, change := range changes {
+		if change.Interface != current {
+			if current != "" {
+				output.WriteString("!\n")
+			}
+			output.WriteString(fmt.Sprintf("interface %s\n", change.Interface))
+			current = change.Interface
+		}
+
+		prefix := ""
+		if change.Remove {
+			prefix = "no "
+		}
+		output.WriteString(fmt.Sprintf(" %sservice-policy %s %s\n", prefix, change.Direction, change.Policy))
+	}
+	if current != "" {
+		output.WriteString("!\n")
+	}
+
+	return output.String()
+}
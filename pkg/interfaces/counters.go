@@ -0,0 +1,138 @@
+package interfaces
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Drops are packet drop counters keyed by interface and direction, e.g.
+// "GigabitEthernet1/0/1 output", or by interface, direction and service
+// policy class, e.g. "GigabitEthernet1/0/1 output VOICE"
+type Drops map[string]uint64
+
+var (
+	interfaceHeaderPattern = regexp.MustCompile(`^(\S+) is (?:up|down|administratively down),`)
+	inputQueuePattern      = regexp.MustCompile(`Input queue: \d+/\d+/(\d+)/\d+`)
+	outputDropsPattern     = regexp.MustCompile(`Total output drops: (\d+)`)
+	servicePolicyPattern   = regexp.MustCompile(`^Service-policy (input|output):`)
+	classMapPattern        = regexp.MustCompile(`^Class-map: (\S+)`)
+	totalDropsPattern      = regexp.MustCompile(`^\(total drops\) (\d+)`)
+	queueDropsPattern      = regexp.MustCompile(`^\(queue depth/total drops/no-buffer drops\) \d+/(\d+)/\d+`)
+)
+
+// ParseInterfaceDrops parses the input queue and output drop counters from
+// "show interfaces"
+func ParseInterfaceDrops(output string) Drops {
+	drops := make(Drops)
+	var name string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := interfaceHeaderPattern.FindStringSubmatch(line); match != nil {
+			name = NormalizeName(match[1])
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		if match := inputQueuePattern.FindStringSubmatch(line); match != nil {
+			drops[name+" input"] = parseUint(match[1])
+		}
+		if match := outputDropsPattern.FindStringSubmatch(line); match != nil {
+			drops[name+" output"] = parseUint(match[1])
+		}
+	}
+	return drops
+}
+
+// ParsePolicyMapDrops parses the drop counters of each service policy class
+// from "show policy-map interface". The priority queue statistics IOS-XE
+// prints ahead of the classes are counted as class "priority".
+func ParsePolicyMapDrops(output string) Drops {
+	drops := make(Drops)
+	var name, direction, class string
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case interfaceNamePattern.MatchString(line):
+			name, direction, class = NormalizeName(line), "", ""
+		case servicePolicyPattern.MatchString(line):
+			direction = servicePolicyPattern.FindStringSubmatch(line)[1]
+			class = ""
+		case strings.HasPrefix(line, "queue stats for all priority classes"):
+			class = "priority"
+		case classMapPattern.MatchString(line):
+			class = classMapPattern.FindStringSubmatch(line)[1]
+		}
+		if name == "" || direction == "" || class == "" {
+			continue
+		}
+
+		match := totalDropsPattern.FindStringSubmatch(line)
+		if match == nil {
+			match = queueDropsPattern.FindStringSubmatch(line)
+		}
+		if match != nil {
+			drops[name+" "+direction+" "+class] += parseUint(match[1])
+		}
+	}
+	return drops
+}
+
+// Increase returns the counters that grew from d to after. A counter that
+// went down was cleared, so all of its value is new.
+func (d Drops) Increase(after Drops) Drops {
+	increase := make(Drops)
+	for key, value := range after {
+		before := d[key]
+		switch {
+		case value > before:
+			increase[key] = value - before
+		case value < before && value > 0:
+			increase[key] = value
+		}
+	}
+	return increase
+}
+
+// Classes returns the service policy class counters of the given classes
+func (d Drops) Classes(classes map[string]bool) Drops {
+	result := make(Drops)
+	for key, value := range d {
+		if fields := strings.Fields(key); len(fields) == 3 && classes[fields[2]] {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// Select returns the counters with the given keys
+func (d Drops) Select(keys map[string]bool) Drops {
+	result := make(Drops)
+	for key, value := range d {
+		if keys[key] {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// Total returns the sum of the counters
+func (d Drops) Total() uint64 {
+	var total uint64
+	for _, value := range d {
+		total += value
+	}
+	return total
+}
+
+// parseUint parses a counter matched by a pattern
+func parseUint(value string) uint64 {
+	n, _ := strconv.ParseUint(value, 10, 64)
+	return n
+}
@@ -0,0 +1,265 @@
+package interfaces
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Interface represents a switch interface and its attached service policies
+type Interface struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Status       string `json:"status,omitempty"`
+	VLAN         string `json:"vlan,omitempty"` // access VLAN number, "trunk" or "routed"
+	InputPolicy  string `json:"input_policy,omitempty"`
+	OutputPolicy string `json:"output_policy,omitempty"`
+}
+
+// AccessVLAN returns the access VLAN of the interface if it is an access port
+func (i Interface) AccessVLAN() (int, bool) {
+	vlan, err := strconv.Atoi(i.VLAN)
+	if err != nil {
+		return 0, false
+	}
+	return vlan, true
+}
+
+// abbreviations maps IOS-XE interface name abbreviations to full names.
+// Longer abbreviations must be checked first ("Twe" before "Tw").
+var abbreviations = []struct {
+	short string
+	full  string
+}{
+	{"TwentyFiveGigE", "TwentyFiveGigE"},
+	{"Twe", "TwentyFiveGigE"},
+	{"TwoGigabitEthernet", "TwoGigabitEthernet"},
+	{"Tw", "TwoGigabitEthernet"},
+	{"TenGigabitEthernet", "TenGigabitEthernet"},
+	{"Te", "TenGigabitEthernet"},
+	{"FiveGigabitEthernet", "FiveGigabitEthernet"},
+	{"Fi", "FiveGigabitEthernet"},
+	{"FortyGigabitEthernet", "FortyGigabitEthernet"},
+	{"Fo", "FortyGigabitEthernet"},
+	{"HundredGigE", "HundredGigE"},
+	{"Hu", "HundredGigE"},
+	{"AppGigabitEthernet", "AppGigabitEthernet"},
+	{"Ap", "AppGigabitEthernet"},
+	{"GigabitEthernet", "GigabitEthernet"},
+	{"Gi", "GigabitEthernet"},
+	{"FastEthernet", "FastEthernet"},
+	{"Fa", "FastEthernet"},
+	{"Port-channel", "Port-channel"},
+	{"Po", "Port-channel"},
+	{"Vlan", "Vlan"},
+	{"Vl", "Vlan"},
+}
+
+var interfaceNamePattern = regexp.MustCompile(`^([A-Za-z-]+)\s*([0-9][0-9/.:]*)$`)
+
+// NormalizeName converts an abbreviated interface name to its full IOS-XE form
+func NormalizeName(name string) string {
+	match := interfaceNamePattern.FindStringSubmatch(strings.TrimSpace(name))
+	if match == nil {
+		return strings.TrimSpace(name)
+	}
+
+	prefix, number := match[1], match[2]
+	for _, abbr := range abbreviations {
+		if strings.EqualFold(prefix, abbr.short) {
+			return abbr.full + number
+		}
+	}
+	return prefix + number
+}
+
+// rangePattern matches an interface name, optionally ending in a range of
+// its last number: prefix, number, subinterface and range end
+var rangePattern = regexp.MustCompile(`^([A-Za-z][A-Za-z-]*[A-Za-z])([0-9]+(?:/[0-9]+)*)(\.[0-9]+)?(?:-([0-9]+))?$`)
+
+// knownPrefix reports whether the prefix is an interface type or abbreviation
+func knownPrefix(prefix string) bool {
+	for _, abbr := range abbreviations {
+		if strings.EqualFold(prefix, abbr.short) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandRange expands an interface range such as "Gi1/0/1-24" into the
+// individual normalized interface names. Anything but a known interface name
+// or a range of its last number is an error.
+func ExpandRange(spec string) ([]string, error) {
+	spec = strings.ReplaceAll(strings.TrimSpace(spec), " ", "")
+	match := rangePattern.FindStringSubmatch(spec)
+	if match == nil || !knownPrefix(match[1]) {
+		return nil, fmt.Errorf("invalid interface %q, expected a name such as Gi1/0/1 or a range such as Gi1/0/1-24", spec)
+	}
+	prefix, number, subinterface, last := match[1], match[2], match[3], match[4]
+	if last == "" {
+		return []string{NormalizeName(spec)}, nil
+	}
+	if subinterface != "" {
+		return nil, fmt.Errorf("invalid interface range %q: subinterfaces cannot be ranged", spec)
+	}
+
+	startIndex := strings.LastIndex(number, "/") + 1
+	start, err := strconv.Atoi(number[startIndex:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid interface range %q", spec)
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil || end < start {
+		return nil, fmt.Errorf("invalid interface range %q", spec)
+	}
+
+	names := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		names = append(names, NormalizeName(fmt.Sprintf("%s%s%d", prefix, number[:startIndex], i)))
+	}
+	return names, nil
+}
+
+// ParseInterfaceStatus parses the output of "show interfaces status"
+func ParseInterfaceStatus(output string) ([]Interface, error) {
+	var result []Interface
+	var nameCol, statusCol int
+	headerSeen := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		line = strings.ReplaceAll(line, "--More--", "")
+
+		if strings.HasPrefix(line, "Port") && strings.Contains(line, "Status") && strings.Contains(line, "Vlan") {
+			nameCol = strings.Index(line, "Name")
+			statusCol = strings.Index(line, "Status")
+			headerSeen = true
+			continue
+		}
+		if !headerSeen || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !interfaceNamePattern.MatchString(fields[0]) {
+			continue
+		}
+
+		iface := Interface{Name: NormalizeName(fields[0])}
+
+		if nameCol > 0 && statusCol > nameCol && len(line) > nameCol {
+			end := statusCol
+			if end > len(line) {
+				end = len(line)
+			}
+			iface.Description = strings.TrimSpace(line[nameCol:end])
+		}
+
+		if statusCol > 0 && len(line) > statusCol {
+			rest := strings.Fields(line[statusCol:])
+			if len(rest) > 0 {
+				iface.Status = rest[0]
+			}
+			if len(rest) > 1 {
+				iface.VLAN = rest[1]
+			}
+		}
+
+		result = append(result, iface)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !headerSeen {
+		return nil, fmt.Errorf("interface status header not found")
+	}
+
+	return result, nil
+}
+
+// ParseRunningConfig extracts interface descriptions, access VLANs and
+// attached service policies from a running configuration
+func ParseRunningConfig(output string) map[string]Interface {
+	result := make(map[string]Interface)
+
+	var current *Interface
+	flush := func() {
+		if current != nil {
+			result[current.Name] = *current
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "interface ") {
+			flush()
+			current = &Interface{Name: NormalizeName(strings.TrimPrefix(line, "interface "))}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			flush()
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 2 && fields[0] == "description":
+			current.Description = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "description"))
+		case len(fields) == 4 && fields[0] == "switchport" && fields[1] == "access" && fields[2] == "vlan":
+			current.VLAN = fields[3]
+		case len(fields) == 3 && fields[0] == "service-policy" && fields[1] == "input":
+			current.InputPolicy = fields[2]
+		case len(fields) == 3 && fields[0] == "service-policy" && fields[1] == "output":
+			current.OutputPolicy = fields[2]
+		}
+	}
+	flush()
+
+	return result
+}
+
+// Merge combines interface status with running configuration details. The
+// running configuration provides the full description and attached policies.
+func Merge(status []Interface, running map[string]Interface) []Interface {
+	result := make([]Interface, 0, len(status))
+	seen := make(map[string]bool)
+
+	for _, iface := range status {
+		if cfg, exists := running[iface.Name]; exists {
+			if cfg.Description != "" {
+				iface.Description = cfg.Description
+			}
+			iface.InputPolicy = cfg.InputPolicy
+			iface.OutputPolicy = cfg.OutputPolicy
+		}
+		seen[iface.Name] = true
+		result = append(result, iface)
+	}
+
+	// Keep interfaces that only appear in the running configuration so
+	// attached policies on them can still be removed
+	names := make([]string, 0)
+	for name := range running {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, running[name])
+	}
+
+	return result
+}
@@ -0,0 +1,223 @@
+// Package maintenance decides when configuration may be pushed to a device:
+// inside one of its maintenance windows and outside every blackout.
+package maintenance
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/schedule"
+)
+
+// searchLimit bounds the search for the next time a push is allowed
+const searchLimit = 400
+
+// Target is the device a push goes to
+type Target struct {
+	Host   string   `json:"host"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Scope selects the devices a window or blackout applies to. An empty scope
+// applies to every device.
+type Scope struct {
+	// Devices are host names, which may contain shell wildcards
+	Devices []string
+	Groups  []string
+}
+
+// Matches reports whether the scope covers the target
+func (s Scope) Matches(target Target) bool {
+	if len(s.Devices) == 0 && len(s.Groups) == 0 {
+		return true
+	}
+	for _, pattern := range s.Devices {
+		if matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(target.Host)); matched {
+			return true
+		}
+	}
+	for _, group := range s.Groups {
+		for _, member := range target.Groups {
+			if group == member {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Window is a period in which pushes are allowed: recurring, opening at each
+// activation of a cron schedule for Duration, or a single calendar period
+// from Start to End
+type Window struct {
+	Name  string
+	Scope Scope
+
+	Schedule schedule.Schedule
+	Duration time.Duration
+
+	Start time.Time
+	End   time.Time
+}
+
+// openAt returns the period of the window containing t
+func (w Window) openAt(t time.Time) (start, end time.Time, ok bool) {
+	if w.Schedule == nil {
+		return w.Start, w.End, !t.Before(w.Start) && t.Before(w.End)
+	}
+	start = w.Schedule.Next(t.Add(-w.Duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.Add(w.Duration), true
+}
+
+// nextOpen returns the first time after t the window opens
+func (w Window) nextOpen(t time.Time) time.Time {
+	if w.Schedule == nil {
+		if w.Start.After(t) {
+			return w.Start
+		}
+		return time.Time{}
+	}
+	return w.Schedule.Next(t)
+}
+
+// Blackout is a period in which no pushes are allowed, even inside a window
+type Blackout struct {
+	Name  string
+	Scope Scope
+	Start time.Time
+	End   time.Time
+}
+
+// Decision is the outcome of a check
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Window is the open window, empty when the target has no windows
+	Window string `json:"window,omitempty"`
+	// Blackout is the blackout preventing the push
+	Blackout string `json:"blackout,omitempty"`
+	// Until is when the open window closes or the blackout ends
+	Until time.Time `json:"until,omitempty"`
+	// Next is the next time pushes are allowed when they are not now; zero
+	// when no window opens again
+	Next time.Time `json:"next,omitempty"`
+}
+
+// Reason explains a refused push
+func (d Decision) Reason() string {
+	var reason string
+	if d.Blackout != "" {
+		reason = fmt.Sprintf("blackout %q until %s", d.Blackout, d.Until.Format(time.RFC3339))
+	} else {
+		reason = "outside the maintenance windows"
+	}
+	if d.Next.IsZero() {
+		return reason + ", no window opens again"
+	}
+	return reason + ", next window opens " + d.Next.Format(time.RFC3339)
+}
+
+// Policy holds the windows and blackouts
+type Policy struct {
+	windows   []Window
+	blackouts []Blackout
+}
+
+// NewPolicy creates a policy. Devices no window applies to may be pushed to
+// at any time outside blackouts.
+func NewPolicy(windows []Window, blackouts []Blackout) (*Policy, error) {
+	for _, w := range windows {
+		if w.Schedule != nil {
+			if w.Duration <= 0 {
+				return nil, fmt.Errorf("window %q needs a positive duration", w.Name)
+			}
+		} else if !w.End.After(w.Start) {
+			return nil, fmt.Errorf("window %q needs a schedule, or an end after its start", w.Name)
+		}
+	}
+	for _, b := range blackouts {
+		if !b.End.After(b.Start) {
+			return nil, fmt.Errorf("blackout %q needs an end after its start", b.Name)
+		}
+	}
+	return &Policy{windows: windows, blackouts: blackouts}, nil
+}
+
+// Check decides whether a push to the target is allowed at t
+func (p *Policy) Check(target Target, t time.Time) Decision {
+	decision := p.check(target, t)
+	if !decision.Allowed {
+		decision.Next = p.next(target, t)
+	}
+	return decision
+}
+
+// check decides without looking for the next allowed time
+func (p *Policy) check(target Target, t time.Time) Decision {
+	for _, b := range p.blackouts {
+		if b.Scope.Matches(target) && !t.Before(b.Start) && t.Before(b.End) {
+			return Decision{Blackout: b.Name, Until: b.End}
+		}
+	}
+
+	windows := p.windowsFor(target)
+	if len(windows) == 0 {
+		return Decision{Allowed: true}
+	}
+	var open Decision
+	for _, w := range windows {
+		if _, end, ok := w.openAt(t); ok && end.After(open.Until) {
+			open = Decision{Allowed: true, Window: w.Name, Until: end}
+		}
+	}
+	return open
+}
+
+// next returns the first time after t a push to the target is allowed
+func (p *Policy) next(target Target, t time.Time) time.Time {
+	windows := p.windowsFor(target)
+	for i := 0; i < searchLimit; i++ {
+		// The candidates are the ends of blackouts and the openings of
+		// windows; the earliest allowed one wins
+		var candidates []time.Time
+		for _, b := range p.blackouts {
+			if b.Scope.Matches(target) && b.End.After(t) {
+				candidates = append(candidates, b.End)
+			}
+		}
+		for _, w := range windows {
+			if next := w.nextOpen(t); !next.IsZero() {
+				candidates = append(candidates, next)
+			}
+		}
+		if len(candidates) == 0 {
+			return time.Time{}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Before(candidates[j])
+		})
+		for _, candidate := range candidates {
+			if p.check(target, candidate).Allowed {
+				return candidate
+			}
+		}
+		t = candidates[len(candidates)-1]
+	}
+	return time.Time{}
+}
+
+// windowsFor returns the windows that apply to the target
+func (p *Policy) windowsFor(target Target) []Window {
+	var windows []Window
+	for _, w := range p.windows {
+		if w.Scope.Matches(target) {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
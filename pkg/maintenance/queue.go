@@ -0,0 +1,152 @@
+package maintenance
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for unknown queued pushes
+var ErrNotFound = errors.New("queued push not found")
+
+// Push is a push waiting for a maintenance window. It carries either the
+// configuration to push or the change to apply. A Catalyst Center push
+// carries its Application Policy as JSON in Config.
+type Push struct {
+	ID     string `json:"id"`
+	Target Target `json:"target"`
+
+	Config     string `json:"config,omitempty"`
+	SaveConfig bool   `json:"save_config,omitempty"`
+	ChangeID   string `json:"change_id,omitempty"`
+	Rollback   bool   `json:"rollback,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	// CatalystCenter sends Config to Catalyst Center instead of the switch
+	CatalystCenter bool `json:"catalyst_center,omitempty"`
+
+	RequestedBy string    `json:"requested_by,omitempty"`
+	QueuedAt    time.Time `json:"queued_at"`
+	// NotBefore is when the next window was expected to open when the push
+	// was queued
+	NotBefore time.Time `json:"not_before,omitempty"`
+}
+
+// Queue holds pushes until their window opens. The file is read before
+// every operation, so pushes queued from the command line are seen by a
+// running server.
+type Queue struct {
+	file string
+
+	mu     sync.Mutex
+	pushes []Push
+}
+
+// NewQueue creates a queue, checking that the file can be read; an empty
+// file name keeps the queue in memory only
+func NewQueue(file string) (*Queue, error) {
+	q := &Queue{file: file}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Add queues a push
+func (q *Queue) Add(push Push) (Push, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return Push{}, fmt.Errorf("failed to generate push ID: %w", err)
+	}
+	push.ID = hex.EncodeToString(b)
+	push.QueuedAt = time.Now()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.load(); err != nil {
+		return Push{}, err
+	}
+	q.pushes = append(q.pushes, push)
+	if err := q.save(); err != nil {
+		return Push{}, err
+	}
+	return push, nil
+}
+
+// List returns the queued pushes, oldest first
+func (q *Queue) List() ([]Push, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return append([]Push{}, q.pushes...), nil
+}
+
+// Remove takes a push off the queue
+func (q *Queue) Remove(id string) (Push, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.load(); err != nil {
+		return Push{}, err
+	}
+	for i, push := range q.pushes {
+		if push.ID == id {
+			q.pushes = append(q.pushes[:i], q.pushes[i+1:]...)
+			if err := q.save(); err != nil {
+				return Push{}, err
+			}
+			return push, nil
+		}
+	}
+	return Push{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// load reads the queue file; the lock must be held
+func (q *Queue) load() error {
+	if q.file == "" {
+		return nil
+	}
+	data, err := os.ReadFile(q.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read push queue: %w", err)
+	}
+	var pushes []Push
+	if err := json.Unmarshal(data, &pushes); err != nil {
+		return fmt.Errorf("failed to parse push queue %s: %w", q.file, err)
+	}
+	q.pushes = pushes
+	return nil
+}
+
+// save writes the queue file atomically; the lock must be held
+func (q *Queue) save() error {
+	if q.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(q.pushes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal push queue: %w", err)
+	}
+	if dir := filepath.Dir(q.file); dir != "." {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	tmp := q.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write push queue: %w", err)
+	}
+	if err := os.Rename(tmp, q.file); err != nil {
+		return fmt.Errorf("failed to replace push queue: %w", err)
+	}
+	return nil
+}
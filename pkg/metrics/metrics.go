@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"time"
@@ -49,6 +50,7 @@ type Metrics struct {
 	config   *config.MetricsConfig
 	logger   *logger.Logger
 	registry *prometheus.Registry
+	server   *http.Server
 }
 
 // New creates a new metrics instance
@@ -401,5 +403,17 @@ func (m *Metrics) StartServer() error {
 		"path":    m.config.Path,
 	}).Info("Starting metrics server")
 
-	return http.ListenAndServe(addr, mux)
+	m.server = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 15 * time.Second}
+	if err := m.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// StopServer shuts the metrics server down
+func (m *Metrics) StopServer(ctx context.Context) error {
+	if m.server == nil {
+		return nil
+	}
+	return m.server.Shutdown(ctx)
 }
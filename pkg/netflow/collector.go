@@ -0,0 +1,250 @@
+package netflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// DefaultListen is the address the collector listens on by default
+const DefaultListen = ":2055"
+
+// maxPacketSize is the largest UDP payload the collector reads
+const maxPacketSize = 65535
+
+// Options configures a Collector
+type Options struct {
+	// Listen is the UDP address to receive exports on
+	Listen string
+	// TemplateTimeout expires templates that are not re-exported
+	TemplateTimeout time.Duration
+}
+
+// Stats describes the collector state
+type Stats struct {
+	DecodeStats
+	Exporters    int `json:"exporters"`
+	Applications int `json:"applications"`
+	Protocols    int `json:"protocols"`
+	// Unresolved counts observed application IDs whose name has not been
+	// received in an option record yet
+	Unresolved int `json:"unresolved"`
+}
+
+// Collector receives NetFlow v9/IPFIX exports and tracks the NBAR protocols
+// seen in flow records. Flow records usually carry only the application ID;
+// the option records of the application table map IDs to names. Application
+// names only count as observed once a flow record references them.
+type Collector struct {
+	options Options
+	decoder *Decoder
+
+	mu           sync.Mutex
+	conn         net.PacketConn
+	applications map[string]map[string]string
+	unresolved   map[string]map[string]bool
+	protocols    map[string]bool
+	pending      []string
+	lastError    error
+}
+
+// New creates a collector
+func New(opts Options) *Collector {
+	if opts.Listen == "" {
+		opts.Listen = DefaultListen
+	}
+	return &Collector{
+		options:      opts,
+		decoder:      NewDecoder(opts.TemplateTimeout),
+		applications: make(map[string]map[string]string),
+		unresolved:   make(map[string]map[string]bool),
+		protocols:    make(map[string]bool),
+	}
+}
+
+// Listen binds the UDP socket
+func (c *Collector) Listen() error {
+	conn, err := net.ListenPacket("udp", c.options.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", c.options.Listen, err)
+	}
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	return nil
+}
+
+// Addr returns the bound address, or nil before Listen
+func (c *Collector) Addr() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.LocalAddr()
+}
+
+// Serve receives exports until the context is cancelled. Listen is called
+// if the socket is not bound yet. Malformed packets are counted and skipped.
+func (c *Collector) Serve(ctx context.Context) error {
+	if c.Addr() == nil {
+		if err := c.Listen(); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buffer := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to read export: %w", err)
+		}
+		exporter := addr.String()
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			exporter = udpAddr.IP.String()
+		}
+		_ = c.Handle(exporter, buffer[:n])
+	}
+}
+
+// Handle processes one export packet from exporter. It is used by Serve and
+// can replay captured packets directly.
+func (c *Collector) Handle(exporter string, packet []byte) error {
+	records, err := c.decoder.Decode(exporter, packet)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.lastError = fmt.Errorf("export from %s: %w", exporter, err)
+	}
+	for _, record := range records {
+		c.addRecord(exporter, record)
+	}
+	return err
+}
+
+// addRecord updates the application table or marks a protocol observed
+func (c *Collector) addRecord(exporter string, record Record) {
+	names := c.applications[exporter]
+	if names == nil {
+		names = make(map[string]string)
+		c.applications[exporter] = names
+	}
+
+	if record.Options {
+		if record.ApplicationID == "" || record.ApplicationName == "" {
+			return
+		}
+		names[record.ApplicationID] = record.ApplicationName
+		if c.unresolved[exporter][record.ApplicationID] {
+			delete(c.unresolved[exporter], record.ApplicationID)
+			c.observe(record.ApplicationName)
+		}
+		return
+	}
+
+	if record.ApplicationName != "" {
+		if record.ApplicationID != "" {
+			names[record.ApplicationID] = record.ApplicationName
+		}
+		c.observe(record.ApplicationName)
+		return
+	}
+	if name, exists := names[record.ApplicationID]; exists {
+		c.observe(name)
+		return
+	}
+	if c.unresolved[exporter] == nil {
+		c.unresolved[exporter] = make(map[string]bool)
+	}
+	c.unresolved[exporter][record.ApplicationID] = true
+}
+
+// observe records a protocol seen in traffic
+func (c *Collector) observe(name string) {
+	protocol := strings.ToLower(strings.TrimSpace(name))
+	if c.protocols[protocol] || !qos.IsValidProtocolName(protocol) {
+		return
+	}
+	c.protocols[protocol] = true
+	c.pending = append(c.pending, protocol)
+}
+
+// Protocols returns all protocols observed so far, sorted
+func (c *Collector) Protocols() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	protocols := make([]string, 0, len(c.protocols))
+	for protocol := range c.protocols {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// TakeNew returns the protocols observed since the previous call
+func (c *Collector) TakeNew() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	protocols := c.pending
+	c.pending = nil
+	return protocols
+}
+
+// LastError returns the most recent decode error
+func (c *Collector) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastError
+}
+
+// Stats returns the decode counters and collector state
+func (c *Collector) Stats() Stats {
+	stats := Stats{DecodeStats: c.decoder.Stats()}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.Exporters = len(c.applications)
+	for _, names := range c.applications {
+		stats.Applications += len(names)
+	}
+	for _, ids := range c.unresolved {
+		stats.Unresolved += len(ids)
+	}
+	stats.Protocols = len(c.protocols)
+	return stats
+}
+
+// Close closes the socket
+func (c *Collector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,342 @@
+// Package netflow decodes NetFlow v9 and IPFIX exports and collects the NBAR
+// applications observed in Flexible NetFlow/AVC records.
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export protocol versions
+const (
+	VersionNetFlow9 = 9
+	VersionIPFIX    = 10
+)
+
+// Information elements used by AVC exports
+const (
+	IEApplicationDescription = 94
+	IEApplicationID          = 95
+	IEApplicationName        = 96
+)
+
+// Set IDs of template and options template sets
+const (
+	netflow9TemplateSet = 0
+	netflow9OptionsSet  = 1
+	ipfixTemplateSet    = 2
+	ipfixOptionsSet     = 3
+	minDataSetID        = 256
+)
+
+// variableLength marks an IPFIX variable-length field
+const variableLength = 65535
+
+// Record holds the application fields of one flow or option record
+type Record struct {
+	// Options is set for option records, which map application IDs to names
+	Options         bool
+	ApplicationID   string
+	ApplicationName string
+	Description     string
+}
+
+// field is a template field specifier
+type field struct {
+	id         uint16
+	length     uint16
+	enterprise uint32
+}
+
+// template is a cached data or options template
+type template struct {
+	fields  []field
+	options bool
+	updated time.Time
+}
+
+// DecodeStats counts the outcome of Decode calls
+type DecodeStats struct {
+	Packets          uint64 `json:"packets"`
+	Records          uint64 `json:"records"`
+	Templates        uint64 `json:"templates"`
+	MissingTemplates uint64 `json:"missing_templates"`
+	Errors           uint64 `json:"errors"`
+}
+
+// Decoder decodes NetFlow v9 and IPFIX packets. Templates are cached per
+// exporter and observation domain.
+type Decoder struct {
+	templateTimeout time.Duration
+	now             func() time.Time
+
+	mu        sync.Mutex
+	templates map[string]*template
+	stats     DecodeStats
+}
+
+// NewDecoder creates a decoder; templates older than templateTimeout are
+// ignored until re-exported, zero keeps them forever
+func NewDecoder(templateTimeout time.Duration) *Decoder {
+	return &Decoder{
+		templateTimeout: templateTimeout,
+		now:             time.Now,
+		templates:       make(map[string]*template),
+	}
+}
+
+// Stats returns the decode counters
+func (d *Decoder) Stats() DecodeStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// Decode decodes one export packet received from exporter and returns the
+// records that carry application fields. Data sets whose template has not
+// been received yet are skipped.
+func (d *Decoder) Decode(exporter string, packet []byte) ([]Record, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stats.Packets++
+	records, err := d.decode(exporter, packet)
+	if err != nil {
+		d.stats.Errors++
+		return records, err
+	}
+	return records, nil
+}
+
+// decode parses the packet header and its sets
+func (d *Decoder) decode(exporter string, packet []byte) ([]Record, error) {
+	if len(packet) < 2 {
+		return nil, fmt.Errorf("packet too short")
+	}
+
+	version := binary.BigEndian.Uint16(packet)
+	var headerLength int
+	var domain uint32
+	switch version {
+	case VersionNetFlow9:
+		headerLength = 20
+		if len(packet) < headerLength {
+			return nil, fmt.Errorf("NetFlow v9 header too short")
+		}
+		domain = binary.BigEndian.Uint32(packet[16:])
+	case VersionIPFIX:
+		headerLength = 16
+		if len(packet) < headerLength {
+			return nil, fmt.Errorf("IPFIX header too short")
+		}
+		length := int(binary.BigEndian.Uint16(packet[2:]))
+		if length < headerLength || length > len(packet) {
+			return nil, fmt.Errorf("IPFIX message length %d does not match packet size %d", length, len(packet))
+		}
+		packet = packet[:length]
+		domain = binary.BigEndian.Uint32(packet[12:])
+	default:
+		return nil, fmt.Errorf("unsupported export version %d", version)
+	}
+
+	var records []Record
+	prefix := fmt.Sprintf("%s/%d/%d/", exporter, version, domain)
+	for offset := headerLength; offset+4 <= len(packet); {
+		setID := binary.BigEndian.Uint16(packet[offset:])
+		setLength := int(binary.BigEndian.Uint16(packet[offset+2:]))
+		if setLength < 4 || offset+setLength > len(packet) {
+			return records, fmt.Errorf("invalid set length %d at offset %d", setLength, offset)
+		}
+		body := packet[offset+4 : offset+setLength]
+		offset += setLength
+
+		var err error
+		switch {
+		case version == VersionNetFlow9 && setID == netflow9TemplateSet:
+			err = d.parseTemplates(prefix, body, false, false)
+		case version == VersionNetFlow9 && setID == netflow9OptionsSet:
+			err = d.parseNetFlow9Options(prefix, body)
+		case version == VersionIPFIX && setID == ipfixTemplateSet:
+			err = d.parseTemplates(prefix, body, true, false)
+		case version == VersionIPFIX && setID == ipfixOptionsSet:
+			err = d.parseTemplates(prefix, body, true, true)
+		case setID >= minDataSetID:
+			var setRecords []Record
+			setRecords, err = d.parseData(prefix, setID, body)
+			records = append(records, setRecords...)
+		}
+		if err != nil {
+			return records, fmt.Errorf("set %d: %w", setID, err)
+		}
+	}
+	return records, nil
+}
+
+// parseTemplates parses a NetFlow v9 template set or an IPFIX template or
+// options template set
+func (d *Decoder) parseTemplates(prefix string, body []byte, ipfix, options bool) error {
+	for len(body) >= 4 {
+		templateID := binary.BigEndian.Uint16(body)
+		fieldCount := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		if templateID < minDataSetID {
+			// Padding at the end of the set
+			return nil
+		}
+		if options {
+			if len(body) < 2 {
+				return fmt.Errorf("options template %d truncated", templateID)
+			}
+			body = body[2:] // scope field count
+		}
+		if fieldCount == 0 {
+			// IPFIX template withdrawal
+			delete(d.templates, templateKey(prefix, templateID))
+			continue
+		}
+
+		fields := make([]field, 0, fieldCount)
+		for i := 0; i < fieldCount; i++ {
+			if len(body) < 4 {
+				return fmt.Errorf("template %d truncated", templateID)
+			}
+			f := field{id: binary.BigEndian.Uint16(body), length: binary.BigEndian.Uint16(body[2:])}
+			body = body[4:]
+			if ipfix && f.id&0x8000 != 0 {
+				if len(body) < 4 {
+					return fmt.Errorf("template %d truncated", templateID)
+				}
+				f.id &= 0x7fff
+				f.enterprise = binary.BigEndian.Uint32(body)
+				body = body[4:]
+			}
+			fields = append(fields, f)
+		}
+		d.storeTemplate(prefix, templateID, fields, options)
+	}
+	return nil
+}
+
+// parseNetFlow9Options parses a NetFlow v9 options template set, whose scope
+// and option fields are given as byte lengths
+func (d *Decoder) parseNetFlow9Options(prefix string, body []byte) error {
+	for len(body) >= 6 {
+		templateID := binary.BigEndian.Uint16(body)
+		scopeLength := int(binary.BigEndian.Uint16(body[2:]))
+		optionLength := int(binary.BigEndian.Uint16(body[4:]))
+		body = body[6:]
+		if templateID < minDataSetID {
+			return nil
+		}
+		if scopeLength%4 != 0 || optionLength%4 != 0 || len(body) < scopeLength+optionLength {
+			return fmt.Errorf("options template %d truncated", templateID)
+		}
+
+		fields := make([]field, 0, (scopeLength+optionLength)/4)
+		for i := 0; i < scopeLength+optionLength; i += 4 {
+			fields = append(fields, field{id: binary.BigEndian.Uint16(body[i:]), length: binary.BigEndian.Uint16(body[i+2:])})
+		}
+		body = body[scopeLength+optionLength:]
+		d.storeTemplate(prefix, templateID, fields, true)
+	}
+	return nil
+}
+
+// storeTemplate caches a template
+func (d *Decoder) storeTemplate(prefix string, templateID uint16, fields []field, options bool) {
+	d.templates[templateKey(prefix, templateID)] = &template{fields: fields, options: options, updated: d.now()}
+	d.stats.Templates++
+}
+
+// parseData decodes the records of a data set
+func (d *Decoder) parseData(prefix string, templateID uint16, body []byte) ([]Record, error) {
+	tmpl, exists := d.templates[templateKey(prefix, templateID)]
+	if exists && d.templateTimeout > 0 && d.now().Sub(tmpl.updated) > d.templateTimeout {
+		delete(d.templates, templateKey(prefix, templateID))
+		exists = false
+	}
+	if !exists {
+		d.stats.MissingTemplates++
+		return nil, nil
+	}
+
+	minLength := 0
+	for _, f := range tmpl.fields {
+		if f.length == variableLength {
+			minLength++
+		} else {
+			minLength += int(f.length)
+		}
+	}
+	if minLength == 0 {
+		return nil, nil
+	}
+
+	var records []Record
+	for len(body) >= minLength {
+		record := Record{Options: tmpl.options}
+		for _, f := range tmpl.fields {
+			length := int(f.length)
+			if f.length == variableLength {
+				if len(body) < 1 {
+					return records, fmt.Errorf("record truncated")
+				}
+				length, body = int(body[0]), body[1:]
+				if length == 255 {
+					if len(body) < 2 {
+						return records, fmt.Errorf("record truncated")
+					}
+					length, body = int(binary.BigEndian.Uint16(body)), body[2:]
+				}
+			}
+			if len(body) < length {
+				return records, fmt.Errorf("record truncated")
+			}
+			value := body[:length]
+			body = body[length:]
+
+			if f.enterprise != 0 {
+				continue
+			}
+			switch f.id {
+			case IEApplicationID:
+				record.ApplicationID = FormatApplicationID(value)
+			case IEApplicationName:
+				record.ApplicationName = decodeString(value)
+			case IEApplicationDescription:
+				record.Description = decodeString(value)
+			}
+		}
+		d.stats.Records++
+		if record.ApplicationID != "" || record.ApplicationName != "" {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// templateKey identifies a template of an exporter and observation domain
+func templateKey(prefix string, templateID uint16) string {
+	return fmt.Sprintf("%s%d", prefix, templateID)
+}
+
+// FormatApplicationID formats an applicationId value the way IOS-XE shows it:
+// the classification engine ID, a colon and the selector ID, e.g. "13:1001"
+func FormatApplicationID(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+	var selector uint64
+	for _, b := range value[1:] {
+		selector = selector<<8 | uint64(b)
+	}
+	return fmt.Sprintf("%d:%d", value[0], selector)
+}
+
+// decodeString trims the NUL padding of fixed-length string fields
+func decodeString(value []byte) string {
+	return strings.TrimSpace(strings.TrimRight(string(value), "\x00"))
+}
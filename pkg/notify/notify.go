@@ -0,0 +1,349 @@
+// Package notify sends notifications about newly classified protocols,
+// configuration pushes and drift to Slack, Microsoft Teams and generic
+// webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Kind is the kind of an event
+type Kind string
+
+// Event kinds
+const (
+	KindNewProtocols  Kind = "new_protocols"
+	KindPushSucceeded Kind = "push_succeeded"
+	KindPushFailed    Kind = "push_failed"
+	KindDrift         Kind = "drift"
+)
+
+// Kinds are the event kinds that can be routed
+var Kinds = []Kind{KindNewProtocols, KindPushSucceeded, KindPushFailed, KindDrift}
+
+// Channel types
+const (
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+	TypeWebhook = "webhook"
+)
+
+// Headers of generic webhook requests. The signature is the hex HMAC-SHA256
+// of the timestamp, a dot and the body, prefixed with "sha256=".
+const (
+	HeaderEvent     = "X-NBAR-Event"
+	HeaderTimestamp = "X-NBAR-Timestamp"
+	HeaderSignature = "X-NBAR-Signature"
+)
+
+// defaultTemplates are the messages of each kind unless configured
+var defaultTemplates = map[Kind]string{
+	KindNewProtocols:  `{{len .Protocols}} new protocol(s) classified{{if .Host}} for {{.Host}}{{end}}: {{range $i, $p := .Protocols}}{{if $i}}, {{end}}{{$p.Protocol}} ({{$p.Class}}){{end}}`,
+	KindPushSucceeded: `Configuration pushed to {{.Host}}{{if .ChangeID}} for change {{.ChangeID}}{{if .Rollback}} (rollback){{end}}{{end}}`,
+	KindPushFailed:    `Configuration push to {{.Host}} failed{{if .ChangeID}} for change {{.ChangeID}}{{if .Rollback}} (rollback){{end}}{{end}}: {{.Error}}`,
+	KindDrift:         `{{len .ClassMaps}} item(s) on {{.Host}} drifted from the desired configuration: {{join .ClassMaps ", "}}`,
+}
+
+// titles head Teams cards
+var titles = map[Kind]string{
+	KindNewProtocols:  "New protocols classified",
+	KindPushSucceeded: "Configuration pushed",
+	KindPushFailed:    "Configuration push failed",
+	KindDrift:         "Configuration drift detected",
+}
+
+// colors are the theme colors of Teams cards
+var colors = map[Kind]string{
+	KindNewProtocols:  "0078D4",
+	KindPushSucceeded: "2EB67D",
+	KindPushFailed:    "D13438",
+	KindDrift:         "FF8C00",
+}
+
+// ProtocolClass is a newly classified protocol
+type ProtocolClass struct {
+	Protocol string `json:"protocol"`
+	Class    string `json:"class"`
+}
+
+// Event is something to notify about. Messages are rendered from it.
+type Event struct {
+	Kind Kind      `json:"kind"`
+	Time time.Time `json:"time"`
+	Host string    `json:"host,omitempty"`
+	// Protocols are the newly classified protocols
+	Protocols []ProtocolClass `json:"protocols,omitempty"`
+	// ClassMaps are the class-maps, policy-maps and interface attachments
+	// that drifted
+	ClassMaps   []string `json:"class_maps,omitempty"`
+	ChangeID    string   `json:"change_id,omitempty"`
+	Rollback    bool     `json:"rollback,omitempty"`
+	RequestedBy string   `json:"requested_by,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// Channel is a destination of notifications
+type Channel struct {
+	Name string
+	Type string
+	URL  string
+	// Secret signs generic webhook requests and is required for them
+	Secret string
+	// Events are the kinds sent to the channel; all kinds when empty
+	Events []Kind
+	// Templates override the messages of the channel by kind
+	Templates map[Kind]string
+}
+
+// Options configures a notifier
+type Options struct {
+	Channels []Channel
+	// Templates override the default messages by kind
+	Templates map[Kind]string
+	// Attempts is the number of times a notification is sent before giving
+	// up; Backoff is the wait before the first retry, doubling each time
+	Attempts int
+	Backoff  time.Duration
+	// Timeout bounds each request when no client is given
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// Notifier sends events to the channels routed to them
+type Notifier struct {
+	channels []channel
+	attempts int
+	backoff  time.Duration
+	client   *http.Client
+}
+
+// channel is a channel with its parsed templates
+type channel struct {
+	Channel
+	events    map[Kind]bool
+	templates map[Kind]*template.Template
+}
+
+// New creates a notifier, checking the channels and parsing the templates
+func New(opts Options) (*Notifier, error) {
+	n := &Notifier{
+		attempts: opts.Attempts,
+		backoff:  opts.Backoff,
+		client:   opts.Client,
+	}
+	if n.attempts < 1 {
+		n.attempts = 1
+	}
+	if n.client == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		n.client = &http.Client{Timeout: timeout}
+	}
+
+	names := make(map[string]bool)
+	for i, c := range opts.Channels {
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("channel %d", i+1)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate notification channel %s", c.Name)
+		}
+		names[c.Name] = true
+
+		switch c.Type {
+		case TypeSlack, TypeTeams, TypeWebhook:
+		default:
+			return nil, fmt.Errorf("notification channel %s: unknown type %q, expected slack, teams or webhook", c.Name, c.Type)
+		}
+		if c.Type == TypeWebhook && c.Secret == "" {
+			return nil, fmt.Errorf("notification channel %s: webhook channels need a secret to sign requests", c.Name)
+		}
+		if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("notification channel %s: invalid URL", c.Name)
+		}
+
+		ch := channel{Channel: c, events: make(map[Kind]bool), templates: make(map[Kind]*template.Template)}
+		for _, kind := range c.Events {
+			if !knownKind(kind) {
+				return nil, fmt.Errorf("notification channel %s: unknown event %q", c.Name, kind)
+			}
+			ch.events[kind] = true
+		}
+		for _, kind := range Kinds {
+			text := defaultTemplates[kind]
+			if override, ok := opts.Templates[kind]; ok {
+				text = override
+			}
+			if override, ok := c.Templates[kind]; ok {
+				text = override
+			}
+			tmpl, err := template.New(string(kind)).Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("notification channel %s: invalid %s template: %w", c.Name, kind, err)
+			}
+			ch.templates[kind] = tmpl
+		}
+		for kind := range c.Templates {
+			if !knownKind(kind) {
+				return nil, fmt.Errorf("notification channel %s: template for unknown event %q", c.Name, kind)
+			}
+		}
+		n.channels = append(n.channels, ch)
+	}
+	for kind := range opts.Templates {
+		if !knownKind(kind) {
+			return nil, fmt.Errorf("template for unknown notification event %q", kind)
+		}
+	}
+	return n, nil
+}
+
+// Notify sends the event to every channel routed to its kind, retrying
+// failed requests. Errors of all channels are returned together.
+func (n *Notifier) Notify(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	var errs []error
+	for _, ch := range n.channels {
+		if len(ch.events) > 0 && !ch.events[event.Kind] {
+			continue
+		}
+		if err := n.send(ctx, ch, event); err != nil {
+			errs = append(errs, fmt.Errorf("notification channel %s: %w", ch.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send renders the message and posts it, retrying server errors and
+// connection failures
+func (n *Notifier) send(ctx context.Context, ch channel, event Event) error {
+	tmpl, ok := ch.templates[event.Kind]
+	if !ok {
+		return fmt.Errorf("unknown event %q", event.Kind)
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, event); err != nil {
+		return fmt.Errorf("failed to render %s message: %w", event.Kind, err)
+	}
+	body, err := payload(ch.Type, event, message.String())
+	if err != nil {
+		return err
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, ch, event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.attempts {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one request and reports whether a failure is worth retrying
+func (n *Notifier) post(ctx context.Context, ch channel, event Event, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ch.Type == TypeWebhook {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderEvent, string(event.Kind))
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, Sign(ch.Secret, timestamp, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// payload builds the request body of a channel type
+func payload(channelType string, event Event, message string) ([]byte, error) {
+	var body interface{}
+	switch channelType {
+	case TypeSlack:
+		body = map[string]string{"text": message}
+	case TypeTeams:
+		body = map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    titles[event.Kind],
+			"title":      titles[event.Kind],
+			"themeColor": colors[event.Kind],
+			"text":       message,
+		}
+	default:
+		body = map[string]interface{}{
+			"event":   event.Kind,
+			"message": message,
+			"data":    event,
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return data, nil
+}
+
+// Sign returns the signature of a generic webhook request
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a generic webhook request, for receivers
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// knownKind reports whether the kind is an event kind
+func knownKind(kind Kind) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// AnsibleTask is a single cisco.ios.ios_config task
+type AnsibleTask struct {
+	Name      string           `yaml:"name"`
+	IOSConfig AnsibleIOSConfig `yaml:"cisco.ios.ios_config"`
+	Tags      []string         `yaml:"tags,omitempty"`
+}
+
+// AnsibleIOSConfig contains the ios_config module arguments
+type AnsibleIOSConfig struct {
+	Parents []string `yaml:"parents,omitempty"`
+	Lines   []string `yaml:"lines"`
+}
+
+// AnsibleVars is the host_vars document describing the QoS configuration
+type AnsibleVars struct {
+	Classes map[qos.Class]AnsibleClassVars `yaml:"nbar_qos_classes"`
+	Default qos.Class                      `yaml:"nbar_qos_default_class"`
+	Blocks  []ConfigBlock                  `yaml:"nbar_qos_config_blocks"`
+}
+
+// AnsibleClassVars contains the DSCP and protocols of a QoS class
+type AnsibleClassVars struct {
+	DSCP      string   `yaml:"dscp"`
+	Protocols []string `yaml:"protocols"`
+}
+
+// AnsibleTasksGenerator renders the device configuration as a task file of
+// cisco.ios.ios_config tasks, one per configuration block
+type AnsibleTasksGenerator struct {
+	device DeviceConfigGenerator
+}
+
+// NewAnsibleTasksGenerator creates an Ansible task file generator
+func NewAnsibleTasksGenerator(device DeviceConfigGenerator) *AnsibleTasksGenerator {
+	return &AnsibleTasksGenerator{device: device}
+}
+
+// Name returns the generator name
+func (g *AnsibleTasksGenerator) Name() string {
+	return "ansible"
+}
+
+// Extension returns the default file extension
+func (g *AnsibleTasksGenerator) Extension() string {
+	return ".tasks.yml"
+}
+
+// DeviceConfig returns the underlying device configuration
+func (g *AnsibleTasksGenerator) DeviceConfig(result *Result) (string, error) {
+	return g.device.DeviceConfig(result)
+}
+
+// Generate renders the Ansible task file
+func (g *AnsibleTasksGenerator) Generate(result *Result) ([]byte, error) {
+	config, err := g.device.DeviceConfig(result)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := ParseConfigBlocks(config)
+	tasks := make([]AnsibleTask, 0, len(blocks))
+	for _, block := range blocks {
+		tasks = append(tasks, AnsibleTask{
+			Name:      taskName(block),
+			IOSConfig: AnsibleIOSConfig{Parents: block.Parents, Lines: block.Lines},
+			Tags:      []string{"nbar_qos"},
+		})
+	}
+
+	return marshalYAML(tasks, fmt.Sprintf("# NBAR QoS configuration tasks generated on %s\n", result.GeneratedAt.Format("2006-01-02")))
+}
+
+// AnsibleVarsGenerator renders the grouped classifications and configuration
+// blocks as host_vars for use by an existing role
+type AnsibleVarsGenerator struct {
+	device DeviceConfigGenerator
+}
+
+// NewAnsibleVarsGenerator creates an Ansible host_vars generator
+func NewAnsibleVarsGenerator(device DeviceConfigGenerator) *AnsibleVarsGenerator {
+	return &AnsibleVarsGenerator{device: device}
+}
+
+// Name returns the generator name
+func (g *AnsibleVarsGenerator) Name() string {
+	return "ansible-vars"
+}
+
+// Extension returns the default file extension
+func (g *AnsibleVarsGenerator) Extension() string {
+	return ".vars.yml"
+}
+
+// DeviceConfig returns the underlying device configuration
+func (g *AnsibleVarsGenerator) DeviceConfig(result *Result) (string, error) {
+	return g.device.DeviceConfig(result)
+}
+
+// Generate renders the host_vars document
+func (g *AnsibleVarsGenerator) Generate(result *Result) ([]byte, error) {
+	config, err := g.device.DeviceConfig(result)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := AnsibleVars{
+		Classes: make(map[qos.Class]AnsibleClassVars),
+		Default: result.DefaultClass,
+		Blocks:  ParseConfigBlocks(config),
+	}
+	for _, def := range result.Model.Definitions() {
+		if def.Name == qos.Other {
+			continue
+		}
+		vars.Classes[def.Name] = AnsibleClassVars{DSCP: def.DSCP, Protocols: make([]string, 0)}
+	}
+	for _, protocol := range result.Protocols() {
+		class := result.Classifications[protocol].Class
+		if classVars, exists := vars.Classes[class]; exists {
+			classVars.Protocols = append(classVars.Protocols, protocol)
+			vars.Classes[class] = classVars
+		}
+	}
+
+	return marshalYAML(vars, "---\n")
+}
+
+// taskName builds a readable task name for a configuration block
+func taskName(block ConfigBlock) string {
+	if len(block.Parents) == 0 {
+		return "Configure " + block.Lines[0]
+	}
+	return "Configure " + strings.Join(block.Parents, " / ")
+}
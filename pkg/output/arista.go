@@ -0,0 +1,352 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// aristaACLs maps NBAR names to the EOS access-list entries that match their
+// traffic; several entries are separated by semicolons
+var aristaACLs = map[string]string{
+	"sip":            "udp any any eq 5060; tcp any any eq 5060",
+	"rtp":            "udp any any range 16384 32767",
+	"rtp-audio":      "udp any any range 16384 32767",
+	"rtp-video":      "udp any any range 16384 32767",
+	"ms-teams-media": "udp any any range 3478 3481",
+	"zoom-meetings":  "udp any any range 8801 8810",
+	"http":           "tcp any any eq 80",
+	"http-alt":       "tcp any any eq 8080",
+	"https":          "tcp any any eq 443",
+	"ssl":            "tcp any any eq 443",
+	"quic":           "udp any any eq 443",
+	"smtp":           "tcp any any eq 25",
+	"secure-smtp":    "tcp any any eq 465",
+	"tftp":           "udp any any eq 69",
+	"ssh":            "tcp any any eq 22",
+	"dns":            "udp any any eq 53; tcp any any eq 53",
+	"ntp":            "udp any any eq 123",
+	"snmp":           "udp any any eq 161",
+	"syslog":         "udp any any eq 514",
+	"bgp":            "tcp any any eq 179",
+}
+
+// AristaGenerator renders an EOS QoS policy for campus switches: access-lists
+// and class-maps of type qos and a policy-map that sets DSCP and traffic
+// class per QoS class. EOS switches cannot match applications, so protocols
+// are matched by the access-list entries of the vendor mapping; classes
+// without mapped protocols match their DSCP as marked upstream. Protocols
+// whose entries overlap those of another class are left out, since the first
+// class in the policy would take the traffic of both.
+type AristaGenerator struct {
+	mapper appMapper
+}
+
+// NewAristaGenerator creates an Arista EOS QoS generator
+func NewAristaGenerator(opts VendorOptions) *AristaGenerator {
+	if opts.PolicyName == "" {
+		opts.PolicyName = "NBAR-QOS"
+	}
+	return &AristaGenerator{mapper: appMapper{builtin: aristaACLs, options: opts}}
+}
+
+// Name returns the generator name
+func (g *AristaGenerator) Name() string {
+	return "arista"
+}
+
+// Extension returns the default file extension
+func (g *AristaGenerator) Extension() string {
+	return ".eos.cfg"
+}
+
+// Unmapped returns the protocols without access-list entries and those left
+// out because their entries overlap another class
+func (g *AristaGenerator) Unmapped(result *Result) []string {
+	_, unmapped, overlapping := g.group(result)
+	return append(unmapped, overlapping...)
+}
+
+// Generate renders the EOS configuration
+func (g *AristaGenerator) Generate(result *Result) ([]byte, error) {
+	classes, unmapped, overlapping := g.group(result)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("! Arista EOS QoS policy generated on %s\n", result.GeneratedAt.Format("2006-01-02")))
+	if len(unmapped) > 0 {
+		output.WriteString(fmt.Sprintf("! Unmapped NBAR protocols: %s\n", strings.Join(unmapped, ", ")))
+	}
+	if len(overlapping) > 0 {
+		output.WriteString(fmt.Sprintf("! NBAR protocols overlapping another class: %s\n", strings.Join(overlapping, ", ")))
+	}
+
+	var matched []vendorClass
+	defaultDSCP := "default"
+	for _, class := range classes {
+		if class.Class == result.DefaultClass {
+			defaultDSCP = class.DSCP
+			continue
+		}
+		matched = append(matched, class)
+		if entries := aristaEntries(class.Applications); len(entries) > 0 {
+			output.WriteString(fmt.Sprintf("ip access-list NBAR_%s_ACL\n", class.Class))
+			for i, entry := range entries {
+				output.WriteString(fmt.Sprintf("   %d permit %s\n", (i+1)*10, entry))
+			}
+			output.WriteString("!\n")
+		}
+	}
+
+	for _, class := range matched {
+		output.WriteString(fmt.Sprintf("class-map type qos match-any NBAR_%s\n", class.Class))
+		if len(class.Applications) > 0 {
+			output.WriteString(fmt.Sprintf("   match ip access-group NBAR_%s_ACL\n", class.Class))
+		} else {
+			output.WriteString(fmt.Sprintf("   match dscp %d\n", dscpValue(class.DSCP)))
+		}
+		output.WriteString("!\n")
+	}
+
+	output.WriteString(fmt.Sprintf("policy-map type qos %s\n", g.mapper.options.PolicyName))
+	writeClass := func(name, dscp string) {
+		value := dscpValue(dscp)
+		output.WriteString(fmt.Sprintf("   class %s\n", name))
+		output.WriteString(fmt.Sprintf("      set dscp %d\n", value))
+		output.WriteString(fmt.Sprintf("      set traffic-class %d\n", aristaTrafficClass(value)))
+		output.WriteString("   !\n")
+	}
+	for _, class := range matched {
+		writeClass("NBAR_"+string(class.Class), class.DSCP)
+	}
+	// Everything else is marked as the default class
+	writeClass("class-default", defaultDSCP)
+	output.WriteString("!\n")
+
+	return []byte(output.String()), nil
+}
+
+// group resolves the classified protocols to access-list entries by class
+// like appMapper.group. Protocols with an entry overlapping an entry of a
+// protocol in another class are left out and returned separately.
+func (g *AristaGenerator) group(result *Result) ([]vendorClass, []string, []string) {
+	entries := make(map[string][]string)
+	for _, protocol := range result.Protocols() {
+		if acl, ok := g.mapper.lookup(protocol); ok {
+			entries[protocol] = aristaEntries([]string{acl})
+		}
+	}
+
+	kept := *result
+	kept.Classifications = make(map[string]qos.Classification, len(result.Classifications))
+	var overlapping []string
+	for _, protocol := range result.Protocols() {
+		classification := result.Classifications[protocol]
+		if aristaOverlaps(protocol, entries, result.Classifications) {
+			overlapping = append(overlapping, protocol)
+			continue
+		}
+		kept.Classifications[protocol] = classification
+	}
+
+	classes, unmapped := g.mapper.group(&kept)
+	return classes, unmapped, overlapping
+}
+
+// aristaOverlaps reports whether an entry of the protocol overlaps an entry
+// of a protocol classified into another class
+func aristaOverlaps(protocol string, entries map[string][]string, classifications map[string]qos.Classification) bool {
+	class := classifications[protocol].Class
+	for other, otherEntries := range entries {
+		if classifications[other].Class == class {
+			continue
+		}
+		for _, entry := range entries[protocol] {
+			for _, otherEntry := range otherEntries {
+				if aristaEntriesOverlap(entry, otherEntry) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// aristaEntriesOverlap reports whether two access-list entries can match the
+// same packet. Entries of the form "<protocol> <source> <destination>
+// [eq <port> | range <low> <high>]" overlap when their addresses are the
+// same, their protocols match and their port ranges intersect; other entries
+// only when they are identical.
+func aristaEntriesOverlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+	first, ok := parseAristaEntry(a)
+	if !ok {
+		return false
+	}
+	second, ok := parseAristaEntry(b)
+	if !ok {
+		return false
+	}
+	if first.source != second.source || first.destination != second.destination {
+		return false
+	}
+	if first.protocol != second.protocol && first.protocol != "ip" && second.protocol != "ip" {
+		return false
+	}
+	return first.low <= second.high && second.low <= first.high
+}
+
+// aristaEntry is an access-list entry matching a destination port range
+type aristaEntry struct {
+	protocol    string
+	source      string
+	destination string
+	low, high   int
+}
+
+// parseAristaEntry parses an access-list entry; entries without a port
+// match every port
+func parseAristaEntry(entry string) (aristaEntry, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) < 3 {
+		return aristaEntry{}, false
+	}
+	parsed := aristaEntry{protocol: fields[0], source: fields[1], destination: fields[2], high: 65535}
+	var err error
+	switch {
+	case len(fields) == 3:
+	case len(fields) == 5 && fields[3] == "eq":
+		parsed.low, err = strconv.Atoi(fields[4])
+		parsed.high = parsed.low
+	case len(fields) == 6 && fields[3] == "range":
+		parsed.low, err = strconv.Atoi(fields[4])
+		if err == nil {
+			parsed.high, err = strconv.Atoi(fields[5])
+		}
+	default:
+		return aristaEntry{}, false
+	}
+	return parsed, err == nil
+}
+
+// aristaEntries splits the access-list entries of a class, dropping
+// duplicates shared by several protocols
+func aristaEntries(mappings []string) []string {
+	var entries []string
+	seen := make(map[string]bool)
+	for _, mapping := range mappings {
+		for _, entry := range strings.Split(mapping, ";") {
+			entry = strings.Join(strings.Fields(entry), " ")
+			if entry != "" && !seen[entry] {
+				seen[entry] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// aristaApplications maps NBAR names that differ from the EOS application name
+var aristaApplications = map[string]string{
+	"ms-teams":       "microsoft-teams",
+	"ms-teams-media": "microsoft-teams",
+	"zoom-meetings":  "zoom",
+	"webex-meeting":  "webex",
+	"ssl":            "https",
+}
+
+// AristaAVTGenerator renders EOS application traffic recognition profiles and
+// an adaptive virtual topology policy that sets DSCP and traffic class per
+// profile, for AVT-capable EOS routers
+type AristaAVTGenerator struct {
+	mapper appMapper
+}
+
+// NewAristaAVTGenerator creates an Arista EOS AVT generator
+func NewAristaAVTGenerator(opts VendorOptions) *AristaAVTGenerator {
+	if opts.PolicyName == "" {
+		opts.PolicyName = "NBAR-QOS"
+	}
+	return &AristaAVTGenerator{mapper: appMapper{builtin: aristaApplications, options: opts, derive: strings.ToLower}}
+}
+
+// Name returns the generator name
+func (g *AristaAVTGenerator) Name() string {
+	return "arista-avt"
+}
+
+// Extension returns the default file extension
+func (g *AristaAVTGenerator) Extension() string {
+	return ".avt.eos.cfg"
+}
+
+// Unmapped returns the protocols without an EOS application
+func (g *AristaAVTGenerator) Unmapped(result *Result) []string {
+	_, unmapped := g.mapper.group(result)
+	return unmapped
+}
+
+// Generate renders the EOS configuration
+func (g *AristaAVTGenerator) Generate(result *Result) ([]byte, error) {
+	classes, unmapped := g.mapper.group(result)
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("! Arista EOS application QoS policy generated on %s\n", result.GeneratedAt.Format("2006-01-02")))
+	if len(unmapped) > 0 {
+		output.WriteString(fmt.Sprintf("! Unmapped NBAR protocols: %s\n", strings.Join(unmapped, ", ")))
+	}
+
+	var profiles []vendorClass
+	defaultDSCP := "default"
+	output.WriteString("application traffic recognition\n")
+	for _, class := range classes {
+		if class.Class == result.DefaultClass {
+			defaultDSCP = class.DSCP
+			continue
+		}
+		if len(class.Applications) == 0 {
+			continue
+		}
+		profiles = append(profiles, class)
+		output.WriteString(fmt.Sprintf("   application-profile NBAR_%s\n", class.Class))
+		for _, app := range class.Applications {
+			output.WriteString(fmt.Sprintf("      application %s\n", app))
+		}
+		output.WriteString("   !\n")
+	}
+	output.WriteString("!\n")
+
+	output.WriteString("router adaptive-virtual-topology\n")
+	output.WriteString(fmt.Sprintf("   policy %s\n", g.mapper.options.PolicyName))
+	writeMatch := func(profile, dscp string) {
+		value := dscpValue(dscp)
+		output.WriteString(fmt.Sprintf("      match application-profile %s\n", profile))
+		output.WriteString(fmt.Sprintf("         dscp %d\n", value))
+		output.WriteString(fmt.Sprintf("         traffic-class %d\n", aristaTrafficClass(value)))
+		output.WriteString("      !\n")
+	}
+	for _, class := range profiles {
+		writeMatch("NBAR_"+string(class.Class), class.DSCP)
+	}
+	// The built-in default profile matches everything else
+	writeMatch("default", defaultDSCP)
+	output.WriteString("!\n")
+
+	return []byte(output.String()), nil
+}
+
+// aristaTrafficClass returns the EOS default traffic class for a DSCP value
+func aristaTrafficClass(dscp int) int {
+	tc := dscp >> 3
+	// EOS maps CS0 to traffic class 1 and CS1 to traffic class 0
+	switch tc {
+	case 0:
+		return 1
+	case 1:
+		return 0
+	}
+	return tc
+}
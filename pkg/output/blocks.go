@@ -0,0 +1,95 @@
+package output
+
+import (
+	"strings"
+)
+
+// ConfigBlock is a set of configuration lines under a parent hierarchy, in the
+// form used by Ansible ios_config and similar line-based tools
+type ConfigBlock struct {
+	Parents []string `json:"parents,omitempty" yaml:"parents,omitempty"`
+	Lines   []string `json:"lines" yaml:"lines"`
+}
+
+// ParseConfigBlocks splits IOS-style configuration into blocks by indentation.
+// Comment lines are dropped and lines that only serve as parents of a later
+// block are not repeated as lines of their own.
+func ParseConfigBlocks(config string) []ConfigBlock {
+	type entry struct {
+		indent int
+		text   string
+	}
+
+	var blocks []ConfigBlock
+	var stack []entry
+
+	for _, line := range strings.Split(config, "\n") {
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "!") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		parents := make([]string, 0, len(stack))
+		for _, e := range stack {
+			parents = append(parents, e.text)
+		}
+
+		if len(blocks) == 0 || !equalStrings(blocks[len(blocks)-1].Parents, parents) {
+			blocks = append(blocks, ConfigBlock{Parents: parents})
+		}
+		last := &blocks[len(blocks)-1]
+		last.Lines = append(last.Lines, text)
+
+		stack = append(stack, entry{indent: indent, text: text})
+	}
+
+	return dropParentLines(blocks)
+}
+
+// dropParentLines removes lines that are the parent of another block
+func dropParentLines(blocks []ConfigBlock) []ConfigBlock {
+	paths := make(map[string]bool)
+	for _, block := range blocks {
+		for i := range block.Parents {
+			paths[strings.Join(block.Parents[:i+1], "\n")] = true
+		}
+	}
+
+	result := make([]ConfigBlock, 0, len(blocks))
+	for _, block := range blocks {
+		prefix := strings.Join(block.Parents, "\n")
+		if prefix != "" {
+			prefix += "\n"
+		}
+
+		lines := make([]string, 0, len(block.Lines))
+		for _, line := range block.Lines {
+			if !paths[prefix+line] {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			block.Lines = lines
+			result = append(result, block)
+		}
+	}
+	return result
+}
+
+// equalStrings compares two string slices
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
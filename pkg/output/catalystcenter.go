@@ -0,0 +1,57 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/catalystcenter"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// CatalystCenterDocument contains the application sets and the
+// app-policy-intent payload. Application sets are referenced by name in the
+// payload and resolved to IDs when pushed.
+type CatalystCenterDocument struct {
+	*catalystcenter.Policy
+	PolicyIntent *catalystcenter.PolicyIntent `json:"applicationPolicyIntent"`
+}
+
+// CatalystCenterGenerator renders the Catalyst Center Application Policy
+type CatalystCenterGenerator struct {
+	mapping map[qos.Class]catalystcenter.ClassMapping
+	scope   string
+}
+
+// NewCatalystCenterGenerator creates a Catalyst Center generator
+func NewCatalystCenterGenerator(mapping map[qos.Class]catalystcenter.ClassMapping, scope string) *CatalystCenterGenerator {
+	return &CatalystCenterGenerator{mapping: mapping, scope: scope}
+}
+
+// Name returns the generator name
+func (g *CatalystCenterGenerator) Name() string {
+	return "catalyst-center"
+}
+
+// Extension returns the default file extension
+func (g *CatalystCenterGenerator) Extension() string {
+	return ".catalyst-center.json"
+}
+
+// Policy builds the Application Policy for the result
+func (g *CatalystCenterGenerator) Policy(result *Result) (*catalystcenter.Policy, error) {
+	return catalystcenter.BuildPolicy(result.Classifications, result.Model, g.mapping, g.scope)
+}
+
+// Generate renders the Application Policy as JSON
+func (g *CatalystCenterGenerator) Generate(result *Result) ([]byte, error) {
+	policy, err := g.Policy(result)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(CatalystCenterDocument{Policy: policy, PolicyIntent: policy.Intent(nil)}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Catalyst Center output: %w", err)
+	}
+	return append(data, '\n'), nil
+}
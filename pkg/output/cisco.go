@@ -0,0 +1,63 @@
+package output
+
+import (
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+)
+
+// CiscoGenerator renders Cisco IOS-XE configuration using the template renderer
+type CiscoGenerator struct {
+	renderer *render.CiscoRenderer
+	queuing  *qos.QueuingPolicy
+}
+
+// NewCiscoGenerator creates a Cisco generator; queuing may be nil
+func NewCiscoGenerator(renderer *render.CiscoRenderer, queuing *qos.QueuingPolicy) *CiscoGenerator {
+	return &CiscoGenerator{renderer: renderer, queuing: queuing}
+}
+
+// Name returns the generator name
+func (g *CiscoGenerator) Name() string {
+	return "cisco"
+}
+
+// Extension returns the default file extension
+func (g *CiscoGenerator) Extension() string {
+	return ".txt"
+}
+
+// Generate renders the Cisco configuration
+func (g *CiscoGenerator) Generate(result *Result) ([]byte, error) {
+	config, err := g.DeviceConfig(result)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(config), nil
+}
+
+// DeviceConfig renders the Cisco configuration followed by any interface
+// service-policy attachments
+func (g *CiscoGenerator) DeviceConfig(result *Result) (string, error) {
+	config, err := g.renderer.Render(result.Classifications, result.Model, result.DefaultClass, g.queuing)
+	if err != nil {
+		return "", err
+	}
+
+	return WithAttachments(config, result.Attachments), nil
+}
+
+// attachmentsHeader starts the interface section of a Cisco configuration
+const attachmentsHeader = "! Interface service-policy attachments\n"
+
+// WithAttachments replaces the interface service-policy attachments at the
+// end of a Cisco configuration with the given changes
+func WithAttachments(config string, changes []interfaces.Change) string {
+	config, _, _ = strings.Cut(config, attachmentsHeader)
+	if len(changes) > 0 {
+		config += attachmentsHeader + interfaces.RenderChanges(changes)
+	}
+	return config
+}
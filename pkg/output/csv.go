@@ -0,0 +1,44 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+)
+
+// CSVGenerator renders one row per protocol classification
+type CSVGenerator struct{}
+
+// Name returns the generator name
+func (g *CSVGenerator) Name() string {
+	return "csv"
+}
+
+// Extension returns the default file extension
+func (g *CSVGenerator) Extension() string {
+	return ".csv"
+}
+
+// Generate renders the classification result as CSV with a header row
+func (g *CSVGenerator) Generate(result *Result) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	rows := [][]string{{"protocol", "class", "dscp", "confidence", "source", "timestamp"}}
+	for _, record := range NewDocument(result).Classifications {
+		rows = append(rows, []string{
+			record.Protocol,
+			string(record.Class),
+			record.DSCP,
+			strconv.FormatFloat(record.Confidence, 'f', -1, 64),
+			record.Source,
+			strconv.FormatInt(record.Timestamp, 10),
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("failed to write CSV output: %w", err)
+	}
+	return buf.Bytes(), nil
+}
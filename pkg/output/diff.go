@@ -0,0 +1,225 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around each hunk
+const diffContext = 3
+
+// maxEdits bounds the work spent on a diff; outputs that differ in more lines
+// are shown as replaced entirely
+const maxEdits = 2000
+
+// Diff is the line difference between two generated outputs
+type Diff struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	// Unified is the difference in unified format, empty when the outputs
+	// are equal
+	Unified string `json:"unified"`
+}
+
+// Changed reports whether the outputs differ
+func (d Diff) Changed() bool {
+	return d.Added > 0 || d.Removed > 0
+}
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Compare returns the unified diff from before to after, labelling the sides
+// with the given names
+func Compare(fromName, toName, before, after string) Diff {
+	ops := editScript(splitLines(before), splitLines(after))
+
+	var diff Diff
+	for _, op := range ops {
+		switch op.kind {
+		case '-':
+			diff.Removed++
+		case '+':
+			diff.Added++
+		}
+	}
+	if !diff.Changed() {
+		return diff
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	writeHunks(&b, ops)
+	diff.Unified = b.String()
+	return diff
+}
+
+// splitLines splits text into lines without their terminators
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// editScript computes a shortest edit script with Myers' algorithm
+func editScript(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace holds the frontier before each step, from diagonal -d-1 to d+1
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		if d > maxEdits {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d)
+			}
+		}
+	}
+	return nil
+}
+
+// replaceAll is the edit script that removes a and adds b
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// backtrack walks the saved frontiers from the end to build the edit script
+func backtrack(a, b []string, trace [][]int, depth int) []diffOp {
+	x, y := len(a), len(b)
+	var ops []diffOp
+	for d := depth; d > 0; d-- {
+		// The frontier before step d starts at diagonal -d-1
+		v, base := trace[d], d+1
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[base+k-1] < v[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[base+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, diffOp{'-', a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, diffOp{' ', a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// writeHunks writes the changed regions of an edit script with context
+func writeHunks(b *strings.Builder, ops []diffOp) {
+	// Line numbers of each op in the old and new text, 1-based
+	oldLine, newLine := make([]int, len(ops)), make([]int, len(ops))
+	x, y := 1, 1
+	for i, op := range ops {
+		oldLine[i], newLine[i] = x, y
+		if op.kind != '+' {
+			x++
+		}
+		if op.kind != '-' {
+			y++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within twice the context
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			} else if j-end > 2*diffContext {
+				break
+			}
+		}
+		end += diffContext + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		var oldCount, newCount int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+}
+
+// hunkRange formats the start and length of one side of a hunk
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty side names the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,115 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// juniperApplications maps NBAR names that differ from the Junos AppID name
+var juniperApplications = map[string]string{
+	"https":          "junos:SSL",
+	"secure-smtp":    "junos:SMTPS",
+	"ms-teams":       "junos:MS-TEAMS",
+	"ms-teams-media": "junos:MS-TEAMS",
+	"zoom-meetings":  "junos:ZOOM",
+	"web-rtc":        "junos:WEBRTC",
+	"vmware-vsphere": "junos:VMWARE-VSPHERE",
+}
+
+// JuniperGenerator renders a Junos AppQoS rule-set that marks traffic by
+// application identification and assigns forwarding classes
+type JuniperGenerator struct {
+	mapper appMapper
+}
+
+// NewJuniperGenerator creates a Junos generator
+func NewJuniperGenerator(opts VendorOptions) *JuniperGenerator {
+	if opts.PolicyName == "" {
+		opts.PolicyName = "NBAR-QOS"
+	}
+	return &JuniperGenerator{mapper: appMapper{builtin: juniperApplications, options: opts, derive: upperDerive("junos:")}}
+}
+
+// Name returns the generator name
+func (g *JuniperGenerator) Name() string {
+	return "juniper"
+}
+
+// Extension returns the default file extension
+func (g *JuniperGenerator) Extension() string {
+	return ".junos.set"
+}
+
+// Unmapped returns the protocols without a Junos application
+func (g *JuniperGenerator) Unmapped(result *Result) []string {
+	_, unmapped := g.mapper.group(result)
+	return unmapped
+}
+
+// Generate renders the rule-set as Junos set commands
+func (g *JuniperGenerator) Generate(result *Result) ([]byte, error) {
+	classes, unmapped := g.mapper.group(result)
+	prefix := "set class-of-service application-traffic-control rule-sets " + g.mapper.options.PolicyName
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("# Junos AppQoS rule-set generated on %s\n", result.GeneratedAt.Format("2006-01-02")))
+
+	var defaultClass *vendorClass
+	for i, class := range classes {
+		if class.Class == result.DefaultClass {
+			defaultClass = &classes[i]
+			continue
+		}
+		if len(class.Applications) == 0 {
+			continue
+		}
+		rule := fmt.Sprintf("%s rule %s", prefix, class.Class)
+		for _, app := range class.Applications {
+			output.WriteString(fmt.Sprintf("%s match application %s\n", rule, app))
+		}
+		output.WriteString(fmt.Sprintf("%s then forwarding-class %s\n", rule, juniperForwardingClass(class.DSCP)))
+		output.WriteString(fmt.Sprintf("%s then dscp-code-point %s\n", rule, juniperDSCP(class.DSCP)))
+	}
+
+	// Everything else falls into the default class, like class-default
+	if defaultClass != nil {
+		rule := fmt.Sprintf("%s rule %s", prefix, defaultClass.Class)
+		output.WriteString(fmt.Sprintf("%s match application-any\n", rule))
+		output.WriteString(fmt.Sprintf("%s then forwarding-class %s\n", rule, juniperForwardingClass(defaultClass.DSCP)))
+		output.WriteString(fmt.Sprintf("%s then dscp-code-point %s\n", rule, juniperDSCP(defaultClass.DSCP)))
+	}
+
+	if len(unmapped) > 0 {
+		output.WriteString(fmt.Sprintf("# Unmapped NBAR protocols: %s\n", strings.Join(unmapped, ", ")))
+	}
+
+	return []byte(output.String()), nil
+}
+
+// juniperForwardingClass picks the default Junos forwarding class for a DSCP
+func juniperForwardingClass(dscp string) string {
+	value := dscpValue(dscp)
+	switch {
+	case value == 46:
+		return "expedited-forwarding"
+	case value >= 48:
+		return "network-control"
+	case value == 0 || value == 8:
+		return "best-effort"
+	default:
+		return "assured-forwarding"
+	}
+}
+
+// juniperDSCP converts a DSCP name to the Junos code point alias
+func juniperDSCP(dscp string) string {
+	value := dscpValue(dscp)
+	if value == 0 {
+		return "be"
+	}
+	if _, err := strconv.Atoi(dscp); err == nil {
+		return fmt.Sprintf("%06b", value)
+	}
+	return strings.ToLower(dscp)
+}
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MerakiRules is the request body of the Meraki appliance traffic shaping
+// rules API (PUT /networks/{networkId}/appliance/trafficShaping/rules)
+type MerakiRules struct {
+	DefaultRulesEnabled bool         `json:"defaultRulesEnabled"`
+	Rules               []MerakiRule `json:"rules"`
+}
+
+// MerakiRule is a single traffic shaping rule
+type MerakiRule struct {
+	Definitions              []MerakiDefinition      `json:"definitions"`
+	PerClientBandwidthLimits MerakiBandwidthSettings `json:"perClientBandwidthLimits"`
+	DSCPTagValue             int                     `json:"dscpTagValue"`
+	Priority                 string                  `json:"priority"`
+}
+
+// MerakiDefinition matches traffic by application
+type MerakiDefinition struct {
+	Type  string               `json:"type"`
+	Value MerakiApplicationRef `json:"value"`
+}
+
+// MerakiApplicationRef references a Meraki layer 7 application
+type MerakiApplicationRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// MerakiBandwidthSettings selects the per-client bandwidth limit settings
+type MerakiBandwidthSettings struct {
+	Settings string `json:"settings"`
+}
+
+// MerakiGenerator renders Meraki traffic shaping rules. Meraki application IDs
+// are dashboard-specific, so only explicitly mapped protocols are included.
+type MerakiGenerator struct {
+	mapper appMapper
+}
+
+// NewMerakiGenerator creates a Meraki generator
+func NewMerakiGenerator(opts VendorOptions) *MerakiGenerator {
+	return &MerakiGenerator{mapper: appMapper{options: opts}}
+}
+
+// Name returns the generator name
+func (g *MerakiGenerator) Name() string {
+	return "meraki"
+}
+
+// Extension returns the default file extension
+func (g *MerakiGenerator) Extension() string {
+	return ".meraki.json"
+}
+
+// Unmapped returns the protocols without a Meraki application ID
+func (g *MerakiGenerator) Unmapped(result *Result) []string {
+	_, unmapped := g.mapper.group(result)
+	return unmapped
+}
+
+// Generate renders the traffic shaping rules as JSON, one rule per class
+func (g *MerakiGenerator) Generate(result *Result) ([]byte, error) {
+	classes, _ := g.mapper.group(result)
+
+	names := make(map[string]string)
+	for _, protocol := range result.Protocols() {
+		if id, ok := g.mapper.lookup(protocol); ok {
+			if _, exists := names[id]; !exists {
+				names[id] = protocol
+			}
+		}
+	}
+
+	rules := MerakiRules{DefaultRulesEnabled: true, Rules: make([]MerakiRule, 0)}
+	for _, class := range classes {
+		if len(class.Applications) == 0 {
+			continue
+		}
+		rule := MerakiRule{
+			PerClientBandwidthLimits: MerakiBandwidthSettings{Settings: "network default"},
+			DSCPTagValue:             dscpValue(class.DSCP),
+			Priority:                 merakiPriority(dscpValue(class.DSCP)),
+		}
+		for _, id := range class.Applications {
+			rule.Definitions = append(rule.Definitions, MerakiDefinition{
+				Type:  "application",
+				Value: MerakiApplicationRef{ID: id, Name: names[id]},
+			})
+		}
+		rules.Rules = append(rules.Rules, rule)
+	}
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Meraki output: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// merakiPriority maps a DSCP value to a Meraki rule priority
+func merakiPriority(dscp int) string {
+	switch {
+	case dscp >= 32:
+		return "high"
+	case dscp == 8:
+		return "low"
+	default:
+		return "normal"
+	}
+}
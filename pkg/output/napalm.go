@@ -0,0 +1,50 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NAPALMGenerator renders the device configuration as a NAPALM merge
+// candidate for load_merge_candidate
+type NAPALMGenerator struct {
+	device DeviceConfigGenerator
+}
+
+// NewNAPALMGenerator creates a NAPALM candidate configuration generator
+func NewNAPALMGenerator(device DeviceConfigGenerator) *NAPALMGenerator {
+	return &NAPALMGenerator{device: device}
+}
+
+// Name returns the generator name
+func (g *NAPALMGenerator) Name() string {
+	return "napalm"
+}
+
+// Extension returns the default file extension
+func (g *NAPALMGenerator) Extension() string {
+	return ".cfg"
+}
+
+// DeviceConfig returns the underlying device configuration
+func (g *NAPALMGenerator) DeviceConfig(result *Result) (string, error) {
+	return g.device.DeviceConfig(result)
+}
+
+// Generate renders the candidate configuration terminated with "end"
+func (g *NAPALMGenerator) Generate(result *Result) ([]byte, error) {
+	config, err := g.device.DeviceConfig(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("! NAPALM merge candidate generated on %s\n", result.GeneratedAt.Format("2006-01-02")))
+	output.WriteString(config)
+	if !strings.HasSuffix(config, "\n") {
+		output.WriteString("\n")
+	}
+	output.WriteString("end\n")
+
+	return []byte(output.String()), nil
+}
@@ -0,0 +1,181 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// Stdout is the destination name that writes output to standard output
+const Stdout = "-"
+
+// DefaultBaseName is the file name used when no output file is given
+const DefaultBaseName = "nbar-protocols-qos"
+
+// Result is the classification result passed to output generators
+type Result struct {
+	Classifications map[string]qos.Classification
+	Model           *qos.Model
+	DefaultClass    qos.Class
+	Attachments     []interfaces.Change
+	GeneratedAt     time.Time
+}
+
+// Protocols returns the classified protocol names in sorted order
+func (r *Result) Protocols() []string {
+	protocols := make([]string, 0, len(r.Classifications))
+	for protocol := range r.Classifications {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// Generator renders a classification result in a specific format
+type Generator interface {
+	Name() string
+	Extension() string
+	Generate(result *Result) ([]byte, error)
+}
+
+// DeviceConfigGenerator is a generator whose output is derived from the
+// Cisco device configuration
+type DeviceConfigGenerator interface {
+	Generator
+	DeviceConfig(result *Result) (string, error)
+}
+
+// Registry holds the available output generators by name
+type Registry struct {
+	mu         sync.RWMutex
+	generators map[string]Generator
+}
+
+// NewRegistry creates an empty generator registry
+func NewRegistry() *Registry {
+	return &Registry{generators: make(map[string]Generator)}
+}
+
+// NewDefaultRegistry creates a registry with the built-in text, JSON, YAML and
+// CSV generators
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, generator := range []Generator{&TextGenerator{}, &JSONGenerator{}, &YAMLGenerator{}, &CSVGenerator{}} {
+		// Built-in names are unique, registration cannot fail
+		_ = r.Register(generator)
+	}
+	return r
+}
+
+// Register adds a generator to the registry
+func (r *Registry) Register(generator Generator) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := generator.Name()
+	if name == "" {
+		return fmt.Errorf("generator name cannot be empty")
+	}
+	if _, exists := r.generators[name]; exists {
+		return fmt.Errorf("output generator %s is already registered", name)
+	}
+	r.generators[name] = generator
+	return nil
+}
+
+// Get returns the generator with the given name
+func (r *Registry) Get(name string) (Generator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	generator, exists := r.generators[name]
+	if !exists {
+		return nil, fmt.Errorf("unsupported output format %s (available: %s)", name, strings.Join(r.namesLocked(), ", "))
+	}
+	return generator, nil
+}
+
+// Names returns the names of all registered generators
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.namesLocked()
+}
+
+// namesLocked returns the sorted generator names; the caller holds the lock
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.generators))
+	for name := range r.generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Target is an output format and the destination it is written to
+type Target struct {
+	Format string
+	Path   string
+}
+
+// Targets pairs the requested formats with output files by position. Formats
+// without a file are written to DefaultBaseName plus the format's extension.
+func (r *Registry) Targets(formats, files []string) ([]Target, error) {
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("at least one output format is required")
+	}
+	if len(files) > len(formats) {
+		return nil, fmt.Errorf("%d output files given for %d output formats", len(files), len(formats))
+	}
+
+	targets := make([]Target, 0, len(formats))
+	used := make(map[string]string)
+	for i, format := range formats {
+		generator, err := r.Get(format)
+		if err != nil {
+			return nil, err
+		}
+
+		target := Target{Format: format, Path: DefaultBaseName + generator.Extension()}
+		if i < len(files) && files[i] != "" {
+			target.Path = files[i]
+		}
+
+		if target.Path != Stdout {
+			if other, exists := used[target.Path]; exists {
+				return nil, fmt.Errorf("outputs %s and %s both write to %s, use --output-file to separate them", other, format, target.Path)
+			}
+			used[target.Path] = format
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// SplitList splits a comma-separated flag value into trimmed, non-empty items
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Write writes data to the target path, or to stdout for Stdout
+func Write(path string, data []byte, stdout io.Writer) error {
+	if path == Stdout {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,134 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"gopkg.in/yaml.v3"
+)
+
+// Document is the structured representation of a classification result
+type Document struct {
+	GeneratedAt     time.Time           `json:"generated_at" yaml:"generated_at"`
+	DefaultClass    qos.Class           `json:"default_class" yaml:"default_class"`
+	Classes         []ClassRecord       `json:"classes" yaml:"classes"`
+	Classifications []Record            `json:"classifications" yaml:"classifications"`
+	Attachments     []interfaces.Change `json:"attachments,omitempty" yaml:"attachments,omitempty"`
+}
+
+// ClassRecord describes a QoS class of the model
+type ClassRecord struct {
+	Name        qos.Class `json:"name" yaml:"name"`
+	DisplayName string    `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty"`
+	DSCP        string    `json:"dscp" yaml:"dscp"`
+	Priority    int       `json:"priority" yaml:"priority"`
+}
+
+// Record is a single protocol classification
+type Record struct {
+	Protocol   string    `json:"protocol" yaml:"protocol"`
+	Class      qos.Class `json:"class" yaml:"class"`
+	DSCP       string    `json:"dscp" yaml:"dscp"`
+	Confidence float64   `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+	Source     string    `json:"source,omitempty" yaml:"source,omitempty"`
+	Timestamp  int64     `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+}
+
+// NewDocument builds the structured document for a result, with
+// classifications sorted by protocol name
+func NewDocument(result *Result) *Document {
+	doc := &Document{
+		GeneratedAt:     result.GeneratedAt,
+		DefaultClass:    result.DefaultClass,
+		Classes:         make([]ClassRecord, 0),
+		Classifications: make([]Record, 0, len(result.Classifications)),
+		Attachments:     result.Attachments,
+	}
+
+	dscp := make(map[qos.Class]string)
+	for _, def := range result.Model.Definitions() {
+		doc.Classes = append(doc.Classes, ClassRecord{
+			Name:        def.Name,
+			DisplayName: def.DisplayName,
+			Description: def.Description,
+			DSCP:        def.DSCP,
+			Priority:    def.Priority,
+		})
+		dscp[def.Name] = def.DSCP
+	}
+
+	for _, protocol := range result.Protocols() {
+		classification := result.Classifications[protocol]
+		doc.Classifications = append(doc.Classifications, Record{
+			Protocol:   protocol,
+			Class:      classification.Class,
+			DSCP:       dscp[classification.Class],
+			Confidence: classification.Confidence,
+			Source:     classification.Source,
+			Timestamp:  classification.Timestamp,
+		})
+	}
+
+	return doc
+}
+
+// JSONGenerator renders the result as an indented JSON document
+type JSONGenerator struct{}
+
+// Name returns the generator name
+func (g *JSONGenerator) Name() string {
+	return "json"
+}
+
+// Extension returns the default file extension
+func (g *JSONGenerator) Extension() string {
+	return ".json"
+}
+
+// Generate renders the classification result as JSON
+func (g *JSONGenerator) Generate(result *Result) ([]byte, error) {
+	data, err := json.MarshalIndent(NewDocument(result), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON output: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// YAMLGenerator renders the result as a YAML document
+type YAMLGenerator struct{}
+
+// Name returns the generator name
+func (g *YAMLGenerator) Name() string {
+	return "yaml"
+}
+
+// Extension returns the default file extension
+func (g *YAMLGenerator) Extension() string {
+	return ".yaml"
+}
+
+// Generate renders the classification result as YAML
+func (g *YAMLGenerator) Generate(result *Result) ([]byte, error) {
+	return marshalYAML(NewDocument(result), "")
+}
+
+// marshalYAML encodes a value as YAML with two-space indentation after a header
+func marshalYAML(value interface{}, header string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML output: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML output: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,60 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// TextGenerator renders a human-readable protocol list grouped by class
+type TextGenerator struct{}
+
+// Name returns the generator name
+func (g *TextGenerator) Name() string {
+	return "text"
+}
+
+// Extension returns the default file extension
+func (g *TextGenerator) Extension() string {
+	return ".txt"
+}
+
+// Generate renders the classification result as text
+func (g *TextGenerator) Generate(result *Result) ([]byte, error) {
+	grouped := make(map[qos.Class][]string)
+	for _, protocol := range result.Protocols() {
+		class := result.Classifications[protocol].Class
+		grouped[class] = append(grouped[class], protocol)
+	}
+
+	var output strings.Builder
+
+	output.WriteString("# NBAR Protocols Classified by QoS\n")
+	output.WriteString(fmt.Sprintf("# Generated on %s using AI\n", result.GeneratedAt.Format("2006-01-02")))
+	output.WriteString("# For use with Cisco 9300 Switch\n\n")
+
+	// Write each QoS class section
+	for _, class := range result.Model.MarkingClasses() {
+		protocols := grouped[class]
+		if len(protocols) == 0 {
+			continue
+		}
+
+		output.WriteString(fmt.Sprintf("## %s - %s\n", class, classDescription(result.Model, class)))
+		for i, protocol := range protocols {
+			output.WriteString(fmt.Sprintf("%d. %s\n", i+1, protocol))
+		}
+		output.WriteString("\n")
+	}
+
+	return []byte(output.String()), nil
+}
+
+// classDescription describes a class using the result's model
+func classDescription(model *qos.Model, class qos.Class) string {
+	if def, exists := model.Lookup(class); exists {
+		return def.FullDescription()
+	}
+	return class.Description()
+}
@@ -0,0 +1,103 @@
+package output
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// VendorOptions contains the settings shared by the multi-vendor generators
+type VendorOptions struct {
+	// PolicyName is the rule-set or policy name on the device
+	PolicyName string
+	// Applications maps NBAR protocol names to vendor application IDs and
+	// overrides the built-in mapping
+	Applications map[string]string
+	// Strict disables deriving application IDs for unmapped protocols
+	Strict bool
+}
+
+// UnmappedReporter is implemented by generators that skip protocols without
+// a vendor application ID or whose vendor match would be ambiguous
+type UnmappedReporter interface {
+	Unmapped(result *Result) []string
+}
+
+// appMapper resolves NBAR protocol names to vendor application IDs
+type appMapper struct {
+	builtin map[string]string
+	options VendorOptions
+	derive  func(protocol string) string
+}
+
+// lookup returns the vendor application ID for an NBAR protocol
+func (m appMapper) lookup(protocol string) (string, bool) {
+	if id, exists := m.options.Applications[protocol]; exists {
+		return id, id != ""
+	}
+	if id, exists := m.builtin[protocol]; exists {
+		return id, true
+	}
+	if m.derive == nil || m.options.Strict {
+		return "", false
+	}
+	return m.derive(protocol), true
+}
+
+// vendorClass is a QoS class with the vendor application IDs classified into it
+type vendorClass struct {
+	Class        qos.Class
+	DSCP         string
+	Applications []string
+}
+
+// group resolves the classified protocols to vendor application IDs, grouped
+// by class in model order, and returns the protocols without an ID
+func (m appMapper) group(result *Result) ([]vendorClass, []string) {
+	byClass := make(map[qos.Class][]string)
+	seen := make(map[string]bool)
+	var unmapped []string
+
+	for _, protocol := range result.Protocols() {
+		id, ok := m.lookup(protocol)
+		if !ok {
+			unmapped = append(unmapped, protocol)
+			continue
+		}
+		class := result.Classifications[protocol].Class
+		// Several NBAR names can map to the same vendor application
+		if key := string(class) + "\x00" + id; !seen[key] {
+			seen[key] = true
+			byClass[class] = append(byClass[class], id)
+		}
+	}
+
+	var classes []vendorClass
+	for _, def := range result.Model.Definitions() {
+		if def.Name == qos.Other {
+			continue
+		}
+		apps := byClass[def.Name]
+		sort.Strings(apps)
+		classes = append(classes, vendorClass{Class: def.Name, DSCP: def.DSCP, Applications: apps})
+	}
+
+	return classes, unmapped
+}
+
+// dscpValue returns the numeric DSCP value, treating invalid values as 0
+func dscpValue(dscp string) int {
+	value, err := qos.ParseDSCP(dscp)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// upperDerive derives an application ID by upper-casing the NBAR name
+func upperDerive(prefix string) func(string) string {
+	return func(protocol string) string {
+		return prefix + strings.ToUpper(protocol)
+	}
+}
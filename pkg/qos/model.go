@@ -0,0 +1,226 @@
+package qos
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClassDefinition describes a single QoS class in a class model
+type ClassDefinition struct {
+	Name        Class  `yaml:"name" json:"name"`
+	DisplayName string `yaml:"display_name" json:"display_name,omitempty"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	DSCP        string `yaml:"dscp" json:"dscp"`
+	Priority    int    `yaml:"priority" json:"priority"`
+}
+
+// FullDescription returns the display name and description joined together
+func (d ClassDefinition) FullDescription() string {
+	switch {
+	case d.DisplayName != "" && d.Description != "":
+		return d.DisplayName + " - " + d.Description
+	case d.DisplayName != "":
+		return d.DisplayName
+	default:
+		return d.Description
+	}
+}
+
+// Model is a validated set of QoS class definitions
+type Model struct {
+	classes      map[Class]ClassDefinition
+	order        []Class
+	defaultClass Class
+}
+
+var classNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// dscpNames maps the symbolic DSCP names accepted by IOS-XE to their values
+var dscpNames = map[string]int{
+	"default": 0,
+	"cs0":     0, "cs1": 8, "cs2": 16, "cs3": 24, "cs4": 32, "cs5": 40, "cs6": 48, "cs7": 56,
+	"af11": 10, "af12": 12, "af13": 14,
+	"af21": 18, "af22": 20, "af23": 22,
+	"af31": 26, "af32": 28, "af33": 30,
+	"af41": 34, "af42": 36, "af43": 38,
+	"ef": 46,
+}
+
+// ParseDSCP returns the numeric value of a symbolic or numeric DSCP marking
+func ParseDSCP(dscp string) (int, error) {
+	dscp = strings.ToLower(strings.TrimSpace(dscp))
+	if value, exists := dscpNames[dscp]; exists {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(dscp)
+	if err != nil || value < 0 || value > 63 {
+		return 0, fmt.Errorf("invalid DSCP value: %q", dscp)
+	}
+	return value, nil
+}
+
+// DefaultClassDefinitions returns the built-in four-class model
+func DefaultClassDefinitions() []ClassDefinition {
+	return []ClassDefinition{
+		{Name: EF, DisplayName: "Expedited Forwarding", Description: "Real-time traffic (Voice, Video calls)", DSCP: "ef", Priority: 1},
+		{Name: AF41, DisplayName: "Assured Forwarding 41", Description: "Business-critical applications", DSCP: "af41", Priority: 2},
+		{Name: AF21, DisplayName: "Assured Forwarding 21", Description: "Important data applications", DSCP: "af21", Priority: 3},
+		{Name: CS1, DisplayName: "Class Selector 1", Description: "Background traffic", DSCP: "cs1", Priority: 4},
+	}
+}
+
+// NewModel validates the given class definitions and builds a class model.
+// The Other class is always part of the model; if it is not defined explicitly
+// it is added with the lowest priority and a cs0 marking. Each class needs a
+// priority of its own so the queue order is unambiguous.
+func NewModel(definitions []ClassDefinition, defaultClass Class) (*Model, error) {
+	if len(definitions) == 0 {
+		return nil, fmt.Errorf("class model must define at least one class")
+	}
+
+	m := &Model{
+		classes: make(map[Class]ClassDefinition),
+	}
+
+	priorities := make(map[int]Class)
+	lowestPriority := 0
+	for _, def := range definitions {
+		if def.Name == "" {
+			return nil, fmt.Errorf("class name cannot be empty")
+		}
+		if !classNamePattern.MatchString(string(def.Name)) {
+			return nil, fmt.Errorf("invalid class name: %s", def.Name)
+		}
+		if _, exists := m.classes[def.Name]; exists {
+			return nil, fmt.Errorf("duplicate class: %s", def.Name)
+		}
+		if def.Priority < 1 {
+			return nil, fmt.Errorf("class %s: priority must be positive", def.Name)
+		}
+		if other, exists := priorities[def.Priority]; exists {
+			return nil, fmt.Errorf("class %s: priority %d is already used by class %s", def.Name, def.Priority, other)
+		}
+		priorities[def.Priority] = def.Name
+		if _, err := ParseDSCP(def.DSCP); err != nil {
+			return nil, fmt.Errorf("class %s: %w", def.Name, err)
+		}
+
+		def.DSCP = strings.ToLower(def.DSCP)
+		m.classes[def.Name] = def
+		m.order = append(m.order, def.Name)
+
+		if def.Priority > lowestPriority {
+			lowestPriority = def.Priority
+		}
+	}
+
+	if _, exists := m.classes[Other]; !exists {
+		m.classes[Other] = ClassDefinition{
+			Name:        Other,
+			Description: "Unclassified traffic",
+			DSCP:        "cs0",
+			Priority:    lowestPriority + 1,
+		}
+		m.order = append(m.order, Other)
+	}
+
+	sort.SliceStable(m.order, func(i, j int) bool {
+		pi, pj := m.classes[m.order[i]].Priority, m.classes[m.order[j]].Priority
+		if pi != pj {
+			return pi < pj
+		}
+		return m.order[i] < m.order[j]
+	})
+
+	if defaultClass == "" {
+		defaultClass = m.order[len(m.order)-1]
+	}
+	if _, exists := m.classes[defaultClass]; !exists {
+		return nil, fmt.Errorf("default class %s is not defined in the class model", defaultClass)
+	}
+	m.defaultClass = defaultClass
+
+	return m, nil
+}
+
+// DefaultModel returns the built-in four-class model with CS1 as default class
+func DefaultModel() *Model {
+	m, err := NewModel(DefaultClassDefinitions(), CS1)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in class model: %v", err))
+	}
+	return m
+}
+
+// Classes returns all classes in the model ordered by priority
+func (m *Model) Classes() []Class {
+	result := make([]Class, len(m.order))
+	copy(result, m.order)
+	return result
+}
+
+// MarkingClasses returns all classes ordered by priority, excluding Other
+func (m *Model) MarkingClasses() []Class {
+	result := make([]Class, 0, len(m.order))
+	for _, class := range m.order {
+		if class != Other {
+			result = append(result, class)
+		}
+	}
+	return result
+}
+
+// Definitions returns all class definitions ordered by priority
+func (m *Model) Definitions() []ClassDefinition {
+	result := make([]ClassDefinition, 0, len(m.order))
+	for _, class := range m.order {
+		result = append(result, m.classes[class])
+	}
+	return result
+}
+
+// Lookup returns the definition of a class
+func (m *Model) Lookup(class Class) (ClassDefinition, bool) {
+	def, exists := m.classes[class]
+	return def, exists
+}
+
+// Contains checks if the class is part of the model
+func (m *Model) Contains(class Class) bool {
+	_, exists := m.classes[class]
+	return exists
+}
+
+// DefaultClass returns the class used for unclassified protocols
+func (m *Model) DefaultClass() Class {
+	return m.defaultClass
+}
+
+// lowestPriority returns the numerically largest priority in the model
+func (m *Model) lowestPriority() int {
+	return m.classes[m.order[len(m.order)-1]].Priority
+}
+
+var (
+	currentModel = DefaultModel()
+	modelMutex   sync.RWMutex
+)
+
+// SetModel replaces the active class model used by Class methods
+func SetModel(m *Model) {
+	modelMutex.Lock()
+	defer modelMutex.Unlock()
+	currentModel = m
+}
+
+// CurrentModel returns the active class model
+func CurrentModel() *Model {
+	modelMutex.RLock()
+	defer modelMutex.RUnlock()
+	return currentModel
+}
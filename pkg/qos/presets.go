@@ -0,0 +1,145 @@
+package qos
+
+import (
+	"sort"
+)
+
+// Preset is a reference QoS class model with default protocol seed lists
+type Preset struct {
+	Name         string
+	Description  string
+	Classes      []ClassDefinition
+	DefaultClass Class
+	Protocols    map[Class][]string
+}
+
+// Preset names
+const (
+	Preset4Class  = "4-class"
+	Preset8Class  = "cisco-8-class"
+	Preset12Class = "rfc4594-12-class"
+)
+
+// DefaultPreset is used when no preset and no classes are configured
+const DefaultPreset = Preset4Class
+
+// Additional classes used by the 8-class and 12-class models
+const (
+	CS6  Class = "CS6"  // Network control
+	CS5  Class = "CS5"  // Broadcast video
+	CS4  Class = "CS4"  // Real-time interactive
+	AF31 Class = "AF31" // Multimedia streaming
+	CS3  Class = "CS3"  // Call signaling
+	CS2  Class = "CS2"  // Network management (OAM)
+	AF11 Class = "AF11" // Bulk data
+	DF   Class = "DF"   // Default forwarding (best effort)
+)
+
+var presets = map[string]Preset{
+	Preset4Class: {
+		Name:         Preset4Class,
+		Description:  "Four-class model (EF, AF41, AF21, CS1)",
+		Classes:      DefaultClassDefinitions(),
+		DefaultClass: CS1,
+		Protocols: map[Class][]string{
+			EF: {
+				"rtp", "rtp-audio", "rtp-video", "rtcp", "sip", "facetime", "wifi-calling",
+				"web-rtc", "web-rtc-audio", "ms-teams", "ms-teams-media",
+			},
+			AF41: {"zoom-meetings", "skype", "discord", "vmware-vsphere", "youtube", "netflix"},
+			AF21: {"smtp", "secure-smtp", "tftp", "http", "http-alt", "https", "quic", "ssl"},
+		},
+	},
+	Preset8Class: {
+		Name:        Preset8Class,
+		Description: "Cisco eight-class model",
+		Classes: []ClassDefinition{
+			{Name: CS6, DisplayName: "Network Control", Description: "Routing and network control protocols", DSCP: "cs6", Priority: 1},
+			{Name: EF, DisplayName: "Voice", Description: "Real-time voice traffic", DSCP: "ef", Priority: 2},
+			{Name: AF41, DisplayName: "Interactive Video", Description: "Video conferencing", DSCP: "af41", Priority: 3},
+			{Name: AF31, DisplayName: "Streaming Video", Description: "Buffered video streaming", DSCP: "af31", Priority: 4},
+			{Name: CS3, DisplayName: "Signaling", Description: "Call signaling", DSCP: "cs3", Priority: 5},
+			{Name: AF21, DisplayName: "Transactional Data", Description: "Interactive business applications", DSCP: "af21", Priority: 6},
+			{Name: DF, DisplayName: "Best Effort", Description: "Default traffic", DSCP: "default", Priority: 7},
+			{Name: CS1, DisplayName: "Scavenger", Description: "Non-business and background traffic", DSCP: "cs1", Priority: 8},
+		},
+		DefaultClass: DF,
+		Protocols: map[Class][]string{
+			CS6:  {"bgp", "ospf", "eigrp", "rip", "hsrp", "vrrp"},
+			EF:   {"rtp-audio", "webex-audio", "ms-teams-audio", "wifi-calling", "facetime"},
+			AF41: {"rtp-video", "webex-video", "ms-teams-video", "zoom-meetings", "telepresence-media"},
+			AF31: {"youtube", "netflix", "hulu", "rtsp"},
+			CS3:  {"sip", "sip-tls", "skinny", "h323", "mgcp", "rtcp"},
+			AF21: {"citrix", "ms-office-365", "salesforce", "sqlnet", "ssh", "snmp"},
+			CS1:  {"bittorrent", "edonkey", "gnutella"},
+		},
+	},
+	Preset12Class: {
+		Name:        Preset12Class,
+		Description: "RFC 4594 twelve-class model",
+		Classes: []ClassDefinition{
+			{Name: CS6, DisplayName: "Network Control", Description: "Routing and network control protocols", DSCP: "cs6", Priority: 1},
+			{Name: EF, DisplayName: "VoIP Telephony", Description: "Real-time voice traffic", DSCP: "ef", Priority: 2},
+			{Name: CS5, DisplayName: "Broadcast Video", Description: "Broadcast TV and video surveillance", DSCP: "cs5", Priority: 3},
+			{Name: AF41, DisplayName: "Multimedia Conferencing", Description: "Desktop video conferencing", DSCP: "af41", Priority: 4},
+			{Name: CS4, DisplayName: "Real-Time Interactive", Description: "Telepresence and interactive gaming", DSCP: "cs4", Priority: 5},
+			{Name: AF31, DisplayName: "Multimedia Streaming", Description: "Buffered video and audio streaming", DSCP: "af31", Priority: 6},
+			{Name: CS3, DisplayName: "Call Signaling", Description: "Voice and video signaling", DSCP: "cs3", Priority: 7},
+			{Name: AF21, DisplayName: "Low-Latency Data", Description: "Transactional business applications", DSCP: "af21", Priority: 8},
+			{Name: CS2, DisplayName: "OAM", Description: "Network operations, administration and management", DSCP: "cs2", Priority: 9},
+			{Name: AF11, DisplayName: "High-Throughput Data", Description: "Bulk data transfers", DSCP: "af11", Priority: 10},
+			{Name: DF, DisplayName: "Best Effort", Description: "Default traffic", DSCP: "default", Priority: 11},
+			{Name: CS1, DisplayName: "Low-Priority Data", Description: "Scavenger traffic", DSCP: "cs1", Priority: 12},
+		},
+		DefaultClass: DF,
+		Protocols: map[Class][]string{
+			CS6:  {"bgp", "ospf", "eigrp", "rip", "hsrp", "vrrp"},
+			EF:   {"rtp-audio", "webex-audio", "ms-teams-audio", "wifi-calling", "facetime"},
+			CS5:  {"cisco-ip-camera"},
+			AF41: {"rtp-video", "webex-video", "ms-teams-video", "zoom-meetings"},
+			CS4:  {"telepresence-media"},
+			AF31: {"youtube", "netflix", "hulu", "rtsp"},
+			CS3:  {"sip", "sip-tls", "skinny", "h323", "mgcp", "rtcp"},
+			AF21: {"citrix", "ms-office-365", "salesforce", "sqlnet"},
+			CS2:  {"ssh", "snmp", "syslog", "telnet", "tacacs", "radius"},
+			AF11: {"ftp", "ftp-data", "smtp", "imap", "pop3", "cifs"},
+			CS1:  {"bittorrent", "edonkey", "gnutella"},
+		},
+	},
+}
+
+// LookupPreset returns the preset with the given name
+func LookupPreset(name string) (Preset, bool) {
+	preset, exists := presets[name]
+	if !exists {
+		return Preset{}, false
+	}
+
+	// Return a copy so callers cannot modify the built-in presets
+	classes := make([]ClassDefinition, len(preset.Classes))
+	copy(classes, preset.Classes)
+	preset.Classes = classes
+
+	protocols := make(map[Class][]string, len(preset.Protocols))
+	for class, list := range preset.Protocols {
+		protocols[class] = append([]string(nil), list...)
+	}
+	preset.Protocols = protocols
+
+	return preset, true
+}
+
+// PresetNames returns the names of all available presets
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Model builds the class model defined by the preset
+func (p Preset) Model() (*Model, error) {
+	return NewModel(p.Classes, p.DefaultClass)
+}
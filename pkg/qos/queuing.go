@@ -0,0 +1,135 @@
+package qos
+
+import (
+	"fmt"
+)
+
+// Catalyst 9300 egress queue architecture limits
+const (
+	MaxEgressQueues   = 8 // 2P6Q3T: up to eight queues including class-default
+	MaxPriorityLevels = 2 // priority level 1 and 2
+)
+
+// QueueDefinition describes the egress queuing treatment of a QoS class
+type QueueDefinition struct {
+	Class                     Class `yaml:"class" json:"class"`
+	PriorityLevel             int   `yaml:"priority_level" json:"priority_level,omitempty"`
+	PolicePercent             int   `yaml:"police_percent" json:"police_percent,omitempty"`
+	BandwidthRemainingPercent int   `yaml:"bandwidth_remaining_percent" json:"bandwidth_remaining_percent,omitempty"`
+	QueueBuffersRatio         int   `yaml:"queue_buffers_ratio" json:"queue_buffers_ratio,omitempty"`
+}
+
+// IsPriority checks if the queue is a strict priority queue
+func (q QueueDefinition) IsPriority() bool {
+	return q.PriorityLevel > 0
+}
+
+// QueuingPolicy is a validated egress queuing policy
+type QueuingPolicy struct {
+	Name    string
+	Queues  []QueueDefinition
+	Default QueueDefinition
+}
+
+// NewQueuingPolicy validates the queue definitions against the class model and
+// the Catalyst 9300 queue architecture. The queue defined for the model's
+// default class configures class-default.
+func NewQueuingPolicy(name string, queues []QueueDefinition, model *Model) (*QueuingPolicy, error) {
+	if name == "" {
+		return nil, fmt.Errorf("queuing policy name cannot be empty")
+	}
+
+	policy := &QueuingPolicy{
+		Name:    name,
+		Default: QueueDefinition{Class: model.DefaultClass()},
+	}
+
+	byClass := make(map[Class]QueueDefinition)
+	for _, queue := range queues {
+		if !model.Contains(queue.Class) {
+			return nil, fmt.Errorf("queue references unknown QoS class %s", queue.Class)
+		}
+		if _, exists := byClass[queue.Class]; exists {
+			return nil, fmt.Errorf("duplicate queue for class %s", queue.Class)
+		}
+		if err := validateQueue(queue); err != nil {
+			return nil, err
+		}
+		byClass[queue.Class] = queue
+	}
+
+	if queue, exists := byClass[model.DefaultClass()]; exists {
+		if queue.IsPriority() {
+			return nil, fmt.Errorf("default class %s cannot be a priority queue", queue.Class)
+		}
+		policy.Default = queue
+	}
+
+	// Order queues by class priority
+	for _, class := range model.Classes() {
+		if queue, exists := byClass[class]; exists && class != model.DefaultClass() {
+			policy.Queues = append(policy.Queues, queue)
+		}
+	}
+
+	if len(policy.Queues)+1 > MaxEgressQueues {
+		return nil, fmt.Errorf("queuing policy uses %d queues, maximum is %d including class-default", len(policy.Queues)+1, MaxEgressQueues)
+	}
+
+	levels := make(map[int]Class)
+	bandwidth := policy.Default.BandwidthRemainingPercent
+	buffers := policy.Default.QueueBuffersRatio
+	for _, queue := range policy.Queues {
+		if queue.IsPriority() {
+			if other, exists := levels[queue.PriorityLevel]; exists {
+				return nil, fmt.Errorf("classes %s and %s share priority level %d", other, queue.Class, queue.PriorityLevel)
+			}
+			levels[queue.PriorityLevel] = queue.Class
+		} else if queue.BandwidthRemainingPercent == 0 {
+			return nil, fmt.Errorf("class %s: bandwidth remaining percent is required for non-priority queues", queue.Class)
+		}
+		bandwidth += queue.BandwidthRemainingPercent
+		buffers += queue.QueueBuffersRatio
+	}
+
+	if bandwidth != 100 {
+		return nil, fmt.Errorf("bandwidth remaining percentages must sum to 100, got %d", bandwidth)
+	}
+	if buffers > 100 {
+		return nil, fmt.Errorf("queue-buffers ratios must not exceed 100 in total, got %d", buffers)
+	}
+
+	return policy, nil
+}
+
+// validateQueue validates a single queue definition
+func validateQueue(queue QueueDefinition) error {
+	if queue.PriorityLevel < 0 || queue.PriorityLevel > MaxPriorityLevels {
+		return fmt.Errorf("class %s: priority level must be between 1 and %d", queue.Class, MaxPriorityLevels)
+	}
+	if queue.IsPriority() && queue.BandwidthRemainingPercent != 0 {
+		return fmt.Errorf("class %s: priority queues cannot have bandwidth remaining percent", queue.Class)
+	}
+	if !queue.IsPriority() && queue.PolicePercent != 0 {
+		return fmt.Errorf("class %s: police rate is only supported on priority queues", queue.Class)
+	}
+	if queue.PolicePercent < 0 || queue.PolicePercent > 100 {
+		return fmt.Errorf("class %s: police percent must be between 0 and 100", queue.Class)
+	}
+	if queue.BandwidthRemainingPercent < 0 || queue.BandwidthRemainingPercent > 100 {
+		return fmt.Errorf("class %s: bandwidth remaining percent must be between 0 and 100", queue.Class)
+	}
+	if queue.QueueBuffersRatio < 0 || queue.QueueBuffersRatio > 100 {
+		return fmt.Errorf("class %s: queue-buffers ratio must be between 0 and 100", queue.Class)
+	}
+	return nil
+}
+
+// Classes returns the classes with a dedicated egress queue
+func (p *QueuingPolicy) Classes() []Class {
+	classes := make([]Class, 0, len(p.Queues))
+	for _, queue := range p.Queues {
+		classes = append(classes, queue.Class)
+	}
+	return classes
+}
@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 )
 
 // Class represents a QoS classification
 type Class string
 
-// Standard QoS classes
+// Standard QoS classes of the built-in four-class model
 const (
 	EF    Class = "EF"    // Expedited Forwarding - Highest priority, real-time
 	AF41  Class = "AF41"  // Assured Forwarding 41 - High priority, business-critical
@@ -18,9 +19,9 @@ const (
 	Other Class = "OTHER" // Default/unclassified
 )
 
-// AllClasses returns all available QoS classes
+// AllClasses returns all QoS classes of the active class model ordered by priority
 func AllClasses() []Class {
-	return []Class{EF, AF41, AF21, CS1, Other}
+	return CurrentModel().Classes()
 }
 
 // String returns the string representation of the QoS class
@@ -28,66 +29,34 @@ func (c Class) String() string {
 	return string(c)
 }
 
-// IsValid checks if the QoS class is valid
+// IsValid checks if the QoS class is part of the active class model
 func (c Class) IsValid() bool {
-	for _, class := range AllClasses() {
-		if c == class {
-			return true
-		}
-	}
-	return false
+	return CurrentModel().Contains(c)
 }
 
 // Priority returns the priority level of the QoS class (lower number = higher priority)
 func (c Class) Priority() int {
-	switch c {
-	case EF:
-		return 1
-	case AF41:
-		return 2
-	case AF21:
-		return 3
-	case CS1:
-		return 4
-	case Other:
-		return 5
-	default:
-		return 6
+	model := CurrentModel()
+	if def, exists := model.Lookup(c); exists {
+		return def.Priority
 	}
+	return model.lowestPriority() + 1
 }
 
 // DSCP returns the DSCP marking for the QoS class
 func (c Class) DSCP() string {
-	switch c {
-	case EF:
-		return "ef"
-	case AF41:
-		return "af41"
-	case AF21:
-		return "af21"
-	case CS1:
-		return "cs1"
-	default:
-		return "cs0"
+	if def, exists := CurrentModel().Lookup(c); exists {
+		return def.DSCP
 	}
+	return "cs0"
 }
 
 // Description returns a human-readable description of the QoS class
 func (c Class) Description() string {
-	switch c {
-	case EF:
-		return "Expedited Forwarding - Real-time traffic (Voice, Video calls)"
-	case AF41:
-		return "Assured Forwarding 41 - Business-critical applications"
-	case AF21:
-		return "Assured Forwarding 21 - Important data applications"
-	case CS1:
-		return "Class Selector 1 - Background traffic"
-	case Other:
-		return "Unclassified traffic"
-	default:
-		return "Unknown QoS class"
+	if def, exists := CurrentModel().Lookup(c); exists {
+		return def.FullDescription()
 	}
+	return "Unknown QoS class"
 }
 
 // Classification represents a protocol and its QoS classification
@@ -178,8 +147,10 @@ type ClassConfig struct {
 	Protocols   []string `yaml:"protocols" json:"protocols"`
 }
 
-// Classifier handles protocol classification logic
+// Classifier handles protocol classification logic. It is safe for
+// concurrent use, so rules can change while protocols are classified.
 type Classifier struct {
+	mu                        sync.RWMutex
 	predefinedClassifications map[string]Class
 	customRules               []*Rule
 	defaultClass              Class
@@ -198,9 +169,22 @@ func NewClassifier(defaultClass Class, confidenceThreshold float64) *Classifier
 
 // AddPredefinedClassification adds a predefined classification
 func (c *Classifier) AddPredefinedClassification(protocol string, class Class) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.predefinedClassifications[strings.ToLower(protocol)] = class
 }
 
+// RemovePredefinedClassification removes a predefined classification and
+// reports whether it existed
+func (c *Classifier) RemovePredefinedClassification(protocol string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	protocol = strings.ToLower(protocol)
+	_, exists := c.predefinedClassifications[protocol]
+	delete(c.predefinedClassifications, protocol)
+	return exists
+}
+
 // AddCustomRule adds a custom classification rule
 func (c *Classifier) AddCustomRule(rule *Rule) error {
 	if err := rule.Validate(); err != nil {
@@ -209,14 +193,53 @@ func (c *Classifier) AddCustomRule(rule *Rule) error {
 	if err := rule.CompileRegex(); err != nil {
 		return err
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.customRules = append(c.customRules, rule)
 	return nil
 }
 
+// UpdateCustomRule replaces the custom rule with the given name, keeping its
+// position in the evaluation order
+func (c *Classifier) UpdateCustomRule(name string, rule *Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if err := rule.CompileRegex(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, existing := range c.customRules {
+		if existing.Name == name {
+			c.customRules[i] = rule
+			return nil
+		}
+	}
+	return fmt.Errorf("custom rule %q not found", name)
+}
+
+// RemoveCustomRule removes the custom rule with the given name and reports
+// whether it existed
+func (c *Classifier) RemoveCustomRule(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, rule := range c.customRules {
+		if rule.Name == name {
+			c.customRules = append(c.customRules[:i:i], c.customRules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // ClassifyProtocol classifies a single protocol
 func (c *Classifier) ClassifyProtocol(protocol string) Classification {
 	protocol = strings.ToLower(protocol)
 
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	// Check predefined classifications first
 	if class, exists := c.predefinedClassifications[protocol]; exists {
 		return Classification{
@@ -259,6 +282,8 @@ func (c *Classifier) ClassifyProtocols(protocols []string) map[string]Classifica
 
 // GetPredefinedClassifications returns all predefined classifications
 func (c *Classifier) GetPredefinedClassifications() map[string]Class {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	result := make(map[string]Class)
 	for k, v := range c.predefinedClassifications {
 		result[k] = v
@@ -268,6 +293,8 @@ func (c *Classifier) GetPredefinedClassifications() map[string]Class {
 
 // GetCustomRules returns all custom rules
 func (c *Classifier) GetCustomRules() []*Rule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	result := make([]*Rule, len(c.customRules))
 	copy(result, c.customRules)
 	return result
@@ -275,21 +302,29 @@ func (c *Classifier) GetCustomRules() []*Rule {
 
 // SetDefaultClass sets the default QoS class
 func (c *Classifier) SetDefaultClass(class Class) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.defaultClass = class
 }
 
 // GetDefaultClass returns the default QoS class
 func (c *Classifier) GetDefaultClass() Class {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.defaultClass
 }
 
 // SetConfidenceThreshold sets the confidence threshold
 func (c *Classifier) SetConfidenceThreshold(threshold float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.confidenceThreshold = threshold
 }
 
 // GetConfidenceThreshold returns the confidence threshold
 func (c *Classifier) GetConfidenceThreshold() float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.confidenceThreshold
 }
 
@@ -0,0 +1,297 @@
+package render
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// Default class handling modes
+const (
+	// DefaultClassImplicit leaves the default class to class-default
+	DefaultClassImplicit = "class-default"
+	// DefaultClassExplicit gives the default class its own class-maps in the policy
+	DefaultClassExplicit = "explicit"
+)
+
+// Default naming scheme
+const (
+	DefaultClassMapName      = `QOS_{{.Class}}{{if gt .Count 1}}_{{.Index}}{{end}}`
+	DefaultQueueClassMapName = `QOS_Q_{{.Class}}`
+	DefaultMaxProtocols      = 16
+)
+
+// CiscoOptions contains the template and naming settings for Cisco rendering
+type CiscoOptions struct {
+	// Template is a built-in template name (wired, wireless) or a file path
+	Template                string
+	ClassMapName            string
+	QueueClassMapName       string
+	MarkingPolicyName       string
+	MaxProtocolsPerClassMap int
+	DefaultClassHandling    string
+	ClassDefaultDSCP        string
+}
+
+// CiscoRenderer renders Cisco IOS-XE QoS configuration from templates
+type CiscoRenderer struct {
+	options       CiscoOptions
+	template      *template.Template
+	classMapName  *template.Template
+	queueCMapName *template.Template
+}
+
+// ClassMap is a single class-map with its protocols
+type ClassMap struct {
+	Name      string
+	Protocols []string
+}
+
+// ClassData contains a QoS class and the class-maps generated for it
+type ClassData struct {
+	Class       qos.Class
+	Description string
+	DSCP        string
+	ClassMaps   []ClassMap
+}
+
+// QueueData contains an egress queue and its DSCP-matching class-map
+type QueueData struct {
+	ClassMap    string
+	Class       qos.Class
+	Description string
+	DSCP        string
+	Queue       qos.QueueDefinition
+}
+
+// QueuingData contains the egress queuing policy
+type QueuingData struct {
+	Name    string
+	Queues  []QueueData
+	Default qos.QueueDefinition
+}
+
+// CiscoData is the data passed to Cisco configuration templates
+type CiscoData struct {
+	GeneratedAt          time.Time
+	MarkingPolicy        string
+	Classes              []ClassData
+	PolicyClasses        []ClassData
+	DefaultClass         ClassData
+	DefaultClassExplicit bool
+	ClassDefaultDSCP     string
+	Queuing              *QueuingData
+}
+
+// nameData is the data passed to naming templates
+type nameData struct {
+	Class qos.Class
+	Index int
+	Count int
+}
+
+// BuiltinTemplates returns the names of the built-in Cisco templates
+func BuiltinTemplates() []string {
+	entries, _ := builtinTemplates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		if name != "queuing" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewCiscoRenderer parses the configured templates and naming scheme
+func NewCiscoRenderer(opts CiscoOptions) (*CiscoRenderer, error) {
+	if opts.Template == "" {
+		opts.Template = "wired"
+	}
+	if opts.ClassMapName == "" {
+		opts.ClassMapName = DefaultClassMapName
+	}
+	if opts.QueueClassMapName == "" {
+		opts.QueueClassMapName = DefaultQueueClassMapName
+	}
+	if opts.MaxProtocolsPerClassMap == 0 {
+		opts.MaxProtocolsPerClassMap = DefaultMaxProtocols
+	}
+	if opts.DefaultClassHandling == "" {
+		opts.DefaultClassHandling = DefaultClassImplicit
+	}
+
+	if opts.MarkingPolicyName == "" {
+		return nil, fmt.Errorf("marking policy name cannot be empty")
+	}
+	if opts.MaxProtocolsPerClassMap < 1 {
+		return nil, fmt.Errorf("max protocols per class-map must be positive")
+	}
+	if opts.DefaultClassHandling != DefaultClassImplicit && opts.DefaultClassHandling != DefaultClassExplicit {
+		return nil, fmt.Errorf("invalid default class handling %q (expected %s or %s)",
+			opts.DefaultClassHandling, DefaultClassImplicit, DefaultClassExplicit)
+	}
+	if opts.ClassDefaultDSCP != "" {
+		if _, err := qos.ParseDSCP(opts.ClassDefaultDSCP); err != nil {
+			return nil, fmt.Errorf("invalid class-default DSCP: %w", err)
+		}
+	}
+
+	r := &CiscoRenderer{options: opts}
+
+	var err error
+	if r.classMapName, err = template.New("class_map_name").Option("missingkey=error").Parse(opts.ClassMapName); err != nil {
+		return nil, fmt.Errorf("invalid class-map naming template: %w", err)
+	}
+	if r.queueCMapName, err = template.New("queue_class_map_name").Option("missingkey=error").Parse(opts.QueueClassMapName); err != nil {
+		return nil, fmt.Errorf("invalid queue class-map naming template: %w", err)
+	}
+
+	// Shared partials are always available to built-in and custom templates
+	r.template, err = template.New("cisco").ParseFS(builtinTemplates, "templates/queuing.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse shared templates: %w", err)
+	}
+
+	if content, readErr := builtinTemplates.ReadFile("templates/" + opts.Template + ".tmpl"); readErr == nil && !strings.ContainsAny(opts.Template, "/\\") {
+		_, err = r.template.New(opts.Template).Parse(string(content))
+	} else {
+		var content []byte
+		content, err = os.ReadFile(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("template %q is neither built-in (%s) nor a readable file: %w",
+				opts.Template, strings.Join(BuiltinTemplates(), ", "), err)
+		}
+		opts.Template = filepath.Base(opts.Template)
+		r.options.Template = opts.Template
+		_, err = r.template.New(opts.Template).Parse(string(content))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", opts.Template, err)
+	}
+
+	return r, nil
+}
+
+// Render renders the Cisco configuration for the given classifications
+func (r *CiscoRenderer) Render(classifications map[string]qos.Classification, model *qos.Model, defaultClass qos.Class, queuing *qos.QueuingPolicy) (string, error) {
+	data, err := r.buildData(classifications, model, defaultClass, queuing)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := r.template.ExecuteTemplate(&buf, r.options.Template, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", r.options.Template, err)
+	}
+
+	return buf.String(), nil
+}
+
+// buildData prepares the template data
+func (r *CiscoRenderer) buildData(classifications map[string]qos.Classification, model *qos.Model, defaultClass qos.Class, queuing *qos.QueuingPolicy) (*CiscoData, error) {
+	grouped := qos.GroupProtocolsByClass(classifications)
+
+	data := &CiscoData{
+		GeneratedAt:          time.Now(),
+		MarkingPolicy:        r.options.MarkingPolicyName,
+		DefaultClassExplicit: r.options.DefaultClassHandling == DefaultClassExplicit,
+		ClassDefaultDSCP:     r.options.ClassDefaultDSCP,
+	}
+
+	for _, class := range model.MarkingClasses() {
+		protocols := append([]string(nil), grouped[class]...)
+		sort.Strings(protocols)
+
+		classData := newClassData(model, class)
+
+		max := r.options.MaxProtocolsPerClassMap
+		count := (len(protocols) + max - 1) / max
+		for i := 0; i < count; i++ {
+			name, err := r.executeName(r.classMapName, nameData{Class: class, Index: i + 1, Count: count})
+			if err != nil {
+				return nil, err
+			}
+
+			end := (i + 1) * max
+			if end > len(protocols) {
+				end = len(protocols)
+			}
+			classData.ClassMaps = append(classData.ClassMaps, ClassMap{
+				Name:      name,
+				Protocols: protocols[i*max : end],
+			})
+		}
+
+		data.Classes = append(data.Classes, classData)
+		if class == defaultClass {
+			data.DefaultClass = classData
+			if !data.DefaultClassExplicit {
+				continue
+			}
+		}
+		data.PolicyClasses = append(data.PolicyClasses, classData)
+	}
+
+	if data.DefaultClass.Class == "" {
+		data.DefaultClass = newClassData(model, defaultClass)
+	}
+
+	if data.ClassDefaultDSCP == "" {
+		data.ClassDefaultDSCP = data.DefaultClass.DSCP
+	}
+
+	if queuing != nil {
+		data.Queuing = &QueuingData{Name: queuing.Name, Default: queuing.Default}
+		for _, queue := range queuing.Queues {
+			name, err := r.executeName(r.queueCMapName, nameData{Class: queue.Class, Index: 1, Count: 1})
+			if err != nil {
+				return nil, err
+			}
+			class := newClassData(model, queue.Class)
+			data.Queuing.Queues = append(data.Queuing.Queues, QueueData{
+				ClassMap:    name,
+				Class:       queue.Class,
+				Description: class.Description,
+				DSCP:        class.DSCP,
+				Queue:       queue,
+			})
+		}
+	}
+
+	return data, nil
+}
+
+// newClassData describes a class using the given model
+func newClassData(model *qos.Model, class qos.Class) ClassData {
+	if def, exists := model.Lookup(class); exists {
+		return ClassData{Class: class, Description: def.FullDescription(), DSCP: def.DSCP}
+	}
+	return ClassData{Class: class, Description: class.Description(), DSCP: class.DSCP()}
+}
+
+// executeName renders a naming template
+func (r *CiscoRenderer) executeName(tmpl *template.Template, data nameData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render name for class %s: %w", data.Class, err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return "", fmt.Errorf("invalid class-map name %q for class %s", name, data.Class)
+	}
+	return name, nil
+}
@@ -0,0 +1,395 @@
+// Package rollout pushes configuration to a fleet of switches in waves. The
+// first wave is the canary; every wave is verified and soaked before the
+// next one starts, and the rollout halts at the first failure.
+package rollout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/transport"
+)
+
+// ErrHalted is returned when a wave fails and the rollout stops
+var ErrHalted = errors.New("rollout halted")
+
+// Status is the state of a rollout, wave or device
+type Status string
+
+// Statuses
+const (
+	StatusPending   Status = "pending"
+	StatusPushed    Status = "pushed"
+	StatusPassed    Status = "passed"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+	StatusCompleted Status = "completed"
+	StatusHalted    Status = "halted"
+)
+
+// Device is the switch CLI a rollout uses. ssh.Client implements it.
+type Device interface {
+	FetchRunningConfig() (string, error)
+	FetchDrops() (interfaces.Drops, error)
+	PushConfig(configCommands string) error
+	SaveConfig() error
+	Close() error
+}
+
+// Dialer connects to a device
+type Dialer func(target maintenance.Target) (Device, error)
+
+// Wave is a set of devices pushed together
+type Wave struct {
+	Name    string
+	Targets []maintenance.Target
+	// Soak is how long the wave runs before its drop counters are checked
+	Soak time.Duration
+}
+
+// Event reports the progress of a rollout. When a wave ends, every device
+// that passed or failed is reported with stage "done" and its final status.
+type Event struct {
+	Wave   string
+	Host   string
+	Stage  string
+	Status Status
+	Error  string
+}
+
+// Options configures a rollout
+type Options struct {
+	Waves []Wave
+	// Parallel is the number of devices of a wave pushed at once
+	Parallel int
+	// MaxDrops is the number of packets a device may drop in the classes of
+	// the pushed class-maps while soaking
+	MaxDrops uint64
+	// MaxInterfaceDrops is the number of output drops a device may count on
+	// the interfaces that carry a pushed policy-map while soaking
+	MaxInterfaceDrops uint64
+	SaveConfig        bool
+	// Gate is called for every device of a wave before the wave starts. An
+	// error halts the rollout before the wave is pushed.
+	Gate func(target maintenance.Target) error
+	// Observe receives progress events
+	Observe func(Event)
+}
+
+// DeviceResult is the outcome of a rollout on one device
+type DeviceResult struct {
+	Host     string    `json:"host"`
+	Status   Status    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	PushedAt time.Time `json:"pushed_at,omitempty"`
+	// Drops are the counters of the pushed classes, and the output drops of
+	// the interfaces carrying the pushed policy-maps, that grew while the
+	// wave soaked
+	Drops interfaces.Drops `json:"drops,omitempty"`
+}
+
+// WaveResult is the outcome of a wave
+type WaveResult struct {
+	Name       string         `json:"name"`
+	Status     Status         `json:"status"`
+	StartedAt  time.Time      `json:"started_at,omitempty"`
+	FinishedAt time.Time      `json:"finished_at,omitempty"`
+	Devices    []DeviceResult `json:"devices"`
+}
+
+// Report is the outcome of a rollout
+type Report struct {
+	Status     Status       `json:"status"`
+	Reason     string       `json:"reason,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Waves      []WaveResult `json:"waves"`
+}
+
+// Run rolls the configuration out wave by wave. A wave passes when the
+// configuration was pushed to every device, its class-maps are in the
+// running configuration and no device dropped more than MaxDrops packets in
+// those classes, or counted more than MaxInterfaceDrops output drops on the
+// interfaces carrying its policy-maps, while the wave soaked. Otherwise the rollout halts and the
+// report says which devices have the configuration.
+func Run(ctx context.Context, opts Options, config string, dial Dialer) (Report, error) {
+	desired, err := transport.ParseCLI(config)
+	if err != nil {
+		return Report{}, fmt.Errorf("invalid rollout configuration: %w", err)
+	}
+	if len(opts.Waves) == 0 {
+		return Report{}, fmt.Errorf("rollout has no waves")
+	}
+	if opts.Parallel < 1 {
+		opts.Parallel = 1
+	}
+
+	r := &runner{opts: opts, config: config, desired: desired.ClassMaps, classes: make(map[string]bool), policies: make(map[string]bool), dial: dial}
+	for _, classMap := range desired.ClassMaps {
+		r.classes[classMap.Name] = true
+	}
+	for _, policyMap := range desired.PolicyMaps {
+		r.policies[policyMap.Name] = true
+	}
+	report := Report{Status: StatusCompleted, StartedAt: time.Now()}
+	for _, wave := range opts.Waves {
+		result := WaveResult{Name: wave.Name, Status: StatusPending}
+		for _, target := range wave.Targets {
+			result.Devices = append(result.Devices, DeviceResult{Host: target.Host, Status: StatusPending})
+		}
+		report.Waves = append(report.Waves, result)
+	}
+
+	for i, wave := range opts.Waves {
+		err := r.runWave(ctx, wave, &report.Waves[i])
+		r.done(&report.Waves[i])
+		if err != nil {
+			report.Status = StatusHalted
+			report.Reason = fmt.Sprintf("wave %s: %v", wave.Name, err)
+			for j := i + 1; j < len(report.Waves); j++ {
+				skip(&report.Waves[j])
+			}
+			r.observe(Event{Wave: wave.Name, Stage: "halted", Error: err.Error()})
+			break
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	if report.Status == StatusHalted {
+		return report, fmt.Errorf("%w: %s", ErrHalted, report.Reason)
+	}
+	r.observe(Event{Stage: "completed"})
+	return report, nil
+}
+
+// runner holds the state of a rollout
+type runner struct {
+	opts    Options
+	config  string
+	desired []transport.ClassMap
+	// classes are the pushed class-maps, whose drops are checked
+	classes map[string]bool
+	// policies are the pushed policy-maps; output drops are checked on the
+	// interfaces they are attached to
+	policies map[string]bool
+	dial     Dialer
+}
+
+// pushed is a device the wave pushed to, kept open until the wave ends
+type pushed struct {
+	device   Device
+	baseline interfaces.Drops
+	// ports are the output drop counters of the interfaces carrying a
+	// pushed policy-map
+	ports map[string]bool
+}
+
+// runWave pushes, verifies and soaks a wave
+func (r *runner) runWave(ctx context.Context, wave Wave, result *WaveResult) error {
+	result.StartedAt = time.Now()
+	defer func() { result.FinishedAt = time.Now() }()
+	r.observe(Event{Wave: wave.Name, Stage: "started"})
+
+	if r.opts.Gate != nil {
+		for i, target := range wave.Targets {
+			if err := r.opts.Gate(target); err != nil {
+				fail(result, i, err)
+				skipPending(result)
+				return fmt.Errorf("%s: %w", target.Host, err)
+			}
+		}
+	}
+
+	devices := make([]*pushed, len(wave.Targets))
+	defer func() {
+		for _, p := range devices {
+			if p != nil {
+				p.device.Close()
+			}
+		}
+	}()
+
+	// Devices are pushed Parallel at a time; once one fails no more are
+	// started
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []string
+	)
+	slots := make(chan struct{}, r.opts.Parallel)
+	for i, target := range wave.Targets {
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		slots <- struct{}{}
+		mu.Lock()
+		stop := len(failed) > 0
+		mu.Unlock()
+		if stop {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, target maintenance.Target) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			p, err := r.pushDevice(target)
+			mu.Lock()
+			defer mu.Unlock()
+			devices[i] = p
+			if err != nil {
+				fail(result, i, err)
+				failed = append(failed, target.Host)
+				r.observe(Event{Wave: wave.Name, Host: target.Host, Stage: "failed", Error: err.Error()})
+				return
+			}
+			result.Devices[i].Status = StatusPushed
+			result.Devices[i].PushedAt = time.Now()
+			r.observe(Event{Wave: wave.Name, Host: target.Host, Stage: "verified"})
+		}(i, target)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		skipPending(result)
+		return fmt.Errorf("push failed on %s", strings.Join(failed, ", "))
+	}
+	if err := ctx.Err(); err != nil {
+		skipPending(result)
+		return err
+	}
+
+	r.observe(Event{Wave: wave.Name, Stage: "soaking"})
+	select {
+	case <-time.After(wave.Soak):
+	case <-ctx.Done():
+		result.Status = StatusFailed
+		return ctx.Err()
+	}
+
+	for i, p := range devices {
+		after, err := p.device.FetchDrops()
+		if err != nil {
+			fail(result, i, fmt.Errorf("failed to read drop counters: %w", err))
+			failed = append(failed, wave.Targets[i].Host)
+			continue
+		}
+		increase := p.baseline.Increase(after)
+		classes := increase.Classes(r.classes)
+		ports := increase.Select(p.ports)
+		result.Devices[i].Drops = make(interfaces.Drops)
+		for _, drops := range []interfaces.Drops{classes, ports} {
+			for key, value := range drops {
+				result.Devices[i].Drops[key] = value
+			}
+		}
+		if total := classes.Total(); total > r.opts.MaxDrops {
+			fail(result, i, fmt.Errorf("dropped %d packets while soaking, %d allowed", total, r.opts.MaxDrops))
+			failed = append(failed, wave.Targets[i].Host)
+			continue
+		}
+		if total := ports.Total(); total > r.opts.MaxInterfaceDrops {
+			fail(result, i, fmt.Errorf("counted %d output drops on policed interfaces while soaking, %d allowed", total, r.opts.MaxInterfaceDrops))
+			failed = append(failed, wave.Targets[i].Host)
+			continue
+		}
+		result.Devices[i].Status = StatusPassed
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("verification failed on %s", strings.Join(failed, ", "))
+	}
+
+	result.Status = StatusPassed
+	r.observe(Event{Wave: wave.Name, Stage: "passed"})
+	return nil
+}
+
+// pushDevice pushes the configuration to a device and checks that its
+// class-maps are in the running configuration. The device is returned open,
+// with its drop counters from before the push, even when the push fails.
+// The running configuration also tells which interfaces carry the pushed
+// policy-maps.
+func (r *runner) pushDevice(target maintenance.Target) (*pushed, error) {
+	device, err := r.dial(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	p := &pushed{device: device}
+
+	p.baseline, err = device.FetchDrops()
+	if err != nil {
+		return p, fmt.Errorf("failed to read drop counters: %w", err)
+	}
+	if err := device.PushConfig(r.config); err != nil {
+		return p, err
+	}
+	if r.opts.SaveConfig {
+		if err := device.SaveConfig(); err != nil {
+			return p, err
+		}
+	}
+
+	running, err := device.FetchRunningConfig()
+	if err != nil {
+		return p, fmt.Errorf("failed to fetch running configuration: %w", err)
+	}
+	if missing := transport.ChangedClassMaps(transport.ParseRunningClassMaps(running), r.desired); len(missing) > 0 {
+		return p, fmt.Errorf("class-maps not applied: %s", strings.Join(missing, ", "))
+	}
+	p.ports = make(map[string]bool)
+	for _, iface := range interfaces.ParseRunningConfig(running) {
+		if r.policies[iface.OutputPolicy] {
+			p.ports[iface.Name+" "+string(interfaces.Output)] = true
+		}
+	}
+	return p, nil
+}
+
+// done reports the final status of the devices of a wave that ended
+func (r *runner) done(result *WaveResult) {
+	for _, device := range result.Devices {
+		if device.Status == StatusPassed || device.Status == StatusFailed {
+			r.observe(Event{Wave: result.Name, Host: device.Host, Stage: "done", Status: device.Status, Error: device.Error})
+		}
+	}
+}
+
+// observe reports an event
+func (r *runner) observe(event Event) {
+	if r.opts.Observe != nil {
+		r.opts.Observe(event)
+	}
+}
+
+// fail marks a device and its wave failed
+func fail(result *WaveResult, i int, err error) {
+	result.Status = StatusFailed
+	result.Devices[i].Status = StatusFailed
+	result.Devices[i].Error = err.Error()
+}
+
+// skipPending marks the wave failed and the devices not pushed to skipped
+func skipPending(result *WaveResult) {
+	result.Status = StatusFailed
+	for i := range result.Devices {
+		if result.Devices[i].Status == StatusPending {
+			result.Devices[i].Status = StatusSkipped
+		}
+	}
+}
+
+// skip marks a wave that was not started
+func skip(result *WaveResult) {
+	result.Status = StatusSkipped
+	for i := range result.Devices {
+		result.Devices[i].Status = StatusSkipped
+	}
+}
@@ -0,0 +1,626 @@
+// Package rules manages classification overrides and custom rules changed at
+// runtime. Changes are layered over the entries from the configuration file,
+// applied to the live classifier, persisted to a store file and recorded in
+// an audit trail.
+package rules
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// Errors returned by the manager
+var (
+	ErrNotFound = errors.New("not found")
+	ErrExists   = errors.New("already exists")
+	ErrReadOnly = errors.New("defined in the configuration file")
+)
+
+// Origins of overrides and rules
+const (
+	OriginConfig = "config"
+	OriginAPI    = "api"
+)
+
+// Audit trail actions and kinds
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+
+	KindOverride = "override"
+	KindRule     = "rule"
+)
+
+// maxHistory bounds the changes kept in memory for History
+const maxHistory = 1000
+
+// Override pins a protocol to a class ahead of rules and AI classification
+type Override struct {
+	Protocol    string    `json:"protocol"`
+	Class       qos.Class `json:"class"`
+	Description string    `json:"description,omitempty"`
+	Origin      string    `json:"origin"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// Rule is a custom classification rule with its origin
+type Rule struct {
+	qos.Rule
+	Origin    string    `json:"origin"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Change is an audit trail entry; Before is empty for creations and After
+// for deletions
+type Change struct {
+	Time   time.Time       `json:"time"`
+	Actor  string          `json:"actor"`
+	Action string          `json:"action"`
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Options configures the manager
+type Options struct {
+	// StoreFile persists runtime changes; empty keeps them in memory only
+	StoreFile string
+	// AuditFile receives one JSON line per change; empty keeps the trail in
+	// memory only
+	AuditFile string
+}
+
+// Invalidator drops cached classifications that a change makes stale; it is
+// satisfied by the classification cache
+type Invalidator interface {
+	GetAll() map[string]qos.Classification
+	Delete(protocol string)
+}
+
+// storeData is the store file layout
+type storeData struct {
+	Overrides []Override `json:"overrides"`
+	Rules     []Rule     `json:"rules"`
+}
+
+// Manager applies runtime overrides and rules to a classifier
+type Manager struct {
+	options    Options
+	classifier *qos.Classifier
+	cache      Invalidator
+
+	mu            sync.Mutex
+	baseOverrides map[string]qos.Class
+	baseRules     map[string]qos.Rule
+	overrides     map[string]Override
+	rules         []Rule
+	history       []Change
+}
+
+// New layers the stored changes over the classifier's current overrides and
+// rules, which are taken to come from the configuration file. The cache may
+// be nil.
+func New(opts Options, classifier *qos.Classifier, cache Invalidator) (*Manager, error) {
+	m := &Manager{
+		options:       opts,
+		classifier:    classifier,
+		cache:         cache,
+		baseOverrides: classifier.GetPredefinedClassifications(),
+		baseRules:     make(map[string]qos.Rule),
+		overrides:     make(map[string]Override),
+	}
+	for _, rule := range classifier.GetCustomRules() {
+		m.baseRules[rule.Name] = *rule
+	}
+
+	data, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range data.Overrides {
+		if !override.Class.IsValid() {
+			return nil, fmt.Errorf("stored override for %s has invalid class %q", override.Protocol, override.Class)
+		}
+		override.Origin = OriginAPI
+		m.overrides[override.Protocol] = override
+		classifier.AddPredefinedClassification(override.Protocol, override.Class)
+	}
+	for _, rule := range data.Rules {
+		rule.Origin = OriginAPI
+		if err := m.applyRule(rule.Rule); err != nil {
+			return nil, fmt.Errorf("stored rule %s: %w", rule.Name, err)
+		}
+		m.rules = append(m.rules, rule)
+	}
+
+	if err := m.loadHistory(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Overrides returns the effective overrides sorted by protocol
+func (m *Manager) Overrides() []Override {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	overrides := make([]Override, 0, len(m.baseOverrides)+len(m.overrides))
+	for protocol, class := range m.baseOverrides {
+		if _, exists := m.overrides[protocol]; !exists {
+			overrides = append(overrides, Override{Protocol: protocol, Class: class, Origin: OriginConfig})
+		}
+	}
+	for _, override := range m.overrides {
+		overrides = append(overrides, override)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Protocol < overrides[j].Protocol
+	})
+	return overrides
+}
+
+// Override returns the effective override for a protocol
+func (m *Manager) Override(protocol string) (Override, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.override(strings.ToLower(protocol))
+}
+
+// SetOverride creates or replaces the runtime override for a protocol and
+// reports whether it was created
+func (m *Manager) SetOverride(override Override, actor string) (Override, bool, error) {
+	override.Protocol = strings.ToLower(override.Protocol)
+	if err := qos.ValidateProtocolName(override.Protocol); err != nil {
+		return Override{}, false, err
+	}
+	if !override.Class.IsValid() {
+		return Override{}, false, fmt.Errorf("invalid QoS class: %s", override.Class)
+	}
+	override.Origin = OriginAPI
+	override.UpdatedBy = actor
+	override.UpdatedAt = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	action := ActionUpdate
+	var before interface{}
+	if current, err := m.override(override.Protocol); err == nil {
+		before = current
+	} else {
+		action = ActionCreate
+	}
+	previous, existed := m.overrides[override.Protocol]
+
+	m.overrides[override.Protocol] = override
+	if err := m.commit(action, KindOverride, override.Protocol, actor, before, override); err != nil {
+		if existed {
+			m.overrides[override.Protocol] = previous
+		} else {
+			delete(m.overrides, override.Protocol)
+		}
+		return Override{}, false, err
+	}
+
+	m.classifier.AddPredefinedClassification(override.Protocol, override.Class)
+	m.invalidateProtocol(override.Protocol)
+	return override, action == ActionCreate, nil
+}
+
+// DeleteOverride removes the runtime override for a protocol; an override
+// from the configuration file it replaced takes effect again
+func (m *Manager) DeleteOverride(protocol, actor string) error {
+	protocol = strings.ToLower(protocol)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, exists := m.overrides[protocol]
+	if !exists {
+		if _, base := m.baseOverrides[protocol]; base {
+			return fmt.Errorf("override for %s is %w", protocol, ErrReadOnly)
+		}
+		return fmt.Errorf("override for %s %w", protocol, ErrNotFound)
+	}
+
+	delete(m.overrides, protocol)
+	after, restored := m.baseOverrides[protocol]
+	var afterValue interface{}
+	if restored {
+		afterValue = Override{Protocol: protocol, Class: after, Origin: OriginConfig}
+	}
+	if err := m.commit(ActionDelete, KindOverride, protocol, actor, previous, afterValue); err != nil {
+		m.overrides[protocol] = previous
+		return err
+	}
+
+	if restored {
+		m.classifier.AddPredefinedClassification(protocol, after)
+	} else {
+		m.classifier.RemovePredefinedClassification(protocol)
+	}
+	m.invalidateProtocol(protocol)
+	return nil
+}
+
+// Rules returns the effective custom rules in evaluation order
+func (m *Manager) Rules() []Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rules []Rule
+	for _, rule := range m.classifier.GetCustomRules() {
+		if current, err := m.rule(rule.Name); err == nil {
+			rules = append(rules, current)
+		}
+	}
+	return rules
+}
+
+// Rule returns the effective custom rule with the given name
+func (m *Manager) Rule(name string) (Rule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rule(name)
+}
+
+// CreateRule adds a runtime rule, evaluated after the existing rules
+func (m *Manager) CreateRule(rule qos.Rule, actor string) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.rule(rule.Name); err == nil {
+		return Rule{}, fmt.Errorf("rule %s %w", rule.Name, ErrExists)
+	}
+
+	created := Rule{Rule: rule, Origin: OriginAPI, UpdatedBy: actor, UpdatedAt: time.Now()}
+	m.rules = append(m.rules, created)
+	if err := m.commit(ActionCreate, KindRule, rule.Name, actor, nil, created); err != nil {
+		m.rules = m.rules[:len(m.rules)-1]
+		return Rule{}, err
+	}
+
+	if err := m.applyRule(rule); err != nil {
+		return Rule{}, err
+	}
+	m.invalidateRules(&rule)
+	return created, nil
+}
+
+// UpdateRule replaces a rule; updating a rule from the configuration file
+// records a runtime replacement for it
+func (m *Manager) UpdateRule(name string, rule qos.Rule, actor string) (Rule, error) {
+	rule.Name = name
+	if err := rule.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before, err := m.rule(name)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	updated := Rule{Rule: rule, Origin: OriginAPI, UpdatedBy: actor, UpdatedAt: time.Now()}
+	previous := m.rules
+	m.rules = append([]Rule(nil), m.rules...)
+	if index := m.ruleIndex(name); index >= 0 {
+		m.rules[index] = updated
+	} else {
+		m.rules = append(m.rules, updated)
+	}
+	if err := m.commit(ActionUpdate, KindRule, name, actor, before, updated); err != nil {
+		m.rules = previous
+		return Rule{}, err
+	}
+
+	if err := m.applyRule(rule); err != nil {
+		return Rule{}, err
+	}
+	m.invalidateRules(&before.Rule, &rule)
+	return updated, nil
+}
+
+// DeleteRule removes a runtime rule; a rule from the configuration file it
+// replaced takes effect again
+func (m *Manager) DeleteRule(name, actor string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.ruleIndex(name)
+	if index < 0 {
+		if _, base := m.baseRules[name]; base {
+			return fmt.Errorf("rule %s is %w", name, ErrReadOnly)
+		}
+		return fmt.Errorf("rule %s %w", name, ErrNotFound)
+	}
+
+	previous := m.rules[index]
+	saved := m.rules
+	m.rules = append(m.rules[:index:index], m.rules[index+1:]...)
+	base, restored := m.baseRules[name]
+	var afterValue interface{}
+	if restored {
+		afterValue = Rule{Rule: base, Origin: OriginConfig}
+	}
+	if err := m.commit(ActionDelete, KindRule, name, actor, previous, afterValue); err != nil {
+		m.rules = saved
+		return err
+	}
+
+	if restored {
+		if err := m.applyRule(base); err != nil {
+			return err
+		}
+		m.invalidateRules(&previous.Rule, &base)
+	} else {
+		m.classifier.RemoveCustomRule(name)
+		m.invalidateRules(&previous.Rule)
+	}
+	return nil
+}
+
+// History returns up to limit audit trail entries, newest first; a limit of
+// zero returns all entries kept in memory
+func (m *Manager) History(limit int) []Change {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := len(m.history)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	changes := make([]Change, 0, count)
+	for i := len(m.history) - 1; i >= 0 && len(changes) < count; i-- {
+		changes = append(changes, m.history[i])
+	}
+	return changes
+}
+
+// override returns the effective override; the lock must be held
+func (m *Manager) override(protocol string) (Override, error) {
+	if override, exists := m.overrides[protocol]; exists {
+		return override, nil
+	}
+	if class, exists := m.baseOverrides[protocol]; exists {
+		return Override{Protocol: protocol, Class: class, Origin: OriginConfig}, nil
+	}
+	return Override{}, fmt.Errorf("override for %s %w", protocol, ErrNotFound)
+}
+
+// rule returns the effective rule; the lock must be held
+func (m *Manager) rule(name string) (Rule, error) {
+	if index := m.ruleIndex(name); index >= 0 {
+		return m.rules[index], nil
+	}
+	if rule, exists := m.baseRules[name]; exists {
+		return Rule{Rule: rule, Origin: OriginConfig}, nil
+	}
+	return Rule{}, fmt.Errorf("rule %s %w", name, ErrNotFound)
+}
+
+// ruleIndex returns the index of a runtime rule or -1
+func (m *Manager) ruleIndex(name string) int {
+	for i, rule := range m.rules {
+		if rule.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyRule adds or replaces a rule in the classifier
+func (m *Manager) applyRule(rule qos.Rule) error {
+	for _, existing := range m.classifier.GetCustomRules() {
+		if existing.Name == rule.Name {
+			return m.classifier.UpdateCustomRule(rule.Name, &rule)
+		}
+	}
+	return m.classifier.AddCustomRule(&rule)
+}
+
+// invalidateProtocol drops the cached classification of a protocol
+func (m *Manager) invalidateProtocol(protocol string) {
+	if m.cache != nil {
+		m.cache.Delete(protocol)
+	}
+}
+
+// invalidateRules drops cached classifications matched by any of the rules
+func (m *Manager) invalidateRules(rules ...*qos.Rule) {
+	if m.cache == nil {
+		return
+	}
+	for _, rule := range rules {
+		if err := rule.CompileRegex(); err != nil {
+			continue
+		}
+		// Match ignores disabled rules, but disabling one changes the result
+		// for the protocols it matched
+		check := *rule
+		check.Enabled = true
+		for protocol := range m.cache.GetAll() {
+			if check.Match(protocol) {
+				m.cache.Delete(protocol)
+			}
+		}
+	}
+}
+
+// commit records a change in the audit trail, then saves the store; the lock
+// must be held. A change that cannot be audited is not made.
+func (m *Manager) commit(action, kind, name, actor string, before, after interface{}) error {
+	change := Change{
+		Time:   time.Now(),
+		Actor:  actor,
+		Action: action,
+		Kind:   kind,
+		Name:   name,
+	}
+	var err error
+	if change.Before, err = marshalValue(before); err != nil {
+		return err
+	}
+	if change.After, err = marshalValue(after); err != nil {
+		return err
+	}
+
+	if err := m.appendAudit(change); err != nil {
+		return err
+	}
+	m.history = append(m.history, change)
+	if len(m.history) > maxHistory {
+		m.history = m.history[len(m.history)-maxHistory:]
+	}
+	return m.save()
+}
+
+// marshalValue encodes an audit value; nil values stay empty
+func marshalValue(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	return data, nil
+}
+
+// appendAudit appends a change to the audit file
+func (m *Manager) appendAudit(change Change) error {
+	if m.options.AuditFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+	if err := ensureDir(m.options.AuditFile); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(m.options.AuditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open rules audit file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write rules audit file: %w", err)
+	}
+	return nil
+}
+
+// loadHistory reads the most recent audit file entries
+func (m *Manager) loadHistory() error {
+	if m.options.AuditFile == "" {
+		return nil
+	}
+	file, err := os.Open(m.options.AuditFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open rules audit file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var change Change
+		if err := json.Unmarshal(scanner.Bytes(), &change); err != nil {
+			continue
+		}
+		m.history = append(m.history, change)
+		if len(m.history) > maxHistory {
+			m.history = m.history[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read rules audit file: %w", err)
+	}
+	return nil
+}
+
+// load reads the store file
+func (m *Manager) load() (storeData, error) {
+	var data storeData
+	if m.options.StoreFile == "" {
+		return data, nil
+	}
+	content, err := os.ReadFile(m.options.StoreFile)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to read rules store: %w", err)
+	}
+	if err := json.Unmarshal(content, &data); err != nil {
+		return data, fmt.Errorf("failed to parse rules store %s: %w", m.options.StoreFile, err)
+	}
+	return data, nil
+}
+
+// save writes the store file atomically; the lock must be held
+func (m *Manager) save() error {
+	if m.options.StoreFile == "" {
+		return nil
+	}
+
+	data := storeData{Overrides: make([]Override, 0, len(m.overrides)), Rules: m.rules}
+	for _, override := range m.overrides {
+		data.Overrides = append(data.Overrides, override)
+	}
+	sort.Slice(data.Overrides, func(i, j int) bool {
+		return data.Overrides[i].Protocol < data.Overrides[j].Protocol
+	})
+	if data.Rules == nil {
+		data.Rules = []Rule{}
+	}
+
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal rules store: %w", err)
+	}
+	if err := ensureDir(m.options.StoreFile); err != nil {
+		return err
+	}
+	tmp := m.options.StoreFile + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return fmt.Errorf("failed to write rules store: %w", err)
+	}
+	if err := os.Rename(tmp, m.options.StoreFile); err != nil {
+		return fmt.Errorf("failed to replace rules store: %w", err)
+	}
+	return nil
+}
+
+// ensureDir creates the parent directory of a file
+func ensureDir(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
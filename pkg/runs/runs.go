@@ -0,0 +1,584 @@
+// Package runs queues classification runs requested through the API,
+// executes them one at a time and keeps their history.
+package runs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the state of a run
+type Status string
+
+// Run states
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Finished reports whether the run has ended
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCancelled
+}
+
+// Protocol sources of a run
+const (
+	SourceSwitch  = "switch"
+	SourceFile    = "file"
+	SourceNetFlow = "netflow"
+	// SourceInline classifies the protocols given in the request
+	SourceInline = "inline"
+	// SourceChange applies or rolls back an approved change
+	SourceChange = "change"
+)
+
+// Errors returned by the Manager
+var (
+	ErrNotFound  = errors.New("run not found")
+	ErrFinished  = errors.New("run has already finished")
+	ErrQueueFull = errors.New("too many queued runs")
+	ErrClosed    = errors.New("run manager is closed")
+)
+
+// Request describes a run
+type Request struct {
+	// Source is switch, file, netflow, inline or change
+	Source string `json:"source"`
+	// ChangeID is the change a change source applies, or with Rollback
+	// rolls back
+	ChangeID string `json:"change_id,omitempty"`
+	Rollback bool   `json:"rollback,omitempty"`
+	// Comment is recorded in the change's history
+	Comment string `json:"comment,omitempty"`
+	// InputFile is relative to the configured input directory
+	InputFile   string   `json:"input_file,omitempty"`
+	InputFormat string   `json:"input_format,omitempty"`
+	Protocols   []string `json:"protocols,omitempty"`
+	// Output is a comma-separated list of output formats
+	Output       string `json:"output,omitempty"`
+	DryRun       bool   `json:"dry_run,omitempty"`
+	Push         bool   `json:"push,omitempty"`
+	SaveConfig   bool   `json:"save_config,omitempty"`
+	PushCatalyst bool   `json:"push_catalyst_center,omitempty"`
+	// Emergency pushes outside the maintenance windows
+	Emergency   bool   `json:"emergency,omitempty"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// Progress summarizes how far a run got
+type Progress struct {
+	Protocols    int `json:"protocols"`
+	Classified   int `json:"classified"`
+	BatchesDone  int `json:"batches_done"`
+	BatchesTotal int `json:"batches_total"`
+}
+
+// Batch is the result of one AI classification batch
+type Batch struct {
+	Index      int           `json:"index"`
+	Total      int           `json:"total"`
+	Protocols  []string      `json:"protocols"`
+	Provider   string        `json:"provider,omitempty"`
+	Classified int           `json:"classified"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Run is a queued, running or finished run
+type Run struct {
+	ID         string    `json:"id"`
+	Status     Status    `json:"status"`
+	Request    Request   `json:"request"`
+	CreatedAt  time.Time `json:"created_at"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+
+	// Stage is the step the run is in, e.g. discovery or classification
+	Stage    string   `json:"stage,omitempty"`
+	Progress Progress `json:"progress"`
+	Batches  []Batch  `json:"batches,omitempty"`
+	// Summary counts the classified protocols per class
+	Summary map[string]int `json:"summary,omitempty"`
+	// Outputs holds the generated outputs by format
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// ChangeID is the change the run proposed or applied
+	ChangeID string `json:"change_id,omitempty"`
+}
+
+// Executor performs a run, reporting its progress to the tracker
+type Executor func(ctx context.Context, req Request, tracker *Tracker) error
+
+// Options configures a Manager
+type Options struct {
+	// HistoryFile persists the runs; empty keeps them in memory only
+	HistoryFile string
+	// MaxHistory is the number of finished runs kept
+	MaxHistory int
+	// MaxQueued is the number of runs that may wait for execution
+	MaxQueued int
+	// Timeout bounds a single run, zero means no limit
+	Timeout time.Duration
+	// InputDir is the directory file sources are read from; file sources are
+	// refused when it is empty
+	InputDir string
+}
+
+// Manager queues runs and executes them one at a time
+type Manager struct {
+	options Options
+	execute Executor
+
+	mu      sync.Mutex
+	runs    []*Run
+	cancels map[string]context.CancelFunc
+	queue   chan string
+	closed  bool
+	ctx     context.Context
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// New loads the run history and starts the worker. Runs that were queued or
+// running when the history was saved are marked failed.
+func New(opts Options, execute Executor) (*Manager, error) {
+	if opts.MaxHistory <= 0 {
+		opts.MaxHistory = 100
+	}
+	if opts.MaxQueued <= 0 {
+		opts.MaxQueued = 10
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		options: opts,
+		execute: execute,
+		cancels: make(map[string]context.CancelFunc),
+		queue:   make(chan string, opts.MaxQueued),
+		ctx:     ctx,
+		stop:    stop,
+		done:    make(chan struct{}),
+	}
+	if err := m.load(); err != nil {
+		stop()
+		return nil, err
+	}
+
+	go m.work()
+	return m, nil
+}
+
+// Submit validates and queues a run
+func (m *Manager) Submit(req Request) (Run, error) {
+	if err := m.validate(&req); err != nil {
+		return Run{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Run{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Run{}, ErrClosed
+	}
+
+	run := &Run{ID: id, Status: StatusQueued, Request: req, CreatedAt: time.Now()}
+	select {
+	case m.queue <- id:
+	default:
+		return Run{}, ErrQueueFull
+	}
+	m.runs = append(m.runs, run)
+	m.save()
+	return copyRun(run), nil
+}
+
+// Get returns a run
+func (m *Manager) Get(id string) (Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.find(id)
+	if run == nil {
+		return Run{}, ErrNotFound
+	}
+	return copyRun(run), nil
+}
+
+// List returns the runs, newest first
+func (m *Manager) List() []Run {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	runs := make([]Run, 0, len(m.runs))
+	for i := len(m.runs) - 1; i >= 0; i-- {
+		runs = append(runs, copyRun(m.runs[i]))
+	}
+	return runs
+}
+
+// Cancel cancels a queued or running run
+func (m *Manager) Cancel(id string) (Run, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	run := m.find(id)
+	if run == nil {
+		return Run{}, ErrNotFound
+	}
+	if run.Status.Finished() {
+		return copyRun(run), ErrFinished
+	}
+
+	if cancel, running := m.cancels[id]; running {
+		// The worker records the cancellation when the executor returns
+		cancel()
+		return copyRun(run), nil
+	}
+	run.Status = StatusCancelled
+	run.FinishedAt = time.Now()
+	m.save()
+	return copyRun(run), nil
+}
+
+// Close cancels all runs and waits for the running one to return
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.mu.Unlock()
+
+	m.stop()
+	<-m.done
+}
+
+// work executes queued runs until the manager is closed
+func (m *Manager) work() {
+	defer close(m.done)
+	for {
+		select {
+		case <-m.ctx.Done():
+			m.cancelQueued()
+			return
+		case id := <-m.queue:
+			m.run(id)
+		}
+	}
+}
+
+// run executes one queued run
+func (m *Manager) run(id string) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if m.options.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(m.ctx, m.options.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(m.ctx)
+	}
+	defer cancel()
+
+	m.mu.Lock()
+	run := m.find(id)
+	if run == nil || run.Status != StatusQueued {
+		m.mu.Unlock()
+		return
+	}
+	run.Status = StatusRunning
+	run.StartedAt = time.Now()
+	req := run.Request
+	m.cancels[id] = cancel
+	m.save()
+	m.mu.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("run panicked: %v", r)
+			}
+		}()
+		return m.execute(ctx, req, &Tracker{manager: m, id: id})
+	}()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.cancels, id)
+	run.FinishedAt = time.Now()
+	switch {
+	case err == nil:
+		run.Status = StatusSucceeded
+	case errors.Is(ctx.Err(), context.Canceled):
+		run.Status = StatusCancelled
+		run.Error = err.Error()
+	default:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+	m.trim()
+	m.save()
+}
+
+// cancelQueued marks the runs still waiting as cancelled
+func (m *Manager) cancelQueued() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, run := range m.runs {
+		if run.Status == StatusQueued {
+			run.Status = StatusCancelled
+			run.FinishedAt = time.Now()
+		}
+	}
+	m.save()
+}
+
+// validate checks a request and resolves its input file
+func (m *Manager) validate(req *Request) error {
+	req.Source = strings.ToLower(strings.TrimSpace(req.Source))
+	switch req.Source {
+	case SourceSwitch, SourceNetFlow:
+	case SourceFile:
+		if m.options.InputDir == "" {
+			return fmt.Errorf("file sources are disabled, no input directory is configured")
+		}
+		if req.InputFile == "" {
+			return fmt.Errorf("source file requires input_file")
+		}
+		// Keep the path inside the input directory
+		req.InputFile = filepath.Join(m.options.InputDir, filepath.Clean("/"+req.InputFile))
+	case SourceInline:
+		if len(req.Protocols) == 0 {
+			return fmt.Errorf("source inline requires protocols")
+		}
+	case SourceChange:
+		if req.ChangeID == "" {
+			return fmt.Errorf("source change requires change_id")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown source %q, expected switch, file, netflow, inline or change", req.Source)
+	}
+	if req.Rollback {
+		return fmt.Errorf("rollback requires source change")
+	}
+	if req.Emergency && !req.Push {
+		return fmt.Errorf("emergency requires push")
+	}
+	if req.SaveConfig && !req.Push {
+		return fmt.Errorf("save_config requires push")
+	}
+	return nil
+}
+
+// find returns a run by ID; the lock must be held
+func (m *Manager) find(id string) *Run {
+	for _, run := range m.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+// update applies fn to a run and saves the history
+func (m *Manager) update(id string, fn func(*Run)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if run := m.find(id); run != nil {
+		fn(run)
+		m.save()
+	}
+}
+
+// trim drops the oldest finished runs beyond MaxHistory; the lock must be
+// held
+func (m *Manager) trim() {
+	finished := 0
+	for _, run := range m.runs {
+		if run.Status.Finished() {
+			finished++
+		}
+	}
+	kept := m.runs[:0]
+	for _, run := range m.runs {
+		if run.Status.Finished() && finished > m.options.MaxHistory {
+			finished--
+			continue
+		}
+		kept = append(kept, run)
+	}
+	m.runs = kept
+}
+
+// load reads the history file
+func (m *Manager) load() error {
+	if m.options.HistoryFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(m.options.HistoryFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read run history: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.runs); err != nil {
+		return fmt.Errorf("failed to parse run history %s: %w", m.options.HistoryFile, err)
+	}
+
+	sort.SliceStable(m.runs, func(i, j int) bool {
+		return m.runs[i].CreatedAt.Before(m.runs[j].CreatedAt)
+	})
+	for _, run := range m.runs {
+		if !run.Status.Finished() {
+			run.Status = StatusFailed
+			run.Error = "interrupted by restart"
+			run.FinishedAt = time.Now()
+		}
+	}
+	m.trim()
+	return nil
+}
+
+// save writes the history file atomically; the lock must be held. Errors
+// are ignored so a full disk does not fail the runs themselves.
+func (m *Manager) save() {
+	if m.options.HistoryFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(m.runs, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := m.options.HistoryFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, m.options.HistoryFile)
+}
+
+// copyRun returns a copy that does not share slices or maps with the run
+func copyRun(run *Run) Run {
+	c := *run
+	c.Request.Protocols = append([]string(nil), run.Request.Protocols...)
+	c.Batches = append([]Batch(nil), run.Batches...)
+	if run.Summary != nil {
+		c.Summary = make(map[string]int, len(run.Summary))
+		for class, count := range run.Summary {
+			c.Summary[class] = count
+		}
+	}
+	if run.Outputs != nil {
+		c.Outputs = make(map[string]string, len(run.Outputs))
+		for format, data := range run.Outputs {
+			c.Outputs[format] = data
+		}
+	}
+	return c
+}
+
+// newID returns a random run ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate run ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Tracker reports the progress of a run. A nil Tracker ignores all reports,
+// so code shared with the command line can report unconditionally.
+type Tracker struct {
+	manager *Manager
+	id      string
+}
+
+// ID returns the ID of the run, empty for a nil Tracker
+func (t *Tracker) ID() string {
+	if t == nil {
+		return ""
+	}
+	return t.id
+}
+
+// Stage records the step the run is in
+func (t *Tracker) Stage(stage string) {
+	if t == nil {
+		return
+	}
+	t.manager.update(t.id, func(run *Run) {
+		run.Stage = stage
+	})
+}
+
+// Protocols records the number of protocols to classify
+func (t *Tracker) Protocols(count int) {
+	if t == nil {
+		return
+	}
+	t.manager.update(t.id, func(run *Run) {
+		run.Progress.Protocols = count
+	})
+}
+
+// Batch records the result of an AI batch
+func (t *Tracker) Batch(result Batch) {
+	if t == nil {
+		return
+	}
+	t.manager.update(t.id, func(run *Run) {
+		run.Batches = append(run.Batches, result)
+		run.Progress.BatchesDone++
+		run.Progress.BatchesTotal = result.Total
+	})
+}
+
+// Classified records the classification outcome per class
+func (t *Tracker) Classified(summary map[string]int) {
+	if t == nil {
+		return
+	}
+	t.manager.update(t.id, func(run *Run) {
+		run.Summary = summary
+		run.Progress.Classified = 0
+		for _, count := range summary {
+			run.Progress.Classified += count
+		}
+	})
+}
+
+// Output records a generated output
+func (t *Tracker) Output(format, data string) {
+	if t == nil {
+		return
+	}
+	t.manager.update(t.id, func(run *Run) {
+		if run.Outputs == nil {
+			run.Outputs = make(map[string]string)
+		}
+		run.Outputs[format] = data
+	})
+}
+
+// Change records the change the run proposed or applied
+func (t *Tracker) Change(id string) {
+	if t == nil {
+		return
+	}
+	t.manager.update(t.id, func(run *Run) {
+		run.ChangeID = id
+	})
+}
@@ -1,18 +1,16 @@
 package ssh
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
-	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -239,6 +237,16 @@ func (c *Client) ExecuteCommand(command string) (string, error) {
 
 // FetchProtocols fetches NBAR protocols from the switch
 func (c *Client) FetchProtocols() ([]string, error) {
+	discovery, err := c.FetchProtocolDiscovery()
+	if err != nil {
+		return nil, err
+	}
+	return discovery.Protocols(), nil
+}
+
+// FetchProtocolDiscovery fetches the NBAR protocol discovery counters from
+// the switch
+func (c *Client) FetchProtocolDiscovery() (*ProtocolDiscovery, error) {
 	c.logger.WithComponent("ssh").WithField("operation", "fetch_protocols").Info("Starting protocol discovery")
 
 	start := time.Now()
@@ -256,169 +264,92 @@ func (c *Client) FetchProtocols() ([]string, error) {
 		return nil, fmt.Errorf("failed to fetch protocols: %w", err)
 	}
 
-	// Parse the output to extract protocol names
-	protocols, err := c.parseProtocolOutput(output)
+	// Parse the per-interface protocol tables
+	discovery, err := ParseProtocolDiscoveryStats(output)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse protocol output: %w", err)
 	}
 
-	c.logger.ProtocolDiscovery(c.config.Host, len(protocols), time.Since(start))
+	c.logger.ProtocolDiscovery(c.config.Host, len(discovery.Protocols()), time.Since(start))
 
-	return protocols, nil
+	return discovery, nil
 }
 
-// parseProtocolOutput parses the NBAR protocol discovery output
-func (c *Client) parseProtocolOutput(output string) ([]string, error) {
-	protocolSet := make(map[string]bool)
-	scanner := bufio.NewScanner(strings.NewReader(output))
-
-	// Look for the command output section
-	cmdOutputStart := strings.Index(output, "show ip nbar protocol-discovery")
-	if cmdOutputStart >= 0 {
-		// Find the end of the command line
-		cmdLineEnd := strings.Index(output[cmdOutputStart:], "\n")
-		if cmdLineEnd >= 0 {
-			// Extract just the command output
-			cmdOutput := output[cmdOutputStart+cmdLineEnd+1:]
-			scanner = bufio.NewScanner(strings.NewReader(cmdOutput))
-		}
-	}
-
-	inProtocolTable := false
-	lineNum := 0
-	protocolHeaderSeen := false
-
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-
-		// Check for interface headers (e.g., "TenGigabitEthernet1/0/1")
-		if strings.Contains(line, "Ethernet") || strings.Contains(line, "GigabitEthernet") {
-			// Reset for new interface section
-			protocolHeaderSeen = false
-			continue
-		}
-
-		// Detect the start of a protocol table by looking for the header
-		if strings.Contains(line, "Protocol") && strings.Contains(line, "Packet Count") {
-			protocolHeaderSeen = true
-			continue
-		}
-
-		// Look for the dashed separator line that comes after the header
-		if protocolHeaderSeen && strings.Contains(line, "-----") {
-			inProtocolTable = true
-			protocolHeaderSeen = false
-			continue
-		}
-
-		// If we're in a protocol table and find a line with "Total", we've reached the end
-		if inProtocolTable && strings.HasPrefix(strings.TrimSpace(line), "Total") {
-			inProtocolTable = false
-			continue
-		}
-
-		// Process protocol lines only when we're in a protocol table
-		if inProtocolTable {
-			protocol := c.extractProtocolName(line, lineNum)
-			if protocol != "" {
-				protocolSet[protocol] = true
-			}
-		}
-	}
+// FetchRunningConfig fetches the running configuration from the switch
+func (c *Client) FetchRunningConfig() (string, error) {
+	c.logger.WithComponent("ssh").WithField("operation", "fetch_config").Info("Fetching running configuration")
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		c.logger.Performance("fetch_config", duration, logger.Fields{
+			"host": c.config.Host,
+		})
+	}()
 
-	// Convert set to sorted slice
-	protocols := make([]string, 0, len(protocolSet))
-	for p := range protocolSet {
-		protocols = append(protocols, p)
+	cmd := "show running-config"
+	output, err := c.ExecuteCommand(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch running config: %w", err)
 	}
-	sort.Strings(protocols)
 
-	return protocols, nil
+	return output, nil
 }
 
-// extractProtocolName extracts a protocol name from a line
-func (c *Client) extractProtocolName(line string, lineNum int) string {
-	// Skip empty lines
-	if strings.TrimSpace(line) == "" {
-		return ""
-	}
-
-	// Protocol names are at the beginning of the line, followed by spaces and numbers
-	fields := strings.Fields(line)
-	if len(fields) == 0 {
-		return ""
-	}
-
-	protocolName := fields[0]
-
-	// Skip lines that are part of the protocol data (they are indented and start with numbers)
-	if len(line) > 0 && line[0] == ' ' && len(fields) > 0 && strings.Contains(fields[0], "0") {
-		return ""
-	}
-
-	// Skip lines with dashes or other non-protocol content
-	if strings.HasPrefix(protocolName, "-") {
-		return ""
-	}
+// FetchInterfaceStatus fetches and parses the interface status table from the switch
+func (c *Client) FetchInterfaceStatus() ([]interfaces.Interface, error) {
+	c.logger.WithComponent("ssh").WithField("operation", "fetch_interfaces").Info("Fetching interface status")
 
-	// Skip very short names (likely not protocols)
-	if len(protocolName) < 3 {
-		c.logger.WithField("protocol", protocolName).Debug("Skipping short protocol name")
-		return ""
-	}
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		c.logger.Performance("fetch_interfaces", duration, logger.Fields{
+			"host": c.config.Host,
+		})
+	}()
 
-	// Skip common words that aren't protocols
-	commonWords := map[string]bool{
-		"Line": true, "There": true, "Your": true, "The": true, "This": true,
-		"From": true, "With": true, "That": true, "Have": true, "For": true,
-		"And": true, "Not": true, "Are": true, "Last": true, "Port": true,
-		"Total": true, "Protocol": true,
-	}
-	if commonWords[protocolName] {
-		c.logger.WithField("protocol", protocolName).Debug("Skipping common word")
-		return ""
+	cmd := "terminal length 0 ; show interfaces status"
+	output, err := c.ExecuteCommand(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch interface status: %w", err)
 	}
 
-	// Additional check: ensure it looks like a valid protocol name
-	// Most Cisco protocol names use lowercase with hyphens
-	validProtocolPattern := regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*[a-z0-9]$`)
-	if !validProtocolPattern.MatchString(protocolName) {
-		c.logger.WithField("protocol", protocolName).Debug("Skipping invalid protocol format")
-		return ""
+	result, err := interfaces.ParseInterfaceStatus(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interface status: %w", err)
 	}
 
-	c.logger.WithFields(logger.Fields{
-		"protocol": protocolName,
-		"line":     lineNum,
-	}).Debug("Found valid protocol")
-
-	return protocolName
+	return result, nil
 }
 
-// FetchRunningConfig fetches the running configuration from the switch
-func (c *Client) FetchRunningConfig() (string, error) {
-	c.logger.WithComponent("ssh").WithField("operation", "fetch_config").Info("Fetching running configuration")
+// FetchDrops fetches the interface and service policy drop counters from the
+// switch
+func (c *Client) FetchDrops() (interfaces.Drops, error) {
+	c.logger.WithComponent("ssh").WithField("operation", "fetch_drops").Info("Fetching drop counters")
 
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
-		c.logger.Performance("fetch_config", duration, logger.Fields{
+		c.logger.Performance("fetch_drops", duration, logger.Fields{
 			"host": c.config.Host,
 		})
 	}()
 
-	cmd := "show running-config"
-	output, err := c.ExecuteCommand(cmd)
+	output, err := c.ExecuteCommand("terminal length 0 ; show interfaces")
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch running config: %w", err)
+		return nil, fmt.Errorf("failed to fetch interface counters: %w", err)
 	}
+	drops := interfaces.ParseInterfaceDrops(output)
 
-	return output, nil
+	output, err = c.ExecuteCommand("terminal length 0 ; show policy-map interface")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy-map counters: %w", err)
+	}
+	for key, value := range interfaces.ParsePolicyMapDrops(output) {
+		drops[key] = value
+	}
+
+	return drops, nil
 }
 
 // PushConfig pushes configuration changes to the switch
@@ -0,0 +1,28 @@
+package ssh
+
+import (
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+)
+
+// Device is the switch CLI used by the classifier. Client implements it over
+// SSH; sshtest.FakeDevice replays recorded transcripts in tests.
+type Device interface {
+	// FetchProtocols returns the protocols seen by NBAR protocol discovery
+	FetchProtocols() ([]string, error)
+	// FetchProtocolDiscovery returns the protocol discovery counters
+	FetchProtocolDiscovery() (*ProtocolDiscovery, error)
+	// FetchRunningConfig returns the running configuration
+	FetchRunningConfig() (string, error)
+	// FetchInterfaceStatus returns the parsed interface status table
+	FetchInterfaceStatus() ([]interfaces.Interface, error)
+	// FetchDrops returns the interface and service policy drop counters
+	FetchDrops() (interfaces.Drops, error)
+	// PushConfig applies configuration commands in configuration mode
+	PushConfig(configCommands string) error
+	// SaveConfig copies the running configuration to startup-config
+	SaveConfig() error
+	// Close releases the connections
+	Close() error
+}
+
+var _ Device = (*Client)(nil)
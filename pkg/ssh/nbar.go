@@ -0,0 +1,332 @@
+package ssh
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validProtocolPattern matches NBAR protocol names, which use lowercase with hyphens
+var validProtocolPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*[a-z0-9]$`)
+
+// interfacePattern matches the interface headers of protocol discovery output,
+// including stack member ports (Gi2/0/1), subinterfaces, port-channels and SVIs
+var interfacePattern = regexp.MustCompile(`^(?:[A-Za-z][A-Za-z-]*\d+(?:/\d+)+(?:\.\d+)?|(?:Port-channel|Po|Vlan|Vl|Loopback|Lo|Tunnel|Tu|BDI|Dialer|Virtual-Access|Virtual-Template)\d+(?:\.\d+)?)$`)
+
+// Terminal artifacts left in captures taken without "terminal length 0"
+var (
+	morePattern = regexp.MustCompile(`\s*--More--\s*`)
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// columnGap separates the columns of a table row
+var columnGap = regexp.MustCompile(`\s{2,}`)
+
+// ProtocolStats holds the counters of one protocol on one interface. Counters
+// missing from the output, e.g. with "stats byte-count", are zero.
+type ProtocolStats struct {
+	Name             string `json:"name"`
+	InputPackets     uint64 `json:"input_packets"`
+	OutputPackets    uint64 `json:"output_packets"`
+	InputBytes       uint64 `json:"input_bytes"`
+	OutputBytes      uint64 `json:"output_bytes"`
+	InputBitRate     uint64 `json:"input_bit_rate"`
+	OutputBitRate    uint64 `json:"output_bit_rate"`
+	InputMaxBitRate  uint64 `json:"input_max_bit_rate"`
+	OutputMaxBitRate uint64 `json:"output_max_bit_rate"`
+}
+
+// InterfaceDiscovery is the protocol table of one interface
+type InterfaceDiscovery struct {
+	Interface string          `json:"interface"`
+	Protocols []ProtocolStats `json:"protocols"`
+}
+
+// ProtocolDiscovery is the parsed output of "show ip nbar protocol-discovery",
+// including its "top-n" and "stats" variants
+type ProtocolDiscovery struct {
+	Interfaces []InterfaceDiscovery `json:"interfaces"`
+}
+
+// Protocols returns the sorted, unique protocol names across all interfaces
+func (d *ProtocolDiscovery) Protocols() []string {
+	protocolSet := make(map[string]bool)
+	for _, iface := range d.Interfaces {
+		for _, stats := range iface.Protocols {
+			protocolSet[stats.Name] = true
+		}
+	}
+
+	protocols := make([]string, 0, len(protocolSet))
+	for p := range protocolSet {
+		protocols = append(protocols, p)
+	}
+	sort.Strings(protocols)
+	return protocols
+}
+
+// Traffic returns the input and output bytes of each protocol summed over
+// all interfaces
+func (d *ProtocolDiscovery) Traffic() map[string]uint64 {
+	traffic := make(map[string]uint64)
+	for _, iface := range d.Interfaces {
+		for _, stats := range iface.Protocols {
+			traffic[stats.Name] += stats.InputBytes + stats.OutputBytes
+		}
+	}
+	return traffic
+}
+
+// ParseProtocolDiscovery extracts the protocol names from the output of
+// "show ip nbar protocol-discovery"
+func ParseProtocolDiscovery(output string) ([]string, error) {
+	discovery, err := ParseProtocolDiscoveryStats(output)
+	if err != nil {
+		return nil, err
+	}
+	return discovery.Protocols(), nil
+}
+
+// statKind identifies the counter reported by one row of a protocol record
+type statKind int
+
+const (
+	statUnknown statKind = iota
+	statPackets
+	statBytes
+	statBitRate
+	statMaxBitRate
+)
+
+// discoveryParser is the state of ParseProtocolDiscoveryStats
+type discoveryParser struct {
+	result  ProtocolDiscovery
+	current *InterfaceDiscovery
+
+	// rows lists the counters of a record, one per line, from the table header
+	rows     []statKind
+	inHeader bool
+	inTable  bool
+
+	// record is the protocol being read and row the next counter line
+	record      *ProtocolStats
+	row         int
+	skipRecord  bool
+	pendingName string
+}
+
+// parse reads the output line by line
+func (p *discoveryParser) parse(output string) error {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	for _, line := range strings.Split(output, "\n") {
+		if err := p.parseLine(cleanLine(line)); err != nil {
+			return err
+		}
+	}
+	p.finishRecord()
+	return nil
+}
+
+// ParseProtocolDiscoveryStats parses the per-interface protocol tables of
+// "show ip nbar protocol-discovery". The counters reported by each record are
+// read from the table header, so the full output, "top-n" and the single
+// counter "stats" variants share one parser. Long protocol names that push
+// their counters onto the next line and "--More--" pager artifacts are
+// handled.
+func ParseProtocolDiscoveryStats(output string) (*ProtocolDiscovery, error) {
+	p := &discoveryParser{}
+	if err := p.parse(output); err != nil {
+		return nil, err
+	}
+	return &p.result, nil
+}
+
+// parseLine handles one line of output
+func (p *discoveryParser) parseLine(line string) error {
+	trimmed := strings.TrimSpace(line)
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, "%") {
+		if strings.Contains(trimmed, "Invalid input") || strings.Contains(trimmed, "Incomplete command") || strings.Contains(trimmed, "Ambiguous command") {
+			return fmt.Errorf("switch rejected the command: %s", trimmed)
+		}
+		return nil
+	}
+
+	// Echoed prompts, e.g. "c9300#show ip nbar protocol-discovery"
+	if strings.Contains(fields[0], "#") || strings.HasSuffix(fields[0], ">") {
+		return nil
+	}
+
+	if len(fields) == 1 && interfacePattern.MatchString(fields[0]) && p.pendingName == "" {
+		p.finishRecord()
+		p.result.Interfaces = append(p.result.Interfaces, InterfaceDiscovery{Interface: fields[0], Protocols: []ProtocolStats{}})
+		p.current = &p.result.Interfaces[len(p.result.Interfaces)-1]
+		p.inHeader, p.inTable = false, false
+		return nil
+	}
+
+	if fields[0] == "Protocol" {
+		p.finishRecord()
+		p.rows = []statKind{parseStatLabel(strings.TrimSpace(strings.TrimPrefix(trimmed, "Protocol")))}
+		p.inHeader, p.inTable = true, false
+		return nil
+	}
+
+	if isSeparator(trimmed) {
+		if p.inHeader {
+			p.inHeader, p.inTable = false, true
+		}
+		return nil
+	}
+
+	if p.inHeader {
+		p.rows = append(p.rows, parseStatLabel(trimmed))
+		return nil
+	}
+
+	if p.inTable {
+		p.parseTableLine(fields)
+	}
+	return nil
+}
+
+// parseTableLine handles a line inside a protocol table: either the first
+// line of a record, which starts with the protocol name, or a counter line
+func (p *discoveryParser) parseTableLine(fields []string) {
+	if _, err := parseCounter(fields[0]); err == nil {
+		if pending := p.takePending(); pending != "" {
+			p.startRecord(pending)
+		}
+		p.addCounters(fields)
+		return
+	}
+
+	name := fields[0]
+	if pending := p.takePending(); pending != "" {
+		// A name wrapped at a hyphen continues on the next line
+		if strings.HasSuffix(pending, "-") {
+			name = pending + name
+		} else {
+			p.startRecord(pending)
+		}
+	}
+
+	if len(fields) == 1 {
+		// The counters follow on the next line
+		p.storeRecord()
+		p.pendingName = name
+		return
+	}
+	p.startRecord(name)
+	p.addCounters(fields[1:])
+}
+
+// startRecord begins a protocol record; totals and invalid names are skipped
+func (p *discoveryParser) startRecord(name string) {
+	p.storeRecord()
+	p.row = 0
+	p.skipRecord = name == "Total" || !validProtocolPattern.MatchString(name)
+	p.record = &ProtocolStats{Name: name}
+}
+
+// addCounters stores the input and output values of the next counter line
+func (p *discoveryParser) addCounters(fields []string) {
+	if p.record == nil {
+		return
+	}
+	defer func() { p.row++ }()
+	if p.row >= len(p.rows) {
+		return
+	}
+
+	var input, output uint64
+	if len(fields) > 0 {
+		input, _ = parseCounter(fields[0])
+	}
+	if len(fields) > 1 {
+		output, _ = parseCounter(fields[1])
+	}
+
+	switch p.rows[p.row] {
+	case statPackets:
+		p.record.InputPackets, p.record.OutputPackets = input, output
+	case statBytes:
+		p.record.InputBytes, p.record.OutputBytes = input, output
+	case statBitRate:
+		p.record.InputBitRate, p.record.OutputBitRate = input, output
+	case statMaxBitRate:
+		p.record.InputMaxBitRate, p.record.OutputMaxBitRate = input, output
+	}
+}
+
+// takePending returns and clears a protocol name still waiting for counters
+func (p *discoveryParser) takePending() string {
+	name := p.pendingName
+	p.pendingName = ""
+	return name
+}
+
+// finishRecord stores the record being read, including a pending name
+func (p *discoveryParser) finishRecord() {
+	if pending := p.takePending(); pending != "" {
+		p.startRecord(pending)
+	}
+	p.storeRecord()
+}
+
+// storeRecord adds the record being read to the current interface
+func (p *discoveryParser) storeRecord() {
+	if p.record == nil {
+		return
+	}
+	if !p.skipRecord {
+		if p.current == nil {
+			// Output of a single interface without its header
+			p.result.Interfaces = append(p.result.Interfaces, InterfaceDiscovery{})
+			p.current = &p.result.Interfaces[len(p.result.Interfaces)-1]
+		}
+		p.current.Protocols = append(p.current.Protocols, *p.record)
+	}
+	p.record = nil
+}
+
+// parseStatLabel identifies the counter of a header line such as
+// "Byte Count   Byte Count" or "30sec Max Bit Rate (bps) 30sec Max Bit Rate (bps)"
+func parseStatLabel(header string) statKind {
+	label := columnGap.Split(header, 2)[0]
+	switch {
+	case strings.Contains(label, "Max Bit Rate"):
+		return statMaxBitRate
+	case strings.Contains(label, "Bit Rate"):
+		return statBitRate
+	case strings.Contains(label, "Byte Count"):
+		return statBytes
+	case strings.Contains(label, "Packet Count"):
+		return statPackets
+	}
+	return statUnknown
+}
+
+// parseCounter parses a counter value
+func parseCounter(field string) (uint64, error) {
+	return strconv.ParseUint(field, 10, 64)
+}
+
+// isSeparator reports whether a line is a table rule such as "-----  ------"
+func isSeparator(line string) bool {
+	return strings.Trim(line, "- ") == "" && strings.Contains(line, "---")
+}
+
+// cleanLine removes pager prompts, backspaces and escape sequences
+func cleanLine(line string) string {
+	line = ansiPattern.ReplaceAllString(line, "")
+	line = morePattern.ReplaceAllString(line, " ")
+	line = strings.ReplaceAll(line, "\b", "")
+	return strings.TrimRight(line, "\r")
+}
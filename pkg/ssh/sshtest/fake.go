@@ -0,0 +1,160 @@
+package sshtest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh"
+)
+
+// Commands run by the ssh.Client for each Device method
+const (
+	ProtocolDiscoveryCommand = "show ip nbar protocol-discovery"
+	RunningConfigCommand     = "show running-config"
+	InterfaceStatusCommand   = "show interfaces status"
+	InterfaceCountersCommand = "show interfaces"
+	PolicyMapCommand         = "show policy-map interface"
+)
+
+// FakeDevice is an ssh.Device that answers from a transcript and records the
+// configuration pushed to it
+type FakeDevice struct {
+	// PushError and SaveError are returned by PushConfig and SaveConfig when set
+	PushError error
+	SaveError error
+
+	mu         sync.Mutex
+	transcript *Transcript
+	commands   []string
+	pushed     []string
+	saves      int
+	closed     bool
+}
+
+var _ ssh.Device = (*FakeDevice)(nil)
+
+// NewFakeDevice creates a fake device replaying the transcript
+func NewFakeDevice(transcript *Transcript) *FakeDevice {
+	return &FakeDevice{transcript: transcript}
+}
+
+// run returns the recorded output of a command
+func (d *FakeDevice) run(command string) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return "", fmt.Errorf("device is closed")
+	}
+	d.commands = append(d.commands, command)
+	output, exists := d.transcript.Output(command)
+	if !exists {
+		return "", fmt.Errorf("command %q is not in the transcript of %s", command, d.transcript.Hostname)
+	}
+	return output, nil
+}
+
+// FetchProtocols parses the recorded protocol discovery output
+func (d *FakeDevice) FetchProtocols() ([]string, error) {
+	output, err := d.run(ProtocolDiscoveryCommand)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParseProtocolDiscovery(output)
+}
+
+// FetchProtocolDiscovery parses the recorded protocol discovery counters
+func (d *FakeDevice) FetchProtocolDiscovery() (*ssh.ProtocolDiscovery, error) {
+	output, err := d.run(ProtocolDiscoveryCommand)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParseProtocolDiscoveryStats(output)
+}
+
+// FetchRunningConfig returns the recorded running configuration
+func (d *FakeDevice) FetchRunningConfig() (string, error) {
+	return d.run(RunningConfigCommand)
+}
+
+// FetchInterfaceStatus parses the recorded interface status table
+func (d *FakeDevice) FetchInterfaceStatus() ([]interfaces.Interface, error) {
+	output, err := d.run(InterfaceStatusCommand)
+	if err != nil {
+		return nil, err
+	}
+	return interfaces.ParseInterfaceStatus(output)
+}
+
+// FetchDrops parses the recorded interface and policy-map counters
+func (d *FakeDevice) FetchDrops() (interfaces.Drops, error) {
+	output, err := d.run(InterfaceCountersCommand)
+	if err != nil {
+		return nil, err
+	}
+	drops := interfaces.ParseInterfaceDrops(output)
+
+	output, err = d.run(PolicyMapCommand)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range interfaces.ParsePolicyMapDrops(output) {
+		drops[key] = value
+	}
+	return drops, nil
+}
+
+// PushConfig records the configuration commands
+func (d *FakeDevice) PushConfig(configCommands string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.PushError != nil {
+		return d.PushError
+	}
+	d.pushed = append(d.pushed, configCommands)
+	return nil
+}
+
+// SaveConfig counts the save
+func (d *FakeDevice) SaveConfig() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.SaveError != nil {
+		return d.SaveError
+	}
+	d.saves++
+	return nil
+}
+
+// Close marks the device closed
+func (d *FakeDevice) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	return nil
+}
+
+// Commands returns the show commands run so far
+func (d *FakeDevice) Commands() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.commands...)
+}
+
+// Pushed returns the configuration pushed so far
+func (d *FakeDevice) Pushed() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.pushed...)
+}
+
+// Saves returns the number of saves
+func (d *FakeDevice) Saves() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.saves
+}
@@ -0,0 +1,277 @@
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+// User is the username accepted by the server
+const User = "admin"
+
+// IOS-XE CLI responses
+const (
+	invalidInput = "% Invalid input detected at '^' marker.\n"
+	configPrompt = "Enter configuration commands, one per line.  End with CNTL/Z.\n"
+	saveOutput   = "Building configuration...\n[OK]\n"
+)
+
+// Server is an in-process SSH server that emulates the IOS-XE exec CLI. Show
+// commands are answered from a transcript, "configure terminal" blocks are
+// recorded and "write memory" is counted.
+type Server struct {
+	Host string
+	Port string
+	// ClientKey is the PEM private key the server accepts for User
+	ClientKey string
+
+	transcript *Transcript
+	listener   net.Listener
+	config     *cryptossh.ServerConfig
+	wg         sync.WaitGroup
+
+	mu           sync.Mutex
+	conns        map[net.Conn]bool
+	rejectConfig string
+	commands     []string
+	pushed       []string
+	saves        int
+}
+
+// NewServer starts a server on a random local port
+func NewServer(transcript *Transcript) (*Server, error) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate host key: %w", err)
+	}
+	hostSigner, err := cryptossh.NewSignerFromKey(hostKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host key signer: %w", err)
+	}
+
+	clientPublic, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client key: %w", err)
+	}
+	authorized, err := cryptossh.NewPublicKey(clientPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client public key: %w", err)
+	}
+	block, err := cryptossh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal client key: %w", err)
+	}
+
+	s := &Server{
+		ClientKey:  string(pem.EncodeToMemory(block)),
+		transcript: transcript,
+		conns:      make(map[net.Conn]bool),
+	}
+	s.config = &cryptossh.ServerConfig{
+		PublicKeyCallback: func(conn cryptossh.ConnMetadata, key cryptossh.PublicKey) (*cryptossh.Permissions, error) {
+			if conn.User() == User && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied")
+		},
+	}
+	s.config.AddHostKey(hostSigner)
+
+	s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	s.Host, s.Port, _ = net.SplitHostPort(s.listener.Addr().String())
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// SSHConfig returns client settings that connect to the server
+func (s *Server) SSHConfig() config.SSHConfig {
+	return config.SSHConfig{
+		Host:               s.Host,
+		Port:               s.Port,
+		User:               User,
+		KeyFile:            s.ClientKey,
+		Timeout:            5 * time.Second,
+		MaxConnections:     5,
+		ConnectionPoolSize: 3,
+		KeepAlive:          30 * time.Second,
+	}
+}
+
+// RejectConfig makes configuration pushes containing the text fail the way
+// IOS-XE rejects an invalid command
+func (s *Server) RejectConfig(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectConfig = text
+}
+
+// Commands returns the exec commands received so far
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Pushed returns the configuration blocks received so far
+func (s *Server) Pushed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.pushed...)
+}
+
+// Saves returns the number of "write memory" commands received
+func (s *Server) Saves() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+// Close stops the server and closes open connections
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// serve accepts connections until the listener is closed
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn runs the SSH handshake and serves session channels
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	_, channels, requests, err := cryptossh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	go cryptossh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(cryptossh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		s.wg.Add(1)
+		go s.handleSession(channel, channelRequests)
+	}
+}
+
+// handleSession answers the exec request of a session
+func (s *Server) handleSession(channel cryptossh.Channel, requests <-chan *cryptossh.Request) {
+	defer s.wg.Done()
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type != "exec" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := cryptossh.Unmarshal(req.Payload, &payload); err != nil {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		_ = req.Reply(true, nil)
+
+		output, status := s.exec(payload.Command)
+		_, _ = channel.Write([]byte(output))
+		_, _ = channel.SendRequest("exit-status", false, cryptossh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+// exec runs a command line and returns its output and exit status
+func (s *Server) exec(commandLine string) (string, uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.HasPrefix(commandLine, "configure terminal") {
+		return s.configure(commandLine)
+	}
+
+	// The client chains commands with ";"
+	var output strings.Builder
+	for _, command := range strings.Split(commandLine, ";") {
+		command = normalizeCommand(command)
+		if command == "" {
+			continue
+		}
+		s.commands = append(s.commands, command)
+
+		switch {
+		case strings.HasPrefix(command, "terminal length"):
+		case command == "write memory" || command == "copy running-config startup-config":
+			s.saves++
+			output.WriteString(saveOutput)
+		default:
+			recorded, exists := s.transcript.Output(command)
+			if !exists {
+				output.WriteString(invalidInput)
+				return output.String(), 1
+			}
+			output.WriteString(recorded)
+		}
+	}
+	return output.String(), 0
+}
+
+// configure records a "configure terminal ... end" block
+func (s *Server) configure(commandLine string) (string, uint32) {
+	var lines []string
+	for _, line := range strings.Split(commandLine, "\n")[1:] {
+		if strings.TrimSpace(line) == "end" {
+			break
+		}
+		lines = append(lines, line)
+	}
+	block := strings.Join(lines, "\n")
+
+	if s.rejectConfig != "" && strings.Contains(block, s.rejectConfig) {
+		return configPrompt + invalidInput, 1
+	}
+	s.pushed = append(s.pushed, block)
+	return configPrompt, 0
+}
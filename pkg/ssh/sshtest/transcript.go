@@ -0,0 +1,103 @@
+// Package sshtest provides a scripted fake switch and an in-process SSH server
+// that emulates the IOS-XE CLI from recorded session transcripts.
+package sshtest
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// promptLine matches an exec prompt followed by a command, e.g. "c9300#show version"
+var promptLine = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)#(.*)$`)
+
+// Transcript is a recorded CLI session: the output of each command run on a
+// switch, keyed by the command as typed after the prompt
+type Transcript struct {
+	Hostname string
+	outputs  map[string]string
+	commands []string
+}
+
+// ParseTranscript parses a session capture. Every line that starts with the
+// exec prompt begins a command; the lines up to the next prompt are its
+// output. The hostname is taken from the first prompt.
+func ParseTranscript(data string) (*Transcript, error) {
+	t := &Transcript{outputs: make(map[string]string)}
+
+	var command string
+	var output []string
+	inCommand := false
+	flush := func() {
+		if inCommand {
+			if _, exists := t.outputs[command]; !exists {
+				t.commands = append(t.commands, command)
+			}
+			t.outputs[command] = joinOutput(output)
+		}
+		output = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if m := promptLine.FindStringSubmatch(line); m != nil && (t.Hostname == "" || m[1] == t.Hostname) {
+			flush()
+			t.Hostname = m[1]
+			command = normalizeCommand(m[2])
+			inCommand = command != ""
+			continue
+		}
+		if inCommand {
+			output = append(output, line)
+		}
+	}
+	flush()
+
+	if len(t.commands) == 0 {
+		return nil, fmt.Errorf("transcript contains no commands")
+	}
+	return t, nil
+}
+
+// LoadTranscript reads and parses a transcript file
+func LoadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	t, err := ParseTranscript(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Output returns the recorded output of a command
+func (t *Transcript) Output(command string) (string, bool) {
+	output, exists := t.outputs[normalizeCommand(command)]
+	return output, exists
+}
+
+// Commands returns the recorded commands in transcript order
+func (t *Transcript) Commands() []string {
+	return append([]string(nil), t.commands...)
+}
+
+// normalizeCommand collapses whitespace so commands match regardless of spacing
+func normalizeCommand(command string) string {
+	return strings.Join(strings.Fields(command), " ")
+}
+
+// joinOutput joins output lines, dropping leading and trailing blank lines
+func joinOutput(lines []string) string {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,369 @@
+package transport
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+)
+
+// ClassMap is a QoS class-map
+type ClassMap struct {
+	Name        string
+	Prematch    string
+	Description string
+	Protocols   []string
+	DSCP        []string
+}
+
+// PolicyClass is a class entry of a policy-map with its actions
+type PolicyClass struct {
+	Name                      string
+	SetDSCP                   string
+	PriorityLevel             int
+	PolicePercent             int
+	BandwidthRemainingPercent int
+	QueueBuffersRatio         int
+}
+
+// PolicyMap is a QoS policy-map
+type PolicyMap struct {
+	Name        string
+	Description string
+	Classes     []PolicyClass
+}
+
+// QoSConfig is the structured form of the generated QoS configuration
+type QoSConfig struct {
+	ClassMaps   []ClassMap
+	PolicyMaps  []PolicyMap
+	Attachments []interfaces.Change
+}
+
+var (
+	classMapLine   = regexp.MustCompile(`^class-map(?:\s+(match-any|match-all))?\s+(\S+)$`)
+	policyMapLine  = regexp.MustCompile(`^policy-map\s+(\S+)$`)
+	interfaceLine  = regexp.MustCompile(`^interface\s+(\S+)$`)
+	servicePolicy  = regexp.MustCompile(`^(no\s+)?service-policy\s+(input|output)\s+(\S+)$`)
+	priorityLevel  = regexp.MustCompile(`^priority level (\d+)$`)
+	policePercent  = regexp.MustCompile(`^police rate percent (\d+)$`)
+	remainingLine  = regexp.MustCompile(`^bandwidth remaining percent (\d+)$`)
+	queueBuffers   = regexp.MustCompile(`^queue-buffers ratio (\d+)$`)
+	interfaceParts = regexp.MustCompile(`^([A-Za-z-]+)(\d\S*)$`)
+)
+
+// ParseCLI converts rendered IOS-XE QoS configuration into its structured
+// form. Only the statements the Cisco templates generate are supported;
+// anything else is rejected so a push never silently drops configuration.
+func ParseCLI(config string) (*QoSConfig, error) {
+	result := &QoSConfig{}
+
+	var classMap *ClassMap
+	var policyMap *PolicyMap
+	var policyClass *PolicyClass
+	var iface string
+
+	flush := func() {
+		if classMap != nil {
+			result.ClassMaps = append(result.ClassMaps, *classMap)
+			classMap = nil
+		}
+		if policyMap != nil {
+			if policyClass != nil {
+				policyMap.Classes = append(policyMap.Classes, *policyClass)
+				policyClass = nil
+			}
+			result.PolicyMaps = append(result.PolicyMaps, *policyMap)
+			policyMap = nil
+		}
+		iface = ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(config))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		text := strings.TrimSpace(line)
+		if text == "" || strings.HasPrefix(text, "!") || text == "end" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if indent == 0 {
+			flush()
+			if m := classMapLine.FindStringSubmatch(text); m != nil {
+				prematch := m[1]
+				if prematch == "" {
+					prematch = "match-all"
+				}
+				classMap = &ClassMap{Name: m[2], Prematch: prematch}
+			} else if m := policyMapLine.FindStringSubmatch(text); m != nil {
+				policyMap = &PolicyMap{Name: m[1]}
+			} else if m := interfaceLine.FindStringSubmatch(text); m != nil {
+				iface = m[1]
+			} else {
+				return nil, fmt.Errorf("line %d: unsupported statement %q", lineNum, text)
+			}
+			continue
+		}
+
+		switch {
+		case classMap != nil:
+			if err := parseClassMapLine(classMap, text); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+		case policyMap != nil && indent == 1:
+			if description, ok := strings.CutPrefix(text, "description "); ok {
+				policyMap.Description = description
+				continue
+			}
+			name, ok := strings.CutPrefix(text, "class ")
+			if !ok {
+				return nil, fmt.Errorf("line %d: unsupported policy-map statement %q", lineNum, text)
+			}
+			if policyClass != nil {
+				policyMap.Classes = append(policyMap.Classes, *policyClass)
+			}
+			policyClass = &PolicyClass{Name: name}
+		case policyClass != nil:
+			if err := parsePolicyClassLine(policyClass, text); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNum, err)
+			}
+		case iface != "":
+			m := servicePolicy.FindStringSubmatch(text)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: unsupported interface statement %q", lineNum, text)
+			}
+			result.Attachments = append(result.Attachments, interfaces.Change{
+				Interface: iface,
+				Direction: interfaces.Direction(m[2]),
+				Policy:    m[3],
+				Remove:    m[1] != "",
+			})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected indented statement %q", lineNum, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	flush()
+
+	return result, nil
+}
+
+// ParseRunningClassMaps extracts the class-maps from a running
+// configuration. Unlike ParseCLI it skips everything it does not understand,
+// including class-map statements the templates never generate.
+func ParseRunningClassMaps(runningConfig string) []ClassMap {
+	var classMaps []ClassMap
+	var classMap *ClassMap
+	for _, line := range strings.Split(runningConfig, "\n") {
+		line = strings.TrimRight(line, "\r")
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			if classMap != nil {
+				classMaps = append(classMaps, *classMap)
+				classMap = nil
+			}
+			if m := classMapLine.FindStringSubmatch(text); m != nil {
+				prematch := m[1]
+				if prematch == "" {
+					prematch = "match-all"
+				}
+				classMap = &ClassMap{Name: m[2], Prematch: prematch}
+			}
+			continue
+		}
+		if classMap != nil {
+			_ = parseClassMapLine(classMap, text)
+		}
+	}
+	if classMap != nil {
+		classMaps = append(classMaps, *classMap)
+	}
+	return classMaps
+}
+
+// ParseRunningPolicyMaps extracts the policy-maps from a running
+// configuration. Like ParseRunningClassMaps it skips everything it does not
+// understand, including class actions the templates never generate.
+func ParseRunningPolicyMaps(runningConfig string) []PolicyMap {
+	var policyMaps []PolicyMap
+	var policyMap *PolicyMap
+	var policyClass *PolicyClass
+	flush := func() {
+		if policyMap == nil {
+			return
+		}
+		if policyClass != nil {
+			policyMap.Classes = append(policyMap.Classes, *policyClass)
+			policyClass = nil
+		}
+		policyMaps = append(policyMaps, *policyMap)
+		policyMap = nil
+	}
+
+	for _, line := range strings.Split(runningConfig, "\n") {
+		line = strings.TrimRight(line, "\r")
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == 0 {
+			flush()
+			if m := policyMapLine.FindStringSubmatch(text); m != nil {
+				policyMap = &PolicyMap{Name: m[1]}
+			}
+			continue
+		}
+		if policyMap == nil {
+			continue
+		}
+		if indent == 1 {
+			if description, ok := strings.CutPrefix(text, "description "); ok {
+				policyMap.Description = description
+			} else if name, ok := strings.CutPrefix(text, "class "); ok {
+				if policyClass != nil {
+					policyMap.Classes = append(policyMap.Classes, *policyClass)
+				}
+				policyClass = &PolicyClass{Name: name}
+			}
+			continue
+		}
+		if policyClass != nil {
+			_ = parsePolicyClassLine(policyClass, text)
+		}
+	}
+	flush()
+	return policyMaps
+}
+
+// parseClassMapLine applies a class-map sub-command
+func parseClassMapLine(classMap *ClassMap, text string) error {
+	if description, ok := strings.CutPrefix(text, "description "); ok {
+		classMap.Description = description
+		return nil
+	}
+	if protocol, ok := strings.CutPrefix(text, "match protocol "); ok {
+		classMap.Protocols = append(classMap.Protocols, protocol)
+		return nil
+	}
+	if dscp, ok := strings.CutPrefix(text, "match dscp "); ok {
+		classMap.DSCP = append(classMap.DSCP, strings.Fields(dscp)...)
+		return nil
+	}
+	return fmt.Errorf("unsupported class-map statement %q", text)
+}
+
+// parsePolicyClassLine applies a policy-map class action
+func parsePolicyClassLine(class *PolicyClass, text string) error {
+	if dscp, ok := strings.CutPrefix(text, "set dscp "); ok {
+		class.SetDSCP = dscp
+		return nil
+	}
+	for _, action := range []struct {
+		pattern *regexp.Regexp
+		value   *int
+	}{
+		{priorityLevel, &class.PriorityLevel},
+		{policePercent, &class.PolicePercent},
+		{remainingLine, &class.BandwidthRemainingPercent},
+		{queueBuffers, &class.QueueBuffersRatio},
+	} {
+		if m := action.pattern.FindStringSubmatch(text); m != nil {
+			value, _ := strconv.Atoi(m[1])
+			*action.value = value
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported policy-map class action %q", text)
+}
+
+// splitInterface splits an interface name into the YANG list type and key,
+// e.g. GigabitEthernet1/0/1 into GigabitEthernet and 1/0/1
+func splitInterface(name string) (string, string, error) {
+	m := interfaceParts.FindStringSubmatch(name)
+	if m == nil {
+		return "", "", fmt.Errorf("cannot split interface name %q", name)
+	}
+	return m[1], m[2], nil
+}
+
+// ChangedClassMaps returns the names of the desired class-maps that are
+// missing or differ from the current ones
+func ChangedClassMaps(current, desired []ClassMap) []string {
+	existing := make(map[string]ClassMap, len(current))
+	for _, classMap := range current {
+		existing[classMap.Name] = classMap
+	}
+
+	var changed []string
+	for _, classMap := range desired {
+		old, exists := existing[classMap.Name]
+		if !exists || old.Prematch != classMap.Prematch || old.Description != classMap.Description ||
+			!equalSets(old.Protocols, classMap.Protocols) || !equalSets(old.DSCP, classMap.DSCP) {
+			changed = append(changed, classMap.Name)
+		}
+	}
+	return changed
+}
+
+// ChangedPolicyMaps returns the names of the desired policy-maps that are
+// missing or differ from the current ones. Classes are compared in order,
+// since the first matching class of a policy-map wins.
+func ChangedPolicyMaps(current, desired []PolicyMap) []string {
+	existing := make(map[string]PolicyMap, len(current))
+	for _, policyMap := range current {
+		existing[policyMap.Name] = policyMap
+	}
+
+	var changed []string
+	for _, policyMap := range desired {
+		old, exists := existing[policyMap.Name]
+		if !exists || old.Description != policyMap.Description || !equalClasses(old.Classes, policyMap.Classes) {
+			changed = append(changed, policyMap.Name)
+		}
+	}
+	return changed
+}
+
+// equalClasses reports whether two policy-maps have the same classes and
+// actions in the same order
+func equalClasses(a, b []PolicyClass) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalSets reports whether two string slices contain the same values
+func equalSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[string]int, len(a))
+	for _, value := range a {
+		counts[value]++
+	}
+	for _, value := range b {
+		counts[value]--
+		if counts[value] < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,525 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// NETCONF capabilities
+const (
+	capabilityBase10          = "urn:ietf:params:netconf:base:1.0"
+	capabilityBase11          = "urn:ietf:params:netconf:base:1.1"
+	capabilityCandidate       = "urn:ietf:params:netconf:capability:candidate:"
+	capabilityConfirmedCommit = "urn:ietf:params:netconf:capability:confirmed-commit:"
+	capabilityValidate        = "urn:ietf:params:netconf:capability:validate:"
+)
+
+// endOfMessage terminates NETCONF 1.0 messages and the hello exchange
+const endOfMessage = "]]>]]>"
+
+// RPCError is an rpc-error returned by the device
+type RPCError struct {
+	Type     string `xml:"error-type"`
+	Tag      string `xml:"error-tag"`
+	Severity string `xml:"error-severity"`
+	Path     string `xml:"error-path"`
+	Message  string `xml:"error-message"`
+}
+
+// Error implements the error interface
+func (e *RPCError) Error() string {
+	message := strings.TrimSpace(e.Message)
+	if message == "" {
+		message = e.Tag
+	}
+	if path := strings.TrimSpace(e.Path); path != "" {
+		return fmt.Sprintf("netconf %s error: %s (%s)", e.Type, message, path)
+	}
+	return fmt.Sprintf("netconf %s error: %s", e.Type, message)
+}
+
+// rpcReply is a NETCONF rpc-reply
+type rpcReply struct {
+	XMLName   xml.Name   `xml:"rpc-reply"`
+	MessageID string     `xml:"message-id,attr"`
+	Errors    []RPCError `xml:"rpc-error"`
+	Data      struct {
+		Native yangNative `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-native native"`
+	} `xml:"data"`
+}
+
+// netconfHello is the hello message exchanged at session start
+type netconfHello struct {
+	XMLName      xml.Name `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 hello"`
+	Capabilities []string `xml:"capabilities>capability"`
+	SessionID    string   `xml:"session-id,omitempty"`
+}
+
+// netconfSession is a NETCONF session over the SSH "netconf" subsystem
+type netconfSession struct {
+	client       *ssh.Client
+	session      *ssh.Session
+	reader       *bufio.Reader
+	writer       io.Writer
+	chunked      bool
+	capabilities []string
+	messageID    int
+}
+
+// dialNETCONF opens an SSH connection and starts the NETCONF subsystem
+func dialNETCONF(opts Options) (*netconfSession, error) {
+	var auth []ssh.AuthMethod
+	if opts.KeyFile != "" {
+		key, err := privateKey(opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if opts.Password != "" {
+		auth = append(auth, ssh.Password(opts.Password))
+	}
+
+	addr := net.JoinHostPort(opts.Host, opts.Port)
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            opts.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         opts.Timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	s, err := newNETCONFSession(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// newNETCONFSession starts the subsystem and exchanges hello messages
+func newNETCONFSession(client *ssh.Client) (*netconfSession, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open NETCONF input: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open NETCONF output: %w", err)
+	}
+	if err := session.RequestSubsystem("netconf"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start NETCONF subsystem: %w", err)
+	}
+
+	s := &netconfSession{client: client, session: session, reader: bufio.NewReader(stdout), writer: stdin}
+
+	hello, err := xml.Marshal(netconfHello{Capabilities: []string{capabilityBase10, capabilityBase11}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hello: %w", err)
+	}
+	if err := s.write(hello); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	data, err := s.read()
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to read NETCONF hello: %w", err)
+	}
+	var serverHello netconfHello
+	if err := xml.Unmarshal(data, &serverHello); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to parse NETCONF hello: %w", err)
+	}
+	s.capabilities = serverHello.Capabilities
+	// Chunked framing is used once both peers advertise base:1.1
+	s.chunked = s.supports(capabilityBase11)
+
+	return s, nil
+}
+
+// supports reports whether the device advertised a capability
+func (s *netconfSession) supports(capability string) bool {
+	for _, advertised := range s.capabilities {
+		if strings.HasPrefix(strings.TrimSpace(advertised), capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// write sends a message with the negotiated framing
+func (s *netconfSession) write(message []byte) error {
+	var frame bytes.Buffer
+	if s.chunked {
+		fmt.Fprintf(&frame, "\n#%d\n", len(message))
+		frame.Write(message)
+		frame.WriteString("\n##\n")
+	} else {
+		frame.Write(message)
+		frame.WriteString(endOfMessage)
+	}
+	if _, err := s.writer.Write(frame.Bytes()); err != nil {
+		return fmt.Errorf("failed to send NETCONF message: %w", err)
+	}
+	return nil
+}
+
+// read receives a message with the negotiated framing
+func (s *netconfSession) read() ([]byte, error) {
+	if !s.chunked {
+		var message []byte
+		for {
+			b, err := s.reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			message = append(message, b)
+			if bytes.HasSuffix(message, []byte(endOfMessage)) {
+				return bytes.TrimSpace(message[:len(message)-len(endOfMessage)]), nil
+			}
+		}
+	}
+
+	var message []byte
+	for {
+		// Each chunk header is "\n#<size>\n"; "\n##\n" ends the message
+		header, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if header == "\n" {
+			if header, err = s.reader.ReadString('\n'); err != nil {
+				return nil, err
+			}
+		}
+		header = strings.TrimSpace(header)
+		if header == "##" {
+			return message, nil
+		}
+		if !strings.HasPrefix(header, "#") {
+			return nil, fmt.Errorf("invalid NETCONF chunk header %q", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid NETCONF chunk size %q", header)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(s.reader, chunk); err != nil {
+			return nil, err
+		}
+		message = append(message, chunk...)
+	}
+}
+
+// rpc sends an operation and returns the reply, failing on rpc-errors
+func (s *netconfSession) rpc(ctx context.Context, operation string) (*rpcReply, error) {
+	s.messageID++
+	id := strconv.Itoa(s.messageID)
+	request := fmt.Sprintf(`<rpc message-id="%s" xmlns="%s">%s</rpc>`, id, netconfNamespace, operation)
+
+	type response struct {
+		data []byte
+		err  error
+	}
+	done := make(chan response, 1)
+	go func() {
+		if err := s.write([]byte(request)); err != nil {
+			done <- response{err: err}
+			return
+		}
+		data, err := s.read()
+		done <- response{data: data, err: err}
+	}()
+
+	var resp response
+	select {
+	case <-ctx.Done():
+		// The session cannot be reused once a reply is abandoned
+		s.close()
+		return nil, ctx.Err()
+	case resp = <-done:
+	}
+	if resp.err != nil {
+		return nil, fmt.Errorf("failed to read NETCONF reply: %w", resp.err)
+	}
+
+	var reply rpcReply
+	if err := xml.Unmarshal(resp.data, &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse NETCONF reply: %w", err)
+	}
+	if reply.MessageID != id {
+		return nil, fmt.Errorf("NETCONF reply message-id %q does not match request %s", reply.MessageID, id)
+	}
+	for i := range reply.Errors {
+		if reply.Errors[i].Severity != "warning" {
+			return nil, &reply.Errors[i]
+		}
+	}
+	return &reply, nil
+}
+
+// close ends the session and the SSH connection
+func (s *netconfSession) close() error {
+	s.session.Close()
+	return s.client.Close()
+}
+
+// NETCONF is a Transport using NETCONF over SSH
+type NETCONF struct {
+	options Options
+	mu      sync.Mutex
+	session *netconfSession
+}
+
+// newNETCONF creates a NETCONF transport; the session is opened on first use
+func newNETCONF(opts Options) (*NETCONF, error) {
+	if opts.KeyFile == "" && opts.Password == "" {
+		return nil, fmt.Errorf("NETCONF requires a password or SSH key")
+	}
+	return &NETCONF{options: opts}, nil
+}
+
+// Name returns the transport type
+func (n *NETCONF) Name() string {
+	return TypeNETCONF
+}
+
+// connect returns the open session, dialing the device if needed
+func (n *NETCONF) connect() (*netconfSession, error) {
+	if n.session == nil {
+		session, err := dialNETCONF(n.options)
+		if err != nil {
+			return nil, err
+		}
+		n.session = session
+	}
+	return n.session, nil
+}
+
+// call runs an operation, dropping the session when it fails at the
+// transport level so the next call reconnects
+func (n *NETCONF) call(ctx context.Context, operation string) (*rpcReply, error) {
+	session, err := n.connect()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := session.rpc(ctx, operation)
+	if err != nil {
+		if _, isRPCError := err.(*RPCError); !isRPCError {
+			session.close()
+			n.session = nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// getPolicy reads the native policy container from the running datastore
+func (n *NETCONF) getPolicy(ctx context.Context, node string) (*yangPolicy, error) {
+	filter := fmt.Sprintf(`<native xmlns="%s"><policy><%s xmlns="%s"/></policy></native>`, nativeNamespace, node, policyNamespace)
+	reply, err := n.call(ctx, `<get-config><source><running/></source><filter type="subtree">`+filter+`</filter></get-config>`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s configuration: %w", node, err)
+	}
+	if reply.Data.Native.Policy == nil {
+		return &yangPolicy{}, nil
+	}
+	return reply.Data.Native.Policy, nil
+}
+
+// ClassMaps returns the class-maps in the running configuration
+func (n *NETCONF) ClassMaps(ctx context.Context) ([]ClassMap, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	policy, err := n.getPolicy(ctx, "class-map")
+	if err != nil {
+		return nil, err
+	}
+	classMaps := make([]ClassMap, 0, len(policy.ClassMaps))
+	for _, entry := range policy.ClassMaps {
+		classMaps = append(classMaps, classMapFromYANG(entry))
+	}
+	return classMaps, nil
+}
+
+// PolicyMaps returns the policy-maps in the running configuration
+func (n *NETCONF) PolicyMaps(ctx context.Context) ([]PolicyMap, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	policy, err := n.getPolicy(ctx, "policy-map")
+	if err != nil {
+		return nil, err
+	}
+	policyMaps := make([]PolicyMap, 0, len(policy.PolicyMaps))
+	for _, entry := range policy.PolicyMaps {
+		policyMaps = append(policyMaps, policyMapFromYANG(entry))
+	}
+	return policyMaps, nil
+}
+
+// Apply edits the candidate datastore and commits it when the device
+// supports candidate configuration, otherwise it edits running directly.
+// With ConfirmedCommit the commit is confirmed only after the class-maps have
+// been read back, so a lost session rolls the change back.
+func (n *NETCONF) Apply(ctx context.Context, config *QoSConfig) (*ApplyResult, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	native, err := nativeEdit(config)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := xml.Marshal(native)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+
+	session, err := n.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{
+		Transport:   TypeNETCONF,
+		Datastore:   "running",
+		ClassMaps:   len(config.ClassMaps),
+		PolicyMaps:  len(config.PolicyMaps),
+		Attachments: len(config.Attachments),
+	}
+	candidate := session.supports(capabilityCandidate)
+	if candidate {
+		result.Datastore = "candidate"
+	}
+	if n.options.ConfirmedCommit && !(candidate && session.supports(capabilityConfirmedCommit)) {
+		return nil, fmt.Errorf("device does not support confirmed commit")
+	}
+
+	datastore := fmt.Sprintf("<target><%s/></target>", result.Datastore)
+	if _, err := n.call(ctx, "<lock>"+datastore+"</lock>"); err != nil {
+		return nil, fmt.Errorf("failed to lock %s datastore: %w", result.Datastore, err)
+	}
+	// Unlocking is best effort; the lock is released with the session
+	defer n.cleanup(session, "<unlock>"+datastore+"</unlock>")
+
+	edit := "<edit-config>" + datastore + "<default-operation>merge</default-operation>"
+	if !candidate {
+		edit += "<error-option>rollback-on-error</error-option>"
+	}
+	edit += "<config>" + string(payload) + "</config></edit-config>"
+
+	if _, err := n.call(ctx, edit); err != nil {
+		if candidate {
+			n.cleanup(session, "<discard-changes/>")
+		}
+		return nil, fmt.Errorf("edit-config failed: %w", err)
+	}
+	if !candidate {
+		return result, nil
+	}
+
+	if session.supports(capabilityValidate) {
+		if _, err := n.call(ctx, "<validate><source><candidate/></source></validate>"); err != nil {
+			n.cleanup(session, "<discard-changes/>")
+			return nil, fmt.Errorf("candidate validation failed: %w", err)
+		}
+	}
+
+	if !n.options.ConfirmedCommit {
+		if _, err := n.call(ctx, "<commit/>"); err != nil {
+			n.cleanup(session, "<discard-changes/>")
+			return nil, fmt.Errorf("commit failed: %w", err)
+		}
+		return result, nil
+	}
+
+	timeout := int(n.options.ConfirmTimeout.Seconds())
+	if _, err := n.call(ctx, fmt.Sprintf("<commit><confirmed/><confirm-timeout>%d</confirm-timeout></commit>", timeout)); err != nil {
+		n.cleanup(session, "<discard-changes/>")
+		return nil, fmt.Errorf("confirmed commit failed: %w", err)
+	}
+	if err := n.verify(ctx, config); err != nil {
+		n.cleanup(session, "<cancel-commit/>")
+		return nil, fmt.Errorf("commit verification failed, change rolled back: %w", err)
+	}
+	if _, err := n.call(ctx, "<commit/>"); err != nil {
+		return nil, fmt.Errorf("failed to confirm commit, device rolls back after %ds: %w", timeout, err)
+	}
+	result.ConfirmedCommit = true
+
+	return result, nil
+}
+
+// verify checks that the committed class-maps are in the running datastore
+func (n *NETCONF) verify(ctx context.Context, config *QoSConfig) error {
+	policy, err := n.getPolicy(ctx, "class-map")
+	if err != nil {
+		return err
+	}
+	current := make([]ClassMap, 0, len(policy.ClassMaps))
+	for _, entry := range policy.ClassMaps {
+		current = append(current, classMapFromYANG(entry))
+	}
+	if changed := ChangedClassMaps(current, config.ClassMaps); len(changed) > 0 {
+		return fmt.Errorf("class-maps not applied: %s", strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// cleanup runs a best-effort operation on the session that made the change.
+// Locks, candidate changes and confirmed commits belong to that session, so
+// nothing is sent once it has been dropped.
+func (n *NETCONF) cleanup(session *netconfSession, operation string) {
+	if n.session == session {
+		_, _ = n.call(context.Background(), operation)
+	}
+}
+
+// Save copies the running configuration to startup-config
+func (n *NETCONF) Save(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, err := n.call(ctx, `<save-config xmlns="http://cisco.com/yang/cisco-ia"/>`); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	return nil
+}
+
+// Close ends the NETCONF session
+func (n *NETCONF) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.session == nil {
+		return nil
+	}
+	_, _ = n.session.rpc(context.Background(), "<close-session/>")
+	err := n.session.close()
+	n.session = nil
+	return err
+}
@@ -0,0 +1,234 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RESTCONF paths
+const (
+	restconfData       = "/restconf/data/" + nativeModule + ":native"
+	restconfPolicy     = restconfData + "/policy"
+	restconfSaveConfig = "/restconf/operations/cisco-ia:save-config"
+	restconfMediaType  = "application/yang-data+json"
+)
+
+// RESTCONF is a Transport using RESTCONF over HTTPS. RESTCONF writes to the
+// running datastore directly; each class-map and policy-map is replaced in
+// its own request, class-maps first so policy-maps never reference a missing
+// class.
+type RESTCONF struct {
+	options    Options
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newRESTCONF creates a RESTCONF transport
+func newRESTCONF(opts Options) (*RESTCONF, error) {
+	if opts.Username == "" || opts.Password == "" {
+		return nil, fmt.Errorf("RESTCONF requires a username and password")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec G402 -- switches commonly use self-signed certificates
+	}
+
+	return &RESTCONF{
+		options:    opts,
+		baseURL:    "https://" + net.JoinHostPort(opts.Host, opts.Port),
+		httpClient: &http.Client{Timeout: opts.Timeout, Transport: transport},
+	}, nil
+}
+
+// Name returns the transport type
+func (r *RESTCONF) Name() string {
+	return TypeRESTCONF
+}
+
+// ClassMaps returns the class-maps in the running configuration
+func (r *RESTCONF) ClassMaps(ctx context.Context) ([]ClassMap, error) {
+	var policy yangPolicy
+	if err := r.get(ctx, restconfPolicy+"/"+policyModule+":class-map", &policy); err != nil {
+		return nil, fmt.Errorf("failed to get class-map configuration: %w", err)
+	}
+	classMaps := make([]ClassMap, 0, len(policy.ClassMaps))
+	for _, entry := range policy.ClassMaps {
+		classMaps = append(classMaps, classMapFromYANG(entry))
+	}
+	return classMaps, nil
+}
+
+// PolicyMaps returns the policy-maps in the running configuration
+func (r *RESTCONF) PolicyMaps(ctx context.Context) ([]PolicyMap, error) {
+	var policy yangPolicy
+	if err := r.get(ctx, restconfPolicy+"/"+policyModule+":policy-map", &policy); err != nil {
+		return nil, fmt.Errorf("failed to get policy-map configuration: %w", err)
+	}
+	policyMaps := make([]PolicyMap, 0, len(policy.PolicyMaps))
+	for _, entry := range policy.PolicyMaps {
+		policyMaps = append(policyMaps, policyMapFromYANG(entry))
+	}
+	return policyMaps, nil
+}
+
+// Apply replaces the class-maps and policy-maps and updates the interface
+// service-policy attachments
+func (r *RESTCONF) Apply(ctx context.Context, config *QoSConfig) (*ApplyResult, error) {
+	for _, classMap := range config.ClassMaps {
+		path := restconfPolicy + "/" + policyModule + ":class-map=" + url.PathEscape(classMap.Name)
+		body := map[string][]yangClassMap{policyModule + ":class-map": {classMapToYANG(classMap)}}
+		if err := r.send(ctx, http.MethodPut, path, body, nil); err != nil {
+			return nil, fmt.Errorf("failed to replace class-map %s: %w", classMap.Name, err)
+		}
+	}
+
+	for _, policyMap := range config.PolicyMaps {
+		path := restconfPolicy + "/" + policyModule + ":policy-map=" + url.PathEscape(policyMap.Name)
+		body := map[string][]yangPolicyMap{policyModule + ":policy-map": {policyMapToYANG(policyMap)}}
+		if err := r.send(ctx, http.MethodPut, path, body, nil); err != nil {
+			return nil, fmt.Errorf("failed to replace policy-map %s: %w", policyMap.Name, err)
+		}
+	}
+
+	for _, change := range config.Attachments {
+		kind, key, err := splitInterface(change.Interface)
+		if err != nil {
+			return nil, err
+		}
+		path := restconfData + "/interface/" + kind + "=" + url.PathEscape(key) + "/" + policyModule + ":service-policy"
+		direction := string(change.Direction)
+		if change.Remove {
+			err = r.send(ctx, http.MethodDelete, path+"/"+direction, nil, nil)
+		} else {
+			body := map[string]map[string]string{policyModule + ":service-policy": {direction: change.Policy}}
+			err = r.send(ctx, http.MethodPatch, path, body, nil)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update service-policy on %s: %w", change.Interface, err)
+		}
+	}
+
+	return &ApplyResult{
+		Transport:   TypeRESTCONF,
+		Datastore:   "running",
+		ClassMaps:   len(config.ClassMaps),
+		PolicyMaps:  len(config.PolicyMaps),
+		Attachments: len(config.Attachments),
+	}, nil
+}
+
+// Save copies the running configuration to startup-config
+func (r *RESTCONF) Save(ctx context.Context) error {
+	if err := r.send(ctx, http.MethodPost, restconfSaveConfig, nil, nil); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	return nil
+}
+
+// Close releases idle connections
+func (r *RESTCONF) Close() error {
+	r.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// get reads a resource; a missing resource leaves out unchanged
+func (r *RESTCONF) get(ctx context.Context, path string, out interface{}) error {
+	err := r.send(ctx, http.MethodGet, path, nil, out)
+	if statusErr, ok := err.(*StatusError); ok && statusErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// StatusError is a RESTCONF error response
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s returned status %d", e.Method, e.Path, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s returned status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// send performs a request with an optional JSON body and decodes the response
+func (r *RESTCONF) send(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(r.options.Username, r.options.Password)
+	req.Header.Set("Accept", restconfMediaType)
+	if body != nil {
+		req.Header.Set("Content-Type", restconfMediaType)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: restconfErrorMessage(data)}
+	}
+
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
+
+// restconfErrorMessage extracts the error messages of an ietf-restconf:errors
+// body, falling back to the raw body
+func restconfErrorMessage(data []byte) string {
+	var body struct {
+		Errors struct {
+			Error []struct {
+				Tag     string `json:"error-tag"`
+				Message string `json:"error-message"`
+			} `json:"error"`
+		} `json:"ietf-restconf:errors"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil || len(body.Errors.Error) == 0 {
+		return strings.TrimSpace(string(data))
+	}
+	var messages []string
+	for _, e := range body.Errors.Error {
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		} else {
+			messages = append(messages, e.Tag)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
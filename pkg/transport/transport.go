@@ -0,0 +1,116 @@
+// Package transport reads and writes the QoS configuration of IOS-XE devices
+// through the model-driven NETCONF and RESTCONF interfaces, using the
+// Cisco-IOS-XE-native and Cisco-IOS-XE-policy YANG models.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Transport types
+const (
+	TypeCLI      = "cli"
+	TypeNETCONF  = "netconf"
+	TypeRESTCONF = "restconf"
+)
+
+// Default ports
+const (
+	DefaultNETCONFPort  = "830"
+	DefaultRESTCONFPort = "443"
+)
+
+// DefaultConfirmTimeout is the rollback timeout of a confirmed commit
+const DefaultConfirmTimeout = 120 * time.Second
+
+// Transport reads class-maps and policy-maps from a device and applies
+// structured QoS configuration
+type Transport interface {
+	// Name returns the transport type
+	Name() string
+	// ClassMaps returns the class-maps in the running configuration
+	ClassMaps(ctx context.Context) ([]ClassMap, error)
+	// PolicyMaps returns the policy-maps in the running configuration
+	PolicyMaps(ctx context.Context) ([]PolicyMap, error)
+	// Apply replaces the given class-maps and policy-maps and applies the
+	// service-policy attachments
+	Apply(ctx context.Context, config *QoSConfig) (*ApplyResult, error)
+	// Save copies the running configuration to startup-config
+	Save(ctx context.Context) error
+	// Close releases the connection
+	Close() error
+}
+
+// Options contains the NETCONF/RESTCONF connection and commit settings
+type Options struct {
+	Type               string
+	Host               string
+	Port               string
+	Username           string
+	Password           string
+	KeyFile            string
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	// ConfirmedCommit commits the candidate with a rollback timeout and
+	// confirms it once the result has been read back (NETCONF only)
+	ConfirmedCommit bool
+	ConfirmTimeout  time.Duration
+}
+
+// ApplyResult summarizes an applied configuration
+type ApplyResult struct {
+	Transport       string
+	Datastore       string
+	ConfirmedCommit bool
+	ClassMaps       int
+	PolicyMaps      int
+	Attachments     int
+}
+
+// New creates a transport of the configured type
+func New(opts Options) (Transport, error) {
+	if opts.Host == "" {
+		return nil, fmt.Errorf("transport host is required")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.ConfirmTimeout == 0 {
+		opts.ConfirmTimeout = DefaultConfirmTimeout
+	}
+
+	switch opts.Type {
+	case TypeNETCONF:
+		if opts.Port == "" {
+			opts.Port = DefaultNETCONFPort
+		}
+		return newNETCONF(opts)
+	case TypeRESTCONF:
+		if opts.Port == "" {
+			opts.Port = DefaultRESTCONFPort
+		}
+		if opts.ConfirmedCommit {
+			return nil, fmt.Errorf("confirmed commit requires NETCONF")
+		}
+		return newRESTCONF(opts)
+	default:
+		return nil, fmt.Errorf("unsupported transport %q", opts.Type)
+	}
+}
+
+// privateKey returns the key data, reading it from a file unless the option
+// already holds the key itself (e.g. resolved from 1Password)
+func privateKey(keyFile string) ([]byte, error) {
+	if strings.HasPrefix(keyFile, "-----BEGIN") {
+		return []byte(keyFile), nil
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key file: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,267 @@
+package transport
+
+import (
+	"encoding/xml"
+)
+
+// YANG namespaces and RESTCONF module names
+const (
+	nativeNamespace  = "http://cisco.com/ns/yang/Cisco-IOS-XE-native"
+	policyNamespace  = "http://cisco.com/ns/yang/Cisco-IOS-XE-policy"
+	netconfNamespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+	nativeModule     = "Cisco-IOS-XE-native"
+	policyModule     = "Cisco-IOS-XE-policy"
+)
+
+// NETCONF edit-config operations
+const (
+	operationReplace = "replace"
+	operationDelete  = "delete"
+)
+
+// yangNative is the Cisco-IOS-XE-native container restricted to the nodes
+// used for QoS. The same types encode NETCONF XML and RESTCONF JSON.
+type yangNative struct {
+	XMLName   xml.Name        `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-native native" json:"-"`
+	XC        string          `xml:"xmlns:xc,attr,omitempty" json:"-"`
+	Policy    *yangPolicy     `xml:"policy,omitempty" json:"-"`
+	Interface *yangInterfaces `xml:"interface,omitempty" json:"-"`
+}
+
+// yangPolicy is the native policy container augmented by Cisco-IOS-XE-policy
+type yangPolicy struct {
+	ClassMaps  []yangClassMap  `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-policy class-map" json:"Cisco-IOS-XE-policy:class-map,omitempty"`
+	PolicyMaps []yangPolicyMap `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-policy policy-map" json:"Cisco-IOS-XE-policy:policy-map,omitempty"`
+}
+
+type yangClassMap struct {
+	Operation   string     `xml:"xc:operation,attr,omitempty" json:"-"`
+	Name        string     `xml:"name" json:"name"`
+	Prematch    string     `xml:"prematch" json:"prematch"`
+	Description string     `xml:"description,omitempty" json:"description,omitempty"`
+	Match       *yangMatch `xml:"match,omitempty" json:"match,omitempty"`
+}
+
+type yangMatch struct {
+	DSCP     []string           `xml:"dscp,omitempty" json:"dscp,omitempty"`
+	Protocol *yangProtocolMatch `xml:"protocol,omitempty" json:"protocol,omitempty"`
+}
+
+type yangProtocolMatch struct {
+	Protocols []string `xml:"protocols-list" json:"protocols-list"`
+}
+
+type yangPolicyMap struct {
+	Operation   string            `xml:"xc:operation,attr,omitempty" json:"-"`
+	Name        string            `xml:"name" json:"name"`
+	Description string            `xml:"description,omitempty" json:"description,omitempty"`
+	Classes     []yangPolicyClass `xml:"class" json:"class,omitempty"`
+}
+
+type yangPolicyClass struct {
+	Name    string       `xml:"name" json:"name"`
+	Actions []yangAction `xml:"action-list" json:"action-list,omitempty"`
+}
+
+type yangAction struct {
+	Type              string                 `xml:"action-type" json:"action-type"`
+	Set               *yangSet               `xml:"set,omitempty" json:"set,omitempty"`
+	Priority          *yangPriority          `xml:"priority,omitempty" json:"priority,omitempty"`
+	PoliceRatePercent *yangPoliceRatePercent `xml:"police-rate-percent,omitempty" json:"police-rate-percent,omitempty"`
+	Bandwidth         *yangBandwidth         `xml:"bandwidth,omitempty" json:"bandwidth,omitempty"`
+	QueueBuffers      *yangQueueBuffers      `xml:"queue-buffers,omitempty" json:"queue-buffers,omitempty"`
+}
+
+type yangSet struct {
+	DSCP yangDSCP `xml:"dscp" json:"dscp"`
+}
+
+type yangDSCP struct {
+	Value string `xml:"dscp-val" json:"dscp-val"`
+}
+
+type yangPriority struct {
+	Level int `xml:"level" json:"level"`
+}
+
+type yangPoliceRatePercent struct {
+	Police yangPolice `xml:"police" json:"police"`
+}
+
+type yangPolice struct {
+	Rate yangRate `xml:"rate" json:"rate"`
+}
+
+type yangRate struct {
+	Percent yangPercent `xml:"percent" json:"percent"`
+}
+
+type yangPercent struct {
+	Percentage int `xml:"percentage" json:"percentage"`
+}
+
+type yangBandwidth struct {
+	Remaining yangRemaining `xml:"remaining" json:"remaining"`
+}
+
+type yangRemaining struct {
+	Option  string `xml:"rem-option" json:"rem-option"`
+	Percent int    `xml:"bandwidth-remaining" json:"bandwidth-remaining"`
+}
+
+type yangQueueBuffers struct {
+	Ratio int `xml:"ratio" json:"ratio"`
+}
+
+// yangInterfaces is the native interface container. Each interface type is
+// its own list, so entries carry their type as the element name.
+type yangInterfaces struct {
+	Entries []yangInterface `xml:",any"`
+}
+
+type yangInterface struct {
+	XMLName       xml.Name           `xml:""`
+	Name          string             `xml:"name"`
+	ServicePolicy *yangServicePolicy `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-policy service-policy"`
+}
+
+type yangServicePolicy struct {
+	Input  *yangPolicyRef `xml:"input,omitempty"`
+	Output *yangPolicyRef `xml:"output,omitempty"`
+}
+
+type yangPolicyRef struct {
+	Operation string `xml:"xc:operation,attr,omitempty"`
+	Name      string `xml:",chardata"`
+}
+
+// classMapToYANG converts a class-map to its YANG form
+func classMapToYANG(classMap ClassMap) yangClassMap {
+	entry := yangClassMap{Name: classMap.Name, Prematch: classMap.Prematch, Description: classMap.Description}
+	if len(classMap.Protocols) > 0 || len(classMap.DSCP) > 0 {
+		entry.Match = &yangMatch{DSCP: classMap.DSCP}
+		if len(classMap.Protocols) > 0 {
+			entry.Match.Protocol = &yangProtocolMatch{Protocols: classMap.Protocols}
+		}
+	}
+	return entry
+}
+
+// classMapFromYANG converts a YANG class-map to a ClassMap
+func classMapFromYANG(entry yangClassMap) ClassMap {
+	classMap := ClassMap{Name: entry.Name, Prematch: entry.Prematch, Description: entry.Description}
+	if entry.Match != nil {
+		classMap.DSCP = entry.Match.DSCP
+		if entry.Match.Protocol != nil {
+			classMap.Protocols = entry.Match.Protocol.Protocols
+		}
+	}
+	return classMap
+}
+
+// policyMapToYANG converts a policy-map to its YANG form
+func policyMapToYANG(policyMap PolicyMap) yangPolicyMap {
+	entry := yangPolicyMap{Name: policyMap.Name, Description: policyMap.Description}
+	for _, class := range policyMap.Classes {
+		yangClass := yangPolicyClass{Name: class.Name}
+		if class.SetDSCP != "" {
+			yangClass.Actions = append(yangClass.Actions, yangAction{Type: "set", Set: &yangSet{DSCP: yangDSCP{Value: class.SetDSCP}}})
+		}
+		if class.PriorityLevel > 0 {
+			yangClass.Actions = append(yangClass.Actions, yangAction{Type: "priority", Priority: &yangPriority{Level: class.PriorityLevel}})
+		}
+		if class.PolicePercent > 0 {
+			yangClass.Actions = append(yangClass.Actions, yangAction{
+				Type:              "police",
+				PoliceRatePercent: &yangPoliceRatePercent{Police: yangPolice{Rate: yangRate{Percent: yangPercent{Percentage: class.PolicePercent}}}},
+			})
+		}
+		if class.BandwidthRemainingPercent > 0 {
+			yangClass.Actions = append(yangClass.Actions, yangAction{
+				Type:      "bandwidth",
+				Bandwidth: &yangBandwidth{Remaining: yangRemaining{Option: "percent", Percent: class.BandwidthRemainingPercent}},
+			})
+		}
+		if class.QueueBuffersRatio > 0 {
+			yangClass.Actions = append(yangClass.Actions, yangAction{Type: "queue-buffers", QueueBuffers: &yangQueueBuffers{Ratio: class.QueueBuffersRatio}})
+		}
+		entry.Classes = append(entry.Classes, yangClass)
+	}
+	return entry
+}
+
+// policyMapFromYANG converts a YANG policy-map to a PolicyMap
+func policyMapFromYANG(entry yangPolicyMap) PolicyMap {
+	policyMap := PolicyMap{Name: entry.Name, Description: entry.Description}
+	for _, yangClass := range entry.Classes {
+		class := PolicyClass{Name: yangClass.Name}
+		for _, action := range yangClass.Actions {
+			switch {
+			case action.Set != nil:
+				class.SetDSCP = action.Set.DSCP.Value
+			case action.Priority != nil:
+				class.PriorityLevel = action.Priority.Level
+			case action.PoliceRatePercent != nil:
+				class.PolicePercent = action.PoliceRatePercent.Police.Rate.Percent.Percentage
+			case action.Bandwidth != nil:
+				class.BandwidthRemainingPercent = action.Bandwidth.Remaining.Percent
+			case action.QueueBuffers != nil:
+				class.QueueBuffersRatio = action.QueueBuffers.Ratio
+			}
+		}
+		policyMap.Classes = append(policyMap.Classes, class)
+	}
+	return policyMap
+}
+
+// nativeEdit builds the edit-config payload. Class-maps and policy-maps are
+// replaced as a whole so stale matches and actions are removed.
+func nativeEdit(config *QoSConfig) (*yangNative, error) {
+	native := &yangNative{XC: netconfNamespace}
+
+	if len(config.ClassMaps) > 0 || len(config.PolicyMaps) > 0 {
+		native.Policy = &yangPolicy{}
+		for _, classMap := range config.ClassMaps {
+			entry := classMapToYANG(classMap)
+			entry.Operation = operationReplace
+			native.Policy.ClassMaps = append(native.Policy.ClassMaps, entry)
+		}
+		for _, policyMap := range config.PolicyMaps {
+			entry := policyMapToYANG(policyMap)
+			entry.Operation = operationReplace
+			native.Policy.PolicyMaps = append(native.Policy.PolicyMaps, entry)
+		}
+	}
+
+	if len(config.Attachments) > 0 {
+		byName := make(map[string]*yangInterface)
+		var names []string
+		for _, change := range config.Attachments {
+			kind, key, err := splitInterface(change.Interface)
+			if err != nil {
+				return nil, err
+			}
+			entry, exists := byName[change.Interface]
+			if !exists {
+				entry = &yangInterface{XMLName: xml.Name{Local: kind}, Name: key, ServicePolicy: &yangServicePolicy{}}
+				byName[change.Interface] = entry
+				names = append(names, change.Interface)
+			}
+			ref := &yangPolicyRef{Name: change.Policy}
+			if change.Remove {
+				ref.Operation = operationDelete
+			}
+			if change.Direction == "output" {
+				entry.ServicePolicy.Output = ref
+			} else {
+				entry.ServicePolicy.Input = ref
+			}
+		}
+		native.Interface = &yangInterfaces{}
+		for _, name := range names {
+			native.Interface.Entries = append(native.Interface.Entries, *byName[name])
+		}
+	}
+
+	return native, nil
+}
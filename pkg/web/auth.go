@@ -0,0 +1,125 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+)
+
+// authorize wraps a handler so that it requires a principal with at least
+// the given role. Without an authenticator the API is open.
+func (s *Server) authorize(role auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backend, _ := s.state()
+		if backend.Auth == nil {
+			next(w, r)
+			return
+		}
+
+		// Browsers carry a session cookie, automation a bearer token
+		var principal auth.Principal
+		var ok bool
+		if backend.OIDC != nil {
+			principal, ok = s.sessionPrincipal(r)
+		}
+		var err error
+		if !ok {
+			principal, err = backend.Auth.Authenticate(r)
+		}
+		if err != nil {
+			s.logger.Security("auth_failed", logger.Fields{
+				"reason":      err.Error(),
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"remote_addr": r.RemoteAddr,
+			})
+			w.Header().Set("WWW-Authenticate", `Bearer realm="nbar-classifier"`)
+			s.writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+		if !s.allowed(w, r, principal, role) {
+			return
+		}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// allowed checks that a principal has the required role, writing a 403 and
+// logging a security event when it does not
+func (s *Server) allowed(w http.ResponseWriter, r *http.Request, principal auth.Principal, role auth.Role) bool {
+	if principal.Role.Allows(role) {
+		return true
+	}
+	s.logger.Security("access_denied", logger.Fields{
+		"subject":       principal.Subject,
+		"role":          principal.Role,
+		"required_role": role,
+		"method":        r.Method,
+		"path":          r.URL.Path,
+		"remote_addr":   r.RemoteAddr,
+	})
+	s.writeError(w, http.StatusForbidden, fmt.Errorf("role %s is required", role))
+	return false
+}
+
+// requireRole checks an additional role inside a handler, e.g. for a
+// request option; it passes when the API is open
+func (s *Server) requireRole(w http.ResponseWriter, r *http.Request, role auth.Role) bool {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return true
+	}
+	return s.allowed(w, r, principal, role)
+}
+
+// actor identifies who made a change: the authenticated subject, or the
+// remote address when the API is open
+func actor(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Subject
+	}
+	return r.RemoteAddr
+}
+
+// Whoami endpoint returns the authenticated principal
+func (s *Server) handleWhoami(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("authentication is disabled"))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, principal)
+}
+
+// Issue token endpoint exchanges the current credentials, e.g. an API token,
+// for a JWT with the same subject and role
+func (s *Server) handleIssueToken(w http.ResponseWriter, r *http.Request) {
+	backend, _ := s.state()
+	principal, ok := auth.FromContext(r.Context())
+	if !ok || backend.Auth == nil || !backend.Auth.CanIssue() {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("token issuing is not configured"))
+		return
+	}
+
+	token, expires, err := backend.Auth.Issue(principal.Subject, principal.Role)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.logger.Audit("token_issue", logger.Fields{
+		"subject":     principal.Subject,
+		"role":        principal.Role,
+		"expires_at":  expires,
+		"remote_addr": r.RemoteAddr,
+	})
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token":      token,
+		"token_type": "Bearer",
+		"expires_at": expires,
+		"expires_in": int(time.Until(expires).Seconds()),
+	})
+}
@@ -0,0 +1,244 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+)
+
+// changeRequest is the optional body of change actions
+type changeRequest struct {
+	Comment string `json:"comment"`
+	// Emergency applies or rolls back outside the maintenance windows
+	Emergency bool `json:"emergency"`
+}
+
+// changeManager returns the change manager or writes an error
+func (s *Server) changeManager(w http.ResponseWriter) *changes.Manager {
+	backend, _ := s.state()
+	if backend.Changes == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("change approval is not enabled"))
+	}
+	return backend.Changes
+}
+
+// writeChangeError maps change manager errors to HTTP status codes
+func (s *Server) writeChangeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, changes.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, changes.ErrState), errors.Is(err, changes.ErrNoRollback):
+		status = http.StatusConflict
+	case errors.Is(err, changes.ErrSelfApproval), errors.Is(err, changes.ErrNoActor):
+		status = http.StatusForbidden
+	}
+	s.writeError(w, status, err)
+}
+
+// decodeChangeRequest reads the optional body of a change action
+func decodeChangeRequest(w http.ResponseWriter, r *http.Request) (changeRequest, error) {
+	var req changeRequest
+	if err := decodeJSON(w, r, &req); err != nil && !errors.Is(err, io.EOF) {
+		return req, fmt.Errorf("invalid change request: %w", err)
+	}
+	return req, nil
+}
+
+// List changes endpoint returns the changes, newest first, optionally
+// filtered by state
+func (s *Server) handleListChanges(w http.ResponseWriter, r *http.Request) {
+	manager := s.changeManager(w)
+	if manager == nil {
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	list, err := manager.List()
+	if err != nil {
+		s.writeChangeError(w, err)
+		return
+	}
+
+	states := make(map[changes.State]bool)
+	for _, value := range splitQuery(r.URL.Query().Get("state")) {
+		states[changes.State(value)] = true
+	}
+
+	matched := make([]changes.Change, 0)
+	for _, change := range list {
+		if len(states) == 0 || states[change.State] {
+			// Configurations can be long; they are returned for a single
+			// change
+			change.Config, change.BaseConfig, change.Diff.Unified = "", "", ""
+			change.Classifications = nil
+			matched = append(matched, change)
+		}
+	}
+
+	total := len(matched)
+	low, high := page.bounds(total)
+	response := map[string]interface{}{
+		"changes":   matched[low:high],
+		"count":     high - low,
+		"total":     total,
+		"offset":    page.Offset,
+		"limit":     page.Limit,
+		"timestamp": time.Now().Unix(),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// Get change endpoint returns a change with its configuration, diff and
+// history
+func (s *Server) handleGetChange(w http.ResponseWriter, r *http.Request) {
+	manager := s.changeManager(w)
+	if manager == nil {
+		return
+	}
+
+	change, err := manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		s.writeChangeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, change)
+}
+
+// Approve change endpoint approves a draft change. The approver must be
+// authenticated so they can be told apart from the author.
+func (s *Server) handleApproveChange(w http.ResponseWriter, r *http.Request) {
+	manager := s.changeManager(w)
+	if manager == nil {
+		return
+	}
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		s.writeError(w, http.StatusForbidden, fmt.Errorf("approving changes requires web authentication"))
+		return
+	}
+	req, err := decodeChangeRequest(w, r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	change, err := manager.Approve(mux.Vars(r)["id"], principal.Subject, req.Comment)
+	if err != nil {
+		s.writeChangeError(w, err)
+		return
+	}
+	s.logger.Audit("change_approve", logger.Fields{
+		"change_id":   change.ID,
+		"author":      change.Author,
+		"actor":       principal.Subject,
+		"remote_addr": r.RemoteAddr,
+	})
+
+	s.writeJSON(w, http.StatusOK, change)
+}
+
+// Reject change endpoint rejects a pending change
+func (s *Server) handleRejectChange(w http.ResponseWriter, r *http.Request) {
+	manager := s.changeManager(w)
+	if manager == nil {
+		return
+	}
+	req, err := decodeChangeRequest(w, r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	change, err := manager.Reject(mux.Vars(r)["id"], actor(r), req.Comment)
+	if err != nil {
+		s.writeChangeError(w, err)
+		return
+	}
+	s.logger.Audit("change_reject", logger.Fields{
+		"change_id":   change.ID,
+		"actor":       actor(r),
+		"remote_addr": r.RemoteAddr,
+	})
+
+	s.writeJSON(w, http.StatusOK, change)
+}
+
+// Apply change endpoint queues a run pushing an approved change
+func (s *Server) handleApplyChange(w http.ResponseWriter, r *http.Request) {
+	s.submitChangeRun(w, r, false)
+}
+
+// Rollback change endpoint queues a run restoring the configuration an
+// applied change replaced
+func (s *Server) handleRollbackChange(w http.ResponseWriter, r *http.Request) {
+	s.submitChangeRun(w, r, true)
+}
+
+// submitChangeRun queues a run applying or rolling back a change. The state
+// is checked up front so obvious mistakes fail before anything is queued.
+func (s *Server) submitChangeRun(w http.ResponseWriter, r *http.Request, rollback bool) {
+	manager := s.changeManager(w)
+	if manager == nil {
+		return
+	}
+	runManager := s.runManager(w)
+	if runManager == nil {
+		return
+	}
+	req, err := decodeChangeRequest(w, r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	change, err := manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		s.writeChangeError(w, err)
+		return
+	}
+	required := changes.StateApproved
+	if rollback {
+		required = changes.StateApplied
+	}
+	if change.State != required {
+		s.writeError(w, http.StatusConflict, fmt.Errorf("change %s is %s, expected %s: %w", change.ID, change.State, required, changes.ErrState))
+		return
+	}
+
+	run, err := runManager.Submit(runs.Request{
+		Source:      runs.SourceChange,
+		ChangeID:    change.ID,
+		Rollback:    rollback,
+		Comment:     req.Comment,
+		Emergency:   req.Emergency,
+		RequestedBy: actor(r),
+	})
+	if err != nil {
+		s.writeRunError(w, err)
+		return
+	}
+	s.logger.Audit("change_run_submit", logger.Fields{
+		"run_id":      run.ID,
+		"change_id":   change.ID,
+		"rollback":    rollback,
+		"emergency":   req.Emergency,
+		"actor":       actor(r),
+		"remote_addr": r.RemoteAddr,
+	})
+
+	w.Header().Set("Location", "/api/v1/runs/"+run.ID)
+	s.writeJSON(w, http.StatusAccepted, newRunView(run))
+}
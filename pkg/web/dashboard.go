@@ -0,0 +1,258 @@
+package web
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+//go:embed ui/templates/*.tmpl ui/static
+var uiFiles embed.FS
+
+// indexTemplate renders the dashboard page
+var indexTemplate = template.Must(template.ParseFS(uiFiles, "ui/templates/*.tmpl"))
+
+// uiStatic serves the dashboard scripts and styles
+func uiStatic() http.Handler {
+	static, err := fs.Sub(uiFiles, "ui/static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/ui/", http.FileServer(http.FS(static)))
+}
+
+// dashboardEntry is a protocol as shown on the dashboard
+type dashboardEntry struct {
+	qos.Classification
+	// Traffic is the byte count reported by the source, zero when unknown
+	Traffic uint64 `json:"traffic_bytes"`
+	// Override is the origin of the protocol's override, if it has one
+	Override string `json:"override,omitempty"`
+	// Review is set for classifications a person should confirm
+	Review bool `json:"review"`
+}
+
+// dashboardClass is a class column of the dashboard
+type dashboardClass struct {
+	qos.ClassDefinition
+	Protocols []dashboardEntry `json:"protocols"`
+	Traffic   uint64           `json:"traffic_bytes"`
+}
+
+// classChange is a protocol whose class differs from the last run
+type classChange struct {
+	Protocol string    `json:"protocol"`
+	From     qos.Class `json:"from"`
+	To       qos.Class `json:"to"`
+}
+
+// effectiveClassifications applies the classifier's current overrides and
+// rules to the last run, which is what the next run would produce; the
+// classifications of other sources, e.g. AI, are kept
+func effectiveClassifications(backend Backend, lastRun *RunResult) map[string]qos.Classification {
+	classifications := make(map[string]qos.Classification, len(lastRun.Classifications))
+	for protocol, classification := range lastRun.Classifications {
+		if classification.Protocol == "" {
+			classification.Protocol = protocol
+		}
+		if backend.Classifier != nil {
+			if current := backend.Classifier.ClassifyProtocol(protocol); current.Source != "default" {
+				classification = current
+			}
+		}
+		classifications[protocol] = classification
+	}
+	return classifications
+}
+
+// needsReview reports whether a classification was guessed rather than
+// matched by an override or rule with enough confidence
+func needsReview(classification qos.Classification, threshold float64) bool {
+	switch classification.Source {
+	case "default":
+		return true
+	case "predefined", "custom_rule":
+		return false
+	}
+	return classification.Confidence < threshold
+}
+
+// Index page serves the dashboard
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	backend, _ := s.state()
+	data := map[string]interface{}{
+		"Version":     backend.Version,
+		"AuthEnabled": backend.Auth != nil,
+		"SSO":         backend.Auth != nil && backend.OIDC != nil,
+	}
+
+	// Scripts and styles are separate files, so nothing inline has to run
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.ExecuteTemplate(w, "index.html.tmpl", data); err != nil {
+		s.logger.WithError(err).Error("Failed to render dashboard")
+	}
+}
+
+// Dashboard endpoint groups the last run's protocols by class, with their
+// traffic and the ones waiting for review
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	backend, lastRun := s.state()
+	threshold := 0.0
+	if backend.Classifier != nil {
+		threshold = backend.Classifier.GetConfidenceThreshold()
+	}
+
+	model := qos.CurrentModel()
+	columns := make(map[qos.Class]*dashboardClass)
+	var classes []*dashboardClass
+	for _, def := range model.Definitions() {
+		column := &dashboardClass{ClassDefinition: def, Protocols: []dashboardEntry{}}
+		columns[def.Name] = column
+		classes = append(classes, column)
+	}
+
+	review := []dashboardEntry{}
+	response := map[string]interface{}{
+		"confidence_threshold": threshold,
+		"formats":              []string{},
+		"timestamp":            time.Now().Unix(),
+	}
+	if backend.Outputs != nil {
+		response["formats"] = backend.Outputs.Names()
+	}
+	if lastRun != nil {
+		overrides := make(map[string]string)
+		if backend.Rules != nil {
+			for _, override := range backend.Rules.Overrides() {
+				overrides[override.Protocol] = override.Origin
+			}
+		}
+
+		for protocol, classification := range effectiveClassifications(backend, lastRun) {
+			entry := dashboardEntry{
+				Classification: classification,
+				Traffic:        lastRun.Traffic[protocol],
+				Override:       overrides[protocol],
+				Review:         needsReview(classification, threshold),
+			}
+			column, exists := columns[classification.Class]
+			if !exists {
+				// Classes dropped from the model still show up
+				column = &dashboardClass{
+					ClassDefinition: qos.ClassDefinition{Name: classification.Class, Priority: len(columns) + 1},
+				}
+				columns[classification.Class] = column
+				classes = append(classes, column)
+			}
+			column.Protocols = append(column.Protocols, entry)
+			column.Traffic += entry.Traffic
+			if entry.Review {
+				review = append(review, entry)
+			}
+		}
+
+		response["run"] = lastRun
+	}
+
+	for _, column := range classes {
+		sortEntries(column.Protocols)
+	}
+	sortEntries(review)
+	response["classes"] = classes
+	response["review"] = review
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// sortEntries orders entries by traffic, busiest first, then by name
+func sortEntries(entries []dashboardEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Traffic != entries[j].Traffic {
+			return entries[i].Traffic > entries[j].Traffic
+		}
+		return entries[i].Protocol < entries[j].Protocol
+	})
+}
+
+// Config preview endpoint generates an output from the current overrides and
+// rules and diffs it against the same output for the last run
+func (s *Server) handleConfigPreview(w http.ResponseWriter, r *http.Request) {
+	backend, lastRun := s.state()
+	if backend.Outputs == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("outputs are not available"))
+		return
+	}
+	if lastRun == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("no classification run has finished yet"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "cisco"
+	}
+	generator, err := backend.Outputs.Get(format)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	defaultClass := qos.CurrentModel().DefaultClass()
+	if backend.Classifier != nil {
+		defaultClass = backend.Classifier.GetDefaultClass()
+	}
+	generate := func(classifications map[string]qos.Classification) (string, error) {
+		data, err := generator.Generate(&output.Result{
+			Classifications: classifications,
+			Model:           qos.CurrentModel(),
+			DefaultClass:    defaultClass,
+			Attachments:     lastRun.Attachments,
+			GeneratedAt:     time.Now(),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to generate %s output: %w", format, err)
+		}
+		return string(data), nil
+	}
+
+	effective := effectiveClassifications(backend, lastRun)
+	before, err := generate(lastRun.Classifications)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	after, err := generate(effective)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	changes := []classChange{}
+	for protocol, classification := range effective {
+		if previous := lastRun.Classifications[protocol]; previous.Class != classification.Class {
+			changes = append(changes, classChange{Protocol: protocol, From: previous.Class, To: classification.Class})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Protocol < changes[j].Protocol
+	})
+
+	response := map[string]interface{}{
+		"format":    format,
+		"config":    after,
+		"diff":      output.Compare("last-run."+format, "proposed."+format, before, after),
+		"changes":   changes,
+		"base_run":  lastRun.FinishedAt,
+		"timestamp": time.Now().Unix(),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
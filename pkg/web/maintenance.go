@@ -0,0 +1,76 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+)
+
+// Maintenance endpoint returns whether pushes are allowed now, the next
+// window and the queued pushes
+func (s *Server) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	backend, _ := s.state()
+	if backend.Maintenance == nil {
+		s.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"enabled":   false,
+			"timestamp": time.Now().Unix(),
+		})
+		return
+	}
+
+	queue := make([]maintenance.Push, 0)
+	if backend.PushQueue != nil {
+		pushes, err := backend.PushQueue.List()
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, push := range pushes {
+			// Configurations can be long; the queue shows what waits
+			push.Config = ""
+			queue = append(queue, push)
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":   true,
+		"queueing":  backend.PushQueue != nil,
+		"target":    backend.MaintenanceTarget,
+		"decision":  backend.Maintenance.Check(backend.MaintenanceTarget, time.Now()),
+		"queue":     queue,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// Cancel queued push endpoint takes a push off the queue
+func (s *Server) handleCancelQueuedPush(w http.ResponseWriter, r *http.Request) {
+	backend, _ := s.state()
+	if backend.PushQueue == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("pushes are not queued"))
+		return
+	}
+
+	push, err := backend.PushQueue.Remove(mux.Vars(r)["id"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, maintenance.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		s.writeError(w, status, err)
+		return
+	}
+	s.logger.Audit("queued_push_cancel", logger.Fields{
+		"push_id":      push.ID,
+		"change_id":    push.ChangeID,
+		"requested_by": push.RequestedBy,
+		"actor":        actor(r),
+		"remote_addr":  r.RemoteAddr,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,212 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+)
+
+// Cookies of the single sign-on flow
+const (
+	sessionCookie = "nbar_session"
+	loginCookie   = "nbar_login"
+)
+
+// loginTimeout bounds the time between starting and finishing a login
+const loginTimeout = 10 * time.Minute
+
+// pendingLogin is a login waiting for the identity provider callback
+type pendingLogin struct {
+	Nonce     string
+	Verifier  string
+	Redirect  string
+	ExpiresAt time.Time
+}
+
+// sessionPrincipal returns the principal of the request's login session
+func (s *Server) sessionPrincipal(r *http.Request) (auth.Principal, bool) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return auth.Principal{}, false
+	}
+	session, ok := s.sessions.Get(cookie.Value)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	return session.Principal, true
+}
+
+// oidcProvider returns the OIDC provider or writes an error
+func (s *Server) oidcProvider(w http.ResponseWriter) *auth.OIDCProvider {
+	backend, _ := s.state()
+	if backend.Auth == nil || backend.OIDC == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("single sign-on is not configured"))
+		return nil
+	}
+	return backend.OIDC
+}
+
+// Login endpoint redirects the browser to the identity provider
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	provider := s.oidcProvider(w)
+	if provider == nil {
+		return
+	}
+
+	req, err := provider.AuthCodeURL(r.Context())
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to start OIDC login")
+		s.writeError(w, http.StatusBadGateway, fmt.Errorf("identity provider is unavailable"))
+		return
+	}
+
+	s.loginMu.Lock()
+	now := time.Now()
+	for state, login := range s.logins {
+		if now.After(login.ExpiresAt) {
+			delete(s.logins, state)
+		}
+	}
+	s.logins[req.State] = pendingLogin{
+		Nonce:     req.Nonce,
+		Verifier:  req.Verifier,
+		Redirect:  localRedirect(r.URL.Query().Get("redirect")),
+		ExpiresAt: now.Add(loginTimeout),
+	}
+	s.loginMu.Unlock()
+
+	// Binds the callback to the browser that started the login
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    req.State,
+		Path:     "/auth/",
+		MaxAge:   int(loginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   provider.SecureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, req.URL, http.StatusFound)
+}
+
+// Callback endpoint finishes a login and starts a session
+func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
+	provider := s.oidcProvider(w)
+	if provider == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	fail := func(status int, reason string, err error) {
+		fields := logger.Fields{
+			"reason":      reason,
+			"remote_addr": r.RemoteAddr,
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		s.logger.Security("oidc_login_failed", fields)
+		s.writeError(w, status, fmt.Errorf("login failed: %s", reason))
+	}
+
+	if message := query.Get("error"); message != "" {
+		fail(http.StatusUnauthorized, "identity provider returned "+message, nil)
+		return
+	}
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil || state == "" || cookie.Value != state {
+		fail(http.StatusBadRequest, "state does not match", nil)
+		return
+	}
+
+	s.loginMu.Lock()
+	login, exists := s.logins[state]
+	delete(s.logins, state)
+	s.loginMu.Unlock()
+	if !exists || time.Now().After(login.ExpiresAt) {
+		fail(http.StatusBadRequest, "login expired", nil)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/auth/", MaxAge: -1})
+
+	identity, err := provider.Exchange(r.Context(), query.Get("code"), login.Verifier, login.Nonce)
+	if errors.Is(err, auth.ErrNoRole) {
+		s.logger.Security("access_denied", logger.Fields{
+			"subject":     identity.Username(),
+			"groups":      identity.Groups,
+			"reason":      err.Error(),
+			"remote_addr": r.RemoteAddr,
+		})
+		s.writeError(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		fail(http.StatusUnauthorized, "token verification failed", err)
+		return
+	}
+
+	session, err := s.sessions.Create(auth.Principal{
+		Subject: identity.Username(),
+		Role:    identity.Role,
+	}, provider.SessionTimeout())
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.logger.Audit("login", logger.Fields{
+		"subject":     session.Principal.Subject,
+		"role":        session.Principal.Role,
+		"groups":      identity.Groups,
+		"expires_at":  session.ExpiresAt,
+		"remote_addr": r.RemoteAddr,
+	})
+
+	// The server decides when the session expires, so the cookie lasts for
+	// the browser session
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    session.ID,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   provider.SecureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, login.Redirect, http.StatusFound)
+}
+
+// Logout endpoint ends the session and sends the browser to the identity
+// provider's logout page when it has one
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if session, ok := s.sessions.Get(cookie.Value); ok {
+			s.logger.Audit("logout", logger.Fields{
+				"subject":     session.Principal.Subject,
+				"remote_addr": r.RemoteAddr,
+			})
+		}
+		s.sessions.Delete(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+
+	target := "/"
+	backend, _ := s.state()
+	if backend.OIDC != nil {
+		if endSession := backend.OIDC.EndSessionURL(r.Context()); endSession != "" {
+			target = endSession
+		}
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// localRedirect accepts only paths on this server as login redirects
+func localRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.Contains(target, `\`) {
+		return "/"
+	}
+	return target
+}
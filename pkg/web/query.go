@@ -0,0 +1,142 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+// Pagination defaults
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// page is an offset/limit window over a sorted list
+type page struct {
+	Offset int
+	Limit  int
+}
+
+// parsePage reads the offset and limit query parameters
+func parsePage(r *http.Request) (page, error) {
+	p := page{Limit: defaultLimit}
+	query := r.URL.Query()
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return p, fmt.Errorf("invalid offset %q", value)
+		}
+		p.Offset = offset
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			return p, fmt.Errorf("invalid limit %q, expected 1-%d", value, maxLimit)
+		}
+		p.Limit = limit
+	}
+	return p, nil
+}
+
+// bounds returns the slice bounds of the page in a list of total items
+func (p page) bounds(total int) (int, int) {
+	low := p.Offset
+	if low > total {
+		low = total
+	}
+	high := low + p.Limit
+	if high > total {
+		high = total
+	}
+	return low, high
+}
+
+// filter selects classifications by class, source, confidence and protocol
+// name
+type filter struct {
+	classes       map[qos.Class]bool
+	sources       map[string]bool
+	minConfidence float64
+	maxConfidence float64
+	search        string
+}
+
+// parseFilter reads the class, source, min_confidence, max_confidence and q
+// query parameters; class and source accept comma-separated lists
+func parseFilter(r *http.Request) (filter, error) {
+	f := filter{maxConfidence: 1}
+	query := r.URL.Query()
+
+	for _, value := range splitQuery(query.Get("class")) {
+		class := qos.Class(strings.ToUpper(value))
+		if !class.IsValid() {
+			return f, fmt.Errorf("unknown class %q", value)
+		}
+		if f.classes == nil {
+			f.classes = make(map[qos.Class]bool)
+		}
+		f.classes[class] = true
+	}
+	for _, value := range splitQuery(query.Get("source")) {
+		if f.sources == nil {
+			f.sources = make(map[string]bool)
+		}
+		f.sources[strings.ToLower(value)] = true
+	}
+
+	var err error
+	if f.minConfidence, err = parseConfidence(query.Get("min_confidence"), 0); err != nil {
+		return f, err
+	}
+	if f.maxConfidence, err = parseConfidence(query.Get("max_confidence"), 1); err != nil {
+		return f, err
+	}
+	if f.minConfidence > f.maxConfidence {
+		return f, fmt.Errorf("min_confidence must not exceed max_confidence")
+	}
+
+	f.search = strings.ToLower(query.Get("q"))
+	return f, nil
+}
+
+// match reports whether a classification passes the filter
+func (f filter) match(classification qos.Classification) bool {
+	if f.classes != nil && !f.classes[classification.Class] {
+		return false
+	}
+	if f.sources != nil && !f.sources[classification.Source] {
+		return false
+	}
+	if classification.Confidence < f.minConfidence || classification.Confidence > f.maxConfidence {
+		return false
+	}
+	return f.search == "" || strings.Contains(classification.Protocol, f.search)
+}
+
+// parseConfidence parses a confidence between 0 and 1
+func parseConfidence(value string, fallback float64) (float64, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	confidence, err := strconv.ParseFloat(value, 64)
+	if err != nil || confidence < 0 || confidence > 1 {
+		return 0, fmt.Errorf("invalid confidence %q, expected 0-1", value)
+	}
+	return confidence, nil
+}
+
+// splitQuery splits a comma-separated query value
+func splitQuery(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
@@ -0,0 +1,333 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rules"
+)
+
+// ruleRequest is the body of rule create and update requests; Enabled
+// defaults to true
+type ruleRequest struct {
+	Name        string    `json:"name"`
+	Pattern     string    `json:"pattern"`
+	Class       qos.Class `json:"class"`
+	Priority    int       `json:"priority"`
+	Enabled     *bool     `json:"enabled"`
+	Description string    `json:"description"`
+}
+
+// rule converts the request to a classification rule
+func (req ruleRequest) rule() qos.Rule {
+	rule := qos.Rule{
+		Name:        req.Name,
+		Pattern:     req.Pattern,
+		Class:       qos.Class(strings.ToUpper(string(req.Class))),
+		Priority:    req.Priority,
+		Enabled:     true,
+		Description: req.Description,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return rule
+}
+
+// overrideRequest is the body of override requests
+type overrideRequest struct {
+	Class       qos.Class `json:"class"`
+	Description string    `json:"description"`
+}
+
+// rulesManager returns the rules manager or writes an error
+func (s *Server) rulesManager(w http.ResponseWriter) *rules.Manager {
+	backend, _ := s.state()
+	if backend.Rules == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("rules are not available"))
+	}
+	return backend.Rules
+}
+
+// writeRulesError maps rules manager errors to HTTP status codes
+func (s *Server) writeRulesError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, rules.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, rules.ErrExists), errors.Is(err, rules.ErrReadOnly):
+		status = http.StatusConflict
+	}
+	s.writeError(w, status, err)
+}
+
+// List rules endpoint returns the custom rules in evaluation order
+func (s *Server) handleListRules(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	list := manager.Rules()
+	if list == nil {
+		list = []rules.Rule{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"rules":     list,
+		"count":     len(list),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// Create rule endpoint adds a custom rule
+func (s *Server) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	var req ruleRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid rule: %w", err))
+		return
+	}
+
+	rule, err := manager.CreateRule(req.rule(), actor(r))
+	if err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+	s.auditRuleChange(r, rules.ActionCreate, rules.KindRule, rule.Name, rule.Class)
+
+	w.Header().Set("Location", "/api/v1/rules/"+rule.Name)
+	s.writeJSON(w, http.StatusCreated, rule)
+}
+
+// Get rule endpoint returns one custom rule
+func (s *Server) handleGetRule(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	rule, err := manager.Rule(mux.Vars(r)["name"])
+	if err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, rule)
+}
+
+// Update rule endpoint replaces a custom rule; the name comes from the path
+func (s *Server) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	var req ruleRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid rule: %w", err))
+		return
+	}
+	name := mux.Vars(r)["name"]
+	if req.Name != "" && req.Name != name {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("rule name %q does not match path %q", req.Name, name))
+		return
+	}
+
+	rule, err := manager.UpdateRule(name, req.rule(), actor(r))
+	if err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+	s.auditRuleChange(r, rules.ActionUpdate, rules.KindRule, rule.Name, rule.Class)
+
+	s.writeJSON(w, http.StatusOK, rule)
+}
+
+// Delete rule endpoint removes a custom rule added through the API
+func (s *Server) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if err := manager.DeleteRule(name, actor(r)); err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+	s.auditRuleChange(r, rules.ActionDelete, rules.KindRule, name, "")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// List overrides endpoint returns the protocol overrides, optionally
+// filtered by class, origin and protocol name
+func (s *Server) handleListOverrides(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	origin := r.URL.Query().Get("origin")
+
+	matched := make([]rules.Override, 0)
+	for _, override := range manager.Overrides() {
+		if origin != "" && override.Origin != origin {
+			continue
+		}
+		if filter.classes != nil && !filter.classes[override.Class] {
+			continue
+		}
+		if filter.search != "" && !strings.Contains(override.Protocol, filter.search) {
+			continue
+		}
+		matched = append(matched, override)
+	}
+
+	total := len(matched)
+	low, high := page.bounds(total)
+	response := map[string]interface{}{
+		"overrides": matched[low:high],
+		"count":     high - low,
+		"total":     total,
+		"offset":    page.Offset,
+		"limit":     page.Limit,
+		"timestamp": time.Now().Unix(),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// Get override endpoint returns the override of one protocol
+func (s *Server) handleGetOverride(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	override, err := manager.Override(mux.Vars(r)["protocol"])
+	if err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, override)
+}
+
+// Set override endpoint creates or replaces the override of a protocol
+func (s *Server) handleSetOverride(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	var req overrideRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid override: %w", err))
+		return
+	}
+
+	override, created, err := manager.SetOverride(rules.Override{
+		Protocol:    mux.Vars(r)["protocol"],
+		Class:       qos.Class(strings.ToUpper(string(req.Class))),
+		Description: req.Description,
+	}, actor(r))
+	if err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+
+	status, action := http.StatusOK, rules.ActionUpdate
+	if created {
+		status, action = http.StatusCreated, rules.ActionCreate
+	}
+	s.auditRuleChange(r, action, rules.KindOverride, override.Protocol, override.Class)
+
+	s.writeJSON(w, status, override)
+}
+
+// Delete override endpoint removes an override set through the API
+func (s *Server) handleDeleteOverride(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	protocol := strings.ToLower(mux.Vars(r)["protocol"])
+	if err := manager.DeleteOverride(protocol, actor(r)); err != nil {
+		s.writeRulesError(w, err)
+		return
+	}
+	s.auditRuleChange(r, rules.ActionDelete, rules.KindOverride, protocol, "")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Audit endpoint returns the changes made to rules and overrides, newest
+// first, optionally filtered by kind
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	manager := s.rulesManager(w)
+	if manager == nil {
+		return
+	}
+
+	limit := defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxLimit {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q, expected 1-%d", value, maxLimit))
+			return
+		}
+		limit = parsed
+	}
+	kind := r.URL.Query().Get("kind")
+
+	changes := make([]rules.Change, 0)
+	for _, change := range manager.History(0) {
+		if kind != "" && change.Kind != kind {
+			continue
+		}
+		if len(changes) == limit {
+			break
+		}
+		changes = append(changes, change)
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"changes":   changes,
+		"count":     len(changes),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+// auditRuleChange logs a rule or override change
+func (s *Server) auditRuleChange(r *http.Request, action, kind, name string, class qos.Class) {
+	fields := logger.Fields{
+		"kind":        kind,
+		"name":        name,
+		"actor":       actor(r),
+		"remote_addr": r.RemoteAddr,
+	}
+	if class != "" {
+		fields["class"] = class
+	}
+	s.logger.Audit(kind+"_"+action, fields)
+}
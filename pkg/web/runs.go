@@ -0,0 +1,201 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+)
+
+// runView is a run as returned by the API; output contents are served
+// separately
+type runView struct {
+	runs.Run
+	Outputs []string `json:"outputs,omitempty"`
+}
+
+// newRunView converts a run for the API
+func newRunView(run runs.Run) runView {
+	view := runView{Run: run}
+	for format := range run.Outputs {
+		view.Outputs = append(view.Outputs, format)
+	}
+	sort.Strings(view.Outputs)
+	return view
+}
+
+// runManager returns the run manager or writes an error
+func (s *Server) runManager(w http.ResponseWriter) *runs.Manager {
+	backend, _ := s.state()
+	if backend.Runs == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("runs are not available"))
+	}
+	return backend.Runs
+}
+
+// writeRunError maps run manager errors to HTTP status codes
+func (s *Server) writeRunError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, runs.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, runs.ErrFinished):
+		status = http.StatusConflict
+	case errors.Is(err, runs.ErrQueueFull):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, runs.ErrClosed):
+		status = http.StatusServiceUnavailable
+	}
+	s.writeError(w, status, err)
+}
+
+// Create run endpoint queues a run
+func (s *Server) handleCreateRun(w http.ResponseWriter, r *http.Request) {
+	manager := s.runManager(w)
+	if manager == nil {
+		return
+	}
+
+	var req runs.Request
+	if err := decodeJSON(w, r, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run request: %w", err))
+		return
+	}
+
+	// Only deployers may change switch or Catalyst Center configuration
+	if req.Push || req.SaveConfig || req.PushCatalyst || req.Source == runs.SourceChange {
+		if !s.requireRole(w, r, auth.RoleDeployer) {
+			return
+		}
+	}
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		req.RequestedBy = principal.Subject
+	}
+
+	run, err := manager.Submit(req)
+	if err != nil {
+		s.writeRunError(w, err)
+		return
+	}
+	s.logger.Audit("run_submit", logger.Fields{
+		"run_id":       run.ID,
+		"source":       run.Request.Source,
+		"push":         run.Request.Push,
+		"dry_run":      run.Request.DryRun,
+		"emergency":    run.Request.Emergency,
+		"requested_by": run.Request.RequestedBy,
+		"remote_addr":  r.RemoteAddr,
+	})
+
+	w.Header().Set("Location", "/api/v1/runs/"+run.ID)
+	s.writeJSON(w, http.StatusAccepted, newRunView(run))
+}
+
+// List runs endpoint returns the runs, newest first, optionally filtered by
+// status
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	manager := s.runManager(w)
+	if manager == nil {
+		return
+	}
+	page, err := parsePage(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	statuses := make(map[runs.Status]bool)
+	for _, value := range splitQuery(r.URL.Query().Get("status")) {
+		statuses[runs.Status(value)] = true
+	}
+
+	matched := make([]runView, 0)
+	for _, run := range manager.List() {
+		if len(statuses) == 0 || statuses[run.Status] {
+			view := newRunView(run)
+			// Batches can be long; they are returned for a single run
+			view.Batches = nil
+			matched = append(matched, view)
+		}
+	}
+
+	total := len(matched)
+	low, high := page.bounds(total)
+	response := map[string]interface{}{
+		"runs":      matched[low:high],
+		"count":     high - low,
+		"total":     total,
+		"offset":    page.Offset,
+		"limit":     page.Limit,
+		"timestamp": time.Now().Unix(),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// Get run endpoint returns the status, progress and batch results of a run
+func (s *Server) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	manager := s.runManager(w)
+	if manager == nil {
+		return
+	}
+
+	run, err := manager.Get(mux.Vars(r)["id"])
+	if err != nil {
+		s.writeRunError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, newRunView(run))
+}
+
+// Cancel run endpoint cancels a queued or running run
+func (s *Server) handleCancelRun(w http.ResponseWriter, r *http.Request) {
+	manager := s.runManager(w)
+	if manager == nil {
+		return
+	}
+
+	run, err := manager.Cancel(mux.Vars(r)["id"])
+	if err != nil {
+		s.writeRunError(w, err)
+		return
+	}
+	s.logger.Audit("run_cancel", logger.Fields{
+		"run_id":      run.ID,
+		"actor":       actor(r),
+		"status":      run.Status,
+		"remote_addr": r.RemoteAddr,
+	})
+
+	s.writeJSON(w, http.StatusAccepted, newRunView(run))
+}
+
+// Run output endpoint returns a generated output of a run
+func (s *Server) handleRunOutput(w http.ResponseWriter, r *http.Request) {
+	manager := s.runManager(w)
+	if manager == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	run, err := manager.Get(vars["id"])
+	if err != nil {
+		s.writeRunError(w, err)
+		return
+	}
+	data, exists := run.Outputs[vars["format"]]
+	if !exists {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("run %s has no %s output", run.ID, vars["format"]))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(data))
+}
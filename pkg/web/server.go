@@ -4,27 +4,96 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ai"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/cache"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
 	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rules"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
 )
 
+// maxRequestBody bounds JSON request bodies
+const maxRequestBody = 1 << 20
+
+// Backend is the application state the API serves
+type Backend struct {
+	Version    string
+	Cache      *cache.Cache
+	Classifier *qos.Classifier
+	Rules      *rules.Manager
+	// Auth authenticates API requests; nil leaves the API open
+	Auth *auth.Authenticator
+	// OIDC enables single sign-on sessions when set with Auth
+	OIDC *auth.OIDCProvider
+	AI   *ai.Manager
+	Runs *runs.Manager
+	// Outputs generates the dashboard's configuration previews
+	Outputs *output.Registry
+	// Changes holds pushes waiting for approval; nil when approval is off
+	Changes *changes.Manager
+	// Maintenance limits pushes to MaintenanceTarget to its windows; nil
+	// when windows are not enforced
+	Maintenance       *maintenance.Policy
+	MaintenanceTarget maintenance.Target
+	// PushQueue holds pushes waiting for a window; nil when they are refused
+	PushQueue *maintenance.Queue
+}
+
+// RunResult is the outcome of the most recent classification run
+type RunResult struct {
+	// Source is where the protocols came from: switch, file or netflow
+	Source          string                        `json:"source"`
+	Protocols       []string                      `json:"protocols"`
+	Classifications map[string]qos.Classification `json:"-"`
+	// Traffic is the byte count of each protocol when the source reports it
+	Traffic map[string]uint64 `json:"-"`
+	// Attachments are the service-policy changes the run computed
+	Attachments []interfaces.Change `json:"-"`
+	Outputs     []string            `json:"outputs,omitempty"`
+	StartedAt   time.Time           `json:"started_at"`
+	FinishedAt  time.Time           `json:"finished_at"`
+}
+
 // Server represents the web server
 type Server struct {
-	config *config.WebConfig
-	logger *logger.Logger
-	router *mux.Router
-	server *http.Server
+	config    *config.WebConfig
+	logger    *logger.Logger
+	router    *mux.Router
+	server    *http.Server
+	startedAt time.Time
+
+	mu      sync.RWMutex
+	backend Backend
+	lastRun *RunResult
+
+	// Login sessions outlive backend swaps on configuration reload
+	sessions *auth.SessionStore
+	loginMu  sync.Mutex
+	logins   map[string]pendingLogin
 }
 
 // New creates a new web server
-func New(cfg *config.WebConfig, logger *logger.Logger) *Server {
+func New(cfg *config.WebConfig, logger *logger.Logger, backend Backend) *Server {
 	s := &Server{
-		config: cfg,
-		logger: logger,
-		router: mux.NewRouter(),
+		config:    cfg,
+		logger:    logger,
+		router:    mux.NewRouter(),
+		startedAt: time.Now(),
+		backend:   backend,
+		sessions:  auth.NewSessionStore(),
+		logins:    make(map[string]pendingLogin),
 	}
 
 	s.setupRoutes()
@@ -32,17 +101,75 @@ func New(cfg *config.WebConfig, logger *logger.Logger) *Server {
 	return s
 }
 
+// SetBackend replaces the application state, e.g. after a configuration
+// reload
+func (s *Server) SetBackend(backend Backend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backend = backend
+}
+
+// SetLastRun records the most recent classification run
+func (s *Server) SetLastRun(run *RunResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRun = run
+}
+
+// state returns the backend and the last run
+func (s *Server) state() (Backend, *RunResult) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.backend, s.lastRun
+}
+
+// Handler returns the HTTP handler serving the routes. CORS is handled
+// before routing so preflight requests reach it.
+func (s *Server) Handler() http.Handler {
+	return s.corsMiddleware(s.router)
+}
+
 // setupRoutes sets up the HTTP routes
 func (s *Server) setupRoutes() {
 	// API routes
 	api := s.router.PathPrefix("/api/v1").Subrouter()
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
 	api.HandleFunc("/ready", s.handleReady).Methods("GET")
-	api.HandleFunc("/status", s.handleStatus).Methods("GET")
-	api.HandleFunc("/protocols", s.handleProtocols).Methods("GET")
-	api.HandleFunc("/classifications", s.handleClassifications).Methods("GET")
-	api.HandleFunc("/cache/stats", s.handleCacheStats).Methods("GET")
-	api.HandleFunc("/cache/clear", s.handleCacheClear).Methods("POST")
+	api.HandleFunc("/auth/whoami", s.authorize(auth.RoleViewer, s.handleWhoami)).Methods("GET")
+	api.HandleFunc("/auth/token", s.authorize(auth.RoleViewer, s.handleIssueToken)).Methods("POST")
+	api.HandleFunc("/status", s.authorize(auth.RoleViewer, s.handleStatus)).Methods("GET")
+	api.HandleFunc("/protocols", s.authorize(auth.RoleViewer, s.handleProtocols)).Methods("GET")
+	api.HandleFunc("/classifications", s.authorize(auth.RoleViewer, s.handleClassifications)).Methods("GET")
+	api.HandleFunc("/classifications/{protocol}", s.authorize(auth.RoleViewer, s.handleClassification)).Methods("GET")
+	api.HandleFunc("/dashboard", s.authorize(auth.RoleViewer, s.handleDashboard)).Methods("GET")
+	api.HandleFunc("/config/preview", s.authorize(auth.RoleViewer, s.handleConfigPreview)).Methods("GET")
+	api.HandleFunc("/runs/last", s.authorize(auth.RoleViewer, s.handleLastRun)).Methods("GET")
+	api.HandleFunc("/runs", s.authorize(auth.RoleViewer, s.handleListRuns)).Methods("GET")
+	api.HandleFunc("/runs", s.authorize(auth.RoleOperator, s.handleCreateRun)).Methods("POST")
+	api.HandleFunc("/runs/{id}", s.authorize(auth.RoleViewer, s.handleGetRun)).Methods("GET")
+	api.HandleFunc("/runs/{id}/cancel", s.authorize(auth.RoleOperator, s.handleCancelRun)).Methods("POST")
+	api.HandleFunc("/runs/{id}/outputs/{format}", s.authorize(auth.RoleViewer, s.handleRunOutput)).Methods("GET")
+	api.HandleFunc("/changes", s.authorize(auth.RoleViewer, s.handleListChanges)).Methods("GET")
+	api.HandleFunc("/changes/{id}", s.authorize(auth.RoleViewer, s.handleGetChange)).Methods("GET")
+	api.HandleFunc("/changes/{id}/approve", s.authorize(auth.RoleDeployer, s.handleApproveChange)).Methods("POST")
+	api.HandleFunc("/changes/{id}/reject", s.authorize(auth.RoleDeployer, s.handleRejectChange)).Methods("POST")
+	api.HandleFunc("/changes/{id}/apply", s.authorize(auth.RoleDeployer, s.handleApplyChange)).Methods("POST")
+	api.HandleFunc("/changes/{id}/rollback", s.authorize(auth.RoleDeployer, s.handleRollbackChange)).Methods("POST")
+	api.HandleFunc("/maintenance", s.authorize(auth.RoleViewer, s.handleMaintenance)).Methods("GET")
+	api.HandleFunc("/maintenance/queue/{id}", s.authorize(auth.RoleDeployer, s.handleCancelQueuedPush)).Methods("DELETE")
+	api.HandleFunc("/rules", s.authorize(auth.RoleViewer, s.handleListRules)).Methods("GET")
+	api.HandleFunc("/rules", s.authorize(auth.RoleOperator, s.handleCreateRule)).Methods("POST")
+	api.HandleFunc("/rules/{name}", s.authorize(auth.RoleViewer, s.handleGetRule)).Methods("GET")
+	api.HandleFunc("/rules/{name}", s.authorize(auth.RoleOperator, s.handleUpdateRule)).Methods("PUT")
+	api.HandleFunc("/rules/{name}", s.authorize(auth.RoleOperator, s.handleDeleteRule)).Methods("DELETE")
+	api.HandleFunc("/overrides", s.authorize(auth.RoleViewer, s.handleListOverrides)).Methods("GET")
+	api.HandleFunc("/overrides/{protocol}", s.authorize(auth.RoleViewer, s.handleGetOverride)).Methods("GET")
+	api.HandleFunc("/overrides/{protocol}", s.authorize(auth.RoleOperator, s.handleSetOverride)).Methods("PUT")
+	api.HandleFunc("/overrides/{protocol}", s.authorize(auth.RoleOperator, s.handleDeleteOverride)).Methods("DELETE")
+	api.HandleFunc("/audit", s.authorize(auth.RoleViewer, s.handleAudit)).Methods("GET")
+	api.HandleFunc("/ai/stats", s.authorize(auth.RoleViewer, s.handleAIStats)).Methods("GET")
+	api.HandleFunc("/cache/stats", s.authorize(auth.RoleViewer, s.handleCacheStats)).Methods("GET")
+	api.HandleFunc("/cache/clear", s.authorize(auth.RoleAdmin, s.handleCacheClear)).Methods("POST")
 
 	// Static files (if enabled)
 	if s.config.StaticDir != "" {
@@ -51,12 +178,19 @@ func (s *Server) setupRoutes() {
 		)
 	}
 
+	// Dashboard scripts and styles
+	s.router.PathPrefix("/ui/").Handler(uiStatic()).Methods("GET")
+
+	// Single sign-on
+	s.router.HandleFunc("/auth/login", s.handleLogin).Methods("GET")
+	s.router.HandleFunc("/auth/callback", s.handleCallback).Methods("GET")
+	s.router.HandleFunc("/auth/logout", s.handleLogout).Methods("POST")
+
 	// Default route
 	s.router.HandleFunc("/", s.handleIndex).Methods("GET")
 
 	// Add middleware
 	s.router.Use(s.loggingMiddleware)
-	s.router.Use(s.corsMiddleware)
 }
 
 // Start starts the web server
@@ -70,7 +204,7 @@ func (s *Server) Start() error {
 
 	s.server = &http.Server{
 		Addr:         addr,
-		Handler:      s.router,
+		Handler:      s.Handler(),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -81,11 +215,16 @@ func (s *Server) Start() error {
 		"tls_enabled": s.config.TLSEnabled,
 	}).Info("Starting web server")
 
+	var err error
 	if s.config.TLSEnabled {
-		return s.server.ListenAndServeTLS(s.config.CertFile, s.config.KeyFile)
+		err = s.server.ListenAndServeTLS(s.config.CertFile, s.config.KeyFile)
+	} else {
+		err = s.server.ListenAndServe()
 	}
-
-	return s.server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
 // Stop stops the web server
@@ -100,10 +239,11 @@ func (s *Server) Stop() error {
 
 // Health check endpoint
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	backend, _ := s.state()
 	response := map[string]interface{}{
 		"status":    "healthy",
 		"timestamp": time.Now().Unix(),
-		"version":   "2.0.0", // TODO: Get from build info
+		"version":   backend.Version,
 	}
 
 	s.writeJSON(w, http.StatusOK, response)
@@ -111,7 +251,15 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // Readiness check endpoint
 func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
-	// TODO: Check if all dependencies are ready
+	backend, _ := s.state()
+	if backend.Cache == nil || backend.Classifier == nil {
+		s.writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status":    "not_ready",
+			"timestamp": time.Now().Unix(),
+		})
+		return
+	}
+
 	response := map[string]interface{}{
 		"status":    "ready",
 		"timestamp": time.Now().Unix(),
@@ -122,55 +270,222 @@ func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
 
 // Status endpoint
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	backend, lastRun := s.state()
+
+	components := map[string]interface{}{}
+	if backend.Cache != nil {
+		components["cache"] = map[string]interface{}{
+			"size":     backend.Cache.Size(),
+			"hit_rate": backend.Cache.HitRate(),
+		}
+	}
+	if backend.Classifier != nil {
+		components["classifier"] = map[string]interface{}{
+			"predefined":           len(backend.Classifier.GetPredefinedClassifications()),
+			"custom_rules":         len(backend.Classifier.GetCustomRules()),
+			"default_class":        backend.Classifier.GetDefaultClass(),
+			"confidence_threshold": backend.Classifier.GetConfidenceThreshold(),
+		}
+	}
+	if backend.AI != nil {
+		providers := map[string]bool{}
+		for _, provider := range backend.AI.GetProviders() {
+			providers[provider.Name()] = provider.IsAvailable()
+		}
+		components["ai"] = map[string]interface{}{
+			"primary_provider": backend.AI.GetPrimaryProvider().Name(),
+			"providers":        providers,
+		}
+	}
+
 	response := map[string]interface{}{
-		"status":    "running",
-		"timestamp": time.Now().Unix(),
-		"uptime":    "unknown", // TODO: Calculate uptime
-		"version":   "2.0.0",
-		"components": map[string]string{
-			"cache":   "healthy",
-			"ssh":     "healthy",
-			"ai":      "healthy",
-			"metrics": "healthy",
-		},
+		"status":     "running",
+		"timestamp":  time.Now().Unix(),
+		"uptime":     time.Since(s.startedAt).Round(time.Second).String(),
+		"version":    backend.Version,
+		"components": components,
+	}
+	if lastRun != nil {
+		response["last_run"] = lastRun.FinishedAt
 	}
 
 	s.writeJSON(w, http.StatusOK, response)
 }
 
-// Protocols endpoint
+// Protocols endpoint lists the protocols of the last run
 func (s *Server) handleProtocols(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement protocol listing
+	page, err := parsePage(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	_, lastRun := s.state()
+	var protocols []string
+	if lastRun != nil {
+		search := strings.ToLower(r.URL.Query().Get("q"))
+		for _, protocol := range lastRun.Protocols {
+			if search == "" || strings.Contains(protocol, search) {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	sort.Strings(protocols)
+
+	total := len(protocols)
+	low, high := page.bounds(total)
 	response := map[string]interface{}{
-		"protocols": []string{},
-		"count":     0,
+		"protocols": append([]string{}, protocols[low:high]...),
+		"count":     high - low,
+		"total":     total,
+		"offset":    page.Offset,
+		"limit":     page.Limit,
 		"timestamp": time.Now().Unix(),
 	}
 
 	s.writeJSON(w, http.StatusOK, response)
 }
 
-// Classifications endpoint
+// Classifications endpoint lists the classifications of the last run, or the
+// cached classifications with scope=cache or before the first run
 func (s *Server) handleClassifications(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement classification listing
+	page, err := parsePage(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter, err := parseFilter(r)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	backend, lastRun := s.state()
+	scope := r.URL.Query().Get("scope")
+	if scope == "" {
+		scope = "last_run"
+		if lastRun == nil {
+			scope = "cache"
+		}
+	}
+
+	var classifications map[string]qos.Classification
+	switch scope {
+	case "last_run":
+		if lastRun != nil {
+			classifications = lastRun.Classifications
+		}
+	case "cache":
+		if backend.Cache != nil {
+			classifications = backend.Cache.GetAll()
+		}
+	default:
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("unknown scope %q, expected last_run or cache", scope))
+		return
+	}
+
+	matched := make([]qos.Classification, 0, len(classifications))
+	for protocol, classification := range classifications {
+		if classification.Protocol == "" {
+			classification.Protocol = protocol
+		}
+		if filter.match(classification) {
+			matched = append(matched, classification)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Protocol < matched[j].Protocol
+	})
+
+	total := len(matched)
+	low, high := page.bounds(total)
 	response := map[string]interface{}{
-		"classifications": map[string]interface{}{},
-		"count":           0,
+		"classifications": matched[low:high],
+		"scope":           scope,
+		"count":           high - low,
+		"total":           total,
+		"offset":          page.Offset,
+		"limit":           page.Limit,
 		"timestamp":       time.Now().Unix(),
 	}
 
 	s.writeJSON(w, http.StatusOK, response)
 }
 
+// Classification endpoint returns one protocol from the last run, the cache
+// or, failing both, the classifier rules
+func (s *Server) handleClassification(w http.ResponseWriter, r *http.Request) {
+	protocol := strings.ToLower(mux.Vars(r)["protocol"])
+	if err := qos.ValidateProtocolName(protocol); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	backend, lastRun := s.state()
+	if lastRun != nil {
+		if classification, exists := lastRun.Classifications[protocol]; exists {
+			s.writeJSON(w, http.StatusOK, classification)
+			return
+		}
+	}
+	if backend.Cache != nil {
+		if classification, exists := backend.Cache.GetAll()[protocol]; exists {
+			s.writeJSON(w, http.StatusOK, classification)
+			return
+		}
+	}
+	if backend.Classifier == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("protocol %q has not been classified", protocol))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, backend.Classifier.ClassifyProtocol(protocol))
+}
+
+// Last run endpoint
+func (s *Server) handleLastRun(w http.ResponseWriter, r *http.Request) {
+	_, lastRun := s.state()
+	if lastRun == nil {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("no classification run has finished yet"))
+		return
+	}
+
+	response := map[string]interface{}{
+		"run":        lastRun,
+		"statistics": qos.GetClassStatistics(lastRun.Classifications),
+		"timestamp":  time.Now().Unix(),
+	}
+
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+// AI stats endpoint
+func (s *Server) handleAIStats(w http.ResponseWriter, r *http.Request) {
+	backend, _ := s.state()
+	if backend.AI == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("AI manager is not available"))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, backend.AI.GetStats())
+}
+
 // Cache stats endpoint
 func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
-	// TODO: Get actual cache stats
+	backend, _ := s.state()
+	if backend.Cache == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("cache is not available"))
+		return
+	}
+
+	stats := backend.Cache.GetStats()
 	response := map[string]interface{}{
-		"size":      0,
-		"hits":      0,
-		"misses":    0,
-		"hit_rate":  0.0,
-		"timestamp": time.Now().Unix(),
+		"size":         stats.Size,
+		"hits":         stats.Hits,
+		"misses":       stats.Misses,
+		"evictions":    stats.Evictions,
+		"hit_rate":     backend.Cache.HitRate(),
+		"last_cleanup": stats.LastCleanup,
+		"timestamp":    time.Now().Unix(),
 	}
 
 	s.writeJSON(w, http.StatusOK, response)
@@ -178,58 +493,29 @@ func (s *Server) handleCacheStats(w http.ResponseWriter, r *http.Request) {
 
 // Cache clear endpoint
 func (s *Server) handleCacheClear(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement cache clearing
+	backend, _ := s.state()
+	if backend.Cache == nil {
+		s.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("cache is not available"))
+		return
+	}
+
+	cleared := backend.Cache.Size()
+	backend.Cache.Clear()
+	s.logger.Audit("cache_clear", logger.Fields{
+		"entries":     cleared,
+		"actor":       actor(r),
+		"remote_addr": r.RemoteAddr,
+	})
+
 	response := map[string]interface{}{
 		"status":    "cleared",
+		"cleared":   cleared,
 		"timestamp": time.Now().Unix(),
 	}
 
 	s.writeJSON(w, http.StatusOK, response)
 }
 
-// Index page
-func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	html := `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>NBAR QoS Classifier</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 40px; }
-        .header { background: #f4f4f4; padding: 20px; border-radius: 5px; }
-        .section { margin: 20px 0; }
-        .api-link { display: block; margin: 5px 0; color: #0066cc; }
-    </style>
-</head>
-<body>
-    <div class="header">
-        <h1>NBAR QoS Classifier</h1>
-        <p>Network protocol classification and QoS management</p>
-    </div>
-
-    <div class="section">
-        <h2>API Endpoints</h2>
-        <a href="/api/v1/health" class="api-link">Health Check</a>
-        <a href="/api/v1/ready" class="api-link">Readiness Check</a>
-        <a href="/api/v1/status" class="api-link">System Status</a>
-        <a href="/api/v1/protocols" class="api-link">Protocols</a>
-        <a href="/api/v1/classifications" class="api-link">Classifications</a>
-        <a href="/api/v1/cache/stats" class="api-link">Cache Statistics</a>
-    </div>
-
-    <div class="section">
-        <h2>Documentation</h2>
-        <p>For detailed API documentation, please refer to the project README.</p>
-    </div>
-</body>
-</html>
-`
-
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(html))
-}
-
 // writeJSON writes a JSON response
 func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -240,6 +526,21 @@ func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{})
 	}
 }
 
+// decodeJSON decodes a bounded JSON request body, rejecting unknown fields
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// writeError writes a JSON error response
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, map[string]interface{}{
+		"error":     err.Error(),
+		"timestamp": time.Now().Unix(),
+	})
+}
+
 // Logging middleware
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -263,15 +564,34 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// CORS middleware
+// CORS middleware allows the configured origins
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+		w.Header().Add("Vary", "Origin")
+		allowed := s.originAllowed(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
+
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				s.logger.Security("cors_origin_denied", logger.Fields{
+					"origin":      origin,
+					"path":        r.URL.Path,
+					"remote_addr": r.RemoteAddr,
+				})
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
@@ -279,6 +599,16 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// originAllowed checks an origin against the CORS allowlist
+func (s *Server) originAllowed(origin string) bool {
+	for _, allowed := range s.config.CORSOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
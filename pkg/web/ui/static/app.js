@@ -0,0 +1,439 @@
+// Dashboard for reviewing and editing classifications. It only uses the
+// public API; requests carry the session cookie or a token kept for the
+// browser tab.
+(function () {
+    "use strict";
+
+    var API = "/api/v1";
+    var ROLES = ["viewer", "classifier-operator", "deployer", "admin"];
+
+    var state = {
+        token: sessionStorage.getItem("nbar_token") || "",
+        role: "",
+        dashboard: null,
+        preview: null
+    };
+
+    function $(id) {
+        return document.getElementById(id);
+    }
+
+    // el creates an element with text content and attributes
+    function el(tag, text, attrs) {
+        var node = document.createElement(tag);
+        if (text !== undefined && text !== null) {
+            node.textContent = text;
+        }
+        Object.keys(attrs || {}).forEach(function (name) {
+            node.setAttribute(name, attrs[name]);
+        });
+        return node;
+    }
+
+    function allows(role) {
+        return ROLES.indexOf(state.role) >= ROLES.indexOf(role);
+    }
+
+    function formatBytes(bytes) {
+        if (!bytes) {
+            return "-";
+        }
+        var units = ["B", "KB", "MB", "GB", "TB"];
+        var i = 0;
+        while (bytes >= 1024 && i < units.length - 1) {
+            bytes /= 1024;
+            i++;
+        }
+        return bytes.toFixed(i === 0 ? 0 : 1) + " " + units[i];
+    }
+
+    function formatConfidence(confidence) {
+        return confidence ? Math.round(confidence * 100) + "%" : "-";
+    }
+
+    function showMessage(text, isError) {
+        var message = $("message");
+        message.textContent = text;
+        message.className = isError ? "message error" : "message";
+        message.hidden = !text;
+    }
+
+    // api calls the API and resolves with the decoded body, rejecting with
+    // the error message of failed requests
+    function api(method, path, body) {
+        var headers = {};
+        if (state.token) {
+            headers.Authorization = "Bearer " + state.token;
+        }
+        if (body !== undefined) {
+            headers["Content-Type"] = "application/json";
+        }
+        return fetch(API + path, {
+            method: method,
+            headers: headers,
+            credentials: "same-origin",
+            body: body === undefined ? undefined : JSON.stringify(body)
+        }).then(function (resp) {
+            if (resp.status === 204) {
+                return {};
+            }
+            return resp.json().then(function (data) {
+                if (!resp.ok) {
+                    var err = new Error(data.error || resp.statusText);
+                    err.status = resp.status;
+                    throw err;
+                }
+                return data;
+            });
+        });
+    }
+
+    function showLogin() {
+        $("app").hidden = true;
+        $("login").hidden = false;
+    }
+
+    function renderUser(principal) {
+        var user = $("user");
+        user.textContent = "";
+        if (!principal) {
+            return;
+        }
+        user.appendChild(el("div", principal.subject + " (" + principal.role + ")"));
+        if (principal.method === "oidc") {
+            var form = el("form", null, {method: "post", action: "/auth/logout"});
+            form.appendChild(el("button", "Sign out", {type: "submit", "class": "secondary"}));
+            user.appendChild(form);
+        } else if (state.token) {
+            var signOut = el("button", "Forget token", {type: "button", "class": "secondary"});
+            signOut.addEventListener("click", function () {
+                sessionStorage.removeItem("nbar_token");
+                state.token = "";
+                renderUser(null);
+                showLogin();
+            });
+            user.appendChild(signOut);
+        }
+    }
+
+    // start finds out who the user is and loads the dashboard
+    function start() {
+        var whoami = document.body.dataset.auth === "true"
+            ? api("GET", "/auth/whoami")
+            : Promise.resolve(null);
+
+        whoami.then(function (principal) {
+            // Without authentication everyone may do everything
+            state.role = principal ? principal.role : "admin";
+            renderUser(principal);
+            $("login").hidden = true;
+            $("app").hidden = false;
+            $("push").hidden = !allows("deployer");
+            return load();
+        }).catch(function (err) {
+            if (err.status === 401) {
+                showLogin();
+                return;
+            }
+            showMessage(err.message, true);
+        });
+    }
+
+    function load() {
+        return api("GET", "/dashboard").then(function (dashboard) {
+            state.dashboard = dashboard;
+            renderRunInfo(dashboard);
+            renderFormats(dashboard.formats);
+            renderBoard(dashboard);
+            renderReview(dashboard);
+        }).catch(function (err) {
+            showMessage(err.message, true);
+        });
+    }
+
+    function renderRunInfo(dashboard) {
+        var info = $("run-info");
+        if (!dashboard.run) {
+            info.textContent = "No classification run has finished yet";
+            return;
+        }
+        info.textContent = "Last run: " + dashboard.run.source + ", " +
+            dashboard.run.protocols.length + " protocols, " +
+            new Date(dashboard.run.finished_at).toLocaleString();
+    }
+
+    function renderFormats(formats) {
+        var select = $("format");
+        var current = select.value || "cisco";
+        select.textContent = "";
+        formats.forEach(function (format) {
+            var option = el("option", format, {value: format});
+            option.selected = format === current;
+            select.appendChild(option);
+        });
+    }
+
+    function renderBoard(dashboard) {
+        var board = $("board");
+        var editable = allows("classifier-operator");
+        board.textContent = "";
+
+        dashboard.classes.forEach(function (column) {
+            var node = el("div", null, {"class": "column"});
+            node.dataset.class = column.name;
+            node.appendChild(el("h2", column.name + (column.dscp ? " (" + column.dscp + ")" : "")));
+            node.appendChild(el("div", column.protocols.length + " protocols, " +
+                formatBytes(column.traffic_bytes), {"class": "summary muted", title: column.description || ""}));
+
+            column.protocols.forEach(function (entry) {
+                node.appendChild(renderCard(entry, dashboard.classes, editable));
+            });
+
+            if (editable) {
+                node.addEventListener("dragover", function (event) {
+                    event.preventDefault();
+                    node.classList.add("drop-target");
+                });
+                node.addEventListener("dragleave", function () {
+                    node.classList.remove("drop-target");
+                });
+                node.addEventListener("drop", function (event) {
+                    event.preventDefault();
+                    node.classList.remove("drop-target");
+                    var protocol = event.dataTransfer.getData("text/plain");
+                    if (protocol) {
+                        reclassify(protocol, column.name);
+                    }
+                });
+            }
+            board.appendChild(node);
+        });
+    }
+
+    function renderCard(entry, classes, editable) {
+        var card = el("div", null, {"class": "card"});
+        if (entry.review) {
+            card.classList.add("review");
+        }
+        if (entry.override) {
+            card.classList.add("override");
+        }
+        card.appendChild(el("div", entry.protocol, {"class": "name"}));
+
+        var meta = el("div", null, {"class": "meta"});
+        meta.appendChild(el("span", entry.source + (entry.override ? " (" + entry.override + " override)" : "")));
+        meta.appendChild(el("span", formatConfidence(entry.confidence)));
+        meta.appendChild(el("span", formatBytes(entry.traffic_bytes)));
+        card.appendChild(meta);
+
+        if (!editable) {
+            return card;
+        }
+
+        card.setAttribute("draggable", "true");
+        card.addEventListener("dragstart", function (event) {
+            event.dataTransfer.setData("text/plain", entry.protocol);
+            event.dataTransfer.effectAllowed = "move";
+        });
+
+        var select = el("select", null, {"aria-label": "Class of " + entry.protocol});
+        classes.forEach(function (column) {
+            var option = el("option", column.name, {value: column.name});
+            option.selected = column.name === entry.class;
+            select.appendChild(option);
+        });
+        select.addEventListener("change", function () {
+            reclassify(entry.protocol, select.value);
+        });
+        card.appendChild(select);
+
+        if (entry.override === "api") {
+            var actions = el("div", null, {"class": "actions"});
+            var revert = el("button", "Remove override", {type: "button", "class": "secondary"});
+            revert.addEventListener("click", function () {
+                removeOverride(entry.protocol);
+            });
+            actions.appendChild(revert);
+            card.appendChild(actions);
+        }
+        return card;
+    }
+
+    function renderReview(dashboard) {
+        var rows = $("review-rows");
+        var editable = allows("classifier-operator");
+        rows.textContent = "";
+        $("review-count").textContent = dashboard.review.length;
+
+        if (dashboard.review.length === 0) {
+            var empty = el("tr");
+            empty.appendChild(el("td", "Nothing to review", {colspan: "6", "class": "muted"}));
+            rows.appendChild(empty);
+            return;
+        }
+
+        dashboard.review.forEach(function (entry) {
+            var row = el("tr");
+            row.appendChild(el("td", entry.protocol));
+            row.appendChild(el("td", entry.class));
+            row.appendChild(el("td", formatConfidence(entry.confidence)));
+            row.appendChild(el("td", entry.source));
+            row.appendChild(el("td", formatBytes(entry.traffic_bytes)));
+
+            var actions = el("td");
+            if (editable) {
+                var accept = el("button", "Accept", {type: "button"});
+                accept.addEventListener("click", function () {
+                    reclassify(entry.protocol, entry.class, "Accepted in review");
+                });
+                actions.appendChild(accept);
+            }
+            row.appendChild(actions);
+            rows.appendChild(row);
+        });
+    }
+
+    function reclassify(protocol, newClass, description) {
+        api("PUT", "/overrides/" + encodeURIComponent(protocol), {
+            "class": newClass,
+            description: description || "Reclassified in dashboard"
+        }).then(function () {
+            showMessage(protocol + " is now " + newClass, false);
+            return load();
+        }).catch(function (err) {
+            showMessage("Failed to reclassify " + protocol + ": " + err.message, true);
+        });
+    }
+
+    function removeOverride(protocol) {
+        api("DELETE", "/overrides/" + encodeURIComponent(protocol)).then(function () {
+            showMessage("Removed the override of " + protocol, false);
+            return load();
+        }).catch(function (err) {
+            showMessage("Failed to remove the override of " + protocol + ": " + err.message, true);
+        });
+    }
+
+    function loadPreview() {
+        var format = $("format").value || "cisco";
+        $("push").disabled = true;
+        return api("GET", "/config/preview?format=" + encodeURIComponent(format)).then(function (preview) {
+            state.preview = preview;
+            renderPreview(preview);
+            $("push").disabled = format !== "cisco";
+        }).catch(function (err) {
+            showMessage(err.message, true);
+        });
+    }
+
+    function renderPreview(preview) {
+        var changes = $("changes");
+        changes.textContent = "";
+        if (preview.changes.length === 0) {
+            changes.appendChild(el("li", "No class changes since the last run", {"class": "muted"}));
+        }
+        preview.changes.forEach(function (change) {
+            changes.appendChild(el("li", change.protocol + ": " + (change.from || "unclassified") + " → " + change.to));
+        });
+
+        $("diff-stats").textContent = "+" + preview.diff.added + " -" + preview.diff.removed;
+        var diff = $("diff");
+        diff.textContent = "";
+        if (!preview.diff.unified) {
+            diff.textContent = "The configuration is unchanged.";
+        }
+        preview.diff.unified.split("\n").forEach(function (line) {
+            var span = el("span", line + "\n");
+            if (line.indexOf("@@") === 0) {
+                span.className = "hunk";
+            } else if (line[0] === "+" && line.indexOf("+++") !== 0) {
+                span.className = "added";
+            } else if (line[0] === "-" && line.indexOf("---") !== 0) {
+                span.className = "removed";
+            }
+            diff.appendChild(span);
+        });
+        $("config").textContent = preview.config;
+    }
+
+    // push starts a run that regenerates and pushes the configuration, then
+    // follows it until it finishes
+    function push() {
+        var dashboard = state.dashboard;
+        var preview = state.preview;
+        if (!dashboard || !dashboard.run || !preview) {
+            return;
+        }
+        var summary = preview.changes.length + " class changes, +" + preview.diff.added +
+            " -" + preview.diff.removed + " configuration lines.";
+        if (!window.confirm("Push the configuration to the switch?\n\n" + summary)) {
+            return;
+        }
+
+        var request = {output: "cisco", push: true};
+        if (dashboard.run.source === "switch") {
+            request.source = "switch";
+        } else {
+            request.source = "inline";
+            request.protocols = dashboard.run.protocols;
+        }
+
+        $("push").disabled = true;
+        api("POST", "/runs", request).then(function (run) {
+            showMessage("Push queued as run " + run.id, false);
+            follow(run.id);
+        }).catch(function (err) {
+            $("push").disabled = false;
+            showMessage("Failed to start the push: " + err.message, true);
+        });
+    }
+
+    function follow(id) {
+        api("GET", "/runs/" + encodeURIComponent(id)).then(function (run) {
+            if (["succeeded", "failed", "cancelled"].indexOf(run.status) < 0) {
+                showMessage("Run " + id + " is " + run.status + (run.stage ? " (" + run.stage + ")" : ""), false);
+                setTimeout(function () {
+                    follow(id);
+                }, 2000);
+                return;
+            }
+            // With approval enabled the push becomes a change to approve
+            var proposed = run.stage === "approval" && run.change_id ? ", change " + run.change_id + " waits for approval" : "";
+            showMessage("Run " + id + " " + run.status + proposed + (run.error ? ": " + run.error : ""), run.status !== "succeeded");
+            load().then(loadPreview);
+        }).catch(function (err) {
+            showMessage(err.message, true);
+        });
+    }
+
+    function selectTab(name) {
+        Array.prototype.forEach.call(document.querySelectorAll(".tabs button"), function (button) {
+            button.classList.toggle("active", button.dataset.tab === name);
+        });
+        Array.prototype.forEach.call(document.querySelectorAll(".tab"), function (tab) {
+            tab.hidden = tab.id !== "tab-" + name;
+        });
+        if (name === "preview") {
+            loadPreview();
+        }
+    }
+
+    document.addEventListener("DOMContentLoaded", function () {
+        Array.prototype.forEach.call(document.querySelectorAll(".tabs button"), function (button) {
+            button.addEventListener("click", function () {
+                selectTab(button.dataset.tab);
+            });
+        });
+        $("token-form").addEventListener("submit", function (event) {
+            event.preventDefault();
+            state.token = $("token").value.trim();
+            sessionStorage.setItem("nbar_token", state.token);
+            $("token").value = "";
+            start();
+        });
+        $("format").addEventListener("change", loadPreview);
+        $("refresh-preview").addEventListener("click", loadPreview);
+        $("push").addEventListener("click", push);
+        start();
+    });
+}());
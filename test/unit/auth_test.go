@@ -0,0 +1,224 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// signJWT signs arbitrary claims with the test secret
+func signJWT(t *testing.T, header string, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(testJWTSecret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// bearer returns a request with a bearer token
+func bearer(token string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/v1/status", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestAuthenticator(t *testing.T) {
+	authenticator, err := auth.New(auth.Options{
+		JWTSecret: testJWTSecret,
+		Audience:  "nbar-classifier",
+		TokenTTL:  time.Hour,
+		Tokens:    []auth.Token{{Name: "servicenow", Token: "s3cret-token", Role: auth.RoleOperator}},
+	})
+	require.NoError(t, err)
+
+	t.Run("Roles", func(t *testing.T) {
+		assert.True(t, auth.RoleAdmin.Allows(auth.RoleDeployer))
+		assert.True(t, auth.RoleDeployer.Allows(auth.RoleOperator))
+		assert.False(t, auth.RoleOperator.Allows(auth.RoleDeployer))
+		assert.False(t, auth.Role("guest").Allows(auth.RoleViewer))
+	})
+
+	t.Run("API token", func(t *testing.T) {
+		principal, err := authenticator.Authenticate(bearer("s3cret-token"))
+		require.NoError(t, err)
+		assert.Equal(t, "servicenow", principal.Subject)
+		assert.Equal(t, auth.RoleOperator, principal.Role)
+		assert.Equal(t, auth.MethodToken, principal.Method)
+
+		_, err = authenticator.Authenticate(bearer("wrong"))
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		_, err = authenticator.Authenticate(bearer(""))
+		assert.ErrorIs(t, err, auth.ErrNoCredentials)
+	})
+
+	t.Run("Issued JWT", func(t *testing.T) {
+		token, expires, err := authenticator.Issue("alice", auth.RoleDeployer)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expires, time.Minute)
+
+		principal, err := authenticator.Authenticate(bearer(token))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", principal.Subject)
+		assert.Equal(t, auth.RoleDeployer, principal.Role)
+		assert.Equal(t, auth.MethodJWT, principal.Method)
+	})
+
+	t.Run("External JWT", func(t *testing.T) {
+		valid := map[string]interface{}{
+			"sub":   "bob",
+			"roles": []string{"viewer", "admin", "unknown"},
+			"aud":   []string{"other", "nbar-classifier"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		}
+		principal, err := authenticator.Authenticate(bearer(signJWT(t, `{"alg":"HS256"}`, valid)))
+		require.NoError(t, err)
+		assert.Equal(t, auth.RoleAdmin, principal.Role)
+
+		for name, claims := range map[string]map[string]interface{}{
+			"expired":      {"sub": "bob", "role": "admin", "aud": "nbar-classifier", "exp": time.Now().Add(-time.Minute).Unix()},
+			"no expiry":    {"sub": "bob", "role": "admin", "aud": "nbar-classifier"},
+			"not yet":      {"sub": "bob", "role": "admin", "aud": "nbar-classifier", "exp": time.Now().Add(time.Hour).Unix(), "nbf": time.Now().Add(time.Minute).Unix()},
+			"wrong aud":    {"sub": "bob", "role": "admin", "aud": "other", "exp": time.Now().Add(time.Minute).Unix()},
+			"unknown role": {"sub": "bob", "role": "root", "aud": "nbar-classifier", "exp": time.Now().Add(time.Minute).Unix()},
+		} {
+			_, err := authenticator.Authenticate(bearer(signJWT(t, `{"alg":"HS256"}`, claims)))
+			assert.ErrorIs(t, err, auth.ErrInvalidCredentials, name)
+		}
+
+		_, err = authenticator.Authenticate(bearer(signJWT(t, `{"alg":"none"}`, valid)))
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+		token := signJWT(t, `{"alg":"HS256"}`, valid)
+		_, err = authenticator.Authenticate(bearer(token[:len(token)-2] + "xx"))
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		_, err := auth.New(auth.Options{JWTSecret: "short"})
+		assert.Error(t, err)
+		_, err = auth.New(auth.Options{Tokens: []auth.Token{{Name: "ci", Token: "x", Role: "root"}}})
+		assert.Error(t, err)
+	})
+}
+
+func TestWebAuth(t *testing.T) {
+	authenticator, err := auth.New(auth.Options{
+		JWTSecret: testJWTSecret,
+		Tokens: []auth.Token{
+			{Name: "dashboard", Token: "viewer-token", Role: auth.RoleViewer},
+			{Name: "servicenow", Token: "operator-token", Role: auth.RoleOperator},
+			{Name: "release", Token: "deployer-token", Role: auth.RoleDeployer},
+		},
+	})
+	require.NoError(t, err)
+	manager, err := runs.New(runs.Options{}, func(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+		return nil
+	})
+	require.NoError(t, err)
+	defer manager.Close()
+
+	server := web.New(&config.WebConfig{CORSOrigins: []string{"https://noc.example.com"}}, newTestLogger(t), web.Backend{
+		Runs: manager,
+		Auth: authenticator,
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	send := func(method, path, token, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := send("GET", "/api/v1/status", "", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Bearer")
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/health", "", "").StatusCode)
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/status", "viewer-token", "").StatusCode)
+
+	dryRun := `{"source":"inline","protocols":["sip"],"dry_run":true}`
+	push := `{"source":"inline","protocols":["sip"],"push":true,"requested_by":"someone-else"}`
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/v1/runs", "viewer-token", dryRun).StatusCode)
+	assert.Equal(t, http.StatusAccepted, send("POST", "/api/v1/runs", "operator-token", dryRun).StatusCode)
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/v1/runs", "operator-token", push).StatusCode)
+	assert.Equal(t, http.StatusAccepted, send("POST", "/api/v1/runs", "deployer-token", push).StatusCode)
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/v1/cache/clear", "deployer-token", "").StatusCode)
+
+	requestedBy := make(map[string]bool)
+	for _, run := range manager.List() {
+		requestedBy[run.Request.RequestedBy] = true
+	}
+	assert.Equal(t, map[string]bool{"servicenow": true, "release": true}, requestedBy)
+
+	t.Run("Token exchange", func(t *testing.T) {
+		req, err := http.NewRequest("POST", ts.URL+"/api/v1/auth/token", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer operator-token")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		var issued struct {
+			Token string `json:"token"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&issued))
+
+		req, err = http.NewRequest("GET", ts.URL+"/api/v1/auth/whoami", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+issued.Token)
+		whoamiResp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer whoamiResp.Body.Close()
+		var principal auth.Principal
+		require.NoError(t, json.NewDecoder(whoamiResp.Body).Decode(&principal))
+		assert.Equal(t, "servicenow", principal.Subject)
+		assert.Equal(t, auth.RoleOperator, principal.Role)
+		assert.Equal(t, auth.MethodJWT, principal.Method)
+	})
+
+	t.Run("CORS allowlist", func(t *testing.T) {
+		preflight := func(origin string) *http.Response {
+			req, err := http.NewRequest("OPTIONS", ts.URL+"/api/v1/runs", nil)
+			require.NoError(t, err)
+			req.Header.Set("Origin", origin)
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+
+		resp := preflight("https://noc.example.com")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "https://noc.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+
+		resp = preflight("https://evil.example.com")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+}
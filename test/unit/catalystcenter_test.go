@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/catalystcenter"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+func TestCatalystCenterPolicy(t *testing.T) {
+	model := qos.DefaultModel()
+	mapping := catalystcenter.DefaultMapping(model)
+	assert.Equal(t, catalystcenter.ClassMapping{Relevance: catalystcenter.BusinessRelevant, ApplicationSet: "nbar-ef"}, mapping[qos.EF])
+	assert.Equal(t, catalystcenter.BusinessIrrelevant, mapping[qos.CS1].Relevance)
+	assert.Equal(t, catalystcenter.Default, catalystcenter.DefaultRelevance("default"))
+
+	// Two classes sharing one application set
+	mapping[qos.AF41] = catalystcenter.ClassMapping{Relevance: catalystcenter.BusinessRelevant, ApplicationSet: "collaboration-apps"}
+	mapping[qos.EF] = catalystcenter.ClassMapping{Relevance: catalystcenter.BusinessRelevant, ApplicationSet: "collaboration-apps"}
+
+	policy, err := catalystcenter.BuildPolicy(renderClassifications(), model, mapping, "campus")
+	require.NoError(t, err)
+	require.Len(t, policy.ApplicationSets, 3)
+	assert.Equal(t, catalystcenter.ApplicationSet{
+		Name:         "collaboration-apps",
+		Relevance:    catalystcenter.BusinessRelevant,
+		Classes:      []qos.Class{qos.EF, qos.AF41},
+		Applications: []string{"rtp", "sip", "zoom"},
+	}, policy.ApplicationSets[0])
+
+	intent := policy.Intent(map[string]string{"collaboration-apps": "set-1"})
+	assert.Equal(t, "campus_collaboration-apps", intent.CreateList[0].Name)
+	assert.Equal(t, "set-1", intent.CreateList[0].Producer.ScalableGroup[0].IDRef)
+	assert.Equal(t, "nbar-af21", intent.CreateList[1].Producer.ScalableGroup[0].IDRef)
+
+	mapping[qos.AF41] = catalystcenter.ClassMapping{Relevance: catalystcenter.Default, ApplicationSet: "collaboration-apps"}
+	_, err = catalystcenter.BuildPolicy(renderClassifications(), model, mapping, "campus")
+	assert.Error(t, err)
+	assert.Error(t, catalystcenter.ValidateMapping(map[qos.Class]catalystcenter.ClassMapping{qos.EF: {Relevance: "HIGH", ApplicationSet: "voice"}}))
+}
+
+// catalystCenterStub is a minimal in-memory Catalyst Center API
+type catalystCenterStub struct {
+	mu      sync.Mutex
+	sets    map[string]string
+	apps    map[string]string // application name -> application set ID
+	moved   []map[string]interface{}
+	intents []catalystcenter.PolicyIntent
+}
+
+func (s *catalystCenterStub) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	respond := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	task := map[string]interface{}{"response": map[string]string{"taskId": "task-1"}}
+
+	mux.HandleFunc("/dna/system/api/v1/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		respond(w, map[string]string{"Token": "token-1"})
+	})
+
+	authed := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Auth-Token") != "token-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			next(w, r)
+		}
+	}
+
+	mux.HandleFunc("/dna/intent/api/v1/application-policy-application-set", authed(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var body []map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			for _, set := range body {
+				s.sets[set["name"]] = "id-" + set["name"]
+			}
+			respond(w, task)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		var found []map[string]string
+		if id, exists := s.sets[name]; exists {
+			found = append(found, map[string]string{"id": id, "name": name})
+		}
+		respond(w, map[string]interface{}{"response": found})
+	}))
+
+	mux.HandleFunc("/dna/intent/api/v1/applications", authed(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var body []map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			s.moved = append(s.moved, body...)
+			respond(w, task)
+			return
+		}
+		name := r.URL.Query().Get("name")
+		var found []map[string]interface{}
+		if set, exists := s.apps[name]; exists {
+			found = append(found, map[string]interface{}{"id": "app-" + name, "name": name, "applicationSet": map[string]string{"idRef": set}})
+		}
+		respond(w, map[string]interface{}{"response": found})
+	}))
+
+	mux.HandleFunc("/dna/intent/api/v1/app-policy-intent", authed(func(w http.ResponseWriter, r *http.Request) {
+		var intent catalystcenter.PolicyIntent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&intent))
+		s.intents = append(s.intents, intent)
+		respond(w, task)
+	}))
+
+	return mux
+}
+
+func TestCatalystCenterPush(t *testing.T) {
+	stub := &catalystCenterStub{
+		sets: map[string]string{"nbar-ef": "id-nbar-ef"},
+		apps: map[string]string{"sip": "id-nbar-ef", "rtp": "id-other", "https": "id-other"},
+	}
+	server := httptest.NewServer(stub.handler(t))
+	defer server.Close()
+
+	model := qos.DefaultModel()
+	policy, err := catalystcenter.BuildPolicy(renderClassifications(), model, catalystcenter.DefaultMapping(model), "campus")
+	require.NoError(t, err)
+
+	client, err := catalystcenter.New(catalystcenter.Options{BaseURL: server.URL + "/", Username: "admin", Password: "secret"})
+	require.NoError(t, err)
+
+	result, err := client.Push(context.Background(), policy)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"nbar-af41", "nbar-af21", "nbar-cs1"}, result.CreatedSets)
+	assert.Equal(t, 2, result.Moved)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.ElementsMatch(t, []string{"zoom", "bt"}, result.Unknown)
+	assert.Equal(t, []string{"task-1", "task-1"}, result.TaskIDs)
+
+	require.Len(t, stub.moved, 2)
+	assert.Equal(t, "rtp", stub.moved[0]["name"])
+	assert.Equal(t, map[string]interface{}{"idRef": "id-nbar-ef"}, stub.moved[0]["applicationSet"])
+	assert.Equal(t, map[string]interface{}{"idRef": "id-nbar-af21"}, stub.moved[1]["applicationSet"])
+
+	require.Len(t, stub.intents, 1)
+	require.Len(t, stub.intents[0].CreateList, 4)
+	assert.Equal(t, "id-nbar-cs1", stub.intents[0].CreateList[3].Producer.ScalableGroup[0].IDRef)
+	assert.Equal(t, catalystcenter.BusinessIrrelevant, stub.intents[0].CreateList[3].ExclusiveContract.Clause[0].RelevanceLevel)
+
+	// Wrong credentials
+	client, err = catalystcenter.New(catalystcenter.Options{BaseURL: server.URL, Username: "admin", Password: "wrong"})
+	require.NoError(t, err)
+	_, err = client.Push(context.Background(), policy)
+	assert.Error(t, err)
+}
@@ -0,0 +1,351 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/changes"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+const baseChangeConfig = `class-map match-any CM_EF
+ match protocol sip
+!
+policy-map PM_MARK
+ class CM_EF
+  set dscp ef
+!
+`
+
+const nextChangeConfig = `class-map match-any CM_EF
+ match protocol sip
+ match protocol zoom
+!
+class-map match-any CM_AF21
+ match protocol netflix
+!
+policy-map PM_MARK
+ class CM_EF
+  set dscp ef
+ class CM_AF21
+  set dscp af21
+!
+`
+
+// pushRecorder is a change applier remembering what it pushed
+type pushRecorder struct {
+	pushes []string
+	err    error
+}
+
+func (p *pushRecorder) apply(ctx context.Context, change changes.Change, rollback bool) error {
+	if p.err != nil {
+		return p.err
+	}
+	if rollback {
+		p.pushes = append(p.pushes, "rollback "+change.ID)
+	} else {
+		p.pushes = append(p.pushes, "apply "+change.ID)
+	}
+	return nil
+}
+
+func TestChangeManager(t *testing.T) {
+	dir := t.TempDir()
+	opts := changes.Options{
+		StoreFile: filepath.Join(dir, "changes.json"),
+		AuditFile: filepath.Join(dir, "audit.jsonl"),
+		TTL:       time.Hour,
+	}
+	pusher := &pushRecorder{}
+	manager, err := changes.New(opts, pusher.apply)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	first, created, err := manager.Propose(changes.Proposal{
+		Config:          baseChangeConfig,
+		Classifications: map[string]qos.Classification{"sip": {Protocol: "sip", Class: qos.EF}},
+	}, "alice")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, changes.StateDraft, first.State)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), first.ExpiresAt, time.Minute)
+
+	// The same configuration is proposed once
+	again, created, err := manager.Propose(changes.Proposal{Config: baseChangeConfig}, "bob")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, again.ID)
+
+	_, err = manager.Apply(ctx, first.ID, "bob", "")
+	assert.ErrorIs(t, err, changes.ErrState)
+	_, err = manager.Approve(first.ID, "Alice", "")
+	assert.ErrorIs(t, err, changes.ErrSelfApproval)
+	approved, err := manager.Approve(first.ID, "bob", "looks good")
+	require.NoError(t, err)
+	assert.Equal(t, changes.StateApproved, approved.State)
+	assert.Equal(t, "bob", approved.ApprovedBy)
+
+	applied, err := manager.Apply(ctx, first.ID, "bob", "")
+	require.NoError(t, err)
+	assert.Equal(t, changes.StateApplied, applied.State)
+	assert.Equal(t, []string{"apply " + first.ID}, pusher.pushes)
+
+	t.Run("Diff against the applied change", func(t *testing.T) {
+		next, created, err := manager.Propose(changes.Proposal{
+			Config: nextChangeConfig,
+			Classifications: map[string]qos.Classification{
+				"sip":     {Protocol: "sip", Class: qos.EF},
+				"zoom":    {Protocol: "zoom", Class: qos.EF},
+				"netflix": {Protocol: "netflix", Class: qos.AF21},
+			},
+		}, "alice")
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, first.ID, next.BaseID)
+		assert.Equal(t, baseChangeConfig, next.BaseConfig)
+		assert.True(t, next.Diff.Changed())
+		assert.Contains(t, next.Diff.Unified, "+ match protocol zoom\n")
+		assert.Equal(t, []changes.ClassChange{
+			{Protocol: "netflix", To: qos.AF21},
+			{Protocol: "zoom", To: qos.EF},
+		}, next.ClassChanges)
+
+		// Another process sees the approval
+		other, err := changes.New(opts, pusher.apply)
+		require.NoError(t, err)
+		_, err = other.Approve(next.ID, "carol", "")
+		require.NoError(t, err)
+
+		// A failed push keeps the change approved
+		pusher.err = errors.New("connection refused")
+		failed, err := manager.Apply(ctx, next.ID, "carol", "")
+		assert.Error(t, err)
+		assert.Equal(t, changes.StateApproved, failed.State)
+		assert.Equal(t, changes.ActionApplyFailed, failed.History[len(failed.History)-1].Action)
+		assert.Equal(t, "connection refused", failed.History[len(failed.History)-1].Error)
+		pusher.err = nil
+
+		_, err = manager.Apply(ctx, next.ID, "carol", "")
+		require.NoError(t, err)
+
+		// Only the latest applied change can be rolled back
+		_, err = manager.Rollback(ctx, first.ID, "carol", "")
+		assert.ErrorIs(t, err, changes.ErrState)
+		rolledBack, err := manager.Rollback(ctx, next.ID, "carol", "netflix complaints")
+		require.NoError(t, err)
+		assert.Equal(t, changes.StateRolledBack, rolledBack.State)
+		assert.Equal(t, []string{"apply " + first.ID, "apply " + next.ID, "rollback " + next.ID}, pusher.pushes)
+
+		var actions []string
+		for _, event := range rolledBack.History {
+			actions = append(actions, event.Action)
+		}
+		assert.Equal(t, []string{"propose", "approve", "apply_failed", "apply", "rollback"}, actions)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		change, _, err := manager.Propose(changes.Proposal{Config: "class-map match-any CM_CS1\n match protocol bittorrent\n"}, "alice")
+		require.NoError(t, err)
+		rejected, err := manager.Reject(change.ID, "bob", "wrong class")
+		require.NoError(t, err)
+		assert.Equal(t, changes.StateRejected, rejected.State)
+		_, err = manager.Approve(change.ID, "bob", "")
+		assert.ErrorIs(t, err, changes.ErrState)
+		_, err = manager.Get("missing")
+		assert.ErrorIs(t, err, changes.ErrNotFound)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		short, err := changes.New(changes.Options{TTL: time.Millisecond}, pusher.apply)
+		require.NoError(t, err)
+		change, _, err := short.Propose(changes.Proposal{Config: baseChangeConfig}, "alice")
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		expired, err := short.Get(change.ID)
+		require.NoError(t, err)
+		assert.Equal(t, changes.StateExpired, expired.State)
+		assert.Equal(t, changes.SystemActor, expired.History[len(expired.History)-1].Actor)
+		_, err = short.Approve(change.ID, "bob", "")
+		assert.ErrorIs(t, err, changes.ErrState)
+	})
+
+	// Every event is audited with the change it belongs to
+	file, err := os.Open(opts.AuditFile)
+	require.NoError(t, err)
+	defer file.Close()
+	var events []changes.Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event changes.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		assert.NotEmpty(t, event.ChangeID)
+		events = append(events, event)
+	}
+	assert.Len(t, events, 10)
+}
+
+func TestChangeRollOut(t *testing.T) {
+	manager, err := changes.New(changes.Options{StoreFile: filepath.Join(t.TempDir(), "changes.json")}, (&pushRecorder{}).apply)
+	require.NoError(t, err)
+	ctx := context.Background()
+	approve := func(config string) changes.Change {
+		change, _, err := manager.Propose(changes.Proposal{Config: config}, "alice")
+		require.NoError(t, err)
+		change, err = manager.Approve(change.ID, "bob", "")
+		require.NoError(t, err)
+		return change
+	}
+	fleet := func(hosts []string, err error) changes.FleetPusher {
+		return func(ctx context.Context, change changes.Change) ([]string, error) {
+			return hosts, err
+		}
+	}
+
+	// A rollout that reached no device leaves the change approved
+	change := approve(baseChangeConfig)
+	refused, err := manager.RollOut(ctx, change.ID, "carol", "", fleet(nil, errors.New("push refused")))
+	require.Error(t, err)
+	assert.Equal(t, changes.StateApproved, refused.State)
+	assert.Equal(t, changes.ActionRolloutFail, refused.History[len(refused.History)-1].Action)
+
+	applied, err := manager.RollOut(ctx, change.ID, "carol", "fleet", fleet([]string{"sw1", "sw2"}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, changes.StateApplied, applied.State)
+	assert.Equal(t, []string{"sw1", "sw2"}, applied.Hosts)
+	assert.Equal(t, "carol", applied.AppliedBy)
+	last := applied.History[len(applied.History)-1]
+	assert.Equal(t, changes.ActionRollout, last.Action)
+	assert.Equal(t, "fleet", last.Comment)
+	_, err = manager.RollOut(ctx, change.ID, "carol", "", fleet(nil, nil))
+	assert.ErrorIs(t, err, changes.ErrState)
+
+	// A halted rollout partially applies the change, which is final
+	next := approve(nextChangeConfig)
+	partial, err := manager.RollOut(ctx, next.ID, "carol", "", fleet([]string{"sw1"}, errors.New("rollout halted: wave access")))
+	require.Error(t, err)
+	assert.Equal(t, changes.StatePartiallyApplied, partial.State)
+	assert.Equal(t, []string{"sw1"}, partial.Hosts)
+	assert.Equal(t, "rollout halted: wave access", partial.History[len(partial.History)-1].Error)
+	_, err = manager.Apply(ctx, next.ID, "carol", "")
+	assert.ErrorIs(t, err, changes.ErrState)
+}
+
+func TestRollbackConfig(t *testing.T) {
+	rollback, err := changes.RollbackConfig(changes.Change{Config: nextChangeConfig, BaseConfig: baseChangeConfig})
+	require.NoError(t, err)
+	assert.Equal(t, `policy-map PM_MARK
+ no class CM_AF21
+!
+class-map match-any CM_EF
+ no match protocol zoom
+!
+no class-map match-any CM_AF21
+!
+`+baseChangeConfig, rollback)
+
+	_, err = changes.RollbackConfig(changes.Change{Config: nextChangeConfig})
+	assert.ErrorIs(t, err, changes.ErrNoRollback)
+}
+
+func TestWebChanges(t *testing.T) {
+	authenticator, err := auth.New(auth.Options{
+		JWTSecret: testJWTSecret,
+		Tokens: []auth.Token{
+			{Name: "alice", Token: "alice-token", Role: auth.RoleDeployer},
+			{Name: "bob", Token: "bob-token", Role: auth.RoleDeployer},
+			{Name: "servicenow", Token: "operator-token", Role: auth.RoleOperator},
+		},
+	})
+	require.NoError(t, err)
+
+	pusher := &pushRecorder{}
+	manager, err := changes.New(changes.Options{}, pusher.apply)
+	require.NoError(t, err)
+	runManager, err := runs.New(runs.Options{}, func(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+		tracker.Change(req.ChangeID)
+		_, err := manager.Apply(ctx, req.ChangeID, req.RequestedBy, req.Comment)
+		return err
+	})
+	require.NoError(t, err)
+	defer runManager.Close()
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{
+		Auth:    authenticator,
+		Runs:    runManager,
+		Changes: manager,
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	send := func(method, path, token, body string, v interface{}) int {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if v != nil {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	change, _, err := manager.Propose(changes.Proposal{Config: nextChangeConfig}, "alice")
+	require.NoError(t, err)
+
+	var list struct {
+		Changes []changes.Change `json:"changes"`
+		Total   int              `json:"total"`
+	}
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/changes?state=draft", "operator-token", "", &list))
+	require.Equal(t, 1, list.Total)
+	assert.Equal(t, change.ID, list.Changes[0].ID)
+	assert.Empty(t, list.Changes[0].Config)
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/changes?state=applied", "operator-token", "", &list))
+	assert.Equal(t, 0, list.Total)
+
+	var got changes.Change
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/changes/"+change.ID, "operator-token", "", &got))
+	assert.Equal(t, nextChangeConfig, got.Config)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/changes/missing", "operator-token", "", nil))
+
+	path := "/api/v1/changes/" + change.ID
+	assert.Equal(t, http.StatusForbidden, send("POST", path+"/approve", "operator-token", "", nil))
+	assert.Equal(t, http.StatusForbidden, send("POST", path+"/approve", "alice-token", "", nil))
+	assert.Equal(t, http.StatusConflict, send("POST", path+"/apply", "bob-token", "", nil))
+	assert.Equal(t, http.StatusOK, send("POST", path+"/approve", "bob-token", `{"comment":"ok"}`, &got))
+	assert.Equal(t, changes.StateApproved, got.State)
+	assert.Equal(t, "bob", got.ApprovedBy)
+
+	var run runs.Run
+	assert.Equal(t, http.StatusAccepted, send("POST", path+"/apply", "bob-token", "", &run))
+	assert.Equal(t, runs.SourceChange, run.Request.Source)
+	finished := waitForRun(t, runManager, run.ID, runs.StatusSucceeded)
+	assert.Equal(t, change.ID, finished.ChangeID)
+	assert.Equal(t, []string{"apply " + change.ID}, pusher.pushes)
+
+	got, err = manager.Get(change.ID)
+	require.NoError(t, err)
+	assert.Equal(t, changes.StateApplied, got.State)
+	assert.Equal(t, "bob", got.AppliedBy)
+
+	// Change runs are deploys
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/v1/runs", "operator-token", `{"source":"change","change_id":"`+change.ID+`"}`, nil))
+}
@@ -0,0 +1,136 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+)
+
+func TestQoSConfigClassModel(t *testing.T) {
+	t.Run("Built-in model when nothing is configured", func(t *testing.T) {
+		cfg := &config.QoSConfig{DefaultClass: "CS1"}
+
+		model, err := cfg.ClassModel()
+		require.NoError(t, err)
+		assert.Equal(t, []qos.Class{qos.EF, qos.AF41, qos.AF21, qos.CS1, qos.Other}, model.Classes())
+		assert.Contains(t, cfg.ClassProtocols()[qos.EF], "sip")
+	})
+
+	t.Run("Configured classes without preset", func(t *testing.T) {
+		cfg := &config.QoSConfig{
+			DefaultClass: "BULK",
+			Classes: map[string]config.QoSClassConfig{
+				"VOICE": {DSCP: "ef", Priority: 1, Protocols: []string{"sip"}},
+				"BULK":  {DSCP: "af11", Priority: 2},
+			},
+		}
+
+		model, err := cfg.ClassModel()
+		require.NoError(t, err)
+		assert.Equal(t, []qos.Class{"VOICE", "BULK", qos.Other}, model.Classes())
+		protocols := cfg.ClassProtocols()
+		assert.Equal(t, []string{"sip"}, protocols["VOICE"])
+		assert.Empty(t, protocols["BULK"])
+	})
+
+	t.Run("Preset with overrides", func(t *testing.T) {
+		cfg := &config.QoSConfig{
+			Preset:       qos.Preset12Class,
+			DefaultClass: "DF",
+			Classes: map[string]config.QoSClassConfig{
+				"CS3":   {Description: "SIP and SCCP", Protocols: []string{"rtcp"}},
+				"GUEST": {DSCP: "cs1", Priority: 20},
+			},
+		}
+
+		model, err := cfg.ClassModel()
+		require.NoError(t, err)
+		assert.Len(t, model.MarkingClasses(), 13)
+
+		def, exists := model.Lookup("CS3")
+		require.True(t, exists)
+		assert.Equal(t, "SIP and SCCP", def.Description)
+		assert.Equal(t, "cs3", def.DSCP)
+
+		protocols := cfg.ClassProtocols()
+		assert.Contains(t, protocols["CS2"], "ssh")
+		assert.Equal(t, 1, countOccurrences(protocols, "rtcp"))
+	})
+
+	t.Run("Unknown preset", func(t *testing.T) {
+		cfg := &config.QoSConfig{Preset: "16-class"}
+		_, err := cfg.ClassModel()
+		assert.Error(t, err)
+	})
+}
+
+func TestQoSConfigPresetMerging(t *testing.T) {
+	load := func(t *testing.T, qosConfig string) (*config.Config, error) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		data := "ssh:\n  host: sw1\n  user: admin\nai:\n  api_key: test\nqos:\n" + qosConfig
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		return config.LoadConfig(path)
+	}
+
+	t.Run("Preset default class", func(t *testing.T) {
+		cfg, err := load(t, "  preset: rfc4594-12-class\n")
+		require.NoError(t, err)
+		assert.Equal(t, "DF", cfg.QoS.DefaultClass)
+
+		model, err := cfg.QoS.ClassModel()
+		require.NoError(t, err)
+		assert.Equal(t, qos.DF, model.DefaultClass())
+	})
+
+	t.Run("Explicit classes override and extend the preset", func(t *testing.T) {
+		cfg, err := load(t, `  preset: cisco-8-class
+  classes:
+    EF:
+      description: "Voice only"
+      protocols: ["sip"]
+    GUEST:
+      dscp: "cs1"
+      priority: 9
+`)
+		require.NoError(t, err)
+		model, err := cfg.QoS.ClassModel()
+		require.NoError(t, err)
+		assert.Equal(t, []qos.Class{qos.CS6, qos.EF, qos.AF41, qos.AF31, qos.CS3, qos.AF21, qos.DF, qos.CS1, "GUEST"}, model.MarkingClasses())
+		def, exists := model.Lookup(qos.EF)
+		require.True(t, exists)
+		assert.Equal(t, "Voice only", def.Description)
+		assert.Equal(t, 2, def.Priority)
+	})
+
+	t.Run("Explicit classes clashing with preset priorities", func(t *testing.T) {
+		_, err := load(t, `  preset: rfc4594-12-class
+  classes:
+    EF:
+      dscp: "ef"
+      priority: 1
+    GUEST:
+      dscp: "cs1"
+      priority: 4
+`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is already used by class")
+	})
+}
+
+func countOccurrences(protocols map[qos.Class][]string, protocol string) int {
+	count := 0
+	for _, list := range protocols {
+		for _, p := range list {
+			if p == protocol {
+				count++
+			}
+		}
+	}
+	return count
+}
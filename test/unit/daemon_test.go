@@ -0,0 +1,119 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/daemon"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/schedule"
+)
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	log, err := logger.New(&config.LoggingConfig{Level: "error", Output: "stderr"})
+	require.NoError(t, err)
+	return log
+}
+
+func TestStatePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "serve.json")
+	store, err := daemon.OpenStore(path)
+	require.NoError(t, err)
+
+	var added []string
+	require.NoError(t, store.Update(func(state *daemon.State) {
+		added = state.AddProtocols([]string{"sip", "rtp"})
+	}))
+	assert.Equal(t, []string{"sip", "rtp"}, added)
+	require.NoError(t, store.Update(func(state *daemon.State) {
+		added = state.AddProtocols([]string{"rtp", "ssh"})
+		state.Classifications = map[string]qos.Classification{"sip": {Protocol: "sip", Class: qos.EF}}
+		state.Job("discovery").Runs = 2
+	}))
+	assert.Equal(t, []string{"ssh"}, added)
+
+	reopened, err := daemon.OpenStore(path)
+	require.NoError(t, err)
+	state := reopened.Snapshot()
+	assert.Equal(t, []string{"rtp", "sip", "ssh"}, state.Protocols)
+	assert.Equal(t, qos.EF, state.Classifications["sip"].Class)
+	assert.Equal(t, 2, state.Jobs["discovery"].Runs)
+	assert.False(t, state.UpdatedAt.IsZero())
+
+	// Snapshots are copies
+	state.Protocols[0] = "changed"
+	assert.Equal(t, "rtp", reopened.Snapshot().Protocols[0])
+}
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	store, err := daemon.OpenStore("")
+	require.NoError(t, err)
+	scheduler := daemon.NewScheduler(newTestLogger(t), store)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return err
+		}
+	}
+	hourly, err := schedule.Parse("@hourly")
+	require.NoError(t, err)
+	often, err := schedule.Parse("@every 20ms")
+	require.NoError(t, err)
+
+	require.NoError(t, scheduler.SetJobs([]daemon.Job{
+		{Name: "discovery", Schedule: hourly, RunOnStart: true, Run: record("discovery", nil)},
+		{Name: "classification", Schedule: hourly, RunOnStart: true, Run: record("classification", errors.New("AI unavailable"))},
+		{Name: "drift_check", Schedule: often, Run: record("drift_check", nil)},
+		{Name: "push", Schedule: hourly, Run: func(context.Context) error { panic("boom") }},
+	}))
+	assert.Error(t, scheduler.SetJobs([]daemon.Job{{Name: "a", Schedule: hourly}}))
+	assert.Error(t, scheduler.Trigger("unknown"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- scheduler.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		state := store.Snapshot()
+		return state.Job("drift_check").Runs >= 2
+	}, 2*time.Second, 5*time.Millisecond)
+
+	require.NoError(t, scheduler.Trigger("push"))
+	ran := make(chan struct{})
+	scheduler.Do(func() { close(ran) })
+	<-ran
+	require.Eventually(t, func() bool {
+		state := store.Snapshot()
+		return state.Job("push").Runs == 1
+	}, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	mu.Lock()
+	assert.Equal(t, []string{"discovery", "classification"}, order[:2])
+	mu.Unlock()
+
+	state := store.Snapshot()
+	assert.Equal(t, 1, state.Jobs["discovery"].Runs)
+	assert.Empty(t, state.Jobs["discovery"].LastError)
+	assert.False(t, state.Jobs["discovery"].LastSuccess.IsZero())
+	assert.Equal(t, 1, state.Jobs["classification"].Failures)
+	assert.Equal(t, "AI unavailable", state.Jobs["classification"].LastError)
+	assert.Contains(t, state.Jobs["push"].LastError, "boom")
+	assert.True(t, state.Jobs["discovery"].NextRun.After(time.Now()))
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rules"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// dashboardResponse is the part of the dashboard checked by the tests
+type dashboardResponse struct {
+	Classes []struct {
+		Name      qos.Class `json:"name"`
+		Traffic   uint64    `json:"traffic_bytes"`
+		Protocols []struct {
+			Protocol string `json:"protocol"`
+			Source   string `json:"source"`
+			Override string `json:"override"`
+			Review   bool   `json:"review"`
+		} `json:"protocols"`
+	} `json:"classes"`
+	Review []struct {
+		Protocol string `json:"protocol"`
+	} `json:"review"`
+	Formats []string `json:"formats"`
+}
+
+// classOf returns the dashboard column a protocol is in and its override
+func (d dashboardResponse) classOf(protocol string) (qos.Class, string) {
+	for _, column := range d.Classes {
+		for _, entry := range column.Protocols {
+			if entry.Protocol == protocol {
+				return column.Name, entry.Override
+			}
+		}
+	}
+	return "", ""
+}
+
+// reviewQueue returns the protocols waiting for review in order
+func (d dashboardResponse) reviewQueue() []string {
+	var protocols []string
+	for _, entry := range d.Review {
+		protocols = append(protocols, entry.Protocol)
+	}
+	return protocols
+}
+
+func TestWebDashboard(t *testing.T) {
+	classifier := newConfigClassifier(t)
+	manager, err := rules.New(rules.Options{}, classifier, nil)
+	require.NoError(t, err)
+
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{MarkingPolicyName: "PM_MARK"})
+	require.NoError(t, err)
+	outputs := output.NewDefaultRegistry()
+	require.NoError(t, outputs.Register(output.NewCiscoGenerator(renderer, nil)))
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{
+		Version:    "test",
+		Classifier: classifier,
+		Rules:      manager,
+		Outputs:    outputs,
+	})
+	server.SetLastRun(&web.RunResult{
+		Source:    "switch",
+		Protocols: []string{"sip", "netflix", "bittorrent", "mystery-app"},
+		Classifications: map[string]qos.Classification{
+			"sip":         {Protocol: "sip", Class: qos.EF, Confidence: 1, Source: "predefined"},
+			"netflix":     {Protocol: "netflix", Class: qos.AF21, Confidence: 0.9, Source: "ai"},
+			"bittorrent":  {Protocol: "bittorrent", Class: qos.CS1, Confidence: 0.6, Source: "ai"},
+			"mystery-app": {Protocol: "mystery-app", Class: qos.CS1, Confidence: 0.5, Source: "default"},
+		},
+		Traffic:    map[string]uint64{"sip": 1000, "netflix": 5000, "bittorrent": 9000},
+		StartedAt:  time.Now().Add(-time.Minute),
+		FinishedAt: time.Now(),
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	// The page and its assets are embedded
+	resp, err := http.Get(ts.URL + "/")
+	require.NoError(t, err)
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "default-src 'self'")
+	assert.Contains(t, string(page), `<script src="/ui/app.js" defer></script>`)
+	assert.Contains(t, string(page), "test")
+
+	for _, asset := range []string{"/ui/app.js", "/ui/app.css"} {
+		resp, err := http.Get(ts.URL + asset)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, asset)
+	}
+
+	var dashboard dashboardResponse
+	getJSON(t, "GET", ts.URL+"/api/v1/dashboard", http.StatusOK, &dashboard)
+	require.NotEmpty(t, dashboard.Classes)
+	assert.Equal(t, qos.EF, dashboard.Classes[0].Name)
+	assert.Contains(t, dashboard.Formats, "cisco")
+	class, override := dashboard.classOf("sip")
+	assert.Equal(t, qos.EF, class)
+	assert.Equal(t, rules.OriginConfig, override)
+	for _, column := range dashboard.Classes {
+		if column.Name == qos.AF21 {
+			assert.Equal(t, uint64(5000), column.Traffic)
+		}
+	}
+	// Guesses wait for review, busiest first
+	assert.Equal(t, []string{"bittorrent", "mystery-app"}, dashboard.reviewQueue())
+
+	// Reclassifying is an override, which the dashboard and preview pick up
+	req, err := http.NewRequest("PUT", ts.URL+"/api/v1/overrides/bittorrent", bytes.NewBufferString(`{"class":"AF21"}`))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	getJSON(t, "GET", ts.URL+"/api/v1/dashboard", http.StatusOK, &dashboard)
+	class, override = dashboard.classOf("bittorrent")
+	assert.Equal(t, qos.AF21, class)
+	assert.Equal(t, rules.OriginAPI, override)
+	assert.Equal(t, []string{"mystery-app"}, dashboard.reviewQueue())
+
+	var preview struct {
+		Config  string      `json:"config"`
+		Diff    output.Diff `json:"diff"`
+		Changes []struct {
+			Protocol string    `json:"protocol"`
+			From     qos.Class `json:"from"`
+			To       qos.Class `json:"to"`
+		} `json:"changes"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/config/preview?format=cisco", http.StatusOK, &preview)
+	require.Len(t, preview.Changes, 1)
+	assert.Equal(t, "bittorrent", preview.Changes[0].Protocol)
+	assert.Equal(t, qos.CS1, preview.Changes[0].From)
+	assert.Equal(t, qos.AF21, preview.Changes[0].To)
+	assert.True(t, preview.Diff.Changed())
+	assert.Contains(t, preview.Diff.Unified, "+ match protocol bittorrent\n")
+	assert.Contains(t, preview.Config, "match protocol bittorrent")
+
+	var apiErr map[string]interface{}
+	getJSON(t, "GET", ts.URL+"/api/v1/config/preview?format=missing", http.StatusBadRequest, &apiErr)
+}
@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/input"
+)
+
+func TestInputDetect(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected input.Format
+	}{
+		{filepath.Join("testdata", "nbar", "c9300-16.12-stack.txt"), input.FormatProtocolDiscovery},
+		{filepath.Join("testdata", "nbar", "c9300-17.12-stats-byte-count.txt"), input.FormatProtocolDiscovery},
+		{filepath.Join("testdata", "input", "c9300-show-tech.txt"), input.FormatShowTech},
+		{filepath.Join("testdata", "input", "avc-collector.csv"), input.FormatCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.file), func(t *testing.T) {
+			result, err := input.Load(tt.file, input.FormatAuto)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Format)
+			assert.NotEmpty(t, result.Protocols)
+		})
+	}
+
+	assert.Equal(t, input.FormatList, input.Detect("# saved list\nssl\nms-teams\n"))
+	assert.Equal(t, input.FormatRunningConfig, input.Detect("class-map match-any CM_VOICE\n match protocol rtp-audio\n"))
+}
+
+func TestInputParse(t *testing.T) {
+	result, err := input.Parse("# comment\nSSL\n\nms-teams\nssl\nbad name!\n", input.FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, input.FormatList, result.Format)
+	assert.Equal(t, []string{"ssl", "ms-teams"}, result.Protocols)
+	assert.Equal(t, []string{"bad name!"}, result.Invalid)
+
+	result, err = input.Load(filepath.Join("testdata", "input", "c9300-show-tech.txt"), input.FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rtp-audio", "bittorrent", "netflix", "ssl"}, result.Protocols)
+
+	result, err = input.Load(filepath.Join("testdata", "input", "c9300-show-tech.txt"), input.FormatRunningConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rtp-audio", "bittorrent", "netflix"}, result.Protocols)
+
+	result, err = input.Load(filepath.Join("testdata", "input", "avc-collector.csv"), input.FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ms-teams", "webex-meeting", "youtube"}, result.Protocols)
+	assert.Equal(t, []string{"unknown-app-(1/2)"}, result.Invalid)
+
+	result, err = input.Parse("app;bytes\nlayer7 dns;100\n", input.FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dns"}, result.Protocols)
+
+	_, err = input.Parse("host,bytes\nsw1,100\n", input.FormatCSV)
+	assert.Error(t, err)
+
+	_, err = input.ParseFormat("xml")
+	assert.Error(t, err)
+	format, err := input.ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, input.FormatAuto, format)
+}
@@ -0,0 +1,182 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+)
+
+const interfaceStatusOutput = `
+Port         Name               Status       Vlan       Duplex  Speed Type
+Gi1/0/1      AP-Floor1          connected    10         a-full a-1000 10/100/1000BaseTX
+Gi1/0/2                         notconnect   10           auto   auto 10/100/1000BaseTX
+Gi1/0/3      Printer            connected    20         a-full  a-100 10/100/1000BaseTX
+Gi1/0/4      AP-Floor2          connected    30         a-full a-1000 10/100/1000BaseTX
+Te1/1/1      Uplink-Core        connected    trunk        full    10G SFP-10GBase-SR
+Po1          Uplink-Bundle      connected    trunk      a-full a-10G N/A
+`
+
+const interfaceRunningConfig = `
+interface GigabitEthernet1/0/1
+ description AP-Floor1 Lobby
+ switchport access vlan 10
+ switchport mode access
+!
+interface GigabitEthernet1/0/2
+ switchport access vlan 10
+ service-policy input PM_MARK
+!
+interface GigabitEthernet1/0/3
+ description Printer
+ switchport access vlan 20
+ service-policy input PM_MARK
+!
+interface GigabitEthernet1/0/4
+ description AP-Floor2
+ service-policy input PM_LEGACY
+!
+interface TenGigabitEthernet1/1/1
+ description Uplink-Core
+ switchport mode trunk
+!
+`
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "GigabitEthernet1/0/1", interfaces.NormalizeName("Gi1/0/1"))
+	assert.Equal(t, "TwoGigabitEthernet1/0/1", interfaces.NormalizeName("Tw1/0/1"))
+	assert.Equal(t, "TwentyFiveGigE1/1/1", interfaces.NormalizeName("Twe1/1/1"))
+	assert.Equal(t, "FiveGigabitEthernet1/0/1", interfaces.NormalizeName("Fi1/0/1"))
+	assert.Equal(t, "FortyGigabitEthernet1/1/1", interfaces.NormalizeName("Fo1/1/1"))
+	assert.Equal(t, "Port-channel10", interfaces.NormalizeName("Po10"))
+	assert.Equal(t, "TenGigabitEthernet1/1/1", interfaces.NormalizeName("TenGigabitEthernet1/1/1"))
+}
+
+func TestExpandRange(t *testing.T) {
+	names, err := interfaces.ExpandRange("Gi1/0/1-3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GigabitEthernet1/0/1", "GigabitEthernet1/0/2", "GigabitEthernet1/0/3"}, names)
+
+	names, err = interfaces.ExpandRange("Port-channel10")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Port-channel10"}, names)
+
+	names, err = interfaces.ExpandRange("Po10-11")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Port-channel10", "Port-channel11"}, names)
+
+	names, err = interfaces.ExpandRange("Fi1/0/1-12")
+	require.NoError(t, err)
+	require.Len(t, names, 12)
+	assert.Equal(t, "FiveGigabitEthernet1/0/1", names[0])
+	assert.Equal(t, "FiveGigabitEthernet1/0/12", names[11])
+
+	names, err = interfaces.ExpandRange("Gi1/0/1.100")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GigabitEthernet1/0/1.100"}, names)
+
+	for _, spec := range []string{
+		"Gi1/0/5-2",       // reversed range
+		"Gi1/0/1-2/0/4",   // cross-slot range
+		"Gi1/0/1-",        // missing range end
+		"Gi1/0/1-x",       // non-numeric range end
+		"Gi1/0/1.100-200", // ranged subinterface
+		"uplinks",         // no interface number
+		"",                // empty
+		"1/0/1",           // no interface type
+		"Foo1/0/1",        // unknown interface type
+		"Gi1//1",          // malformed number
+	} {
+		_, err := interfaces.ExpandRange(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseInterfaceStatus(t *testing.T) {
+	ifaces, err := interfaces.ParseInterfaceStatus(interfaceStatusOutput)
+	require.NoError(t, err)
+	require.Len(t, ifaces, 6)
+
+	assert.Equal(t, "GigabitEthernet1/0/1", ifaces[0].Name)
+	assert.Equal(t, "AP-Floor1", ifaces[0].Description)
+	assert.Equal(t, "connected", ifaces[0].Status)
+	vlan, isAccess := ifaces[0].AccessVLAN()
+	assert.True(t, isAccess)
+	assert.Equal(t, 10, vlan)
+
+	assert.Equal(t, "", ifaces[1].Description)
+	assert.Equal(t, "notconnect", ifaces[1].Status)
+
+	assert.Equal(t, "trunk", ifaces[4].VLAN)
+	_, isAccess = ifaces[4].AccessVLAN()
+	assert.False(t, isAccess)
+
+	_, err = interfaces.ParseInterfaceStatus("% Invalid input detected")
+	assert.Error(t, err)
+
+	// mGig ports are listed abbreviated and matched to the running config
+	status, err := interfaces.ParseInterfaceStatus(`
+Port         Name               Status       Vlan       Duplex  Speed Type
+Fi1/0/1      AP-Floor3          connected    10         a-full a-5000 100/1000/2.5G/5G/10GBaseTX
+`)
+	require.NoError(t, err)
+	merged := interfaces.Merge(status, interfaces.ParseRunningConfig(`
+interface FiveGigabitEthernet1/0/1
+ switchport access vlan 10
+ service-policy input PM_MARK
+!
+`))
+	require.Len(t, merged, 1)
+	assert.Equal(t, "FiveGigabitEthernet1/0/1", merged[0].Name)
+	assert.Equal(t, "PM_MARK", merged[0].InputPolicy)
+}
+
+func TestAttachmentDiff(t *testing.T) {
+	status, err := interfaces.ParseInterfaceStatus(interfaceStatusOutput)
+	require.NoError(t, err)
+	ifaces := interfaces.Merge(status, interfaces.ParseRunningConfig(interfaceRunningConfig))
+
+	selector, err := interfaces.NewSelector([]interfaces.Target{
+		{AccessVLANs: []int{10}, Direction: interfaces.Input},
+		{DescriptionPattern: "^AP-", Direction: interfaces.Input},
+		{Interfaces: []string{"Te1/1/1"}, Direction: interfaces.Output},
+	})
+	require.NoError(t, err)
+
+	changes, conflicts := interfaces.Diff(ifaces, selector, "PM_MARK", "PM_QUEUE")
+
+	assert.Equal(t, []interfaces.Change{
+		{Interface: "GigabitEthernet1/0/1", Direction: interfaces.Input, Policy: "PM_MARK"},
+		{Interface: "GigabitEthernet1/0/3", Direction: interfaces.Input, Policy: "PM_MARK", Remove: true},
+		{Interface: "TenGigabitEthernet1/1/1", Direction: interfaces.Output, Policy: "PM_QUEUE"},
+	}, changes)
+	assert.Equal(t, []interfaces.Conflict{
+		{Interface: "GigabitEthernet1/0/4", Direction: interfaces.Input, AttachedPolicy: "PM_LEGACY"},
+	}, conflicts)
+
+	assert.Equal(t, `interface GigabitEthernet1/0/1
+ service-policy input PM_MARK
+!
+interface GigabitEthernet1/0/3
+ no service-policy input PM_MARK
+!
+interface TenGigabitEthernet1/1/1
+ service-policy output PM_QUEUE
+!
+`, interfaces.RenderChanges(changes))
+}
+
+func TestSelectorValidation(t *testing.T) {
+	_, err := interfaces.NewSelector([]interfaces.Target{{}})
+	assert.Error(t, err, "empty target")
+
+	_, err = interfaces.NewSelector([]interfaces.Target{{DescriptionPattern: "[invalid"}})
+	assert.Error(t, err, "invalid regex")
+
+	_, err = interfaces.NewSelector([]interfaces.Target{{AccessVLANs: []int{5000}}})
+	assert.Error(t, err, "invalid VLAN")
+
+	_, err = interfaces.NewSelector([]interfaces.Target{{AccessVLANs: []int{10}, Direction: "ingress"}})
+	assert.Error(t, err, "invalid direction")
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// newMaintenanceConfig returns weekend windows for the core switches, a
+// one-off window for the access switches and a year-end freeze
+func newMaintenanceConfig() config.MaintenanceConfig {
+	return config.MaintenanceConfig{
+		Enabled:       true,
+		OutsideWindow: config.OutsideWindowQueue,
+		Timezone:      "Europe/London",
+		Groups:        map[string][]string{"core": {"core-sw1.example.com"}},
+		Windows: []config.MaintenanceWindowConfig{
+			{Name: "weekend", Groups: []string{"core"}, Schedule: "0 22 * * sat", Duration: 4 * time.Hour},
+			{Name: "migration", Devices: []string{"access-*"}, Start: "2026-11-10 01:00", End: "2026-11-10 05:00"},
+		},
+		Blackouts: []config.MaintenanceBlackoutConfig{
+			{Name: "freeze", Dates: []string{"2026-12-20..2027-01-02"}},
+		},
+	}
+}
+
+func TestMaintenancePolicy(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, london)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	cfg := newMaintenanceConfig()
+	policy, err := cfg.Policy()
+	require.NoError(t, err)
+	core := cfg.Target("Core-SW1.example.com")
+	assert.Equal(t, []string{"core"}, core.Groups)
+
+	t.Run("Recurring window", func(t *testing.T) {
+		decision := policy.Check(core, at("2026-11-07 23:00"))
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, "weekend", decision.Window)
+		assert.True(t, at("2026-11-08 02:00").Equal(decision.Until))
+
+		decision = policy.Check(core, at("2026-11-08 03:00"))
+		assert.False(t, decision.Allowed)
+		assert.True(t, at("2026-11-14 22:00").Equal(decision.Next), decision.Next)
+		assert.Contains(t, decision.Reason(), "outside the maintenance windows")
+	})
+
+	t.Run("Blackout", func(t *testing.T) {
+		assert.True(t, policy.Check(core, at("2026-12-19 23:00")).Allowed)
+
+		decision := policy.Check(core, at("2026-12-20 01:00"))
+		assert.False(t, decision.Allowed)
+		assert.Equal(t, "freeze", decision.Blackout)
+		assert.True(t, at("2027-01-03 00:00").Equal(decision.Until))
+		// The window opening on the last day of the freeze is still open
+		// when it ends
+		assert.True(t, at("2027-01-03 00:00").Equal(decision.Next), decision.Next)
+	})
+
+	t.Run("Calendar window", func(t *testing.T) {
+		access := cfg.Target("access-sw7")
+		assert.Empty(t, access.Groups)
+		assert.True(t, policy.Check(access, at("2026-11-10 02:00")).Allowed)
+		assert.False(t, policy.Check(core, at("2026-11-10 02:00")).Allowed)
+
+		decision := policy.Check(access, at("2026-11-10 06:00"))
+		assert.False(t, decision.Allowed)
+		assert.True(t, decision.Next.IsZero())
+		assert.Contains(t, decision.Reason(), "no window opens again")
+	})
+
+	t.Run("Devices without windows", func(t *testing.T) {
+		other := cfg.Target("dist-sw1")
+		assert.True(t, policy.Check(other, at("2026-11-11 12:00")).Allowed)
+		assert.Equal(t, "freeze", policy.Check(other, at("2026-12-25 12:00")).Blackout)
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		for name, window := range map[string]config.MaintenanceWindowConfig{
+			"interval":       {Schedule: "@every 1h", Duration: time.Hour},
+			"no duration":    {Schedule: "0 22 * * sat"},
+			"both":           {Schedule: "0 22 * * sat", Duration: time.Hour, Start: "2026-11-10 01:00", End: "2026-11-10 05:00"},
+			"end first":      {Start: "2026-11-10 05:00", End: "2026-11-10 01:00"},
+			"bad time":       {Start: "tomorrow", End: "2026-11-10 05:00"},
+			"bad time zone":  {Schedule: "0 22 * * sat", Duration: time.Hour, Timezone: "Mars/Olympus"},
+			"bad cron field": {Schedule: "0 25 * * sat", Duration: time.Hour},
+		} {
+			_, err := config.MaintenanceConfig{Windows: []config.MaintenanceWindowConfig{window}}.Policy()
+			assert.Error(t, err, name)
+		}
+		_, err := config.MaintenanceConfig{Blackouts: []config.MaintenanceBlackoutConfig{{Dates: []string{"2026-13-01"}}}}.Policy()
+		assert.Error(t, err)
+	})
+}
+
+func TestMaintenanceQueue(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "queue.json")
+	queue, err := maintenance.NewQueue(file)
+	require.NoError(t, err)
+
+	queued, err := queue.Add(maintenance.Push{
+		Target:      maintenance.Target{Host: "core-sw1.example.com"},
+		Config:      baseChangeConfig,
+		RequestedBy: "alice",
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, queued.ID)
+	assert.False(t, queued.QueuedAt.IsZero())
+
+	// Another process sees the push and can take it
+	other, err := maintenance.NewQueue(file)
+	require.NoError(t, err)
+	pushes, err := other.List()
+	require.NoError(t, err)
+	require.Len(t, pushes, 1)
+	assert.Equal(t, baseChangeConfig, pushes[0].Config)
+
+	_, err = other.Remove(queued.ID)
+	require.NoError(t, err)
+	_, err = queue.Remove(queued.ID)
+	assert.ErrorIs(t, err, maintenance.ErrNotFound)
+	pushes, err = queue.List()
+	require.NoError(t, err)
+	assert.Empty(t, pushes)
+}
+
+func TestWebMaintenance(t *testing.T) {
+	cfg := config.MaintenanceConfig{
+		Windows: []config.MaintenanceWindowConfig{{Name: "past", Start: "2020-01-01 00:00", End: "2020-01-02 00:00"}},
+	}
+	policy, err := cfg.Policy()
+	require.NoError(t, err)
+	queue, err := maintenance.NewQueue("")
+	require.NoError(t, err)
+	queued, err := queue.Add(maintenance.Push{Target: cfg.Target("access-sw7"), ChangeID: "3f2a9c1b7e40", RequestedBy: "alice"})
+	require.NoError(t, err)
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{
+		Maintenance:       policy,
+		MaintenanceTarget: cfg.Target("access-sw7"),
+		PushQueue:         queue,
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	var status struct {
+		Enabled  bool                 `json:"enabled"`
+		Queueing bool                 `json:"queueing"`
+		Target   maintenance.Target   `json:"target"`
+		Decision maintenance.Decision `json:"decision"`
+		Queue    []maintenance.Push   `json:"queue"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/maintenance", http.StatusOK, &status)
+	assert.True(t, status.Enabled)
+	assert.True(t, status.Queueing)
+	assert.Equal(t, "access-sw7", status.Target.Host)
+	assert.False(t, status.Decision.Allowed)
+	assert.True(t, status.Decision.Next.IsZero())
+	require.Len(t, status.Queue, 1)
+	assert.Equal(t, queued.ID, status.Queue[0].ID)
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/api/v1/maintenance/queue/"+queued.ID, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var apiErr map[string]interface{}
+	getJSON(t, "DELETE", ts.URL+"/api/v1/maintenance/queue/"+queued.ID, http.StatusNotFound, &apiErr)
+}
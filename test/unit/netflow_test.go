@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/netflow"
+)
+
+// loadExportCapture reads a capture with one hex-encoded UDP payload per line
+func loadExportCapture(t *testing.T, name string) [][]byte {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", "netflow", name))
+	require.NoError(t, err)
+	defer file.Close()
+
+	var packets [][]byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		packet, err := hex.DecodeString(line)
+		require.NoError(t, err)
+		packets = append(packets, packet)
+	}
+	require.NoError(t, scanner.Err())
+	return packets
+}
+
+func TestNetFlowV9Replay(t *testing.T) {
+	collector := netflow.New(netflow.Options{})
+	for _, packet := range loadExportCapture(t, "c9300-17.09-v9.hex") {
+		require.NoError(t, collector.Handle("10.0.0.1", packet))
+	}
+
+	// bittorrent is in the application table but never seen in a flow
+	assert.Equal(t, []string{"http", "ms-teams", "ssl"}, collector.Protocols())
+	assert.ElementsMatch(t, []string{"ms-teams", "ssl", "http"}, collector.TakeNew())
+	assert.Empty(t, collector.TakeNew())
+
+	stats := collector.Stats()
+	assert.Equal(t, uint64(5), stats.Packets)
+	assert.Equal(t, uint64(1), stats.MissingTemplates)
+	assert.Equal(t, 1, stats.Exporters)
+	assert.Equal(t, 4, stats.Applications)
+	assert.Equal(t, 0, stats.Unresolved)
+	assert.Zero(t, stats.Errors)
+
+	// Templates are per exporter
+	other := netflow.New(netflow.Options{})
+	packets := loadExportCapture(t, "c9300-17.09-v9.hex")
+	require.NoError(t, other.Handle("10.0.0.1", packets[1]))
+	require.NoError(t, other.Handle("10.0.0.2", packets[2]))
+	assert.Equal(t, uint64(1), other.Stats().MissingTemplates)
+}
+
+func TestIPFIXReplayOverUDP(t *testing.T) {
+	collector := netflow.New(netflow.Options{Listen: "127.0.0.1:0", TemplateTimeout: time.Hour})
+	require.NoError(t, collector.Listen())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- collector.Serve(ctx)
+	}()
+
+	conn, err := net.Dial("udp", collector.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	packets := loadExportCapture(t, "c9300-17.12-ipfix.hex")
+	for _, packet := range packets {
+		_, err := conn.Write(packet)
+		require.NoError(t, err)
+		// Keep capture order; the templates must arrive before the data
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	expected := []string{"citrix-ica", "rtp-audio", "webex-meeting"}
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, collector.Protocols())
+	}, 2*time.Second, 10*time.Millisecond)
+
+	stats := collector.Stats()
+	assert.Equal(t, uint64(len(packets)), stats.Packets)
+	assert.Zero(t, stats.MissingTemplates)
+	assert.Zero(t, stats.Errors)
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("collector did not stop")
+	}
+}
+
+func TestNetFlowDecodeErrors(t *testing.T) {
+	decoder := netflow.NewDecoder(0)
+
+	_, err := decoder.Decode("10.0.0.1", []byte{0, 5, 0, 1})
+	assert.Error(t, err)
+
+	// IPFIX header whose length exceeds the packet
+	_, err = decoder.Decode("10.0.0.1", []byte{0, 10, 0, 64, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1})
+	assert.Error(t, err)
+
+	// Set length running past the end of the message
+	_, err = decoder.Decode("10.0.0.1", []byte{0, 10, 0, 20, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 1, 0, 2, 0, 40})
+	assert.Error(t, err)
+	assert.Equal(t, uint64(3), decoder.Stats().Errors)
+
+	assert.Equal(t, "13:1234", netflow.FormatApplicationID([]byte{13, 0, 0x04, 0xd2}))
+	assert.Equal(t, "3:80", netflow.FormatApplicationID([]byte{3, 0, 80}))
+}
@@ -0,0 +1,176 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/notify"
+)
+
+// receivedRequest is a request a notificationReceiver accepted
+type receivedRequest struct {
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// notificationReceiver records notification requests, failing the first
+// Failures of them with Status
+type notificationReceiver struct {
+	Failures int
+	Status   int
+
+	mu       sync.Mutex
+	requests []receivedRequest
+	attempts int
+}
+
+func (r *notificationReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts++
+	if r.attempts <= r.Failures {
+		w.WriteHeader(r.Status)
+		return
+	}
+	r.requests = append(r.requests, receivedRequest{Path: req.URL.Path, Header: req.Header.Clone(), Body: body})
+}
+
+// Requests returns the accepted requests to a path
+func (r *notificationReceiver) Requests(path string) []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []receivedRequest
+	for _, req := range r.requests {
+		if req.Path == path {
+			result = append(result, req)
+		}
+	}
+	return result
+}
+
+func TestNotifier(t *testing.T) {
+	receiver := &notificationReceiver{}
+	ts := httptest.NewServer(receiver)
+	defer ts.Close()
+
+	notificationsConfig := config.NotificationsConfig{
+		Enabled:   true,
+		Attempts:  1,
+		Templates: map[string]string{"drift": `Drift on {{.Host}}: {{join .ClassMaps ", "}}`},
+		Channels: []config.NotificationChannelConfig{
+			{Name: "netops", Type: "slack", URL: ts.URL + "/slack", Events: []string{"push_failed", "drift"}},
+			{Name: "teams", Type: "teams", URL: ts.URL + "/teams", Templates: map[string]string{"push_succeeded": "Pushed {{.ChangeID}}"}},
+			{Name: "itsm", Type: "webhook", URL: ts.URL + "/hook", Secret: "s3cret"},
+		},
+	}
+	notifier, err := notify.New(notificationsConfig.Options())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, notifier.Notify(ctx, notify.Event{
+		Kind:      notify.KindNewProtocols,
+		Host:      "core-sw1",
+		Protocols: []notify.ProtocolClass{{Protocol: "ms-teams", Class: "AF41"}, {Protocol: "zoom", Class: "AF41"}},
+	}))
+	require.NoError(t, notifier.Notify(ctx, notify.Event{Kind: notify.KindPushFailed, Host: "core-sw1", ChangeID: "3f2a9c1b7e40", Error: "% Invalid input"}))
+	require.NoError(t, notifier.Notify(ctx, notify.Event{Kind: notify.KindPushSucceeded, Host: "core-sw1", ChangeID: "3f2a9c1b7e40"}))
+	require.NoError(t, notifier.Notify(ctx, notify.Event{Kind: notify.KindDrift, Host: "core-sw1", ClassMaps: []string{"CM_EF", "CM_AF41"}}))
+
+	t.Run("Routing and templates", func(t *testing.T) {
+		slack := receiver.Requests("/slack")
+		require.Len(t, slack, 2)
+		var message map[string]string
+		require.NoError(t, json.Unmarshal(slack[0].Body, &message))
+		assert.Equal(t, "Configuration push to core-sw1 failed for change 3f2a9c1b7e40: % Invalid input", message["text"])
+		require.NoError(t, json.Unmarshal(slack[1].Body, &message))
+		assert.Equal(t, "Drift on core-sw1: CM_EF, CM_AF41", message["text"])
+
+		teams := receiver.Requests("/teams")
+		require.Len(t, teams, 4)
+		var card map[string]string
+		require.NoError(t, json.Unmarshal(teams[0].Body, &card))
+		assert.Equal(t, "MessageCard", card["@type"])
+		assert.Equal(t, "New protocols classified", card["title"])
+		assert.Equal(t, "2 new protocol(s) classified for core-sw1: ms-teams (AF41), zoom (AF41)", card["text"])
+		require.NoError(t, json.Unmarshal(teams[2].Body, &card))
+		assert.Equal(t, "Pushed 3f2a9c1b7e40", card["text"])
+	})
+
+	t.Run("Signed webhook", func(t *testing.T) {
+		hooks := receiver.Requests("/hook")
+		require.Len(t, hooks, 4)
+		hook := hooks[3]
+		assert.Equal(t, "drift", hook.Header.Get(notify.HeaderEvent))
+		timestamp := hook.Header.Get(notify.HeaderTimestamp)
+		signature := hook.Header.Get(notify.HeaderSignature)
+		assert.True(t, strings.HasPrefix(signature, "sha256="))
+		assert.True(t, notify.Verify("s3cret", timestamp, signature, hook.Body))
+		assert.False(t, notify.Verify("wrong", timestamp, signature, hook.Body))
+		assert.False(t, notify.Verify("s3cret", timestamp, signature, append(hook.Body, ' ')))
+
+		var body struct {
+			Event   notify.Kind  `json:"event"`
+			Message string       `json:"message"`
+			Data    notify.Event `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(hook.Body, &body))
+		assert.Equal(t, notify.KindDrift, body.Event)
+		assert.Equal(t, []string{"CM_EF", "CM_AF41"}, body.Data.ClassMaps)
+		assert.False(t, body.Data.Time.IsZero())
+	})
+
+	t.Run("Invalid configuration", func(t *testing.T) {
+		for name, channel := range map[string]notify.Channel{
+			"type":     {Type: "pager", URL: ts.URL},
+			"url":      {Type: "slack", URL: "ftp://example.com"},
+			"unsigned": {Type: "webhook", URL: ts.URL},
+			"event":    {Type: "slack", URL: ts.URL, Events: []notify.Kind{"reboot"}},
+			"template": {Type: "slack", URL: ts.URL, Templates: map[notify.Kind]string{notify.KindDrift: "{{.Host"}},
+		} {
+			_, err := notify.New(notify.Options{Channels: []notify.Channel{channel}})
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestNotifierRetries(t *testing.T) {
+	send := func(receiver *notificationReceiver, attempts int) error {
+		ts := httptest.NewServer(receiver)
+		defer ts.Close()
+		notifier, err := notify.New(notify.Options{
+			Channels: []notify.Channel{{Name: "netops", Type: notify.TypeSlack, URL: ts.URL}},
+			Attempts: attempts,
+			Backoff:  time.Millisecond,
+		})
+		require.NoError(t, err)
+		return notifier.Notify(context.Background(), notify.Event{Kind: notify.KindPushSucceeded, Host: "core-sw1"})
+	}
+
+	unavailable := &notificationReceiver{Failures: 2, Status: http.StatusServiceUnavailable}
+	require.NoError(t, send(unavailable, 3))
+	assert.Len(t, unavailable.Requests("/"), 1)
+
+	throttled := &notificationReceiver{Failures: 3, Status: http.StatusTooManyRequests}
+	err := send(throttled, 3)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "notification channel netops")
+	assert.Contains(t, err.Error(), "429")
+	assert.Equal(t, 3, throttled.attempts)
+
+	// Client errors are not retried
+	rejected := &notificationReceiver{Failures: 1, Status: http.StatusBadRequest}
+	require.Error(t, send(rejected, 3))
+	assert.Equal(t, 1, rejected.attempts)
+}
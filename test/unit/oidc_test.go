@@ -0,0 +1,237 @@
+package unit
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/auth"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// mockIssuer is a minimal OpenID Connect provider that logs in a fixed user
+// without a login page
+type mockIssuer struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	groups []string
+
+	mu    sync.Mutex
+	codes map[string]url.Values
+}
+
+// newMockIssuer starts a mock issuer for the client ID
+func newMockIssuer(t *testing.T, clientID string, groups ...string) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &mockIssuer{key: key, groups: groups, codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer.URL,
+			"authorization_endpoint": issuer.URL + "/authorize",
+			"token_endpoint":         issuer.URL + "/token",
+			"jwks_uri":               issuer.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("client_id") != clientID || query.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		code := "code-" + query.Get("state")[:8]
+		issuer.mu.Lock()
+		issuer.codes[code] = query
+		issuer.mu.Unlock()
+		http.Redirect(w, r, query.Get("redirect_uri")+"?code="+code+"&state="+url.QueryEscape(query.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		issuer.mu.Lock()
+		auth, exists := issuer.codes[r.PostForm.Get("code")]
+		delete(issuer.codes, r.PostForm.Get("code"))
+		issuer.mu.Unlock()
+
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !exists || base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.Get("code_challenge") {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "opaque",
+			"token_type":   "Bearer",
+			"id_token": issuer.sign(t, map[string]interface{}{
+				"iss":    issuer.URL,
+				"sub":    "00u1",
+				"aud":    clientID,
+				"exp":    time.Now().Add(time.Hour).Unix(),
+				"nonce":  auth.Get("nonce"),
+				"email":  "alice@example.com",
+				"groups": issuer.groups,
+			}),
+		})
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// sign returns an RS256 ID token
+func (m *mockIssuer) sign(t *testing.T, claims map[string]interface{}) string {
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"test-key"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newOIDCServer serves the web API with single sign-on against the issuer
+func newOIDCServer(t *testing.T, issuer *mockIssuer, sessionTimeout time.Duration) *httptest.Server {
+	t.Helper()
+	authenticator, err := auth.New(auth.Options{})
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(nil)
+	provider, err := auth.NewOIDCProvider(auth.OIDCOptions{
+		IssuerURL:      issuer.URL,
+		ClientID:       "nbar",
+		RedirectURL:    "http://" + ts.Listener.Addr().String() + "/auth/callback",
+		GroupRoles:     map[string]auth.Role{"noc": auth.RoleViewer, "noc-leads": auth.RoleDeployer},
+		SessionTimeout: sessionTimeout,
+	})
+	require.NoError(t, err)
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{Auth: authenticator, OIDC: provider})
+	ts.Config.Handler = server.Handler()
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// browser returns a client that keeps cookies and follows redirects
+func browser(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	return &http.Client{Jar: jar}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newMockIssuer(t, "nbar", "noc", "noc-leads", "unrelated")
+	ts := newOIDCServer(t, issuer, time.Hour)
+	client := browser(t)
+
+	resp, err := client.Get(ts.URL + "/api/v1/auth/whoami")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = client.Get(ts.URL + "/auth/login?redirect=/api/v1/auth/whoami")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/api/v1/auth/whoami", resp.Request.URL.Path)
+
+	var principal auth.Principal
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&principal))
+	assert.Equal(t, "alice@example.com", principal.Subject)
+	assert.Equal(t, auth.RoleDeployer, principal.Role)
+	assert.Equal(t, auth.MethodOIDC, principal.Method)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), principal.ExpiresAt, time.Minute)
+
+	// Logging out ends the server-side session
+	noRedirect := &http.Client{Jar: client.Jar, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err = noRedirect.Post(ts.URL+"/auth/logout", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, resp.StatusCode)
+
+	resp, err = client.Get(ts.URL + "/api/v1/auth/whoami")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestOIDCLoginRejected(t *testing.T) {
+	t.Run("Unmapped groups", func(t *testing.T) {
+		issuer := newMockIssuer(t, "nbar", "finance")
+		ts := newOIDCServer(t, issuer, time.Hour)
+
+		resp, err := browser(t).Get(ts.URL + "/auth/login")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Callback without login", func(t *testing.T) {
+		issuer := newMockIssuer(t, "nbar", "noc")
+		ts := newOIDCServer(t, issuer, time.Hour)
+
+		resp, err := browser(t).Get(ts.URL + "/auth/callback?code=forged&state=forged")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Session timeout", func(t *testing.T) {
+		issuer := newMockIssuer(t, "nbar", "noc")
+		ts := newOIDCServer(t, issuer, 50*time.Millisecond)
+		client := browser(t)
+
+		resp, err := client.Get(ts.URL + "/auth/login?redirect=/api/v1/auth/whoami")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		time.Sleep(100 * time.Millisecond)
+		resp, err = client.Get(ts.URL + "/api/v1/auth/whoami")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Open redirect", func(t *testing.T) {
+		issuer := newMockIssuer(t, "nbar", "noc")
+		ts := newOIDCServer(t, issuer, time.Hour)
+
+		resp, err := browser(t).Get(ts.URL + "/auth/login?redirect=//evil.example.com/")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, "/", resp.Request.URL.Path)
+		assert.Equal(t, ts.Listener.Addr().String(), resp.Request.URL.Host)
+	})
+}
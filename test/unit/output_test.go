@@ -0,0 +1,276 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/output"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+	"gopkg.in/yaml.v3"
+)
+
+func outputResult() *output.Result {
+	return &output.Result{
+		Classifications: map[string]qos.Classification{
+			"sip":   {Protocol: "sip", Class: qos.EF, Confidence: 0.95, Source: "ai", Timestamp: 1700000000},
+			"https": {Protocol: "https", Class: qos.AF21, Source: "predefined"},
+			"bt":    {Protocol: "bt", Class: qos.CS1, Source: "custom_rule"},
+		},
+		Model:        qos.DefaultModel(),
+		DefaultClass: qos.CS1,
+		GeneratedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestOutputRegistry(t *testing.T) {
+	registry := output.NewDefaultRegistry()
+	assert.Equal(t, []string{"csv", "json", "text", "yaml"}, registry.Names())
+
+	_, err := registry.Get("xml")
+	assert.Error(t, err)
+	assert.Error(t, registry.Register(&output.JSONGenerator{}))
+
+	targets, err := registry.Targets([]string{"text", "json", "csv"}, []string{"-", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []output.Target{
+		{Format: "text", Path: output.Stdout},
+		{Format: "json", Path: "nbar-protocols-qos.json"},
+		{Format: "csv", Path: "nbar-protocols-qos.csv"},
+	}, targets)
+
+	_, err = registry.Targets([]string{"json", "yaml"}, []string{"out", "out"})
+	assert.Error(t, err)
+	_, err = registry.Targets([]string{"json"}, []string{"a.json", "b.json"})
+	assert.Error(t, err)
+	_, err = registry.Targets(nil, nil)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"cisco", "json"}, output.SplitList(" cisco, ,json "))
+}
+
+func TestStructuredOutput(t *testing.T) {
+	data, err := (&output.JSONGenerator{}).Generate(outputResult())
+	require.NoError(t, err)
+
+	var doc output.Document
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, qos.CS1, doc.DefaultClass)
+	assert.Len(t, doc.Classes, 5)
+	require.Len(t, doc.Classifications, 3)
+	assert.Equal(t, "bt", doc.Classifications[0].Protocol)
+	assert.Equal(t, output.Record{Protocol: "sip", Class: qos.EF, DSCP: "ef", Confidence: 0.95, Source: "ai", Timestamp: 1700000000}, doc.Classifications[2])
+
+	data, err = (&output.YAMLGenerator{}).Generate(outputResult())
+	require.NoError(t, err)
+
+	var yamlDoc output.Document
+	require.NoError(t, yaml.Unmarshal(data, &yamlDoc))
+	assert.Equal(t, doc.Classifications, yamlDoc.Classifications)
+}
+
+func TestCSVOutput(t *testing.T) {
+	data, err := (&output.CSVGenerator{}).Generate(outputResult())
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"protocol", "class", "dscp", "confidence", "source", "timestamp"}, rows[0])
+	assert.Equal(t, []string{"sip", "EF", "ef", "0.95", "ai", "1700000000"}, rows[3])
+}
+
+func TestTextAndCiscoOutput(t *testing.T) {
+	data, err := (&output.TextGenerator{}).Generate(outputResult())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# Generated on 2024-05-01 using AI\n")
+	assert.Contains(t, string(data), "## EF - Expedited Forwarding - Real-time traffic (Voice, Video calls)\n1. sip\n")
+
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{MarkingPolicyName: "PM_MARK"})
+	require.NoError(t, err)
+
+	result := outputResult()
+	result.Attachments = []interfaces.Change{{Interface: "GigabitEthernet1/0/1", Direction: interfaces.Input, Policy: "PM_MARK"}}
+	data, err = output.NewCiscoGenerator(renderer, nil).Generate(result)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "policy-map PM_MARK\n")
+	assert.Contains(t, string(data), "! Interface service-policy attachments\ninterface GigabitEthernet1/0/1\n service-policy input PM_MARK\n!\n")
+
+	// Attachments are replaced with the current ones before a push
+	replaced := output.WithAttachments(string(data), []interfaces.Change{{Interface: "GigabitEthernet1/0/2", Direction: interfaces.Input, Policy: "PM_MARK"}})
+	assert.NotContains(t, replaced, "GigabitEthernet1/0/1")
+	assert.True(t, strings.HasSuffix(replaced, "! Interface service-policy attachments\ninterface GigabitEthernet1/0/2\n service-policy input PM_MARK\n!\n"))
+	assert.NotContains(t, output.WithAttachments(string(data), nil), "Interface service-policy attachments")
+}
+
+func TestCompareOutputs(t *testing.T) {
+	before := "class-map match-any QOS_EF\n match protocol sip\n match protocol rtp\n!\nclass-map match-any QOS_AF41\n match protocol ssh\n!\n"
+	after := "class-map match-any QOS_EF\n match protocol sip\n match protocol rtp\n match protocol zoom\n!\nclass-map match-any QOS_AF41\n!\n"
+
+	diff := output.Compare("a.txt", "b.txt", before, after)
+	assert.True(t, diff.Changed())
+	assert.Equal(t, 1, diff.Added)
+	assert.Equal(t, 1, diff.Removed)
+	assert.Equal(t, "--- a.txt\n+++ b.txt\n@@ -1,7 +1,7 @@\n"+
+		" class-map match-any QOS_EF\n  match protocol sip\n  match protocol rtp\n+ match protocol zoom\n !\n class-map match-any QOS_AF41\n- match protocol ssh\n !\n",
+		diff.Unified)
+
+	// Distant changes get separate hunks
+	lines := strings.Repeat("!\n", 20)
+	diff = output.Compare("a", "b", "first\n"+lines+"last\n", "First\n"+lines+"Last\n")
+	assert.Equal(t, 2, strings.Count(diff.Unified, "@@ -"))
+	assert.Contains(t, diff.Unified, "@@ -1,4 +1,4 @@\n-first\n+First\n")
+	assert.Contains(t, diff.Unified, "@@ -19,4 +19,4 @@\n !\n !\n !\n-last\n+Last\n")
+
+	assert.False(t, output.Compare("a", "b", before, before).Changed())
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n", output.Compare("a", "b", "", "new").Unified)
+}
+
+func TestParseConfigBlocks(t *testing.T) {
+	config := `class-map match-any QOS_EF
+ description Voice
+ match protocol sip
+!
+! Ingress marking policy-map
+policy-map PM_MARK
+ description Marking
+ class QOS_EF
+  set dscp ef
+ class class-default
+  set dscp cs1
+!
+`
+	blocks := output.ParseConfigBlocks(config)
+	assert.Equal(t, []output.ConfigBlock{
+		{Parents: []string{"class-map match-any QOS_EF"}, Lines: []string{"description Voice", "match protocol sip"}},
+		{Parents: []string{"policy-map PM_MARK"}, Lines: []string{"description Marking"}},
+		{Parents: []string{"policy-map PM_MARK", "class QOS_EF"}, Lines: []string{"set dscp ef"}},
+		{Parents: []string{"policy-map PM_MARK", "class class-default"}, Lines: []string{"set dscp cs1"}},
+	}, blocks)
+}
+
+func TestAutomationExports(t *testing.T) {
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{MarkingPolicyName: "PM_MARK"})
+	require.NoError(t, err)
+	cisco := output.NewCiscoGenerator(renderer, nil)
+
+	data, err := output.NewAnsibleTasksGenerator(cisco).Generate(outputResult())
+	require.NoError(t, err)
+
+	var tasks []map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &tasks))
+	require.NotEmpty(t, tasks)
+	assert.Equal(t, "Configure class-map match-any QOS_EF", tasks[0]["name"])
+	assert.Equal(t, map[string]interface{}{
+		"parents": []interface{}{"class-map match-any QOS_EF"},
+		"lines":   []interface{}{"description Expedited Forwarding - Real-time traffic (Voice, Video calls)", "match protocol sip"},
+	}, tasks[0]["cisco.ios.ios_config"])
+
+	data, err = output.NewAnsibleVarsGenerator(cisco).Generate(outputResult())
+	require.NoError(t, err)
+
+	var vars output.AnsibleVars
+	require.NoError(t, yaml.Unmarshal(data, &vars))
+	assert.Equal(t, []string{"https"}, vars.Classes[qos.AF21].Protocols)
+	assert.Equal(t, "af21", vars.Classes[qos.AF21].DSCP)
+	assert.Empty(t, vars.Classes[qos.AF41].Protocols)
+	assert.Equal(t, qos.CS1, vars.Default)
+	assert.NotEmpty(t, vars.Blocks)
+
+	data, err = output.NewNAPALMGenerator(cisco).Generate(outputResult())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "policy-map PM_MARK\n")
+	assert.True(t, bytes.HasSuffix(data, []byte("!\nend\n")))
+}
+
+func TestVendorOutputs(t *testing.T) {
+	result := outputResult()
+
+	juniper := output.NewJuniperGenerator(output.VendorOptions{PolicyName: "CAMPUS"})
+	data, err := juniper.Generate(result)
+	require.NoError(t, err)
+	junos := string(data)
+	assert.Contains(t, junos, "rule-sets CAMPUS rule EF match application junos:SIP\n")
+	assert.Contains(t, junos, "rule-sets CAMPUS rule AF21 match application junos:SSL\n")
+	assert.Contains(t, junos, "rule EF then forwarding-class expedited-forwarding\n")
+	assert.Contains(t, junos, "rule AF21 then dscp-code-point af21\n")
+	assert.True(t, strings.HasSuffix(junos, "rule CS1 then dscp-code-point cs1\n"), "default class rule should be last")
+	assert.Contains(t, junos, "rule CS1 match application-any\n")
+
+	arista := output.NewAristaGenerator(output.VendorOptions{Applications: map[string]string{"https": "tcp any any eq 443; udp any any eq 443"}})
+	data, err = arista.Generate(result)
+	require.NoError(t, err)
+	eos := string(data)
+	assert.Contains(t, eos, "ip access-list NBAR_EF_ACL\n   10 permit udp any any eq 5060\n   20 permit tcp any any eq 5060\n!\n")
+	assert.Contains(t, eos, "ip access-list NBAR_AF21_ACL\n   10 permit tcp any any eq 443\n   20 permit udp any any eq 443\n!\n")
+	assert.Contains(t, eos, "class-map type qos match-any NBAR_EF\n   match ip access-group NBAR_EF_ACL\n!\n")
+	// Classes without mapped protocols match their DSCP as marked upstream
+	assert.Contains(t, eos, "class-map type qos match-any NBAR_AF41\n   match dscp 34\n!\n")
+	assert.Contains(t, eos, "policy-map type qos NBAR-QOS\n   class NBAR_EF\n      set dscp 46\n      set traffic-class 5\n")
+	assert.Contains(t, eos, "   class NBAR_AF21\n      set dscp 18\n      set traffic-class 2\n")
+	assert.True(t, strings.HasSuffix(eos, "   class class-default\n      set dscp 8\n      set traffic-class 0\n   !\n!\n"), "class-default should be last")
+	assert.Contains(t, eos, "! Unmapped NBAR protocols: bt\n")
+	assert.NotContains(t, eos, "NBAR_CS1")
+	assert.NotContains(t, eos, "application-profile")
+
+	// Entries matching the same traffic in several classes are left out, or
+	// the first class in the policy would take the traffic of the others
+	overlapping := outputResult()
+	overlapping.Classifications["rtp-audio"] = qos.Classification{Protocol: "rtp-audio", Class: qos.EF}
+	overlapping.Classifications["rtp-video"] = qos.Classification{Protocol: "rtp-video", Class: qos.AF41}
+	overlapping.Classifications["quic"] = qos.Classification{Protocol: "quic", Class: qos.AF41}
+	overlapping.Classifications["web"] = qos.Classification{Protocol: "web", Class: qos.CS1}
+	arista = output.NewAristaGenerator(output.VendorOptions{Applications: map[string]string{"web": "ip any any"}})
+	assert.Equal(t, []string{"bt", "https", "quic", "rtp-audio", "rtp-video", "sip", "web"}, arista.Unmapped(overlapping))
+	arista = output.NewAristaGenerator(output.VendorOptions{Applications: map[string]string{"web": "tcp any any range 8000 8099"}})
+	assert.Equal(t, []string{"bt", "rtp-audio", "rtp-video"}, arista.Unmapped(overlapping))
+	data, err = arista.Generate(overlapping)
+	require.NoError(t, err)
+	eos = string(data)
+	assert.Contains(t, eos, "! NBAR protocols overlapping another class: rtp-audio, rtp-video\n")
+	assert.NotContains(t, eos, "16384")
+	assert.Contains(t, eos, "ip access-list NBAR_AF41_ACL\n   10 permit udp any any eq 443\n!\n")
+
+	avt := output.NewAristaAVTGenerator(output.VendorOptions{})
+	assert.Equal(t, "arista-avt", avt.Name())
+	data, err = avt.Generate(result)
+	require.NoError(t, err)
+	eos = string(data)
+	assert.Contains(t, eos, "   application-profile NBAR_EF\n      application sip\n")
+	assert.Contains(t, eos, "      match application-profile NBAR_AF21\n         dscp 18\n         traffic-class 2\n")
+	assert.Contains(t, eos, "      match application-profile default\n         dscp 8\n         traffic-class 0\n")
+	assert.NotContains(t, eos, "NBAR_CS1")
+
+	// Meraki only includes explicitly mapped applications
+	meraki := output.NewMerakiGenerator(output.VendorOptions{Applications: map[string]string{
+		"sip": "meraki:layer7/application/100",
+		"bt":  "meraki:layer7/application/42",
+	}})
+	data, err = meraki.Generate(result)
+	require.NoError(t, err)
+	var rules output.MerakiRules
+	require.NoError(t, json.Unmarshal(data, &rules))
+	assert.True(t, rules.DefaultRulesEnabled)
+	require.Len(t, rules.Rules, 2)
+	assert.Equal(t, output.MerakiApplicationRef{ID: "meraki:layer7/application/100", Name: "sip"}, rules.Rules[0].Definitions[0].Value)
+	assert.Equal(t, 46, rules.Rules[0].DSCPTagValue)
+	assert.Equal(t, "high", rules.Rules[0].Priority)
+	assert.Equal(t, "low", rules.Rules[1].Priority)
+	assert.Equal(t, []string{"https"}, meraki.Unmapped(result))
+
+	// Strict mode and empty mappings skip protocols
+	strict := output.NewJuniperGenerator(output.VendorOptions{Strict: true, Applications: map[string]string{"https": ""}})
+	assert.Equal(t, []string{"bt", "https", "sip"}, strict.Unmapped(result))
+	data, err = strict.Generate(result)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "rule EF")
+	assert.Contains(t, string(data), "# Unmapped NBAR protocols: bt, https, sip\n")
+	assert.Empty(t, juniper.Unmapped(result))
+}
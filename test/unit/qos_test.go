@@ -161,6 +161,21 @@ func TestClassifier(t *testing.T) {
 		assert.Equal(t, qos.EF, classifications["voice-app"].Class)
 		assert.Equal(t, qos.CS1, classifications["unknown"].Class)
 	})
+
+	t.Run("Update and remove", func(t *testing.T) {
+		rule, err := qos.NewRule("voice-rule", ".*voice.*", qos.AF41, 1)
+		require.NoError(t, err)
+		require.NoError(t, classifier.UpdateCustomRule("voice-rule", rule))
+		assert.Equal(t, qos.AF41, classifier.ClassifyProtocol("voice-app").Class)
+		assert.Error(t, classifier.UpdateCustomRule("missing", rule))
+
+		assert.True(t, classifier.RemoveCustomRule("voice-rule"))
+		assert.False(t, classifier.RemoveCustomRule("voice-rule"))
+		assert.Equal(t, "default", classifier.ClassifyProtocol("voice-app").Source)
+
+		assert.True(t, classifier.RemovePredefinedClassification("SIP"))
+		assert.Equal(t, "default", classifier.ClassifyProtocol("sip").Source)
+	})
 }
 
 func TestValidateProtocolName(t *testing.T) {
@@ -257,3 +272,204 @@ func TestFilterBySource(t *testing.T) {
 	assert.NotContains(t, filtered, "predefined")
 	assert.NotContains(t, filtered, "cache")
 }
+
+func TestClassModel(t *testing.T) {
+	definitions := []qos.ClassDefinition{
+		{Name: "EF", DisplayName: "Expedited Forwarding", Description: "Voice", DSCP: "ef", Priority: 1},
+		{Name: "CS4", DisplayName: "Class Selector 4", Description: "Real-time interactive", DSCP: "cs4", Priority: 2},
+		{Name: "AF31", DisplayName: "Assured Forwarding 31", Description: "Multimedia streaming", DSCP: "af31", Priority: 3},
+		{Name: "AF11", DisplayName: "Assured Forwarding 11", Description: "Bulk data", DSCP: "10", Priority: 4},
+		{Name: "DEFAULT", Description: "Best effort", DSCP: "default", Priority: 5},
+	}
+
+	t.Run("Custom model", func(t *testing.T) {
+		model, err := qos.NewModel(definitions, "DEFAULT")
+		require.NoError(t, err)
+
+		qos.SetModel(model)
+		defer qos.SetModel(qos.DefaultModel())
+
+		assert.Equal(t, []qos.Class{"EF", "CS4", "AF31", "AF11", "DEFAULT", qos.Other}, qos.AllClasses())
+		assert.Equal(t, qos.Class("DEFAULT"), model.DefaultClass())
+
+		assert.True(t, qos.Class("CS4").IsValid())
+		assert.Equal(t, "cs4", qos.Class("CS4").DSCP())
+		assert.Equal(t, 2, qos.Class("CS4").Priority())
+		assert.Equal(t, "Class Selector 4 - Real-time interactive", qos.Class("CS4").Description())
+
+		assert.False(t, qos.AF41.IsValid())
+		assert.Equal(t, 7, qos.AF41.Priority())
+
+		assert.True(t, qos.Other.IsValid())
+		assert.Equal(t, 6, qos.Other.Priority())
+		assert.Equal(t, "cs0", qos.Other.DSCP())
+	})
+
+	t.Run("Rule validation uses active model", func(t *testing.T) {
+		model, err := qos.NewModel(definitions, "DEFAULT")
+		require.NoError(t, err)
+
+		qos.SetModel(model)
+		defer qos.SetModel(qos.DefaultModel())
+
+		rule, err := qos.NewRule("streaming", ".*stream.*", "AF31", 1)
+		require.NoError(t, err)
+		assert.NoError(t, rule.Validate())
+
+		rule, err = qos.NewRule("legacy", ".*web.*", qos.AF21, 1)
+		require.NoError(t, err)
+		assert.Error(t, rule.Validate())
+	})
+
+	t.Run("Invalid models", func(t *testing.T) {
+		_, err := qos.NewModel(nil, "")
+		assert.Error(t, err)
+
+		_, err = qos.NewModel([]qos.ClassDefinition{{Name: "EF", DSCP: "ef", Priority: 1}, {Name: "EF", DSCP: "ef", Priority: 2}}, "")
+		assert.Error(t, err, "duplicate class")
+
+		_, err = qos.NewModel([]qos.ClassDefinition{{Name: "EF", DSCP: "af51", Priority: 1}}, "")
+		assert.Error(t, err, "invalid DSCP")
+
+		_, err = qos.NewModel([]qos.ClassDefinition{{Name: "EF", DSCP: "ef", Priority: 0}}, "")
+		assert.Error(t, err, "non-positive priority")
+
+		_, err = qos.NewModel([]qos.ClassDefinition{{Name: "EF", DSCP: "ef", Priority: 1}, {Name: "CS6", DSCP: "cs6", Priority: 1}}, "")
+		assert.ErrorContains(t, err, "priority 1 is already used by class EF")
+
+		_, err = qos.NewModel([]qos.ClassDefinition{{Name: "EF", DSCP: "ef", Priority: 1}}, "CS1")
+		assert.Error(t, err, "undefined default class")
+	})
+}
+
+func TestParseDSCP(t *testing.T) {
+	tests := []struct {
+		dscp  string
+		value int
+		valid bool
+	}{
+		{"ef", 46, true},
+		{"AF41", 34, true},
+		{"cs1", 8, true},
+		{"default", 0, true},
+		{"26", 26, true},
+		{"64", 0, false},
+		{"af44", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dscp, func(t *testing.T) {
+			value, err := qos.ParseDSCP(tt.dscp)
+			if tt.valid {
+				require.NoError(t, err)
+				assert.Equal(t, tt.value, value)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPresets(t *testing.T) {
+	tests := []struct {
+		name         string
+		classCount   int
+		defaultClass qos.Class
+	}{
+		{qos.Preset4Class, 4, qos.CS1},
+		{qos.Preset8Class, 8, qos.DF},
+		{qos.Preset12Class, 12, qos.DF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preset, exists := qos.LookupPreset(tt.name)
+			require.True(t, exists)
+			assert.Len(t, preset.Classes, tt.classCount)
+			assert.Equal(t, tt.defaultClass, preset.DefaultClass)
+
+			model, err := preset.Model()
+			require.NoError(t, err)
+			assert.Len(t, model.MarkingClasses(), tt.classCount)
+
+			// Every seeded protocol must belong to a class in the model
+			for class, protocols := range preset.Protocols {
+				assert.True(t, model.Contains(class), "seed class %s", class)
+				for _, protocol := range protocols {
+					assert.NoError(t, qos.ValidateProtocolName(protocol))
+				}
+			}
+		})
+	}
+
+	_, exists := qos.LookupPreset("unknown")
+	assert.False(t, exists)
+}
+
+func TestQueuingPolicy(t *testing.T) {
+	model := qos.DefaultModel()
+
+	valid := []qos.QueueDefinition{
+		{Class: qos.EF, PriorityLevel: 1, PolicePercent: 10, QueueBuffersRatio: 10},
+		{Class: qos.AF21, BandwidthRemainingPercent: 35, QueueBuffersRatio: 30},
+		{Class: qos.AF41, BandwidthRemainingPercent: 40, QueueBuffersRatio: 30},
+		{Class: qos.CS1, BandwidthRemainingPercent: 25, QueueBuffersRatio: 30},
+	}
+
+	t.Run("Valid policy", func(t *testing.T) {
+		policy, err := qos.NewQueuingPolicy("PM_QUEUE", valid, model)
+		require.NoError(t, err)
+
+		assert.Equal(t, []qos.Class{qos.EF, qos.AF41, qos.AF21}, policy.Classes())
+		assert.Equal(t, qos.CS1, policy.Default.Class)
+		assert.Equal(t, 25, policy.Default.BandwidthRemainingPercent)
+		assert.True(t, policy.Queues[0].IsPriority())
+	})
+
+	tests := []struct {
+		name   string
+		modify func([]qos.QueueDefinition) []qos.QueueDefinition
+	}{
+		{"Bandwidth does not sum to 100", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[1].BandwidthRemainingPercent = 30
+			return q
+		}},
+		{"Priority queue with bandwidth", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[0].BandwidthRemainingPercent = 5
+			return q
+		}},
+		{"Police on non-priority queue", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[1].PolicePercent = 10
+			return q
+		}},
+		{"Shared priority level", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[1] = qos.QueueDefinition{Class: qos.AF21, PriorityLevel: 1}
+			q[2].BandwidthRemainingPercent = 75
+			return q
+		}},
+		{"Invalid priority level", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[0].PriorityLevel = 3
+			return q
+		}},
+		{"Unknown class", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			return append(q, qos.QueueDefinition{Class: "CS4", PriorityLevel: 2})
+		}},
+		{"Default class as priority queue", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[3] = qos.QueueDefinition{Class: qos.CS1, PriorityLevel: 2}
+			return q
+		}},
+		{"Queue buffers exceed 100", func(q []qos.QueueDefinition) []qos.QueueDefinition {
+			q[0].QueueBuffersRatio = 20
+			return q
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queues := tt.modify(append([]qos.QueueDefinition(nil), valid...))
+			_, err := qos.NewQueuingPolicy("PM_QUEUE", queues, model)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+)
+
+func renderClassifications() map[string]qos.Classification {
+	return map[string]qos.Classification{
+		"sip":   {Protocol: "sip", Class: qos.EF},
+		"rtp":   {Protocol: "rtp", Class: qos.EF},
+		"zoom":  {Protocol: "zoom", Class: qos.AF41},
+		"https": {Protocol: "https", Class: qos.AF21},
+		"bt":    {Protocol: "bt", Class: qos.CS1},
+	}
+}
+
+func TestCiscoRendererDefault(t *testing.T) {
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{MarkingPolicyName: "PM_MARK_AVC_WIRED_INGRESS"})
+	require.NoError(t, err)
+
+	output, err := renderer.Render(renderClassifications(), qos.DefaultModel(), qos.CS1, nil)
+	require.NoError(t, err)
+
+	expected := `class-map match-any QOS_EF
+ description Expedited Forwarding - Real-time traffic (Voice, Video calls)
+ match protocol rtp
+ match protocol sip
+!
+class-map match-any QOS_AF41
+ description Assured Forwarding 41 - Business-critical applications
+ match protocol zoom
+!
+class-map match-any QOS_AF21
+ description Assured Forwarding 21 - Important data applications
+ match protocol https
+!
+class-map match-any QOS_CS1
+ description Class Selector 1 - Background traffic
+ match protocol bt
+!
+! Ingress marking policy-map
+policy-map PM_MARK_AVC_WIRED_INGRESS
+ description Marks incoming traffic based on App (AVC) or VLAN fallback. CS1 traffic handled by class-default.
+ class QOS_EF
+  set dscp ef
+ class QOS_AF41
+  set dscp af41
+ class QOS_AF21
+  set dscp af21
+ class class-default
+  set dscp cs1
+!
+`
+	assert.Equal(t, expected, output)
+}
+
+func TestCiscoRendererNaming(t *testing.T) {
+	classifications := make(map[string]qos.Classification)
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("app-%d", i)
+		classifications[name] = qos.Classification{Protocol: name, Class: qos.AF21}
+	}
+
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{
+		Template:                "wireless",
+		MarkingPolicyName:       "PM_WLAN",
+		ClassMapName:            `CM-{{.Class}}-{{printf "%02d" .Index}}`,
+		MaxProtocolsPerClassMap: 2,
+		DefaultClassHandling:    render.DefaultClassExplicit,
+		ClassDefaultDSCP:        "default",
+	})
+	require.NoError(t, err)
+
+	output, err := renderer.Render(classifications, qos.DefaultModel(), qos.CS1, nil)
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "! Wireless client ingress marking policy-map")
+	assert.Contains(t, output, "policy-map PM_WLAN\n")
+	assert.Equal(t, 3, strings.Count(output, "class-map match-any CM-AF21-"))
+	assert.Contains(t, output, " class CM-AF21-03\n  set dscp af21\n")
+	assert.Contains(t, output, " class class-default\n  set dscp default\n")
+	assert.NotContains(t, output, "handled by class-default")
+}
+
+func TestCiscoRendererQueuing(t *testing.T) {
+	model := qos.DefaultModel()
+	policy, err := qos.NewQueuingPolicy("PM_QUEUE", []qos.QueueDefinition{
+		{Class: qos.EF, PriorityLevel: 1, PolicePercent: 10},
+		{Class: qos.AF41, BandwidthRemainingPercent: 60},
+		{Class: qos.CS1, BandwidthRemainingPercent: 40},
+	}, model)
+	require.NoError(t, err)
+
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{
+		MarkingPolicyName: "PM_MARK",
+		QueueClassMapName: "EGRESS_{{.Class}}",
+	})
+	require.NoError(t, err)
+
+	output, err := renderer.Render(renderClassifications(), model, qos.CS1, policy)
+	require.NoError(t, err)
+
+	assert.Contains(t, output, "class-map match-any EGRESS_EF\n description Expedited Forwarding - Real-time traffic (Voice, Video calls)\n match dscp ef\n!\n")
+	assert.Contains(t, output, "policy-map PM_QUEUE\n class EGRESS_EF\n  priority level 1\n  police rate percent 10\n class EGRESS_AF41\n  bandwidth remaining percent 60\n class class-default\n  bandwidth remaining percent 40\n!\n")
+}
+
+func TestCiscoRendererCustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{{range .Classes}}{{.Class}}={{len .ClassMaps}} {{end}}{{.MarkingPolicy}}`), 0644))
+
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{Template: path, MarkingPolicyName: "PM_CUSTOM"})
+	require.NoError(t, err)
+
+	output, err := renderer.Render(renderClassifications(), qos.DefaultModel(), qos.CS1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "EF=1 AF41=1 AF21=1 CS1=1 PM_CUSTOM", output)
+}
+
+func TestCiscoRendererValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		options render.CiscoOptions
+	}{
+		{"missing policy name", render.CiscoOptions{}},
+		{"unknown template", render.CiscoOptions{MarkingPolicyName: "PM", Template: "no-such-template"}},
+		{"bad naming template", render.CiscoOptions{MarkingPolicyName: "PM", ClassMapName: "{{.Class"}},
+		{"negative limit", render.CiscoOptions{MarkingPolicyName: "PM", MaxProtocolsPerClassMap: -1}},
+		{"bad default handling", render.CiscoOptions{MarkingPolicyName: "PM", DefaultClassHandling: "drop"}},
+		{"bad class-default DSCP", render.CiscoOptions{MarkingPolicyName: "PM", ClassDefaultDSCP: "af99"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := render.NewCiscoRenderer(tt.options)
+			assert.Error(t, err)
+		})
+	}
+
+	assert.Equal(t, []string{"wired", "wireless"}, render.BuiltinTemplates())
+}
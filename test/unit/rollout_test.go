@@ -0,0 +1,380 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/maintenance"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rollout"
+)
+
+const policyMapCounters = ` GigabitEthernet1/0/1
+
+  Service-policy output: PM_QUEUE
+
+    queue stats for all priority classes:
+      Queueing
+      priority level 1
+
+      (total drops) 4
+      (bytes output) 1203
+
+    Class-map: CM_EF (match-any)
+      0 packets
+      Match: protocol rtp-audio
+      Priority: Strict,
+      Priority Level: 1
+
+    Class-map: class-default (match-any)
+      120931 packets
+      Match: any
+      Queueing
+      (total drops) 17
+      (bytes output) 88212
+ Port-channel1
+
+  Service-policy output: PM_QUEUE_LEGACY
+
+    Class-map: class-default (match-any)
+      queue limit 64 packets
+      (queue depth/total drops/no-buffer drops) 0/9/0
+`
+
+func TestDropCounters(t *testing.T) {
+	transcript := loadC9300Transcript(t)
+	output, ok := transcript.Output("show interfaces")
+	require.True(t, ok)
+	assert.Equal(t, interfaces.Drops{
+		"GigabitEthernet1/0/1 input":     0,
+		"GigabitEthernet1/0/1 output":    12,
+		"GigabitEthernet1/0/2 input":     3,
+		"GigabitEthernet1/0/2 output":    0,
+		"GigabitEthernet1/0/3 input":     0,
+		"GigabitEthernet1/0/3 output":    0,
+		"TenGigabitEthernet1/1/1 input":  0,
+		"TenGigabitEthernet1/1/1 output": 0,
+	}, interfaces.ParseInterfaceDrops(output))
+
+	before := interfaces.ParsePolicyMapDrops(policyMapCounters)
+	assert.Equal(t, interfaces.Drops{
+		"GigabitEthernet1/0/1 output priority":      4,
+		"GigabitEthernet1/0/1 output class-default": 17,
+		"Port-channel1 output class-default":        9,
+	}, before)
+
+	// Counters that went down were cleared
+	after := interfaces.Drops{
+		"GigabitEthernet1/0/1 output priority":      4,
+		"GigabitEthernet1/0/1 output class-default": 20,
+		"Port-channel1 output class-default":        2,
+		"GigabitEthernet1/0/2 input":                1,
+	}
+	increase := before.Increase(after)
+	assert.Equal(t, interfaces.Drops{
+		"GigabitEthernet1/0/1 output class-default": 3,
+		"Port-channel1 output class-default":        2,
+		"GigabitEthernet1/0/2 input":                1,
+	}, increase)
+	assert.Equal(t, uint64(6), increase.Total())
+	assert.Equal(t, interfaces.Drops{
+		"GigabitEthernet1/0/1 output class-default": 3,
+		"Port-channel1 output class-default":        2,
+	}, increase.Classes(map[string]bool{"class-default": true}))
+}
+
+// newRolloutConfig returns an inventory with a canary, two access switches
+// and a core switch grouped by the maintenance settings
+func newRolloutConfig() (config.RolloutConfig, config.MaintenanceConfig) {
+	return config.RolloutConfig{
+		Inventory: []config.InventoryDeviceConfig{
+			{Host: "access-sw1", Groups: []string{"canary"}},
+			{Host: "access-sw2"},
+			{Host: "access-sw3", Port: "2222", User: "rollout"},
+			{Host: "core-sw1"},
+		},
+		Waves: []config.RolloutWaveConfig{
+			{Name: "canary", Groups: []string{"canary"}, Soak: time.Minute},
+			{Name: "access", Devices: []string{"ACCESS-*"}},
+		},
+		Soak: 5 * time.Minute,
+	}, config.MaintenanceConfig{
+		Groups: map[string][]string{"core": {"core-sw1"}, "floor1": {"access-sw1"}},
+	}
+}
+
+func TestRolloutPlan(t *testing.T) {
+	rolloutConfig, maintenanceConfig := newRolloutConfig()
+	waves, err := rolloutConfig.Plan(maintenanceConfig)
+	require.NoError(t, err)
+	require.Len(t, waves, 3)
+
+	assert.Equal(t, "canary", waves[0].Name)
+	assert.Equal(t, time.Minute, waves[0].Soak)
+	assert.Equal(t, []maintenance.Target{{Host: "access-sw1", Groups: []string{"canary", "floor1"}}}, waves[0].Targets)
+
+	assert.Equal(t, "access", waves[1].Name)
+	assert.Equal(t, 5*time.Minute, waves[1].Soak)
+	require.Len(t, waves[1].Targets, 2)
+	assert.Equal(t, "access-sw2", waves[1].Targets[0].Host)
+	assert.Equal(t, "access-sw3", waves[1].Targets[1].Host)
+
+	// Devices no wave selects go last
+	assert.Equal(t, "remaining", waves[2].Name)
+	assert.Equal(t, []maintenance.Target{{Host: "core-sw1", Groups: []string{"core"}}}, waves[2].Targets)
+
+	ssh := rolloutConfig.SSH(config.SSHConfig{Host: "default", Port: "22", User: "admin"}, "access-sw3")
+	assert.Equal(t, config.SSHConfig{Host: "access-sw3", Port: "2222", User: "rollout"}, ssh)
+	ssh = rolloutConfig.SSH(config.SSHConfig{Host: "default", Port: "22", User: "admin"}, "core-sw1")
+	assert.Equal(t, config.SSHConfig{Host: "core-sw1", Port: "22", User: "admin"}, ssh)
+
+	rolloutConfig.Waves = append(rolloutConfig.Waves, config.RolloutWaveConfig{Name: "dist", Groups: []string{"dist"}})
+	_, err = rolloutConfig.Plan(maintenanceConfig)
+	assert.ErrorContains(t, err, "wave dist selects no devices")
+
+	_, err = config.RolloutConfig{}.Plan(maintenanceConfig)
+	assert.Error(t, err)
+}
+
+// rolloutSwitch is a switch whose running configuration grows with the
+// configuration pushed to it and whose drop counters grow on every read: the
+// pushed class CM_EF by Drops, the uplink and class-default by Background
+type rolloutSwitch struct {
+	PushError error
+	// Ignore drops pushed configuration, as if the switch rejected it quietly
+	Ignore     bool
+	Drops      uint64
+	Background uint64
+	// Running is configuration the switch has before any push
+	Running string
+	// PortDrops are output drops counted on every port while soaking
+	PortDrops uint64
+
+	mu         sync.Mutex
+	pushed     []string
+	reads      int
+	dropped    uint64
+	background uint64
+	portDrops  uint64
+	closed     bool
+}
+
+func (s *rolloutSwitch) FetchRunningConfig() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Ignore {
+		return "hostname sw\n" + s.Running, nil
+	}
+	return "hostname sw\n" + s.Running + strings.Join(s.pushed, ""), nil
+}
+
+func (s *rolloutSwitch) FetchDrops() (interfaces.Drops, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reads > 0 {
+		s.dropped += s.Drops
+		s.background += s.Background
+		s.portDrops += s.PortDrops
+	}
+	s.reads++
+	return interfaces.Drops{
+		"GigabitEthernet1/0/1 output CM_EF":         s.dropped,
+		"GigabitEthernet1/0/1 output class-default": s.background,
+		"GigabitEthernet1/0/2 output":               s.portDrops,
+		"GigabitEthernet1/0/3 output":               s.portDrops,
+		"TenGigabitEthernet1/1/1 input":             s.background,
+	}, nil
+}
+
+func (s *rolloutSwitch) PushConfig(configCommands string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.PushError != nil {
+		return s.PushError
+	}
+	s.pushed = append(s.pushed, configCommands)
+	return nil
+}
+
+func (s *rolloutSwitch) SaveConfig() error { return nil }
+
+func (s *rolloutSwitch) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Pushes returns the number of pushes received
+func (s *rolloutSwitch) Pushes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pushed)
+}
+
+func TestRollout(t *testing.T) {
+	waves := []rollout.Wave{
+		{Name: "canary", Targets: []maintenance.Target{{Host: "sw1"}}},
+		{Name: "access", Targets: []maintenance.Target{{Host: "sw2"}, {Host: "sw3"}, {Host: "sw4"}}},
+		{Name: "core", Targets: []maintenance.Target{{Host: "sw5"}}},
+	}
+	fleet := func() (map[string]*rolloutSwitch, rollout.Dialer) {
+		switches := make(map[string]*rolloutSwitch)
+		for _, wave := range waves {
+			for _, target := range wave.Targets {
+				switches[target.Host] = &rolloutSwitch{}
+			}
+		}
+		return switches, func(target maintenance.Target) (rollout.Device, error) {
+			return switches[target.Host], nil
+		}
+	}
+	statuses := func(report rollout.Report) []rollout.Status {
+		var result []rollout.Status
+		for _, wave := range report.Waves {
+			result = append(result, wave.Status)
+		}
+		return result
+	}
+
+	t.Run("Completed", func(t *testing.T) {
+		switches, dial := fleet()
+		var stages []string
+		report, err := rollout.Run(context.Background(), rollout.Options{
+			Waves:    waves,
+			Parallel: 2,
+			Observe:  func(event rollout.Event) { stages = append(stages, event.Stage) },
+		}, baseChangeConfig, dial)
+		require.NoError(t, err)
+		assert.Equal(t, rollout.StatusCompleted, report.Status)
+		assert.Equal(t, []rollout.Status{rollout.StatusPassed, rollout.StatusPassed, rollout.StatusPassed}, statuses(report))
+		for host, sw := range switches {
+			assert.Equal(t, 1, sw.Pushes(), host)
+			assert.True(t, sw.closed, host)
+		}
+		assert.Equal(t, "started", stages[0])
+		assert.Equal(t, "completed", stages[len(stages)-1])
+	})
+
+	t.Run("Canary push fails", func(t *testing.T) {
+		switches, dial := fleet()
+		switches["sw1"].PushError = errors.New("% Invalid input detected")
+		report, err := rollout.Run(context.Background(), rollout.Options{Waves: waves}, baseChangeConfig, dial)
+		require.ErrorIs(t, err, rollout.ErrHalted)
+		assert.Equal(t, rollout.StatusHalted, report.Status)
+		assert.Contains(t, report.Reason, "wave canary")
+		assert.Equal(t, []rollout.Status{rollout.StatusFailed, rollout.StatusSkipped, rollout.StatusSkipped}, statuses(report))
+		assert.Contains(t, report.Waves[0].Devices[0].Error, "Invalid input")
+		assert.Zero(t, switches["sw2"].Pushes())
+	})
+
+	t.Run("Configuration not applied", func(t *testing.T) {
+		switches, dial := fleet()
+		switches["sw1"].Ignore = true
+		report, err := rollout.Run(context.Background(), rollout.Options{Waves: waves}, baseChangeConfig, dial)
+		require.ErrorIs(t, err, rollout.ErrHalted)
+		assert.Contains(t, report.Waves[0].Devices[0].Error, "class-maps not applied: CM_EF")
+		assert.Zero(t, switches["sw2"].Pushes())
+	})
+
+	t.Run("Drops while soaking", func(t *testing.T) {
+		switches, dial := fleet()
+		switches["sw3"].Drops = 40
+		switches["sw4"].Drops = 5
+		// Drops outside the pushed classes do not count
+		switches["sw2"].Background = 1000
+		var done []rollout.Event
+		report, err := rollout.Run(context.Background(), rollout.Options{
+			Waves:    waves,
+			MaxDrops: 10,
+			Observe: func(event rollout.Event) {
+				if event.Stage == "done" {
+					done = append(done, event)
+				}
+			},
+		}, baseChangeConfig, dial)
+		require.ErrorIs(t, err, rollout.ErrHalted)
+		assert.Equal(t, []rollout.Status{rollout.StatusPassed, rollout.StatusFailed, rollout.StatusSkipped}, statuses(report))
+
+		access := report.Waves[1].Devices
+		assert.Equal(t, rollout.StatusPassed, access[0].Status)
+		assert.Equal(t, rollout.StatusFailed, access[1].Status)
+		assert.Contains(t, access[1].Error, "dropped 40 packets while soaking, 10 allowed")
+		assert.Equal(t, interfaces.Drops{"GigabitEthernet1/0/1 output CM_EF": 40}, access[1].Drops)
+		assert.Equal(t, rollout.StatusPassed, access[2].Status)
+		assert.Empty(t, access[0].Drops)
+		assert.Zero(t, switches["sw5"].Pushes())
+
+		// Final statuses are reported once each wave has soaked
+		require.Len(t, done, 4)
+		assert.Equal(t, rollout.Event{Wave: "canary", Host: "sw1", Stage: "done", Status: rollout.StatusPassed}, done[0])
+		assert.Equal(t, "sw3", done[2].Host)
+		assert.Equal(t, rollout.StatusFailed, done[2].Status)
+		assert.Contains(t, done[2].Error, "dropped 40 packets")
+	})
+
+	t.Run("Interface drops while soaking", func(t *testing.T) {
+		switches, dial := fleet()
+		// Only the port carrying the pushed policy-map counts
+		switches["sw1"].Running = "interface GigabitEthernet1/0/2\n service-policy output PM_MARK\n!\n"
+		switches["sw1"].PortDrops = 7
+		switches["sw2"].PortDrops = 1000
+
+		report, err := rollout.Run(context.Background(), rollout.Options{Waves: waves, MaxInterfaceDrops: 5}, baseChangeConfig, dial)
+		require.ErrorIs(t, err, rollout.ErrHalted)
+		canary := report.Waves[0].Devices[0]
+		assert.Equal(t, rollout.StatusFailed, canary.Status)
+		assert.Contains(t, canary.Error, "counted 7 output drops on policed interfaces while soaking, 5 allowed")
+		assert.Equal(t, interfaces.Drops{"GigabitEthernet1/0/2 output": 7}, canary.Drops)
+		assert.Zero(t, switches["sw2"].Pushes())
+
+		switches, dial = fleet()
+		switches["sw1"].Running = "interface GigabitEthernet1/0/2\n service-policy output PM_MARK\n!\n"
+		switches["sw1"].PortDrops = 7
+		switches["sw2"].PortDrops = 1000
+		_, err = rollout.Run(context.Background(), rollout.Options{Waves: waves, MaxInterfaceDrops: 10}, baseChangeConfig, dial)
+		require.NoError(t, err)
+	})
+
+	t.Run("Gate refuses a wave", func(t *testing.T) {
+		switches, dial := fleet()
+		report, err := rollout.Run(context.Background(), rollout.Options{
+			Waves: waves,
+			Gate: func(target maintenance.Target) error {
+				if target.Host == "sw3" {
+					return errors.New("push refused: outside the maintenance windows")
+				}
+				return nil
+			},
+		}, baseChangeConfig, dial)
+		require.ErrorIs(t, err, rollout.ErrHalted)
+		assert.Equal(t, []rollout.Status{rollout.StatusPassed, rollout.StatusFailed, rollout.StatusSkipped}, statuses(report))
+		assert.Zero(t, switches["sw2"].Pushes())
+		assert.Equal(t, rollout.StatusSkipped, report.Waves[1].Devices[0].Status)
+	})
+
+	t.Run("Cancelled while soaking", func(t *testing.T) {
+		_, dial := fleet()
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := []rollout.Wave{{Name: "canary", Targets: waves[0].Targets, Soak: time.Hour}, waves[1]}
+		report, err := rollout.Run(ctx, rollout.Options{
+			Waves: slow,
+			Observe: func(event rollout.Event) {
+				if event.Stage == "soaking" {
+					cancel()
+				}
+			},
+		}, baseChangeConfig, dial)
+		require.ErrorIs(t, err, rollout.ErrHalted)
+		assert.ErrorContains(t, err, context.Canceled.Error())
+		assert.Equal(t, rollout.StatusPushed, report.Waves[0].Devices[0].Status)
+		assert.Equal(t, rollout.StatusSkipped, report.Waves[1].Status)
+	})
+}
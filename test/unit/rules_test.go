@@ -0,0 +1,200 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/cache"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/rules"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// newConfigClassifier returns a classifier with an override and a rule as
+// loaded from the configuration file
+func newConfigClassifier(t *testing.T) *qos.Classifier {
+	t.Helper()
+	classifier := qos.NewClassifier(qos.CS1, 0.7)
+	classifier.AddPredefinedClassification("sip", qos.EF)
+	rule, err := qos.NewRule("video", ".*video.*", qos.AF41, 1)
+	require.NoError(t, err)
+	require.NoError(t, classifier.AddCustomRule(rule))
+	return classifier
+}
+
+func TestRulesManager(t *testing.T) {
+	dir := t.TempDir()
+	opts := rules.Options{
+		StoreFile: filepath.Join(dir, "rules.json"),
+		AuditFile: filepath.Join(dir, "audit.jsonl"),
+	}
+	c := cache.New(&config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 100})
+	c.Set("zoom", qos.Classification{Protocol: "zoom", Class: qos.AF21, Source: "ai"})
+	c.Set("gaming-video", qos.Classification{Protocol: "gaming-video", Class: qos.AF41, Source: "custom_rule"})
+
+	classifier := newConfigClassifier(t)
+	manager, err := rules.New(opts, classifier, c)
+	require.NoError(t, err)
+
+	t.Run("Overrides", func(t *testing.T) {
+		override, created, err := manager.SetOverride(rules.Override{Protocol: "Zoom", Class: qos.EF}, "alice")
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Equal(t, "zoom", override.Protocol)
+		assert.Equal(t, rules.OriginAPI, override.Origin)
+		assert.Equal(t, qos.EF, classifier.ClassifyProtocol("zoom").Class)
+		assert.False(t, c.Exists("zoom"))
+
+		_, created, err = manager.SetOverride(rules.Override{Protocol: "sip", Class: qos.AF21}, "alice")
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, qos.AF21, classifier.ClassifyProtocol("sip").Class)
+
+		_, _, err = manager.SetOverride(rules.Override{Protocol: "ssh", Class: "BOGUS"}, "alice")
+		assert.Error(t, err)
+
+		require.NoError(t, manager.DeleteOverride("sip", "alice"))
+		assert.Equal(t, qos.EF, classifier.ClassifyProtocol("sip").Class)
+		assert.ErrorIs(t, manager.DeleteOverride("sip", "alice"), rules.ErrReadOnly)
+		assert.ErrorIs(t, manager.DeleteOverride("ssh", "alice"), rules.ErrNotFound)
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		_, err := manager.CreateRule(qos.Rule{Name: "video", Pattern: "x", Class: qos.EF, Priority: 1, Enabled: true}, "bob")
+		assert.ErrorIs(t, err, rules.ErrExists)
+		_, err = manager.CreateRule(qos.Rule{Name: "bad", Pattern: "(", Class: qos.EF, Priority: 1, Enabled: true}, "bob")
+		assert.Error(t, err)
+
+		created, err := manager.CreateRule(qos.Rule{Name: "p2p", Pattern: "^torrent", Class: qos.CS1, Priority: 5, Enabled: true}, "bob")
+		require.NoError(t, err)
+		assert.Equal(t, rules.OriginAPI, created.Origin)
+		assert.Equal(t, "custom_rule", classifier.ClassifyProtocol("torrent-dht").Source)
+
+		_, err = manager.UpdateRule("video", qos.Rule{Pattern: ".*video.*", Class: qos.AF21, Priority: 1, Enabled: true}, "bob")
+		require.NoError(t, err)
+		assert.Equal(t, qos.AF21, classifier.ClassifyProtocol("webex-video").Class)
+		assert.False(t, c.Exists("gaming-video"))
+
+		list := manager.Rules()
+		require.Len(t, list, 2)
+		assert.Equal(t, "video", list[0].Name)
+		assert.Equal(t, "p2p", list[1].Name)
+
+		require.NoError(t, manager.DeleteRule("video", "bob"))
+		assert.Equal(t, qos.AF41, classifier.ClassifyProtocol("webex-video").Class)
+		assert.ErrorIs(t, manager.DeleteRule("video", "bob"), rules.ErrReadOnly)
+		_, err = manager.UpdateRule("missing", qos.Rule{Pattern: "x", Class: qos.EF, Priority: 1}, "bob")
+		assert.ErrorIs(t, err, rules.ErrNotFound)
+	})
+
+	t.Run("Audit trail", func(t *testing.T) {
+		history := manager.History(0)
+		require.Len(t, history, 6)
+		assert.Equal(t, rules.ActionDelete, history[0].Action)
+		assert.Equal(t, "bob", history[0].Actor)
+		assert.Equal(t, rules.KindOverride, history[5].Kind)
+		assert.Empty(t, history[5].Before)
+		assert.Len(t, manager.History(2), 2)
+
+		data, err := os.ReadFile(opts.AuditFile)
+		require.NoError(t, err)
+		assert.Equal(t, 6, strings.Count(string(data), "\n"))
+	})
+
+	t.Run("Persistence", func(t *testing.T) {
+		reloaded := newConfigClassifier(t)
+		restored, err := rules.New(opts, reloaded, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, qos.EF, reloaded.ClassifyProtocol("zoom").Class)
+		assert.Equal(t, qos.CS1, reloaded.ClassifyProtocol("torrent-dht").Class)
+		assert.Equal(t, qos.AF41, reloaded.ClassifyProtocol("webex-video").Class)
+		assert.Len(t, restored.History(0), 6)
+
+		override, err := restored.Override("zoom")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", override.UpdatedBy)
+	})
+}
+
+func TestWebRules(t *testing.T) {
+	manager, err := rules.New(rules.Options{}, newConfigClassifier(t), nil)
+	require.NoError(t, err)
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{Rules: manager})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	send := func(method, path, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := send("POST", "/api/v1/rules", `{"name":"p2p","pattern":"^torrent","class":"cs1","priority":5}`)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "/api/v1/rules/p2p", resp.Header.Get("Location"))
+
+	var rule struct {
+		Class   string `json:"class"`
+		Enabled bool   `json:"enabled"`
+		Origin  string `json:"origin"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/rules/p2p", http.StatusOK, &rule)
+	assert.Equal(t, "CS1", rule.Class)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, "api", rule.Origin)
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/api/v1/rules", `{"name":"p2p","pattern":"x","class":"EF","priority":1}`, http.StatusConflict},
+		{"POST", "/api/v1/rules", `{"name":"bad","pattern":"x","class":"EF","priority":0}`, http.StatusBadRequest},
+		{"POST", "/api/v1/rules", `{"name":"bad","unknown":true}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/rules/p2p", `{"name":"other","pattern":"x","class":"EF","priority":1}`, http.StatusBadRequest},
+		{"PUT", "/api/v1/rules/video", `{"pattern":"video","class":"AF21","priority":1}`, http.StatusOK},
+		{"DELETE", "/api/v1/rules/missing", ``, http.StatusNotFound},
+		{"DELETE", "/api/v1/rules/p2p", ``, http.StatusNoContent},
+		{"PUT", "/api/v1/overrides/zoom", `{"class":"EF","description":"exec calls"}`, http.StatusCreated},
+		{"PUT", "/api/v1/overrides/zoom", `{"class":"AF41"}`, http.StatusOK},
+		{"PUT", "/api/v1/overrides/bad name", `{"class":"AF41"}`, http.StatusBadRequest},
+		{"DELETE", "/api/v1/overrides/sip", ``, http.StatusConflict},
+	} {
+		resp := send(tc.method, tc.path, tc.body)
+		resp.Body.Close()
+		assert.Equal(t, tc.status, resp.StatusCode, "%s %s", tc.method, tc.path)
+	}
+
+	var overrides struct {
+		Overrides []rules.Override `json:"overrides"`
+		Total     int              `json:"total"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/overrides?origin=api", http.StatusOK, &overrides)
+	require.Equal(t, 1, overrides.Total)
+	assert.Equal(t, qos.AF41, overrides.Overrides[0].Class)
+	getJSON(t, "GET", ts.URL+"/api/v1/overrides?class=EF", http.StatusOK, &overrides)
+	assert.Equal(t, 1, overrides.Total)
+
+	var audit struct {
+		Changes []json.RawMessage `json:"changes"`
+		Count   int               `json:"count"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/audit?kind=override", http.StatusOK, &audit)
+	assert.Equal(t, 2, audit.Count)
+	getJSON(t, "GET", ts.URL+"/api/v1/audit", http.StatusOK, &audit)
+	assert.Equal(t, 5, audit.Count)
+}
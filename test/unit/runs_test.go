@@ -0,0 +1,205 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/runs"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// waitForRun waits until a run reaches a status
+func waitForRun(t *testing.T, manager *runs.Manager, id string, status runs.Status) runs.Run {
+	t.Helper()
+	var run runs.Run
+	require.Eventually(t, func() bool {
+		var err error
+		run, err = manager.Get(id)
+		require.NoError(t, err)
+		return run.Status == status
+	}, 2*time.Second, 5*time.Millisecond)
+	return run
+}
+
+func TestRunManager(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "runs.json")
+	release := make(chan struct{})
+	var requests []runs.Request
+
+	execute := func(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+		requests = append(requests, req)
+		tracker.Stage("classification")
+		tracker.Protocols(len(req.Protocols))
+		tracker.Batch(runs.Batch{Index: 0, Total: 2, Protocols: req.Protocols, Provider: "test", Classified: len(req.Protocols)})
+		if req.Output == "block" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if req.Output == "fail" {
+			return errors.New("AI unavailable")
+		}
+		tracker.Classified(map[string]int{"EF": len(req.Protocols)})
+		tracker.Output("json", `{"ok":true}`)
+		return nil
+	}
+
+	manager, err := runs.New(runs.Options{HistoryFile: history, MaxQueued: 2, InputDir: "/srv/inputs"}, execute)
+	require.NoError(t, err)
+
+	// Validation
+	for _, req := range []runs.Request{
+		{Source: "ftp"},
+		{Source: runs.SourceInline},
+		{Source: runs.SourceFile},
+		{Source: runs.SourceSwitch, SaveConfig: true},
+	} {
+		_, err := manager.Submit(req)
+		assert.Error(t, err, req.Source)
+	}
+
+	run, err := manager.Submit(runs.Request{Source: "INLINE", Protocols: []string{"sip", "rtp"}})
+	require.NoError(t, err)
+	assert.Equal(t, runs.StatusQueued, run.Status)
+	run = waitForRun(t, manager, run.ID, runs.StatusSucceeded)
+	assert.Equal(t, "classification", run.Stage)
+	assert.Equal(t, runs.Progress{Protocols: 2, Classified: 2, BatchesDone: 1, BatchesTotal: 2}, run.Progress)
+	assert.Equal(t, "test", run.Batches[0].Provider)
+	assert.Equal(t, `{"ok":true}`, run.Outputs["json"])
+
+	failed, err := manager.Submit(runs.Request{Source: runs.SourceInline, Protocols: []string{"ssh"}, Output: "fail"})
+	require.NoError(t, err)
+	failed = waitForRun(t, manager, failed.ID, runs.StatusFailed)
+	assert.Equal(t, "AI unavailable", failed.Error)
+
+	// A running run is cancelled through its context, a queued one directly
+	blocking, err := manager.Submit(runs.Request{Source: runs.SourceSwitch, Output: "block"})
+	require.NoError(t, err)
+	waitForRun(t, manager, blocking.ID, runs.StatusRunning)
+	queued, err := manager.Submit(runs.Request{Source: "file", InputFile: "../../etc/passwd"})
+	require.NoError(t, err)
+	assert.Equal(t, "/srv/inputs/etc/passwd", queued.Request.InputFile)
+
+	cancelled, err := manager.Cancel(queued.ID)
+	require.NoError(t, err)
+	assert.Equal(t, runs.StatusCancelled, cancelled.Status)
+	_, err = manager.Cancel(blocking.ID)
+	require.NoError(t, err)
+	waitForRun(t, manager, blocking.ID, runs.StatusCancelled)
+
+	_, err = manager.Cancel(run.ID)
+	assert.ErrorIs(t, err, runs.ErrFinished)
+	_, err = manager.Get("missing")
+	assert.ErrorIs(t, err, runs.ErrNotFound)
+
+	list := manager.List()
+	require.Len(t, list, 4)
+	assert.Equal(t, queued.ID, list[0].ID)
+	assert.Len(t, requests, 3)
+
+	// Runs interrupted by a restart are marked failed
+	inflight, err := manager.Submit(runs.Request{Source: runs.SourceSwitch, Output: "block"})
+	require.NoError(t, err)
+	waitForRun(t, manager, inflight.ID, runs.StatusRunning)
+	data, err := json.Marshal(manager.List())
+	require.NoError(t, err)
+	manager.Close()
+	_, err = manager.Submit(runs.Request{Source: runs.SourceSwitch})
+	assert.ErrorIs(t, err, runs.ErrClosed)
+
+	var saved []runs.Run
+	require.NoError(t, json.Unmarshal(data, &saved))
+	saved[0].Status = runs.StatusRunning
+	data, err = json.Marshal(saved)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(history, data, 0600))
+
+	reopened, err := runs.New(runs.Options{HistoryFile: history, MaxHistory: 4}, execute)
+	require.NoError(t, err)
+	defer reopened.Close()
+	list = reopened.List()
+	require.Len(t, list, 4)
+	assert.Equal(t, runs.StatusFailed, list[0].Status)
+	assert.Equal(t, "interrupted by restart", list[0].Error)
+	restored, err := reopened.Get(failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "AI unavailable", restored.Error)
+	// The oldest finished run is dropped beyond MaxHistory
+	_, err = reopened.Get(run.ID)
+	assert.ErrorIs(t, err, runs.ErrNotFound)
+}
+
+func TestWebRuns(t *testing.T) {
+	manager, err := runs.New(runs.Options{}, func(ctx context.Context, req runs.Request, tracker *runs.Tracker) error {
+		tracker.Output("cisco", "class-map match-any QOS_EF\n")
+		return nil
+	})
+	require.NoError(t, err)
+	defer manager.Close()
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{Runs: manager})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	post := func(path, body string) *http.Response {
+		resp, err := http.Post(ts.URL+path, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := post("/api/v1/runs", `{"source":"inline","protocols":["sip"],"output":"cisco","dry_run":true}`)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var created struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "/api/v1/runs/"+created.ID, resp.Header.Get("Location"))
+
+	waitForRun(t, manager, created.ID, runs.StatusSucceeded)
+	var run struct {
+		Status  string   `json:"status"`
+		Outputs []string `json:"outputs"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/runs/"+created.ID, http.StatusOK, &run)
+	assert.Equal(t, "succeeded", run.Status)
+	assert.Equal(t, []string{"cisco"}, run.Outputs)
+
+	outputResp, err := http.Get(ts.URL + "/api/v1/runs/" + created.ID + "/outputs/cisco")
+	require.NoError(t, err)
+	defer outputResp.Body.Close()
+	assert.Equal(t, http.StatusOK, outputResp.StatusCode)
+
+	var list struct {
+		Total int `json:"total"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/runs?status=succeeded", http.StatusOK, &list)
+	assert.Equal(t, 1, list.Total)
+	getJSON(t, "GET", ts.URL+"/api/v1/runs?status=failed", http.StatusOK, &list)
+	assert.Equal(t, 0, list.Total)
+
+	var errorResponse map[string]interface{}
+	getJSON(t, "POST", ts.URL+"/api/v1/runs/"+created.ID+"/cancel", http.StatusConflict, &errorResponse)
+	getJSON(t, "GET", ts.URL+"/api/v1/runs/unknown", http.StatusNotFound, &errorResponse)
+	getJSON(t, "GET", ts.URL+"/api/v1/runs/"+created.ID+"/outputs/json", http.StatusNotFound, &errorResponse)
+
+	for _, body := range []string{`{"source":"ftp"}`, `{"source":"switch","unknown":1}`, `not json`} {
+		resp := post("/api/v1/runs", body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
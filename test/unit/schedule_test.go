@@ -0,0 +1,67 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/schedule"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, time.May, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"TZ=UTC */15 * * * *", time.Date(2024, time.May, 15, 10, 15, 0, 0, time.UTC)},
+		{"TZ=UTC 0 * * * *", time.Date(2024, time.May, 15, 11, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 30 2 * * *", time.Date(2024, time.May, 16, 2, 30, 0, 0, time.UTC)},
+		{"TZ=UTC 0 9-17/4 * * mon-fri", time.Date(2024, time.May, 15, 13, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 * * 7", time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 1 jan,jul *", time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC 0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week match when either does
+		{"TZ=UTC 0 0 20 * fri", time.Date(2024, time.May, 17, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC @daily", time.Date(2024, time.May, 16, 0, 0, 0, 0, time.UTC)},
+		{"TZ=UTC @weekly", time.Date(2024, time.May, 19, 0, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=America/New_York 0 6 * * *", time.Date(2024, time.May, 15, 10, 0, 0, 0, time.UTC).Add(24 * time.Hour)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sched, err := schedule.Parse(tt.spec)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(sched.Next(from)), "got %s", sched.Next(from))
+		})
+	}
+
+	never, err := schedule.Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(from).IsZero())
+}
+
+func TestScheduleParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * * funday",
+		"@fortnightly",
+		"@every soon",
+		"@every -1m",
+		"TZ=Mars/Olympus 0 * * * *",
+	} {
+		_, err := schedule.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,201 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/internal/logger"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/ssh/sshtest"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func loadC9300Transcript(t *testing.T) *sshtest.Transcript {
+	t.Helper()
+	transcript, err := sshtest.LoadTranscript(filepath.Join("testdata", "transcripts", "c9300-17.09.txt"))
+	require.NoError(t, err)
+	return transcript
+}
+
+func TestParseTranscript(t *testing.T) {
+	transcript, err := sshtest.ParseTranscript("sw1#show  version\nCisco IOS XE Software\n\nsw1#show clock\n*10:00:00 UTC\nsw1#\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, "sw1", transcript.Hostname)
+	assert.Equal(t, []string{"show version", "show clock"}, transcript.Commands())
+
+	output, exists := transcript.Output("show   version")
+	assert.True(t, exists)
+	assert.Equal(t, "Cisco IOS XE Software\n", output)
+
+	_, exists = transcript.Output("show inventory")
+	assert.False(t, exists)
+
+	_, err = sshtest.ParseTranscript("no prompt here\n")
+	assert.Error(t, err)
+
+	transcript = loadC9300Transcript(t)
+	assert.Equal(t, "c9300-lab", transcript.Hostname)
+	assert.Contains(t, transcript.Commands(), sshtest.ProtocolDiscoveryCommand)
+}
+
+func TestFakeDevice(t *testing.T) {
+	transcript := loadC9300Transcript(t)
+	device := sshtest.NewFakeDevice(transcript)
+
+	protocols, err := device.FetchProtocols()
+	require.NoError(t, err)
+	assert.Subset(t, protocols, []string{"ssl", "ms-teams", "dns", "bittorrent"})
+
+	ifaces, err := device.FetchInterfaceStatus()
+	require.NoError(t, err)
+	require.Len(t, ifaces, 4)
+	assert.Equal(t, "GigabitEthernet1/0/1", ifaces[0].Name)
+
+	running, err := device.FetchRunningConfig()
+	require.NoError(t, err)
+	assert.Contains(t, running, "service-policy input PM_MARK_AVC_WIRED_INGRESS")
+
+	require.NoError(t, device.PushConfig("class-map match-any CM_TEST"))
+	require.NoError(t, device.SaveConfig())
+	assert.Equal(t, []string{"class-map match-any CM_TEST"}, device.Pushed())
+	assert.Equal(t, 1, device.Saves())
+	assert.Equal(t, []string{sshtest.ProtocolDiscoveryCommand, sshtest.InterfaceStatusCommand, sshtest.RunningConfigCommand}, device.Commands())
+
+	device.PushError = errors.New("rejected")
+	assert.Error(t, device.PushConfig("bogus"))
+
+	empty := sshtest.NewFakeDevice(&sshtest.Transcript{Hostname: "empty"})
+	_, err = empty.FetchProtocols()
+	assert.Error(t, err)
+
+	require.NoError(t, device.Close())
+	_, err = device.FetchRunningConfig()
+	assert.Error(t, err)
+}
+
+func TestSSHClientAgainstServer(t *testing.T) {
+	server, err := sshtest.NewServer(loadC9300Transcript(t))
+	require.NoError(t, err)
+	defer server.Close()
+
+	log, err := logger.New(&config.LoggingConfig{Level: "error", Output: "stderr"})
+	require.NoError(t, err)
+
+	cfg := server.SSHConfig()
+	client, err := ssh.New(&cfg, log)
+	require.NoError(t, err)
+	defer client.Close()
+
+	protocols, err := client.FetchProtocols()
+	require.NoError(t, err)
+	assert.Subset(t, protocols, []string{"ssl", "ms-teams", "bittorrent"})
+
+	ifaces, err := client.FetchInterfaceStatus()
+	require.NoError(t, err)
+	assert.Len(t, ifaces, 4)
+
+	running, err := client.FetchRunningConfig()
+	require.NoError(t, err)
+	assert.Contains(t, running, "hostname c9300-lab")
+
+	drops, err := client.FetchDrops()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(15), drops.Total())
+	assert.Equal(t, uint64(3), drops["GigabitEthernet1/0/2 input"])
+
+	require.NoError(t, client.PushConfig("class-map match-any CM_TEST\n match protocol ssl"))
+	assert.Equal(t, []string{"class-map match-any CM_TEST\n match protocol ssl"}, server.Pushed())
+
+	server.RejectConfig("match protocol bogus")
+	err = client.PushConfig("class-map match-any CM_BAD\n match protocol bogus")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid input")
+	assert.Len(t, server.Pushed(), 1)
+
+	require.NoError(t, client.SaveConfig())
+	assert.Equal(t, 1, server.Saves())
+	assert.Contains(t, server.Commands(), sshtest.ProtocolDiscoveryCommand)
+}
+
+// TestProtocolDiscoveryGolden parses every capture in testdata/nbar and
+// compares the result with its .golden.json file
+func TestProtocolDiscoveryGolden(t *testing.T) {
+	captures, err := filepath.Glob(filepath.Join("testdata", "nbar", "*.txt"))
+	require.NoError(t, err)
+	require.NotEmpty(t, captures)
+
+	for _, capture := range captures {
+		capture := capture
+		t.Run(filepath.Base(capture), func(t *testing.T) {
+			data, err := os.ReadFile(capture)
+			require.NoError(t, err)
+
+			discovery, err := ssh.ParseProtocolDiscoveryStats(string(data))
+			require.NoError(t, err)
+			actual, err := json.MarshalIndent(discovery, "", "  ")
+			require.NoError(t, err)
+			actual = append(actual, '\n')
+
+			golden := strings.TrimSuffix(capture, ".txt") + ".golden.json"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, actual, 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestParseProtocolDiscovery(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "nbar", "c9500-17.06-portchannel-svi.txt"))
+	require.NoError(t, err)
+
+	discovery, err := ssh.ParseProtocolDiscoveryStats(string(data))
+	require.NoError(t, err)
+	require.Len(t, discovery.Interfaces, 3)
+	assert.Equal(t, "Port-channel1", discovery.Interfaces[0].Interface)
+	assert.Equal(t, "Port-channel10.100", discovery.Interfaces[1].Interface)
+	assert.Equal(t, "Vlan10", discovery.Interfaces[2].Interface)
+
+	// Long names push their counters onto the next line or wrap at a hyphen
+	office := discovery.Interfaces[0].Protocols[0]
+	assert.Equal(t, "ms-office-365", office.Name)
+	assert.Equal(t, uint64(9120441), office.InputPackets)
+	assert.Equal(t, uint64(24000000), office.OutputMaxBitRate)
+	assert.Equal(t, "cisco-collab-audio-video", discovery.Interfaces[1].Protocols[0].Name)
+
+	protocols, err := ssh.ParseProtocolDiscovery(string(data))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cisco-collab-audio-video", "http", "ms-lync-audio", "ms-office-365", "snmp"}, protocols)
+	traffic := discovery.Traffic()
+	assert.Len(t, traffic, len(protocols))
+	assert.Equal(t, office.InputBytes+office.OutputBytes, traffic["ms-office-365"])
+
+	// Pager artifacts do not shift the counters
+	data, err = os.ReadFile(filepath.Join("testdata", "nbar", "c9200-17.03-more.txt"))
+	require.NoError(t, err)
+	discovery, err = ssh.ParseProtocolDiscoveryStats(string(data))
+	require.NoError(t, err)
+	require.Len(t, discovery.Interfaces, 1)
+	require.Len(t, discovery.Interfaces[0].Protocols, 2)
+	assert.Equal(t, uint64(24000), discovery.Interfaces[0].Protocols[0].OutputBitRate)
+	assert.Equal(t, uint64(4100000), discovery.Interfaces[0].Protocols[1].OutputMaxBitRate)
+
+	_, err = ssh.ParseProtocolDiscovery("sw1#show ip nbar protocol-discovery\n                   ^\n% Invalid input detected at '^' marker.\n")
+	assert.Error(t, err)
+
+	protocols, err = ssh.ParseProtocolDiscovery("")
+	require.NoError(t, err)
+	assert.Empty(t, protocols)
+}
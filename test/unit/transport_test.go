@@ -0,0 +1,707 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/interfaces"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/render"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/transport"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseCLI(t *testing.T) {
+	renderer, err := render.NewCiscoRenderer(render.CiscoOptions{MarkingPolicyName: "PM_MARK"})
+	require.NoError(t, err)
+	queuing, err := qos.NewQueuingPolicy("PM_QUEUE", []qos.QueueDefinition{
+		{Class: qos.EF, PriorityLevel: 1, PolicePercent: 10},
+		{Class: qos.AF41, BandwidthRemainingPercent: 60},
+		{Class: qos.CS1, BandwidthRemainingPercent: 40},
+	}, qos.DefaultModel())
+	require.NoError(t, err)
+	config, err := renderer.Render(renderClassifications(), qos.DefaultModel(), qos.CS1, queuing)
+	require.NoError(t, err)
+	config += interfaces.RenderChanges([]interfaces.Change{
+		{Interface: "GigabitEthernet1/0/1", Direction: interfaces.Input, Policy: "PM_MARK"},
+		{Interface: "GigabitEthernet1/0/2", Direction: interfaces.Output, Policy: "PM_OLD", Remove: true},
+	})
+
+	parsed, err := transport.ParseCLI(config)
+	require.NoError(t, err)
+
+	assert.Equal(t, transport.ClassMap{
+		Name:        "QOS_EF",
+		Prematch:    "match-any",
+		Description: "Expedited Forwarding - Real-time traffic (Voice, Video calls)",
+		Protocols:   []string{"rtp", "sip"},
+	}, parsed.ClassMaps[0])
+
+	require.Len(t, parsed.PolicyMaps, 2)
+	marking := parsed.PolicyMaps[0]
+	assert.Equal(t, "PM_MARK", marking.Name)
+	assert.Equal(t, transport.PolicyClass{Name: "QOS_EF", SetDSCP: "ef"}, marking.Classes[0])
+	assert.Equal(t, "class-default", marking.Classes[len(marking.Classes)-1].Name)
+
+	queue := parsed.PolicyMaps[1]
+	assert.Equal(t, "PM_QUEUE", queue.Name)
+	assert.Equal(t, transport.PolicyClass{Name: "QOS_Q_EF", PriorityLevel: 1, PolicePercent: 10}, queue.Classes[0])
+	assert.Equal(t, transport.PolicyClass{Name: "class-default", BandwidthRemainingPercent: 40}, queue.Classes[2])
+
+	assert.Equal(t, []interfaces.Change{
+		{Interface: "GigabitEthernet1/0/1", Direction: interfaces.Input, Policy: "PM_MARK"},
+		{Interface: "GigabitEthernet1/0/2", Direction: interfaces.Output, Policy: "PM_OLD", Remove: true},
+	}, parsed.Attachments)
+
+	_, err = transport.ParseCLI("class-map match-any X\n match access-group 101\n")
+	assert.Error(t, err)
+	_, err = transport.ParseCLI("ip access-list extended X\n")
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"QOS_EF"}, transport.ChangedClassMaps(
+		[]transport.ClassMap{{Name: "QOS_EF", Prematch: "match-any", Protocols: []string{"sip"}}, parsed.ClassMaps[1]},
+		parsed.ClassMaps[:2],
+	))
+
+	running := transport.ParseRunningPolicyMaps("hostname sw1\n" + config + "policy-map PM_OTHER\n class class-default\n  shape average 1000000\n")
+	require.Len(t, running, 3)
+	assert.Equal(t, parsed.PolicyMaps, running[:2])
+	assert.Empty(t, transport.ChangedPolicyMaps(running, parsed.PolicyMaps))
+	running[1].Classes[0].PolicePercent = 20
+	assert.Equal(t, []string{"PM_QUEUE"}, transport.ChangedPolicyMaps(running, parsed.PolicyMaps))
+	assert.Equal(t, []string{"PM_MARK", "PM_QUEUE"}, transport.ChangedPolicyMaps(nil, parsed.PolicyMaps))
+}
+
+func TestTransportOptions(t *testing.T) {
+	_, err := transport.New(transport.Options{Type: "telnet", Host: "switch"})
+	assert.Error(t, err)
+	_, err = transport.New(transport.Options{Type: transport.TypeNETCONF, Host: "switch", Username: "admin"})
+	assert.Error(t, err)
+	_, err = transport.New(transport.Options{Type: transport.TypeRESTCONF, Host: "switch", Username: "admin", Password: "secret", ConfirmedCommit: true})
+	assert.Error(t, err)
+
+	netconf, err := transport.New(transport.Options{Type: transport.TypeNETCONF, Host: "switch", Username: "admin", Password: "secret"})
+	require.NoError(t, err)
+	assert.Equal(t, transport.TypeNETCONF, netconf.Name())
+	assert.NoError(t, netconf.Close())
+}
+
+// transportConfig is a small configuration used for push tests
+func transportConfig() *transport.QoSConfig {
+	return &transport.QoSConfig{
+		ClassMaps: []transport.ClassMap{
+			{Name: "QOS_EF", Prematch: "match-any", Description: "Voice", Protocols: []string{"rtp", "sip"}},
+			{Name: "QOS_Q_EF", Prematch: "match-any", DSCP: []string{"ef"}},
+		},
+		PolicyMaps: []transport.PolicyMap{{
+			Name: "PM_MARK",
+			Classes: []transport.PolicyClass{
+				{Name: "QOS_EF", SetDSCP: "ef"},
+				{Name: "class-default", SetDSCP: "default"},
+			},
+		}},
+		Attachments: []interfaces.Change{
+			{Interface: "GigabitEthernet1/0/1", Direction: interfaces.Input, Policy: "PM_MARK"},
+			{Interface: "GigabitEthernet1/0/2", Direction: interfaces.Input, Policy: "PM_OLD", Remove: true},
+		},
+	}
+}
+
+// NETCONF stub
+
+type stubClassMap struct {
+	XMLName     xml.Name `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-policy class-map"`
+	Operation   string   `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 operation,attr,omitempty"`
+	Name        string   `xml:"name"`
+	Prematch    string   `xml:"prematch"`
+	Description string   `xml:"description,omitempty"`
+	DSCP        []string `xml:"match>dscp,omitempty"`
+	Protocols   []string `xml:"match>protocol>protocols-list,omitempty"`
+}
+
+type stubPolicyMap struct {
+	XMLName   xml.Name `xml:"http://cisco.com/ns/yang/Cisco-IOS-XE-policy policy-map"`
+	Operation string   `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 operation,attr,omitempty"`
+	Name      string   `xml:"name"`
+	Classes   []struct {
+		Name    string `xml:"name"`
+		Actions []struct {
+			Type string `xml:"action-type"`
+			DSCP string `xml:"set>dscp>dscp-val,omitempty"`
+		} `xml:"action-list"`
+	} `xml:"class"`
+}
+
+type stubPolicyRef struct {
+	Operation string `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 operation,attr"`
+	Name      string `xml:",chardata"`
+}
+
+type stubInterface struct {
+	XMLName xml.Name
+	Name    string         `xml:"name"`
+	Input   *stubPolicyRef `xml:"service-policy>input"`
+	Output  *stubPolicyRef `xml:"service-policy>output"`
+}
+
+type stubDatastore struct {
+	ClassMaps   map[string]stubClassMap
+	PolicyMaps  map[string]stubPolicyMap
+	Attachments map[string]string
+}
+
+func newStubDatastore() stubDatastore {
+	return stubDatastore{ClassMaps: map[string]stubClassMap{}, PolicyMaps: map[string]stubPolicyMap{}, Attachments: map[string]string{}}
+}
+
+func (d stubDatastore) clone() stubDatastore {
+	c := newStubDatastore()
+	for k, v := range d.ClassMaps {
+		c.ClassMaps[k] = v
+	}
+	for k, v := range d.PolicyMaps {
+		c.PolicyMaps[k] = v
+	}
+	for k, v := range d.Attachments {
+		c.Attachments[k] = v
+	}
+	return c
+}
+
+// netconfStub emulates the NETCONF server of an IOS-XE switch
+type netconfStub struct {
+	mu           sync.Mutex
+	capabilities []string
+	failEdit     bool
+	running      stubDatastore
+	candidate    stubDatastore
+	rollback     *stubDatastore
+	operations   []string
+}
+
+func newNETCONFStub(capabilities ...string) *netconfStub {
+	return &netconfStub{capabilities: capabilities, running: newStubDatastore(), candidate: newStubDatastore()}
+}
+
+func (s *netconfStub) ops() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.operations...)
+}
+
+// start runs an SSH server with the netconf subsystem and returns its address
+func (s *netconfStub) start(t *testing.T) (string, string) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	require.NoError(t, err)
+
+	serverConfig := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "admin" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied")
+		},
+	}
+	serverConfig.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, channels, requests, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(requests)
+				for newChannel := range channels {
+					channel, channelRequests, err := newChannel.Accept()
+					if err != nil {
+						continue
+					}
+					go func() {
+						for req := range channelRequests {
+							ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "netconf"
+							_ = req.Reply(ok, nil)
+							if ok {
+								go s.serve(channel)
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	return host, port
+}
+
+// serve exchanges hello messages and answers RPCs on a session
+func (s *netconfStub) serve(channel ssh.Channel) {
+	defer channel.Close()
+	reader := bufio.NewReader(channel)
+
+	hello := `<hello xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><capabilities>`
+	for _, capability := range s.capabilities {
+		hello += "<capability>" + capability + "</capability>"
+	}
+	hello += "</capabilities><session-id>1</session-id></hello>]]>]]>"
+	if _, err := io.WriteString(channel, hello); err != nil {
+		return
+	}
+
+	clientHello, err := readUntil(reader, "]]>]]>")
+	if err != nil {
+		return
+	}
+	chunked := strings.Contains(clientHello, "base:1.1") && strings.Contains(strings.Join(s.capabilities, " "), "base:1.1")
+
+	for {
+		var message string
+		if chunked {
+			message, err = readChunked(reader)
+		} else {
+			message, err = readUntil(reader, "]]>]]>")
+		}
+		if err != nil {
+			return
+		}
+
+		reply, closeSession := s.handle(message)
+		if chunked {
+			_, err = fmt.Fprintf(channel, "\n#%d\n%s\n##\n", len(reply), reply)
+		} else {
+			_, err = io.WriteString(channel, reply+"]]>]]>")
+		}
+		if err != nil || closeSession {
+			return
+		}
+	}
+}
+
+func readUntil(reader *bufio.Reader, delimiter string) (string, error) {
+	var message []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		message = append(message, b)
+		if bytes.HasSuffix(message, []byte(delimiter)) {
+			return string(message[:len(message)-len(delimiter)]), nil
+		}
+	}
+}
+
+func readChunked(reader *bufio.Reader) (string, error) {
+	var message []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "##" {
+			return string(message), nil
+		}
+		size, err := strconv.Atoi(strings.TrimPrefix(line, "#"))
+		if err != nil {
+			return "", err
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return "", err
+		}
+		message = append(message, chunk...)
+	}
+}
+
+// handle answers a single RPC
+func (s *netconfStub) handle(message string) (string, bool) {
+	var rpc struct {
+		MessageID string `xml:"message-id,attr"`
+		Operation struct {
+			XMLName xml.Name
+			Inner   string `xml:",innerxml"`
+		} `xml:",any"`
+	}
+	if err := xml.Unmarshal([]byte(message), &rpc); err != nil {
+		return "<rpc-reply/>", true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	operation := rpc.Operation.XMLName.Local
+	if operation == "commit" && strings.Contains(rpc.Operation.Inner, "<confirmed/>") {
+		operation = "commit-confirmed"
+	}
+	s.operations = append(s.operations, operation)
+
+	reply := func(body string) (string, bool) {
+		return fmt.Sprintf(`<rpc-reply message-id="%s" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">%s</rpc-reply>`, rpc.MessageID, body), operation == "close-session"
+	}
+
+	switch operation {
+	case "get-config":
+		var data bytes.Buffer
+		data.WriteString(`<data><native xmlns="http://cisco.com/ns/yang/Cisco-IOS-XE-native"><policy>`)
+		for _, classMap := range s.running.ClassMaps {
+			classMap.Operation = ""
+			out, _ := xml.Marshal(classMap)
+			data.Write(out)
+		}
+		for _, policyMap := range s.running.PolicyMaps {
+			policyMap.Operation = ""
+			out, _ := xml.Marshal(policyMap)
+			data.Write(out)
+		}
+		data.WriteString(`</policy></native></data>`)
+		return reply(data.String())
+	case "edit-config":
+		if s.failEdit {
+			return reply(`<rpc-error><error-type>application</error-type><error-tag>invalid-value</error-tag><error-severity>error</error-severity><error-message>inconsistent value: Device refused one or more commands</error-message></rpc-error>`)
+		}
+		var edit struct {
+			Target struct {
+				Candidate *struct{} `xml:"candidate"`
+			} `xml:"target"`
+			Native struct {
+				ClassMaps  []stubClassMap  `xml:"policy>class-map"`
+				PolicyMaps []stubPolicyMap `xml:"policy>policy-map"`
+				Interfaces []stubInterface `xml:"interface>GigabitEthernet"`
+			} `xml:"config>native"`
+		}
+		if err := xml.Unmarshal([]byte("<edit-config>"+rpc.Operation.Inner+"</edit-config>"), &edit); err != nil {
+			return reply(`<rpc-error><error-type>rpc</error-type><error-tag>malformed-message</error-tag><error-severity>error</error-severity></rpc-error>`)
+		}
+		target := &s.running
+		if edit.Target.Candidate != nil {
+			target = &s.candidate
+		}
+		for _, classMap := range edit.Native.ClassMaps {
+			target.ClassMaps[classMap.Name] = classMap
+		}
+		for _, policyMap := range edit.Native.PolicyMaps {
+			target.PolicyMaps[policyMap.Name] = policyMap
+		}
+		for _, iface := range edit.Native.Interfaces {
+			for direction, ref := range map[string]*stubPolicyRef{"input": iface.Input, "output": iface.Output} {
+				if ref == nil {
+					continue
+				}
+				key := iface.XMLName.Local + iface.Name + " " + direction
+				if ref.Operation == "delete" {
+					delete(target.Attachments, key)
+				} else {
+					target.Attachments[key] = ref.Name
+				}
+			}
+		}
+		return reply("<ok/>")
+	case "commit-confirmed":
+		backup := s.running.clone()
+		s.rollback = &backup
+		s.running = s.candidate.clone()
+		return reply("<ok/>")
+	case "commit":
+		s.running = s.candidate.clone()
+		s.rollback = nil
+		return reply("<ok/>")
+	case "cancel-commit":
+		if s.rollback != nil {
+			s.running = *s.rollback
+			s.rollback = nil
+		}
+		return reply("<ok/>")
+	case "discard-changes":
+		s.candidate = s.running.clone()
+		return reply("<ok/>")
+	default:
+		return reply("<ok/>")
+	}
+}
+
+const (
+	netconfBase10    = "urn:ietf:params:netconf:base:1.0"
+	netconfBase11    = "urn:ietf:params:netconf:base:1.1"
+	netconfCandidate = "urn:ietf:params:netconf:capability:candidate:1.0"
+	netconfConfirmed = "urn:ietf:params:netconf:capability:confirmed-commit:1.1"
+	netconfValidate  = "urn:ietf:params:netconf:capability:validate:1.1"
+)
+
+func TestNETCONFConfirmedCommit(t *testing.T) {
+	stub := newNETCONFStub(netconfBase10, netconfBase11, netconfCandidate, netconfConfirmed, netconfValidate)
+	host, port := stub.start(t)
+
+	client, err := transport.New(transport.Options{
+		Type: transport.TypeNETCONF, Host: host, Port: port, Username: "admin", Password: "secret",
+		ConfirmedCommit: true, ConfirmTimeout: time.Minute,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Apply(context.Background(), transportConfig())
+	require.NoError(t, err)
+	assert.Equal(t, &transport.ApplyResult{
+		Transport: transport.TypeNETCONF, Datastore: "candidate", ConfirmedCommit: true,
+		ClassMaps: 2, PolicyMaps: 1, Attachments: 2,
+	}, result)
+	assert.Equal(t, []string{"lock", "edit-config", "validate", "commit-confirmed", "get-config", "commit", "unlock"}, stub.ops())
+
+	// Class-maps are replaced as a whole
+	assert.Equal(t, "replace", stub.candidate.ClassMaps["QOS_EF"].Operation)
+	assert.Equal(t, []string{"rtp", "sip"}, stub.running.ClassMaps["QOS_EF"].Protocols)
+	assert.Equal(t, "ef", stub.running.PolicyMaps["PM_MARK"].Classes[0].Actions[0].DSCP)
+	assert.Equal(t, map[string]string{"GigabitEthernet1/0/1 input": "PM_MARK"}, stub.running.Attachments)
+
+	classMaps, err := client.ClassMaps(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transport.ChangedClassMaps(classMaps, transportConfig().ClassMaps))
+
+	policyMaps, err := client.PolicyMaps(context.Background())
+	require.NoError(t, err)
+	require.Len(t, policyMaps, 1)
+	assert.Equal(t, transport.PolicyClass{Name: "QOS_EF", SetDSCP: "ef"}, policyMaps[0].Classes[0])
+
+	require.NoError(t, client.Save(context.Background()))
+	ops := stub.ops()
+	assert.Equal(t, "save-config", ops[len(ops)-1])
+}
+
+func TestNETCONFRunningDatastore(t *testing.T) {
+	// NETCONF 1.0 framing and no candidate datastore
+	stub := newNETCONFStub(netconfBase10)
+	host, port := stub.start(t)
+
+	opts := transport.Options{Type: transport.TypeNETCONF, Host: host, Port: port, Username: "admin", Password: "secret"}
+	client, err := transport.New(opts)
+	require.NoError(t, err)
+	defer client.Close()
+
+	result, err := client.Apply(context.Background(), transportConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "running", result.Datastore)
+	assert.Equal(t, []string{"lock", "edit-config", "unlock"}, stub.ops())
+	assert.Len(t, stub.running.ClassMaps, 2)
+
+	opts.ConfirmedCommit = true
+	confirmed, err := transport.New(opts)
+	require.NoError(t, err)
+	defer confirmed.Close()
+	_, err = confirmed.Apply(context.Background(), transportConfig())
+	assert.ErrorContains(t, err, "confirmed commit")
+
+	opts.Password = "wrong"
+	denied, err := transport.New(opts)
+	require.NoError(t, err)
+	_, err = denied.ClassMaps(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNETCONFEditError(t *testing.T) {
+	stub := newNETCONFStub(netconfBase10, netconfBase11, netconfCandidate)
+	stub.failEdit = true
+	host, port := stub.start(t)
+
+	client, err := transport.New(transport.Options{Type: transport.TypeNETCONF, Host: host, Port: port, Username: "admin", Password: "secret"})
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Apply(context.Background(), transportConfig())
+	require.Error(t, err)
+	var rpcErr *transport.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "invalid-value", rpcErr.Tag)
+	assert.Equal(t, []string{"lock", "edit-config", "discard-changes", "unlock"}, stub.ops())
+	assert.Empty(t, stub.running.ClassMaps)
+}
+
+// RESTCONF stub
+
+type restconfStub struct {
+	mu          sync.Mutex
+	classMaps   map[string]json.RawMessage
+	policyMaps  map[string]json.RawMessage
+	attachments map[string]string
+	requests    []string
+}
+
+func (s *restconfStub) handler(t *testing.T) http.Handler {
+	const policy = "/restconf/data/Cisco-IOS-XE-native:native/policy/Cisco-IOS-XE-policy:"
+	const iface = "/restconf/data/Cisco-IOS-XE-native:native/interface/"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		path := r.URL.EscapedPath()
+		s.requests = append(s.requests, r.Method+" "+path)
+
+		list := func(name string, entries map[string]json.RawMessage) {
+			if len(entries) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			var values []json.RawMessage
+			for _, entry := range entries {
+				values = append(values, entry)
+			}
+			w.Header().Set("Content-Type", "application/yang-data+json")
+			_ = json.NewEncoder(w).Encode(map[string][]json.RawMessage{"Cisco-IOS-XE-policy:" + name: values})
+		}
+		put := func(name string, entries map[string]json.RawMessage, key string) {
+			var body map[string][]json.RawMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			require.Len(t, body["Cisco-IOS-XE-policy:"+name], 1)
+			if key == "BAD" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = io.WriteString(w, `{"ietf-restconf:errors":{"error":[{"error-tag":"invalid-value","error-message":"inconsistent value"}]}}`)
+				return
+			}
+			entries[key] = body["Cisco-IOS-XE-policy:"+name][0]
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+		switch {
+		case r.Method == http.MethodGet && path == policy+"class-map":
+			list("class-map", s.classMaps)
+		case r.Method == http.MethodGet && path == policy+"policy-map":
+			list("policy-map", s.policyMaps)
+		case r.Method == http.MethodPut && strings.HasPrefix(path, policy+"class-map="):
+			put("class-map", s.classMaps, strings.TrimPrefix(path, policy+"class-map="))
+		case r.Method == http.MethodPut && strings.HasPrefix(path, policy+"policy-map="):
+			put("policy-map", s.policyMaps, strings.TrimPrefix(path, policy+"policy-map="))
+		case r.Method == http.MethodPatch && strings.HasPrefix(path, iface):
+			var body map[string]map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			name, _ := url.PathUnescape(strings.TrimPrefix(strings.TrimSuffix(path, "/Cisco-IOS-XE-policy:service-policy"), iface))
+			for direction, policyName := range body["Cisco-IOS-XE-policy:service-policy"] {
+				s.attachments[name+" "+direction] = policyName
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, iface):
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && path == "/restconf/operations/cisco-ia:save-config":
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, `{"cisco-ia:output":{"result":"Save running-config successful"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestRESTCONFApply(t *testing.T) {
+	stub := &restconfStub{classMaps: map[string]json.RawMessage{}, policyMaps: map[string]json.RawMessage{}, attachments: map[string]string{}}
+	server := httptest.NewTLSServer(stub.handler(t))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	opts := transport.Options{
+		Type: transport.TypeRESTCONF, Host: serverURL.Hostname(), Port: serverURL.Port(),
+		Username: "admin", Password: "secret", InsecureSkipVerify: true,
+	}
+	client, err := transport.New(opts)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// An empty datastore is reported as 404
+	classMaps, err := client.ClassMaps(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, classMaps)
+
+	result, err := client.Apply(context.Background(), transportConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "running", result.Datastore)
+	assert.Equal(t, []string{
+		"GET /restconf/data/Cisco-IOS-XE-native:native/policy/Cisco-IOS-XE-policy:class-map",
+		"PUT /restconf/data/Cisco-IOS-XE-native:native/policy/Cisco-IOS-XE-policy:class-map=QOS_EF",
+		"PUT /restconf/data/Cisco-IOS-XE-native:native/policy/Cisco-IOS-XE-policy:class-map=QOS_Q_EF",
+		"PUT /restconf/data/Cisco-IOS-XE-native:native/policy/Cisco-IOS-XE-policy:policy-map=PM_MARK",
+		"PATCH /restconf/data/Cisco-IOS-XE-native:native/interface/GigabitEthernet=1%2F0%2F1/Cisco-IOS-XE-policy:service-policy",
+		"DELETE /restconf/data/Cisco-IOS-XE-native:native/interface/GigabitEthernet=1%2F0%2F2/Cisco-IOS-XE-policy:service-policy/input",
+	}, stub.requests)
+	assert.JSONEq(t, `{"name":"QOS_EF","prematch":"match-any","description":"Voice","match":{"protocol":{"protocols-list":["rtp","sip"]}}}`, string(stub.classMaps["QOS_EF"]))
+	assert.JSONEq(t, `{"name":"PM_MARK","class":[{"name":"QOS_EF","action-list":[{"action-type":"set","set":{"dscp":{"dscp-val":"ef"}}}]},{"name":"class-default","action-list":[{"action-type":"set","set":{"dscp":{"dscp-val":"default"}}}]}]}`, string(stub.policyMaps["PM_MARK"]))
+	assert.Equal(t, map[string]string{"GigabitEthernet=1/0/1 input": "PM_MARK"}, stub.attachments)
+
+	classMaps, err = client.ClassMaps(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, transport.ChangedClassMaps(classMaps, transportConfig().ClassMaps))
+
+	require.NoError(t, client.Save(context.Background()))
+
+	_, err = client.Apply(context.Background(), &transport.QoSConfig{ClassMaps: []transport.ClassMap{{Name: "BAD", Prematch: "match-any"}}})
+	var statusErr *transport.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+	assert.Equal(t, "inconsistent value", statusErr.Message)
+
+	opts.Password = "wrong"
+	denied, err := transport.New(opts)
+	require.NoError(t, err)
+	_, err = denied.ClassMaps(context.Background())
+	assert.Error(t, err)
+}
+
+func TestParseRunningClassMaps(t *testing.T) {
+	running := "hostname sw1\r\n" +
+		"class-map match-any QOS_EF\r\n" +
+		" description Voice\r\n" +
+		" match protocol rtp\r\n" +
+		" match protocol sip\r\n" +
+		"class-map LEGACY\r\n" +
+		" match access-group 101\r\n" +
+		"!\r\n" +
+		"interface GigabitEthernet1/0/1\r\n" +
+		" match protocol ignored\r\n"
+
+	classMaps := transport.ParseRunningClassMaps(running)
+	require.Len(t, classMaps, 2)
+	assert.Equal(t, transport.ClassMap{
+		Name:        "QOS_EF",
+		Prematch:    "match-any",
+		Description: "Voice",
+		Protocols:   []string{"rtp", "sip"},
+	}, classMaps[0])
+	assert.Equal(t, "LEGACY", classMaps[1].Name)
+	assert.Equal(t, "match-all", classMaps[1].Prematch)
+	assert.Empty(t, classMaps[1].Protocols)
+}
@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/cache"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/config"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/qos"
+	"github.com/varuntirumala1/nbar-qos-classifier/pkg/web"
+)
+
+// newWebServer serves a web server with a cache, a classifier and a last run
+func newWebServer(t *testing.T) (*web.Server, *cache.Cache, *httptest.Server) {
+	t.Helper()
+	c := cache.New(&config.CacheConfig{Enabled: true, TTL: time.Hour, MaxSize: 100})
+	c.Set("netflix", qos.Classification{Protocol: "netflix", Class: qos.AF21, Confidence: 0.8, Source: "ai"})
+
+	classifier := qos.NewClassifier(qos.CS1, 0.7)
+	classifier.AddPredefinedClassification("ssh", qos.AF41)
+
+	server := web.New(&config.WebConfig{}, newTestLogger(t), web.Backend{
+		Version:    "test",
+		Cache:      c,
+		Classifier: classifier,
+	})
+	server.SetLastRun(&web.RunResult{
+		Source:    "file",
+		Protocols: []string{"sip", "rtp", "ssh", "bittorrent", "webex-meeting"},
+		Classifications: map[string]qos.Classification{
+			"sip":           {Protocol: "sip", Class: qos.EF, Confidence: 1, Source: "predefined"},
+			"rtp":           {Protocol: "rtp", Class: qos.EF, Confidence: 1, Source: "predefined"},
+			"ssh":           {Protocol: "ssh", Class: qos.AF41, Confidence: 1, Source: "predefined"},
+			"bittorrent":    {Protocol: "bittorrent", Class: qos.CS1, Confidence: 0.95, Source: "ai"},
+			"webex-meeting": {Protocol: "webex-meeting", Class: qos.EF, Confidence: 0.85, Source: "ai"},
+		},
+		StartedAt:  time.Now().Add(-time.Minute),
+		FinishedAt: time.Now(),
+	})
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return server, c, ts
+}
+
+// getJSON decodes the response of a request
+func getJSON(t *testing.T, method, url string, status int, v interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, status, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func TestWebClassifications(t *testing.T) {
+	_, _, ts := newWebServer(t)
+
+	type listResponse struct {
+		Classifications []qos.Classification `json:"classifications"`
+		Scope           string               `json:"scope"`
+		Count           int                  `json:"count"`
+		Total           int                  `json:"total"`
+	}
+	protocols := func(list listResponse) []string {
+		var names []string
+		for _, classification := range list.Classifications {
+			names = append(names, classification.Protocol)
+		}
+		return names
+	}
+
+	var list listResponse
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications", http.StatusOK, &list)
+	assert.Equal(t, "last_run", list.Scope)
+	assert.Equal(t, 5, list.Total)
+	assert.Equal(t, []string{"bittorrent", "rtp", "sip", "ssh", "webex-meeting"}, protocols(list))
+
+	list = listResponse{}
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications?class=ef&offset=1&limit=1", http.StatusOK, &list)
+	assert.Equal(t, 3, list.Total)
+	assert.Equal(t, 1, list.Count)
+	assert.Equal(t, []string{"sip"}, protocols(list))
+
+	list = listResponse{}
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications?source=ai&min_confidence=0.9", http.StatusOK, &list)
+	assert.Equal(t, []string{"bittorrent"}, protocols(list))
+
+	list = listResponse{}
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications?max_confidence=0.9&q=web", http.StatusOK, &list)
+	assert.Equal(t, []string{"webex-meeting"}, protocols(list))
+
+	list = listResponse{}
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications?scope=cache", http.StatusOK, &list)
+	assert.Equal(t, []string{"netflix"}, protocols(list))
+
+	list = listResponse{}
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications?offset=10", http.StatusOK, &list)
+	assert.Equal(t, 5, list.Total)
+	assert.Empty(t, list.Classifications)
+
+	var errorResponse map[string]interface{}
+	for _, query := range []string{"class=GOLD", "limit=0", "offset=-1", "min_confidence=2", "min_confidence=0.9&max_confidence=0.5", "scope=all"} {
+		getJSON(t, "GET", ts.URL+"/api/v1/classifications?"+query, http.StatusBadRequest, &errorResponse)
+		assert.NotEmpty(t, errorResponse["error"], query)
+	}
+
+	var classification qos.Classification
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications/SIP", http.StatusOK, &classification)
+	assert.Equal(t, qos.EF, classification.Class)
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications/netflix", http.StatusOK, &classification)
+	assert.Equal(t, "ai", classification.Source)
+	getJSON(t, "GET", ts.URL+"/api/v1/classifications/telnet", http.StatusOK, &classification)
+	assert.Equal(t, "default", classification.Source)
+	assert.Equal(t, qos.CS1, classification.Class)
+}
+
+func TestWebProtocolsAndCache(t *testing.T) {
+	server, c, ts := newWebServer(t)
+
+	var protocols struct {
+		Protocols []string `json:"protocols"`
+		Total     int      `json:"total"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/protocols?limit=2", http.StatusOK, &protocols)
+	assert.Equal(t, 5, protocols.Total)
+	assert.Equal(t, []string{"bittorrent", "rtp"}, protocols.Protocols)
+
+	var run struct {
+		Run struct {
+			Source    string   `json:"source"`
+			Protocols []string `json:"protocols"`
+		} `json:"run"`
+		Statistics map[string]int `json:"statistics"`
+	}
+	getJSON(t, "GET", ts.URL+"/api/v1/runs/last", http.StatusOK, &run)
+	assert.Equal(t, "file", run.Run.Source)
+	assert.Equal(t, 3, run.Statistics["EF"])
+
+	c.Get("netflix")
+	c.Get("missing")
+	var stats map[string]float64
+	getJSON(t, "GET", ts.URL+"/api/v1/cache/stats", http.StatusOK, &stats)
+	assert.Equal(t, 1.0, stats["size"])
+	assert.Equal(t, 1.0, stats["hits"])
+	assert.Equal(t, 0.5, stats["hit_rate"])
+
+	var cleared map[string]interface{}
+	getJSON(t, "POST", ts.URL+"/api/v1/cache/clear", http.StatusOK, &cleared)
+	assert.Equal(t, 1.0, cleared["cleared"])
+	assert.Equal(t, 0, c.Size())
+
+	var status map[string]interface{}
+	getJSON(t, "GET", ts.URL+"/api/v1/status", http.StatusOK, &status)
+	assert.Equal(t, "test", status["version"])
+	assert.Contains(t, status["components"], "classifier")
+
+	server.SetLastRun(nil)
+	var errorResponse map[string]interface{}
+	getJSON(t, "GET", ts.URL+"/api/v1/runs/last", http.StatusNotFound, &errorResponse)
+	getJSON(t, "GET", ts.URL+"/api/v1/protocols", http.StatusOK, &protocols)
+	assert.Zero(t, protocols.Total)
+}